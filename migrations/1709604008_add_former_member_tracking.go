@@ -0,0 +1,83 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		// Track unbilled months for memberships that were removed and later reinstated
+		memberships, err := dao.FindCollectionByNameOrId("memberships")
+		if err != nil {
+			return err
+		}
+
+		if memberships.Schema.GetFieldByName("inactive_periods") == nil {
+			memberships.Schema.AddField(&schema.SchemaField{
+				Name:     "inactive_periods",
+				Type:     schema.FieldTypeJson,
+				Required: false,
+				Options: &schema.JsonOptions{
+					MaxSize: 65536,
+				},
+			})
+
+			if err := dao.SaveCollection(memberships); err != nil {
+				return err
+			}
+		}
+
+		// Distinguish received payments from owner write-offs
+		payments, err := dao.FindCollectionByNameOrId("payments")
+		if err != nil {
+			return err
+		}
+
+		if payments.Schema.GetFieldByName("kind") == nil {
+			payments.Schema.AddField(&schema.SchemaField{
+				Name:     "kind",
+				Type:     schema.FieldTypeSelect,
+				Required: false,
+				Options: &schema.SelectOptions{
+					Values:    []string{"payment", "write_off"},
+					MaxSelect: 1,
+				},
+			})
+
+			if err := dao.SaveCollection(payments); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		memberships, err := dao.FindCollectionByNameOrId("memberships")
+		if err == nil {
+			if field := memberships.Schema.GetFieldByName("inactive_periods"); field != nil {
+				memberships.Schema.RemoveField(field.Id)
+				if err := dao.SaveCollection(memberships); err != nil {
+					return err
+				}
+			}
+		}
+
+		payments, err := dao.FindCollectionByNameOrId("payments")
+		if err == nil {
+			if field := payments.Schema.GetFieldByName("kind"); field != nil {
+				payments.Schema.RemoveField(field.Id)
+				if err := dao.SaveCollection(payments); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,127 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		links, err := dao.FindCollectionByNameOrId("member_claim_links")
+		if err != nil {
+			return err
+		}
+
+		// Add expiry and revocation timestamps to claim links
+		if links.Schema.GetFieldByName("expires_at") == nil {
+			links.Schema.AddField(&schema.SchemaField{
+				Name:     "expires_at",
+				Type:     schema.FieldTypeDate,
+				Required: false,
+			})
+		}
+
+		if links.Schema.GetFieldByName("revoked_at") == nil {
+			links.Schema.AddField(&schema.SchemaField{
+				Name:     "revoked_at",
+				Type:     schema.FieldTypeDate,
+				Required: false,
+			})
+		}
+
+		if err := dao.SaveCollection(links); err != nil {
+			return err
+		}
+
+		// Give links created before expiry existed a fresh week instead of expiring them immediately
+		expiresAt, err := types.ParseDateTime(time.Now().Add(7 * 24 * time.Hour))
+		if err != nil {
+			return err
+		}
+
+		if _, err := db.NewQuery(`
+			UPDATE member_claim_links
+			SET expires_at = {:expires_at}
+			WHERE expires_at IS NULL OR expires_at = ''
+		`).Bind(dbx.Params{"expires_at": expiresAt.String()}).Execute(); err != nil {
+			return err
+		}
+
+		if _, err := dao.FindCollectionByNameOrId("member_claim_attempts"); err == nil {
+			return nil
+		}
+
+		attempts := &models.Collection{
+			Name: "member_claim_attempts",
+			Type: models.CollectionTypeBase,
+			Schema: schema.NewSchema(
+				&schema.SchemaField{
+					Name:     "plan_id",
+					Type:     schema.FieldTypeText,
+					Required: false,
+				},
+				&schema.SchemaField{
+					Name:     "link_id",
+					Type:     schema.FieldTypeText,
+					Required: false,
+				},
+				&schema.SchemaField{
+					Name:     "artificial_member_id",
+					Type:     schema.FieldTypeText,
+					Required: false,
+				},
+				&schema.SchemaField{
+					Name:     "artificial_name",
+					Type:     schema.FieldTypeText,
+					Required: false,
+				},
+				&schema.SchemaField{
+					Name:     "user_id",
+					Type:     schema.FieldTypeText,
+					Required: false,
+				},
+				&schema.SchemaField{
+					Name:     "succeeded",
+					Type:     schema.FieldTypeBool,
+					Required: false,
+				},
+				&schema.SchemaField{
+					Name:     "reason",
+					Type:     schema.FieldTypeText,
+					Required: false,
+				},
+			),
+		}
+
+		return dao.SaveCollection(attempts)
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		if attempts, err := dao.FindCollectionByNameOrId("member_claim_attempts"); err == nil {
+			if err := dao.DeleteCollection(attempts); err != nil {
+				return err
+			}
+		}
+
+		links, err := dao.FindCollectionByNameOrId("member_claim_links")
+		if err != nil {
+			return nil
+		}
+
+		for _, name := range []string{"expires_at", "revoked_at"} {
+			if field := links.Schema.GetFieldByName(name); field != nil {
+				links.Schema.RemoveField(field.Id)
+			}
+		}
+
+		return dao.SaveCollection(links)
+	})
+}
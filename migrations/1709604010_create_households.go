@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		if _, err := dao.FindCollectionByNameOrId("households"); err == nil {
+			return nil
+		}
+
+		// Households group several family plans so balances can be settled together
+		households := &models.Collection{
+			Name: "households",
+			Type: models.CollectionTypeBase,
+			Schema: schema.NewSchema(
+				&schema.SchemaField{
+					Name:     "name",
+					Type:     schema.FieldTypeText,
+					Required: true,
+				},
+				&schema.SchemaField{
+					Name:     "owner",
+					Type:     schema.FieldTypeText,
+					Required: true,
+				},
+				&schema.SchemaField{
+					Name:     "plan_ids",
+					Type:     schema.FieldTypeJson,
+					Required: false,
+					Options: &schema.JsonOptions{
+						MaxSize: 65536,
+					},
+				},
+			),
+		}
+
+		return dao.SaveCollection(households)
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		households, err := dao.FindCollectionByNameOrId("households")
+		if err != nil {
+			return nil
+		}
+
+		return dao.DeleteCollection(households)
+	})
+}
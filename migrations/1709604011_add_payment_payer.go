@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		payments, err := dao.FindCollectionByNameOrId("payments")
+		if err != nil {
+			return err
+		}
+
+		// Record who handed over the money when it differs from the member being credited
+		if payments.Schema.GetFieldByName("payer_id") == nil {
+			payments.Schema.AddField(&schema.SchemaField{
+				Name:     "payer_id",
+				Type:     schema.FieldTypeText,
+				Required: false,
+			})
+
+			return dao.SaveCollection(payments)
+		}
+
+		return nil
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		payments, err := dao.FindCollectionByNameOrId("payments")
+		if err != nil {
+			return nil
+		}
+
+		if field := payments.Schema.GetFieldByName("payer_id"); field != nil {
+			payments.Schema.RemoveField(field.Id)
+			return dao.SaveCollection(payments)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,58 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		plans, err := dao.FindCollectionByNameOrId("family_plans")
+		if err != nil {
+			return err
+		}
+
+		// Store the ISO 4217 currency each plan is billed in
+		if plans.Schema.GetFieldByName("currency") == nil {
+			plans.Schema.AddField(&schema.SchemaField{
+				Name:     "currency",
+				Type:     schema.FieldTypeText,
+				Required: false,
+				Options: &schema.TextOptions{
+					Pattern: "^[A-Z]{3}$",
+				},
+			})
+
+			if err := dao.SaveCollection(plans); err != nil {
+				return err
+			}
+		}
+
+		// Existing plans were always billed in dollars
+		_, err = db.NewQuery(`
+			UPDATE family_plans
+			SET currency = 'USD'
+			WHERE currency IS NULL OR currency = ''
+		`).Execute()
+
+		return err
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		plans, err := dao.FindCollectionByNameOrId("family_plans")
+		if err != nil {
+			return nil
+		}
+
+		if field := plans.Schema.GetFieldByName("currency"); field != nil {
+			plans.Schema.RemoveField(field.Id)
+			return dao.SaveCollection(plans)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,128 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		payments, err := dao.FindCollectionByNameOrId("payments")
+		if err != nil {
+			return err
+		}
+
+		// Keep what was actually handed over when a payment was made in another currency
+		paymentFields := []*schema.SchemaField{
+			{
+				Name:     "original_amount",
+				Type:     schema.FieldTypeNumber,
+				Required: false,
+			},
+			{
+				Name:     "original_currency",
+				Type:     schema.FieldTypeText,
+				Required: false,
+				Options: &schema.TextOptions{
+					Pattern: "^[A-Z]{3}$",
+				},
+			},
+			{
+				Name:     "exchange_rate",
+				Type:     schema.FieldTypeNumber,
+				Required: false,
+			},
+		}
+
+		changed := false
+		for _, field := range paymentFields {
+			if payments.Schema.GetFieldByName(field.Name) == nil {
+				payments.Schema.AddField(field)
+				changed = true
+			}
+		}
+
+		if changed {
+			if err := dao.SaveCollection(payments); err != nil {
+				return err
+			}
+		}
+
+		if _, err := dao.FindCollectionByNameOrId("exchange_rates"); err == nil {
+			return nil
+		}
+
+		// Rates are either entered by a plan owner or loaded from a local rates file (empty plan_id)
+		exchangeRates := &models.Collection{
+			Name: "exchange_rates",
+			Type: models.CollectionTypeBase,
+			Schema: schema.NewSchema(
+				&schema.SchemaField{
+					Name:     "plan_id",
+					Type:     schema.FieldTypeText,
+					Required: false,
+				},
+				&schema.SchemaField{
+					Name:     "base",
+					Type:     schema.FieldTypeText,
+					Required: true,
+					Options: &schema.TextOptions{
+						Pattern: "^[A-Z]{3}$",
+					},
+				},
+				&schema.SchemaField{
+					Name:     "quote",
+					Type:     schema.FieldTypeText,
+					Required: true,
+					Options: &schema.TextOptions{
+						Pattern: "^[A-Z]{3}$",
+					},
+				},
+				&schema.SchemaField{
+					Name:     "rate",
+					Type:     schema.FieldTypeNumber,
+					Required: true,
+				},
+				&schema.SchemaField{
+					Name:     "source",
+					Type:     schema.FieldTypeText,
+					Required: false,
+				},
+			),
+		}
+
+		return dao.SaveCollection(exchangeRates)
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		if exchangeRates, err := dao.FindCollectionByNameOrId("exchange_rates"); err == nil {
+			if err := dao.DeleteCollection(exchangeRates); err != nil {
+				return err
+			}
+		}
+
+		payments, err := dao.FindCollectionByNameOrId("payments")
+		if err != nil {
+			return nil
+		}
+
+		changed := false
+		for _, name := range []string{"original_amount", "original_currency", "exchange_rate"} {
+			if field := payments.Schema.GetFieldByName(name); field != nil {
+				payments.Schema.RemoveField(field.Id)
+				changed = true
+			}
+		}
+
+		if changed {
+			return dao.SaveCollection(payments)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,138 @@
+package migrations
+
+import (
+	"math"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"golang.org/x/text/currency"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		if err := rescaleMoneyColumns(db, false); err != nil {
+			return err
+		}
+
+		// Amounts are whole minor units (cents, yen, ...) from here on
+		return setMoneyFieldsNoDecimal(daos.New(db), true)
+	}, func(db dbx.Builder) error {
+		if err := setMoneyFieldsNoDecimal(daos.New(db), false); err != nil {
+			return err
+		}
+
+		return rescaleMoneyColumns(db, true)
+	})
+}
+
+// moneyFields lists the number fields that hold money, by collection.
+var moneyFields = map[string][]string{
+	"family_plans": {"cost", "individual_cost"},
+	"payments":     {"amount", "original_amount"},
+}
+
+// rescaleMoneyColumns converts stored amounts between major units and the minor units of each currency.
+func rescaleMoneyColumns(db dbx.Builder, toMajor bool) error {
+	plans := []struct {
+		ID       string `db:"id"`
+		Currency string `db:"currency"`
+	}{}
+	if err := db.NewQuery("SELECT id, currency FROM family_plans").All(&plans); err != nil {
+		return err
+	}
+
+	for _, plan := range plans {
+		params := dbx.Params{"id": plan.ID, "scale": minorUnitScale(plan.Currency)}
+
+		if _, err := db.NewQuery(rescaleExpression("family_plans", []string{"cost", "individual_cost"}, "id = {:id}", toMajor)).Bind(params).Execute(); err != nil {
+			return err
+		}
+		if _, err := db.NewQuery(rescaleExpression("payments", []string{"amount"}, "plan_id = {:id}", toMajor)).Bind(params).Execute(); err != nil {
+			return err
+		}
+	}
+
+	originalCurrencies := []struct {
+		Code string `db:"original_currency"`
+	}{}
+	err := db.NewQuery(`
+		SELECT DISTINCT original_currency FROM payments
+		WHERE original_currency IS NOT NULL AND original_currency != ''
+	`).All(&originalCurrencies)
+	if err != nil {
+		return err
+	}
+
+	for _, original := range originalCurrencies {
+		params := dbx.Params{"code": original.Code, "scale": minorUnitScale(original.Code)}
+		if _, err := db.NewQuery(rescaleExpression("payments", []string{"original_amount"}, "original_currency = {:code}", toMajor)).Bind(params).Execute(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func rescaleExpression(table string, columns []string, where string, toMajor bool) string {
+	query := "UPDATE " + table + " SET "
+	for i, column := range columns {
+		if i > 0 {
+			query += ", "
+		}
+
+		if toMajor {
+			query += column + " = " + column + " * 1.0 / {:scale}"
+		} else {
+			query += column + " = CAST(ROUND(" + column + " * {:scale}) AS INTEGER)"
+		}
+	}
+
+	return query + " WHERE " + where
+}
+
+func setMoneyFieldsNoDecimal(dao *daos.Dao, noDecimal bool) error {
+	for collectionName, fieldNames := range moneyFields {
+		collection, err := dao.FindCollectionByNameOrId(collectionName)
+		if err != nil {
+			return err
+		}
+
+		setNoDecimal(collection, fieldNames, noDecimal)
+
+		if err := dao.SaveCollection(collection); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setNoDecimal(collection *models.Collection, fieldNames []string, noDecimal bool) {
+	for _, name := range fieldNames {
+		field := collection.Schema.GetFieldByName(name)
+		if field == nil {
+			continue
+		}
+
+		options, ok := field.Options.(*schema.NumberOptions)
+		if !ok || options == nil {
+			options = &schema.NumberOptions{}
+		}
+		options.NoDecimal = noDecimal
+		field.Options = options
+	}
+}
+
+// minorUnitScale is 10 to the power of the currency's minor-unit digits, defaulting to cents.
+func minorUnitScale(code string) float64 {
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		return 100
+	}
+
+	digits, _ := currency.Standard.Rounding(unit)
+	return math.Pow10(digits)
+}
@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		if _, err := dao.FindCollectionByNameOrId("adjustments"); err == nil {
+			return nil
+		}
+
+		// Owner-entered credits (positive) and charges (negative) in minor units.
+		// An empty user_id applies the adjustment to every member active in for_month.
+		adjustments := &models.Collection{
+			Name: "adjustments",
+			Type: models.CollectionTypeBase,
+			Schema: schema.NewSchema(
+				&schema.SchemaField{
+					Name:     "plan_id",
+					Type:     schema.FieldTypeText,
+					Required: true,
+				},
+				&schema.SchemaField{
+					Name:     "user_id",
+					Type:     schema.FieldTypeText,
+					Required: false,
+				},
+				&schema.SchemaField{
+					Name:     "amount",
+					Type:     schema.FieldTypeNumber,
+					Required: true,
+					Options: &schema.NumberOptions{
+						NoDecimal: true,
+					},
+				},
+				&schema.SchemaField{
+					Name:     "reason",
+					Type:     schema.FieldTypeText,
+					Required: true,
+				},
+				&schema.SchemaField{
+					Name:     "for_month",
+					Type:     schema.FieldTypeDate,
+					Required: true,
+				},
+				&schema.SchemaField{
+					Name:     "created_by",
+					Type:     schema.FieldTypeText,
+					Required: false,
+				},
+			),
+		}
+
+		return dao.SaveCollection(adjustments)
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		adjustments, err := dao.FindCollectionByNameOrId("adjustments")
+		if err != nil {
+			return nil
+		}
+
+		return dao.DeleteCollection(adjustments)
+	})
+}
@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		plans, err := dao.FindCollectionByNameOrId("family_plans")
+		if err != nil {
+			return err
+		}
+
+		// Free months given to new members, starting with the month they join
+		if plans.Schema.GetFieldByName("trial_months") == nil {
+			plans.Schema.AddField(&schema.SchemaField{
+				Name:     "trial_months",
+				Type:     schema.FieldTypeNumber,
+				Required: false,
+				Options: &schema.NumberOptions{
+					Min:       types.Pointer(0.0),
+					NoDecimal: true,
+				},
+			})
+
+			if err := dao.SaveCollection(plans); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		plans, err := dao.FindCollectionByNameOrId("family_plans")
+		if err != nil {
+			return nil
+		}
+
+		if field := plans.Schema.GetFieldByName("trial_months"); field != nil {
+			plans.Schema.RemoveField(field.Id)
+			return dao.SaveCollection(plans)
+		}
+
+		return nil
+	})
+}
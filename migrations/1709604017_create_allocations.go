@@ -0,0 +1,75 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		if _, err := dao.FindCollectionByNameOrId("allocations"); err == nil {
+			return nil
+		}
+
+		// Which payment or credit covers which month of a member's charges, in minor units.
+		// Rows are rebuilt whenever the member's payments, adjustments or the plan's membership change.
+		allocations := &models.Collection{
+			Name: "allocations",
+			Type: models.CollectionTypeBase,
+			Schema: schema.NewSchema(
+				&schema.SchemaField{
+					Name:     "plan_id",
+					Type:     schema.FieldTypeText,
+					Required: true,
+				},
+				&schema.SchemaField{
+					Name:     "user_id",
+					Type:     schema.FieldTypeText,
+					Required: true,
+				},
+				&schema.SchemaField{
+					Name:     "month",
+					Type:     schema.FieldTypeText,
+					Required: true,
+					Options: &schema.TextOptions{
+						Pattern: `^\d{4}-\d{2}$`,
+					},
+				},
+				&schema.SchemaField{
+					Name:     "amount",
+					Type:     schema.FieldTypeNumber,
+					Required: true,
+					Options: &schema.NumberOptions{
+						NoDecimal: true,
+					},
+				},
+				&schema.SchemaField{
+					Name:     "payment_id",
+					Type:     schema.FieldTypeText,
+					Required: false,
+				},
+				&schema.SchemaField{
+					Name:     "adjustment_id",
+					Type:     schema.FieldTypeText,
+					Required: false,
+				},
+			),
+		}
+
+		return dao.SaveCollection(allocations)
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		allocations, err := dao.FindCollectionByNameOrId("allocations")
+		if err != nil {
+			return nil
+		}
+
+		return dao.DeleteCollection(allocations)
+	})
+}
@@ -0,0 +1,122 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		plans, err := dao.FindCollectionByNameOrId("family_plans")
+		if err != nil {
+			return err
+		}
+
+		// Each month's share is due on due_day. Once a month has been unpaid for late_fee_days
+		// past that, a late fee of late_fee_amount (minor units) plus late_fee_percent of what
+		// is still owed for the month is added. Both zero turns late fees off.
+		planFields := []*schema.SchemaField{
+			{
+				Name: "due_day",
+				Type: schema.FieldTypeNumber,
+				Options: &schema.NumberOptions{
+					Min:       types.Pointer(1.0),
+					Max:       types.Pointer(28.0),
+					NoDecimal: true,
+				},
+			},
+			{
+				Name: "late_fee_days",
+				Type: schema.FieldTypeNumber,
+				Options: &schema.NumberOptions{
+					Min:       types.Pointer(0.0),
+					NoDecimal: true,
+				},
+			},
+			{
+				Name: "late_fee_amount",
+				Type: schema.FieldTypeNumber,
+				Options: &schema.NumberOptions{
+					Min:       types.Pointer(0.0),
+					NoDecimal: true,
+				},
+			},
+			{
+				Name: "late_fee_percent",
+				Type: schema.FieldTypeNumber,
+				Options: &schema.NumberOptions{
+					Min: types.Pointer(0.0),
+					Max: types.Pointer(100.0),
+				},
+			},
+		}
+		for _, field := range planFields {
+			if plans.Schema.GetFieldByName(field.Name) == nil {
+				plans.Schema.AddField(field)
+			}
+		}
+		if err := dao.SaveCollection(plans); err != nil {
+			return err
+		}
+
+		adjustments, err := dao.FindCollectionByNameOrId("adjustments")
+		if err != nil {
+			return err
+		}
+
+		// Generated adjustments carry a kind, and an owner can waive them instead of deleting them
+		if adjustments.Schema.GetFieldByName("kind") == nil {
+			adjustments.Schema.AddField(&schema.SchemaField{
+				Name: "kind",
+				Type: schema.FieldTypeText,
+			})
+		}
+		if adjustments.Schema.GetFieldByName("waived") == nil {
+			adjustments.Schema.AddField(&schema.SchemaField{
+				Name: "waived",
+				Type: schema.FieldTypeBool,
+			})
+		}
+		if err := dao.SaveCollection(adjustments); err != nil {
+			return err
+		}
+
+		_, err = db.NewQuery(`
+			UPDATE family_plans
+			SET due_day = 1
+			WHERE due_day IS NULL OR due_day = 0
+		`).Execute()
+
+		return err
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		removeFields := map[string][]string{
+			"family_plans": {"due_day", "late_fee_days", "late_fee_amount", "late_fee_percent"},
+			"adjustments":  {"kind", "waived"},
+		}
+		for collectionName, fieldNames := range removeFields {
+			collection, err := dao.FindCollectionByNameOrId(collectionName)
+			if err != nil {
+				continue
+			}
+
+			for _, name := range fieldNames {
+				if field := collection.Schema.GetFieldByName(name); field != nil {
+					collection.Schema.RemoveField(field.Id)
+				}
+			}
+
+			if err := dao.SaveCollection(collection); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,138 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		// A member's standing order: a claim for amount (minor units) filed on day_of_month of every
+		// month from start_month through end_month (YYYY-MM, empty for no end) until it is cancelled.
+		if _, err := dao.FindCollectionByNameOrId("recurring_claims"); err != nil {
+			recurringClaims := &models.Collection{
+				Name: "recurring_claims",
+				Type: models.CollectionTypeBase,
+				Schema: schema.NewSchema(
+					&schema.SchemaField{
+						Name:     "plan_id",
+						Type:     schema.FieldTypeText,
+						Required: true,
+					},
+					&schema.SchemaField{
+						Name:     "user_id",
+						Type:     schema.FieldTypeText,
+						Required: true,
+					},
+					&schema.SchemaField{
+						Name:     "amount",
+						Type:     schema.FieldTypeNumber,
+						Required: true,
+						Options: &schema.NumberOptions{
+							Min:       types.Pointer(1.0),
+							NoDecimal: true,
+						},
+					},
+					&schema.SchemaField{
+						Name:     "day_of_month",
+						Type:     schema.FieldTypeNumber,
+						Required: true,
+						Options: &schema.NumberOptions{
+							Min:       types.Pointer(1.0),
+							Max:       types.Pointer(28.0),
+							NoDecimal: true,
+						},
+					},
+					&schema.SchemaField{
+						Name:     "start_month",
+						Type:     schema.FieldTypeText,
+						Required: true,
+						Options: &schema.TextOptions{
+							Pattern: `^\d{4}-\d{2}$`,
+						},
+					},
+					&schema.SchemaField{
+						Name: "end_month",
+						Type: schema.FieldTypeText,
+						Options: &schema.TextOptions{
+							Pattern: `^(\d{4}-\d{2})?$`,
+						},
+					},
+					&schema.SchemaField{
+						Name: "cancelled_at",
+						Type: schema.FieldTypeDate,
+					},
+				),
+			}
+
+			if err := dao.SaveCollection(recurringClaims); err != nil {
+				return err
+			}
+		}
+
+		// Claims filed by a standing order point back at it, so each month is only filed once.
+		payments, err := dao.FindCollectionByNameOrId("payments")
+		if err != nil {
+			return err
+		}
+		if payments.Schema.GetFieldByName("recurring_claim_id") == nil {
+			payments.Schema.AddField(&schema.SchemaField{
+				Name: "recurring_claim_id",
+				Type: schema.FieldTypeText,
+			})
+			if err := dao.SaveCollection(payments); err != nil {
+				return err
+			}
+		}
+
+		// Owners can trust a member's standing orders, so their claims are approved as they are filed.
+		memberships, err := dao.FindCollectionByNameOrId("memberships")
+		if err != nil {
+			return err
+		}
+		if memberships.Schema.GetFieldByName("auto_approve_recurring") == nil {
+			memberships.Schema.AddField(&schema.SchemaField{
+				Name: "auto_approve_recurring",
+				Type: schema.FieldTypeBool,
+			})
+			if err := dao.SaveCollection(memberships); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		removeFields := map[string]string{
+			"payments":    "recurring_claim_id",
+			"memberships": "auto_approve_recurring",
+		}
+		for collectionName, fieldName := range removeFields {
+			collection, err := dao.FindCollectionByNameOrId(collectionName)
+			if err != nil {
+				continue
+			}
+
+			if field := collection.Schema.GetFieldByName(fieldName); field != nil {
+				collection.Schema.RemoveField(field.Id)
+				if err := dao.SaveCollection(collection); err != nil {
+					return err
+				}
+			}
+		}
+
+		recurringClaims, err := dao.FindCollectionByNameOrId("recurring_claims")
+		if err != nil {
+			return nil
+		}
+
+		return dao.DeleteCollection(recurringClaims)
+	})
+}
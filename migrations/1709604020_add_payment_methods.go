@@ -0,0 +1,94 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		payments, err := dao.FindCollectionByNameOrId("payments")
+		if err != nil {
+			return err
+		}
+
+		// How the money arrived and the bank or PayPal reference it arrived with, for reconciling statements.
+		if payments.Schema.GetFieldByName("method") == nil {
+			payments.Schema.AddField(&schema.SchemaField{
+				Name:     "method",
+				Type:     schema.FieldTypeSelect,
+				Required: false,
+				Options: &schema.SelectOptions{
+					Values:    []string{"bank_transfer", "paypal", "cash", "card", "other"},
+					MaxSelect: 1,
+				},
+			})
+		}
+		if payments.Schema.GetFieldByName("reference") == nil {
+			payments.Schema.AddField(&schema.SchemaField{
+				Name:     "reference",
+				Type:     schema.FieldTypeText,
+				Required: false,
+				Options: &schema.TextOptions{
+					Max: types.Pointer(100),
+				},
+			})
+		}
+		if err := dao.SaveCollection(payments); err != nil {
+			return err
+		}
+
+		plans, err := dao.FindCollectionByNameOrId("family_plans")
+		if err != nil {
+			return err
+		}
+
+		// The methods the owner accepts, each with instructions such as an IBAN or PayPal handle.
+		if plans.Schema.GetFieldByName("payment_methods") == nil {
+			plans.Schema.AddField(&schema.SchemaField{
+				Name:     "payment_methods",
+				Type:     schema.FieldTypeJson,
+				Required: false,
+				Options: &schema.JsonOptions{
+					MaxSize: 65536,
+				},
+			})
+
+			if err := dao.SaveCollection(plans); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		removeFields := map[string][]string{
+			"payments":     {"method", "reference"},
+			"family_plans": {"payment_methods"},
+		}
+		for collectionName, fieldNames := range removeFields {
+			collection, err := dao.FindCollectionByNameOrId(collectionName)
+			if err != nil {
+				continue
+			}
+
+			for _, name := range fieldNames {
+				if field := collection.Schema.GetFieldByName(name); field != nil {
+					collection.Schema.RemoveField(field.Id)
+				}
+			}
+
+			if err := dao.SaveCollection(collection); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
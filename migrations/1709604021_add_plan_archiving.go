@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		plans, err := dao.FindCollectionByNameOrId("family_plans")
+		if err != nil {
+			return err
+		}
+
+		// When the owner archived the plan. Archived plans are read-only and stop billing.
+		if plans.Schema.GetFieldByName("archived_at") == nil {
+			plans.Schema.AddField(&schema.SchemaField{
+				Name:     "archived_at",
+				Type:     schema.FieldTypeDate,
+				Required: false,
+				Options:  &schema.DateOptions{},
+			})
+
+			if err := dao.SaveCollection(plans); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		plans, err := dao.FindCollectionByNameOrId("family_plans")
+		if err != nil {
+			return nil
+		}
+
+		if field := plans.Schema.GetFieldByName("archived_at"); field != nil {
+			plans.Schema.RemoveField(field.Id)
+			return dao.SaveCollection(plans)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,85 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		if _, err := dao.FindCollectionByNameOrId("audit_events"); err == nil {
+			return nil
+		}
+
+		// Who changed what on a plan, with the changed record's field values before and after.
+		// user_id is the member the change affects, empty for changes to the whole plan.
+		auditEvents := &models.Collection{
+			Name: "audit_events",
+			Type: models.CollectionTypeBase,
+			Schema: schema.NewSchema(
+				&schema.SchemaField{
+					Name:     "plan_id",
+					Type:     schema.FieldTypeText,
+					Required: true,
+				},
+				&schema.SchemaField{
+					Name:     "actor_id",
+					Type:     schema.FieldTypeText,
+					Required: false,
+				},
+				&schema.SchemaField{
+					Name:     "action",
+					Type:     schema.FieldTypeText,
+					Required: true,
+				},
+				&schema.SchemaField{
+					Name:     "target_collection",
+					Type:     schema.FieldTypeText,
+					Required: false,
+				},
+				&schema.SchemaField{
+					Name:     "target_id",
+					Type:     schema.FieldTypeText,
+					Required: false,
+				},
+				&schema.SchemaField{
+					Name:     "user_id",
+					Type:     schema.FieldTypeText,
+					Required: false,
+				},
+				&schema.SchemaField{
+					Name:     "before",
+					Type:     schema.FieldTypeJson,
+					Required: false,
+					Options: &schema.JsonOptions{
+						MaxSize: 65536,
+					},
+				},
+				&schema.SchemaField{
+					Name:     "after",
+					Type:     schema.FieldTypeJson,
+					Required: false,
+					Options: &schema.JsonOptions{
+						MaxSize: 65536,
+					},
+				},
+			),
+		}
+
+		return dao.SaveCollection(auditEvents)
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		auditEvents, err := dao.FindCollectionByNameOrId("audit_events")
+		if err != nil {
+			return nil
+		}
+
+		return dao.DeleteCollection(auditEvents)
+	})
+}
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		plans, err := dao.FindCollectionByNameOrId("family_plans")
+		if err != nil {
+			return err
+		}
+
+		// Set when rebuilding the plan's allocations failed after a change, until a rebuild succeeds.
+		if plans.Schema.GetFieldByName("allocations_stale") == nil {
+			plans.Schema.AddField(&schema.SchemaField{
+				Name:     "allocations_stale",
+				Type:     schema.FieldTypeBool,
+				Required: false,
+			})
+
+			if err := dao.SaveCollection(plans); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		plans, err := dao.FindCollectionByNameOrId("family_plans")
+		if err != nil {
+			return nil
+		}
+
+		if field := plans.Schema.GetFieldByName("allocations_stale"); field != nil {
+			plans.Schema.RemoveField(field.Id)
+			return dao.SaveCollection(plans)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// lateFeeIndex allows one generated adjustment of each kind per member and month. Manual adjustments
+// have no kind and are left out.
+const lateFeeIndex = "CREATE UNIQUE INDEX `idx_adjustments_generated` ON `adjustments` (`plan_id`, `user_id`, `for_month`, `kind`) WHERE `kind` != ''"
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		adjustments, err := dao.FindCollectionByNameOrId("adjustments")
+		if err != nil {
+			return err
+		}
+
+		// Concurrent syncs could file the same late fee twice. Keep the oldest copy of each.
+		_, err = db.NewQuery(`
+			DELETE FROM adjustments
+			WHERE kind != '' AND rowid NOT IN (
+				SELECT MIN(rowid) FROM adjustments
+				WHERE kind != ''
+				GROUP BY plan_id, user_id, for_month, kind
+			)
+		`).Execute()
+		if err != nil {
+			return err
+		}
+
+		for _, index := range adjustments.Indexes {
+			if index == lateFeeIndex {
+				return nil
+			}
+		}
+
+		adjustments.Indexes = append(adjustments.Indexes, lateFeeIndex)
+		return dao.SaveCollection(adjustments)
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		adjustments, err := dao.FindCollectionByNameOrId("adjustments")
+		if err != nil {
+			return nil
+		}
+
+		kept := adjustments.Indexes[:0]
+		for _, index := range adjustments.Indexes {
+			if index != lateFeeIndex {
+				kept = append(kept, index)
+			}
+		}
+
+		adjustments.Indexes = kept
+		return dao.SaveCollection(adjustments)
+	})
+}
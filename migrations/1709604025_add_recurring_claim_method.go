@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		recurringClaims, err := dao.FindCollectionByNameOrId("recurring_claims")
+		if err != nil {
+			return err
+		}
+
+		// How the member pays their standing order, copied onto every claim it files.
+		if recurringClaims.Schema.GetFieldByName("method") != nil {
+			return nil
+		}
+
+		recurringClaims.Schema.AddField(&schema.SchemaField{
+			Name:     "method",
+			Type:     schema.FieldTypeSelect,
+			Required: false,
+			Options: &schema.SelectOptions{
+				Values:    []string{"bank_transfer", "paypal", "cash", "card", "other"},
+				MaxSelect: 1,
+			},
+		})
+		if err := dao.SaveCollection(recurringClaims); err != nil {
+			return err
+		}
+
+		// Standing orders set up before they had a method filed bank transfers.
+		_, err = db.NewQuery(`
+			UPDATE recurring_claims
+			SET method = 'bank_transfer'
+			WHERE method IS NULL OR method = ''
+		`).Execute()
+		return err
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		recurringClaims, err := dao.FindCollectionByNameOrId("recurring_claims")
+		if err != nil {
+			return nil
+		}
+
+		if field := recurringClaims.Schema.GetFieldByName("method"); field != nil {
+			recurringClaims.Schema.RemoveField(field.Id)
+			return dao.SaveCollection(recurringClaims)
+		}
+
+		return nil
+	})
+}
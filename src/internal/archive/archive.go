@@ -0,0 +1,377 @@
+package archive
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/memberclaim"
+	"familyplan/src/internal/planutil"
+	"familyplan/src/internal/support/random"
+
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/list"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Version is the archive format this build writes. Imports of any other version are refused.
+const Version = 1
+
+var (
+	// ErrUnsupportedVersion indicates an archive written in a format this build cannot read.
+	ErrUnsupportedVersion = errors.New("unsupported plan archive version")
+	// ErrInvalidArchive indicates a file that is not a plan archive.
+	ErrInvalidArchive = errors.New("invalid plan archive")
+	// ErrInvalidUserMapping indicates a mapping to a user that does not exist or is used twice.
+	ErrInvalidUserMapping = errors.New("invalid user mapping")
+)
+
+// Archive is a portable copy of one plan and what was recorded against it. Plans keep a single
+// current cost, so cost changes appear in the archive as the owner's shared adjustments.
+type Archive struct {
+	Version     int                 `json:"version"`
+	ExportedAt  types.DateTime      `json:"exported_at"`
+	Plan        Record              `json:"plan"`
+	Users       []User              `json:"users"`
+	Collections map[string][]Record `json:"collections"`
+}
+
+// Record is an archived record: its original id, when it was created and its field values.
+type Record struct {
+	ID      string         `json:"id"`
+	Created types.DateTime `json:"created"`
+	Fields  map[string]any `json:"fields"`
+}
+
+// User identifies a real user referenced by the archive, so they can be mapped on import.
+// Artificial members only appear in their memberships.
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+// collectionSpec describes a collection archived with its plan: the fields holding user ids,
+// the fields pointing at records of another archived collection, and fields never exported.
+type collectionSpec struct {
+	name       string
+	userFields []string
+	refs       map[string]string
+	omit       []string
+}
+
+// collections lists what an archive holds, in the order records are restored.
+var collections = []collectionSpec{
+	{name: "memberships", userFields: []string{"user_id"}},
+	{name: billing.RecurringClaimsCollection, userFields: []string{"user_id"}},
+	{
+		name:       "payments",
+		userFields: []string{"user_id", "payer_id"},
+		refs:       map[string]string{"recurring_claim_id": billing.RecurringClaimsCollection},
+	},
+	{name: billing.AdjustmentsCollection, userFields: []string{"user_id", "created_by"}},
+	{name: memberclaim.CollectionName, userFields: []string{"artificial_member_id"}, omit: []string{"token"}},
+	{name: "exchange_rates"},
+}
+
+// Write encodes an archive as indented JSON.
+func Write(w io.Writer, archive Archive) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(archive)
+}
+
+// Read decodes an archive and checks that this build can import it.
+func Read(r io.Reader) (Archive, error) {
+	var archive Archive
+	if err := json.NewDecoder(r).Decode(&archive); err != nil {
+		return Archive{}, ErrInvalidArchive
+	}
+	if archive.Version != Version {
+		return Archive{}, ErrUnsupportedVersion
+	}
+	if archive.Plan.Fields == nil {
+		return Archive{}, ErrInvalidArchive
+	}
+
+	return archive, nil
+}
+
+// ExportPlanWithDao copies a plan, its memberships, payments, adjustments, standing orders,
+// claim links without their tokens, and exchange rates into an archive.
+func ExportPlanWithDao(dao *daos.Dao, planID string, now time.Time) (Archive, error) {
+	plan, err := dao.FindRecordById("family_plans", planID)
+	if err != nil {
+		return Archive{}, err
+	}
+
+	exportedAt, err := types.ParseDateTime(now)
+	if err != nil {
+		return Archive{}, err
+	}
+
+	archive := Archive{
+		Version:     Version,
+		ExportedAt:  exportedAt,
+		Plan:        newRecord(plan, nil),
+		Collections: map[string][]Record{},
+	}
+
+	userIDs := []string{planutil.OwnerID(plan)}
+	for _, spec := range collections {
+		filter, err := planutil.BuildEqualsFilter(planutil.FilterTerm{Field: "plan_id", Value: planID})
+		if err != nil {
+			return Archive{}, err
+		}
+
+		records, err := dao.FindRecordsByFilter(spec.name, filter.Expression, "created", -1, 0, filter.Params)
+		if err != nil {
+			return Archive{}, err
+		}
+
+		archived := make([]Record, 0, len(records))
+		for _, record := range records {
+			archived = append(archived, newRecord(record, spec.omit))
+			for _, field := range spec.userFields {
+				userIDs = append(userIDs, record.GetString(field))
+			}
+		}
+		archive.Collections[spec.name] = archived
+	}
+
+	// Only ids that belong to user accounts resolve here; artificial members are left out.
+	userRecords, err := dao.FindRecordsByIds("users", userIDs)
+	if err != nil {
+		return Archive{}, err
+	}
+	for _, userRecord := range userRecords {
+		archive.Users = append(archive.Users, User{
+			ID:       userRecord.Id,
+			Username: userRecord.GetString("username"),
+			Name:     userRecord.GetString("name"),
+		})
+	}
+
+	return archive, nil
+}
+
+// ImportPlanWithDao recreates an archived plan owned by ownerID, with a new join code and new
+// record ids. The archived owner becomes ownerID and userMap maps other archived users to
+// existing accounts. Real members who are not mapped come back as artificial members under
+// their name, so the owner can hand them claim links. Allocations are rebuilt as of now.
+func ImportPlanWithDao(dao *daos.Dao, archive Archive, ownerID string, userMap map[string]string, now time.Time) (*pbmodels.Record, error) {
+	if archive.Version != Version {
+		return nil, ErrUnsupportedVersion
+	}
+
+	var plan *pbmodels.Record
+	err := dao.RunInTransaction(func(txDao *daos.Dao) error {
+		users, err := newUserMapper(txDao, archive, ownerID, userMap)
+		if err != nil {
+			return err
+		}
+
+		plan, err = importPlanRecordWithDao(txDao, archive.Plan, ownerID)
+		if err != nil {
+			return err
+		}
+
+		newIDs := map[string]map[string]string{}
+		for _, spec := range collections {
+			collection, err := txDao.FindCollectionByNameOrId(spec.name)
+			if err != nil {
+				return err
+			}
+
+			newIDs[spec.name] = map[string]string{}
+			for _, archived := range archive.Collections[spec.name] {
+				record := pbmodels.NewRecord(collection)
+				setFields(record, archived.Fields, "id", "plan_id")
+				record.Set("plan_id", plan.Id)
+				if !archived.Created.IsZero() {
+					record.Set("created", archived.Created)
+				}
+
+				for _, field := range spec.userFields {
+					oldID := record.GetString(field)
+					newID, err := users.resolve(oldID)
+					if err != nil {
+						return err
+					}
+					record.Set(field, newID)
+
+					if spec.name == "memberships" && oldID != "" && !users.mapped(oldID) && !record.GetBool("is_artificial") {
+						record.Set("is_artificial", true)
+						record.Set("name", users.name(oldID))
+					}
+				}
+
+				for field, refCollection := range spec.refs {
+					if oldID := record.GetString(field); oldID != "" {
+						record.Set(field, newIDs[refCollection][oldID])
+					}
+				}
+
+				if spec.name == memberclaim.CollectionName {
+					token, err := random.GenerateToken()
+					if err != nil {
+						return err
+					}
+					record.Set("token", token)
+				}
+
+				if err := txDao.SaveRecord(record); err != nil {
+					return err
+				}
+				newIDs[spec.name][archived.ID] = record.Id
+			}
+		}
+
+		return billing.ReallocatePlanWithDao(txDao, plan.Id, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func importPlanRecordWithDao(dao *daos.Dao, archived Record, ownerID string) (*pbmodels.Record, error) {
+	collection, err := dao.FindCollectionByNameOrId("family_plans")
+	if err != nil {
+		return nil, err
+	}
+
+	joinCode, err := random.GenerateJoinCode(6)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := pbmodels.NewRecord(collection)
+	setFields(plan, archived.Fields, "id", "owner", "join_code")
+	plan.Set("owner", []string{ownerID})
+	plan.Set("join_code", joinCode)
+	if !archived.Created.IsZero() {
+		plan.Set("created", archived.Created)
+	}
+
+	if err := dao.SaveRecord(plan); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// setFields copies archived values into the fields the record's collection has, skipping some.
+func setFields(record *pbmodels.Record, fields map[string]any, skip ...string) {
+	skipped := map[string]bool{}
+	for _, field := range skip {
+		skipped[field] = true
+	}
+
+	for field, value := range fields {
+		if skipped[field] || record.Collection().Schema.GetFieldByName(field) == nil {
+			continue
+		}
+
+		record.Set(field, value)
+	}
+}
+
+func newRecord(record *pbmodels.Record, omit []string) Record {
+	fields := record.SchemaData()
+	for _, field := range omit {
+		delete(fields, field)
+	}
+
+	return Record{ID: record.Id, Created: record.Created, Fields: fields}
+}
+
+func archivedOwnerID(plan Record) string {
+	ownerIDs := list.ToUniqueStringSlice(plan.Fields["owner"])
+	if len(ownerIDs) == 0 {
+		return ""
+	}
+
+	return ownerIDs[0]
+}
+
+// userMapper gives every archived user id its id in the importing instance.
+type userMapper struct {
+	ids   map[string]string
+	real  map[string]bool
+	names map[string]string
+}
+
+func newUserMapper(dao *daos.Dao, archive Archive, ownerID string, userMap map[string]string) (*userMapper, error) {
+	mapper := &userMapper{ids: map[string]string{}, real: map[string]bool{}, names: map[string]string{}}
+	for _, user := range archive.Users {
+		mapper.names[user.ID] = planutil.Identity{Username: user.Username, Name: user.Name}.DisplayName()
+	}
+
+	targets := map[string]bool{}
+	add := func(oldID, newID string) error {
+		if oldID == "" || newID == "" || targets[newID] {
+			return ErrInvalidUserMapping
+		}
+		if _, err := dao.FindRecordById("users", newID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInvalidUserMapping
+			}
+			return err
+		}
+
+		targets[newID] = true
+		mapper.ids[oldID] = newID
+		mapper.real[oldID] = true
+		return nil
+	}
+
+	if err := add(archivedOwnerID(archive.Plan), ownerID); err != nil {
+		return nil, err
+	}
+	for oldID, newID := range userMap {
+		if _, ok := mapper.ids[oldID]; ok {
+			continue
+		}
+		if err := add(oldID, newID); err != nil {
+			return nil, err
+		}
+	}
+
+	return mapper, nil
+}
+
+// resolve returns the new id for an archived user, inventing one for users that are not mapped.
+func (m *userMapper) resolve(oldID string) (string, error) {
+	if oldID == "" {
+		return "", nil
+	}
+	if newID, ok := m.ids[oldID]; ok {
+		return newID, nil
+	}
+
+	newID, err := random.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+
+	m.ids[oldID] = newID
+	return newID, nil
+}
+
+func (m *userMapper) mapped(oldID string) bool {
+	return m.real[oldID]
+}
+
+func (m *userMapper) name(oldID string) string {
+	if name := m.names[oldID]; name != "" {
+		return name
+	}
+
+	return "Former member"
+}
@@ -0,0 +1,180 @@
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/memberclaim"
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/planutil"
+	"familyplan/src/internal/testapp"
+)
+
+func TestImportPlanRecreatesArchiveUnderNewOwner(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveNamedUser(t, app, "owner", "")
+	member := testapp.SaveNamedUser(t, app, "member", "Mia")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 3000)
+	joined := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	testapp.SaveMembership(t, app, plan.Id, owner.Id, joined)
+	testapp.SaveMembership(t, app, plan.Id, member.Id, joined)
+	testapp.SaveArtificialMembership(t, app, plan.Id, "artificial-grandpa", "Grandpa", joined)
+
+	if _, err := memberclaim.EnsureWithDao(app.Dao(), plan.Id, "artificial-grandpa", time.Now()); err != nil {
+		t.Fatalf("EnsureWithDao returned error: %v", err)
+	}
+
+	order, err := billing.CreateRecurringClaimWithDao(app.Dao(), billing.RecurringClaim{
+		PlanID:     plan.Id,
+		UserID:     member.Id,
+		Amount:     money.New(1000, "USD"),
+		DayOfMonth: 5,
+		StartMonth: joined,
+	})
+	if err != nil {
+		t.Fatalf("CreateRecurringClaimWithDao returned error: %v", err)
+	}
+	payment := testapp.SavePayment(t, app, plan.Id, member.Id, 1000, joined.AddDate(0, 0, 4), "approved")
+	payment.Set("recurring_claim_id", order.Id)
+	if err := app.Dao().SaveRecord(payment); err != nil {
+		t.Fatalf("failed to link payment to standing order: %v", err)
+	}
+	testapp.SavePayment(t, app, plan.Id, "artificial-grandpa", 500, joined.AddDate(0, 1, 2), "approved")
+
+	exportedAt := joined.AddDate(0, 2, 0)
+	exported, err := ExportPlanWithDao(app.Dao(), plan.Id, exportedAt)
+	if err != nil {
+		t.Fatalf("ExportPlanWithDao returned error: %v", err)
+	}
+
+	var file bytes.Buffer
+	if err := Write(&file, exported); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if strings.Contains(file.String(), `"token"`) {
+		t.Fatalf("archive includes claim link tokens:\n%s", file.String())
+	}
+
+	archived, err := Read(&file)
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+
+	newOwner := testapp.SaveNamedUser(t, app, "newowner", "")
+	imported, err := ImportPlanWithDao(app.Dao(), archived, newOwner.Id, nil, exportedAt)
+	if err != nil {
+		t.Fatalf("ImportPlanWithDao returned error: %v", err)
+	}
+
+	if got := planutil.OwnerID(imported); got != newOwner.Id {
+		t.Fatalf("imported owner = %q, want %q", got, newOwner.Id)
+	}
+	if imported.GetString("join_code") == plan.GetString("join_code") {
+		t.Fatalf("imported plan reuses join code %q", imported.GetString("join_code"))
+	}
+
+	memberships, err := app.Dao().FindRecordsByFilter("memberships", "plan_id = {:plan}", "created", -1, 0, map[string]any{"plan": imported.Id})
+	if err != nil {
+		t.Fatalf("failed to load imported memberships: %v", err)
+	}
+	if len(memberships) != 3 {
+		t.Fatalf("imported memberships = %d, want 3", len(memberships))
+	}
+
+	names := map[string]string{}
+	for _, membership := range memberships {
+		userID := membership.GetString("user_id")
+		if userID == member.Id || userID == "artificial-grandpa" {
+			t.Fatalf("membership kept archived user id %q", userID)
+		}
+		if userID == newOwner.Id {
+			continue
+		}
+		if !membership.GetBool("is_artificial") {
+			t.Fatalf("unmapped member %q was not made artificial", userID)
+		}
+		names[membership.GetString("name")] = userID
+	}
+
+	miaID, ok := names["Mia"]
+	if !ok || names["Grandpa"] == "" {
+		t.Fatalf("imported artificial members = %v, want Mia and Grandpa", names)
+	}
+
+	for _, archivedUserID := range []string{member.Id, "artificial-grandpa"} {
+		want, err := billing.CalculateMemberBalanceWithDao(app.Dao(), plan.Id, archivedUserID, exportedAt)
+		if err != nil {
+			t.Fatalf("CalculateMemberBalanceWithDao(original) returned error: %v", err)
+		}
+
+		importedUserID := miaID
+		if archivedUserID == "artificial-grandpa" {
+			importedUserID = names["Grandpa"]
+		}
+		got, err := billing.CalculateMemberBalanceWithDao(app.Dao(), imported.Id, importedUserID, exportedAt)
+		if err != nil {
+			t.Fatalf("CalculateMemberBalanceWithDao(imported) returned error: %v", err)
+		}
+		if got != want {
+			t.Fatalf("imported balance for %q = %v, want %v", archivedUserID, got, want)
+		}
+	}
+
+	payments, err := app.Dao().FindRecordsByFilter("payments", "plan_id = {:plan} && recurring_claim_id != ''", "", -1, 0, map[string]any{"plan": imported.Id})
+	if err != nil || len(payments) != 1 {
+		t.Fatalf("imported standing order payments = %d, %v; want 1", len(payments), err)
+	}
+	if _, err := app.Dao().FindRecordById(billing.RecurringClaimsCollection, payments[0].GetString("recurring_claim_id")); err != nil {
+		t.Fatalf("imported payment points at a missing standing order: %v", err)
+	}
+
+	link, err := memberclaim.FindForArtificialMemberWithDao(app.Dao(), imported.Id, names["Grandpa"], time.Now())
+	if err != nil || link == nil {
+		t.Fatalf("imported claim link = %v, %v; want a link for Grandpa", link, err)
+	}
+}
+
+func TestImportPlanMapsUsersAndRejectsBadMappings(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveNamedUser(t, app, "owner", "")
+	member := testapp.SaveNamedUser(t, app, "member", "")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 3000)
+	testapp.SaveMembership(t, app, plan.Id, member.Id, time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	archived, err := ExportPlanWithDao(app.Dao(), plan.Id, time.Now())
+	if err != nil {
+		t.Fatalf("ExportPlanWithDao returned error: %v", err)
+	}
+
+	newOwner := testapp.SaveNamedUser(t, app, "newowner", "")
+	if _, err := ImportPlanWithDao(app.Dao(), archived, newOwner.Id, map[string]string{member.Id: "missing-user"}, time.Now()); !errors.Is(err, ErrInvalidUserMapping) {
+		t.Fatalf("ImportPlanWithDao(missing user) error = %v, want ErrInvalidUserMapping", err)
+	}
+	if _, err := ImportPlanWithDao(app.Dao(), archived, newOwner.Id, map[string]string{member.Id: newOwner.Id}, time.Now()); !errors.Is(err, ErrInvalidUserMapping) {
+		t.Fatalf("ImportPlanWithDao(member mapped to owner) error = %v, want ErrInvalidUserMapping", err)
+	}
+
+	imported, err := ImportPlanWithDao(app.Dao(), archived, newOwner.Id, map[string]string{member.Id: member.Id}, time.Now())
+	if err != nil {
+		t.Fatalf("ImportPlanWithDao returned error: %v", err)
+	}
+
+	membership, err := planutil.FindMembershipWithDao(app.Dao(), imported.Id, member.Id)
+	if err != nil || membership == nil {
+		t.Fatalf("mapped membership = %v, %v; want the member's own membership", membership, err)
+	}
+	if membership.GetBool("is_artificial") {
+		t.Fatalf("mapped member was made artificial")
+	}
+
+	archived.Version = Version + 1
+	if _, err := ImportPlanWithDao(app.Dao(), archived, newOwner.Id, nil, time.Now()); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("ImportPlanWithDao(newer version) error = %v, want ErrUnsupportedVersion", err)
+	}
+}
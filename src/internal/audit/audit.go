@@ -0,0 +1,372 @@
+// Package audit records who changed what on a plan, so balances can be traced back to the
+// approvals, cost changes and membership changes behind them.
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// CollectionName stores audit events.
+const CollectionName = "audit_events"
+
+// Actions are named <category>.<what happened>; the category is what the activity feed filters on.
+const (
+	ActionPlanCreated          = "plan.created"
+	ActionPlanUpdated          = "plan.updated"
+	ActionPlanArchived         = "plan.archived"
+	ActionPlanRestored         = "plan.restored"
+	ActionPlanImported         = "plan.imported"
+	ActionPaymentMethodsSaved  = "plan.payment_methods_saved"
+	ActionExchangeRateSaved    = "plan.exchange_rate_saved"
+	ActionJoinRequested        = "join_request.created"
+	ActionJoinApproved         = "join_request.approved"
+	ActionJoinDenied           = "join_request.denied"
+	ActionMemberAdded          = "membership.added"
+	ActionMemberRemoved        = "membership.removed"
+	ActionMemberReinstated     = "membership.reinstated"
+	ActionBalanceWrittenOff    = "membership.written_off"
+	ActionGraceGranted         = "membership.grace_granted"
+	ActionMemberPaused         = "membership.paused"
+	ActionMemberResumed        = "membership.resumed"
+	ActionMemberLeft           = "membership.left"
+	ActionLeaveRequested       = "membership.leave_requested"
+	ActionMemberTransferred    = "membership.transferred"
+	ActionMemberMerged         = "membership.merged"
+	ActionMemberClaimed        = "membership.claimed"
+	ActionClaimLinkCreated     = "claim_link.created"
+	ActionClaimLinkRegenerated = "claim_link.regenerated"
+	ActionClaimLinkRevoked     = "claim_link.revoked"
+	ActionPaymentClaimed       = "payment.claimed"
+	ActionPaymentAdded         = "payment.added"
+	ActionPaymentFiled         = "payment.filed"
+	ActionPaymentApproved      = "payment.approved"
+	ActionPaymentRejected      = "payment.rejected"
+	ActionAdjustmentAdded      = "adjustment.added"
+	ActionAdjustmentDeleted    = "adjustment.deleted"
+	ActionAdjustmentWaived     = "adjustment.waived"
+	ActionStandingOrderCreated = "standing_order.created"
+	ActionStandingOrderEnded   = "standing_order.cancelled"
+	ActionStandingOrderTrusted = "standing_order.trust_changed"
+)
+
+// Categories lists the action categories the activity feed can be filtered by.
+var Categories = []string{"plan", "join_request", "membership", "claim_link", "payment", "adjustment", "standing_order"}
+
+var categoryLabels = map[string]string{
+	"plan":           "Plan settings",
+	"join_request":   "Join requests",
+	"membership":     "Memberships",
+	"claim_link":     "Claim links",
+	"payment":        "Payments",
+	"adjustment":     "Adjustments",
+	"standing_order": "Standing orders",
+}
+
+var labels = map[string]string{
+	ActionPlanCreated:          "Plan created",
+	ActionPlanUpdated:          "Plan settings changed",
+	ActionPlanArchived:         "Plan archived",
+	ActionPlanRestored:         "Plan restored",
+	ActionPlanImported:         "Plan imported from an archive",
+	ActionPaymentMethodsSaved:  "Payment methods changed",
+	ActionExchangeRateSaved:    "Exchange rate saved",
+	ActionJoinRequested:        "Asked to join",
+	ActionJoinApproved:         "Join request approved",
+	ActionJoinDenied:           "Join request denied",
+	ActionMemberAdded:          "Artificial member added",
+	ActionMemberRemoved:        "Member removed",
+	ActionMemberReinstated:     "Member reinstated",
+	ActionBalanceWrittenOff:    "Balance written off",
+	ActionGraceGranted:         "Grace period granted",
+	ActionMemberPaused:         "Membership paused",
+	ActionMemberResumed:        "Membership resumed",
+	ActionMemberLeft:           "Left the plan",
+	ActionLeaveRequested:       "Asked to leave",
+	ActionMemberTransferred:    "Membership transferred",
+	ActionMemberMerged:         "Members merged",
+	ActionMemberClaimed:        "Membership claimed",
+	ActionClaimLinkCreated:     "Claim link created",
+	ActionClaimLinkRegenerated: "Claim link regenerated",
+	ActionClaimLinkRevoked:     "Claim link revoked",
+	ActionPaymentClaimed:       "Payment claimed",
+	ActionPaymentAdded:         "Payment recorded by owner",
+	ActionPaymentFiled:         "Payment claimed by standing order",
+	ActionPaymentApproved:      "Payment approved",
+	ActionPaymentRejected:      "Payment rejected",
+	ActionAdjustmentAdded:      "Adjustment added",
+	ActionAdjustmentDeleted:    "Adjustment removed",
+	ActionAdjustmentWaived:     "Late fee waived",
+	ActionStandingOrderCreated: "Standing order set up",
+	ActionStandingOrderEnded:   "Standing order cancelled",
+	ActionStandingOrderTrusted: "Standing order approval changed",
+}
+
+// ErrInvalidEvent indicates an event without a plan or an action.
+var ErrInvalidEvent = errors.New("audit events need a plan and an action")
+
+// Event is one change to a plan. Before and After hold the target record's field values, either
+// of which is empty when the record was created or deleted.
+type Event struct {
+	PlanID   string
+	ActorID  string
+	Action   string
+	Target   string
+	TargetID string
+	// UserID is the member the change affects. It defaults to the target's user_id, and is left
+	// empty for changes to the whole plan, which every member can see.
+	UserID string
+	Before map[string]any
+	After  map[string]any
+}
+
+// Change is one field that differs between an event's before and after values.
+type Change struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// Filter narrows the activity feed. Empty fields match everything.
+type Filter struct {
+	Category string
+	UserID   string
+	ActorID  string
+}
+
+// Label describes an action for people.
+func Label(action string) string {
+	if label, ok := labels[action]; ok {
+		return label
+	}
+
+	return action
+}
+
+// CategoryLabel describes a category for people.
+func CategoryLabel(category string) string {
+	if label, ok := categoryLabels[category]; ok {
+		return label
+	}
+
+	return category
+}
+
+// Category returns the part of an action the feed filters on.
+func Category(action string) string {
+	category, _, _ := strings.Cut(action, ".")
+	return category
+}
+
+// secretFields are never copied into events, since members can read the events about them.
+var secretFields = []string{"token"}
+
+// Snapshot copies a record's field values for an event. A nil record has none.
+func Snapshot(record *pbmodels.Record) map[string]any {
+	if record == nil {
+		return nil
+	}
+
+	values := record.SchemaData()
+	for _, field := range secretFields {
+		delete(values, field)
+	}
+
+	return values
+}
+
+// SnapshotWithDao loads a record and copies its field values. A missing record has none.
+func SnapshotWithDao(dao *daos.Dao, collection, id string) (map[string]any, error) {
+	if id == "" {
+		return nil, nil
+	}
+
+	record, err := dao.FindRecordById(collection, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return Snapshot(record), nil
+}
+
+// RecordWithDao stores an event. Pass the dao of the transaction making the change, so the event
+// is only kept when the change is.
+func RecordWithDao(dao *daos.Dao, event Event) error {
+	if event.PlanID == "" || event.Action == "" {
+		return ErrInvalidEvent
+	}
+
+	if event.UserID == "" {
+		event.UserID = stringField(event.After, "user_id")
+	}
+	if event.UserID == "" {
+		event.UserID = stringField(event.Before, "user_id")
+	}
+
+	collection, err := dao.FindCollectionByNameOrId(CollectionName)
+	if err != nil {
+		return err
+	}
+
+	record := pbmodels.NewRecord(collection)
+	record.Set("plan_id", event.PlanID)
+	record.Set("actor_id", event.ActorID)
+	record.Set("action", event.Action)
+	record.Set("target_collection", event.Target)
+	record.Set("target_id", event.TargetID)
+	record.Set("user_id", event.UserID)
+	if event.Before != nil {
+		record.Set("before", event.Before)
+	}
+	if event.After != nil {
+		record.Set("after", event.After)
+	}
+
+	return dao.SaveRecord(record)
+}
+
+// TrackWithDao makes a change in a transaction and records it with the values of the event's target
+// record before and after the change.
+func TrackWithDao(dao *daos.Dao, event Event, change func(txDao *daos.Dao) error) error {
+	return dao.RunInTransaction(func(txDao *daos.Dao) error {
+		before, err := SnapshotWithDao(txDao, event.Target, event.TargetID)
+		if err != nil {
+			return err
+		}
+
+		if err := change(txDao); err != nil {
+			return err
+		}
+
+		after, err := SnapshotWithDao(txDao, event.Target, event.TargetID)
+		if err != nil {
+			return err
+		}
+
+		event.Before = before
+		event.After = after
+		return RecordWithDao(txDao, event)
+	})
+}
+
+// FindWithDao returns a plan's most recent events matching the filter, newest first.
+func FindWithDao(dao *daos.Dao, planID string, filter Filter, limit int) ([]*pbmodels.Record, error) {
+	terms := []planutil.FilterTerm{{Field: "plan_id", Value: planID}}
+	if filter.UserID != "" {
+		terms = append(terms, planutil.FilterTerm{Field: "user_id", Value: filter.UserID})
+	}
+	if filter.ActorID != "" {
+		terms = append(terms, planutil.FilterTerm{Field: "actor_id", Value: filter.ActorID})
+	}
+
+	query, err := planutil.BuildEqualsFilter(terms...)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.Category != "" {
+		query.Expression += " && action ~ {:category}"
+		query.Params["category"] = filter.Category + ".%"
+	}
+
+	return dao.FindRecordsByFilter(CollectionName, query.Expression, "-created", limit, 0, query.Params)
+}
+
+// FindForMemberWithDao returns the most recent events a member can see: those affecting them and
+// those affecting the whole plan, newest first.
+func FindForMemberWithDao(dao *daos.Dao, planID, userID string, limit int) ([]*pbmodels.Record, error) {
+	return dao.FindRecordsByFilter(
+		CollectionName,
+		"plan_id = {:plan} && (user_id = {:user} || user_id = '')",
+		"-created",
+		limit,
+		0,
+		dbx.Params{"plan": planID, "user": userID},
+	)
+}
+
+// Changes lists the fields an event changed, in field order.
+func Changes(event *pbmodels.Record) []Change {
+	before := jsonField(event, "before")
+	after := jsonField(event, "after")
+
+	fields := map[string]bool{}
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+
+	changes := []Change{}
+	for field := range fields {
+		beforeValue := formatValue(before[field])
+		afterValue := formatValue(after[field])
+		if beforeValue != afterValue {
+			changes = append(changes, Change{Field: field, Before: beforeValue, After: afterValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+
+	return changes
+}
+
+const maxValueLength = 120
+
+// formatValue renders a stored value on one line, shortening long JSON values.
+func formatValue(value any) string {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		text = v
+	case float64, bool:
+		text = fmt.Sprint(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		text = string(encoded)
+	}
+
+	if text == "[]" || text == "{}" || text == "null" {
+		return ""
+	}
+	if len(text) > maxValueLength {
+		return text[:maxValueLength] + "…"
+	}
+
+	return text
+}
+
+func jsonField(record *pbmodels.Record, field string) map[string]any {
+	values := map[string]any{}
+	if err := record.UnmarshalJSONField(field, &values); err != nil {
+		return map[string]any{}
+	}
+
+	return values
+}
+
+func stringField(values map[string]any, field string) string {
+	value, _ := values[field].(string)
+	return value
+}
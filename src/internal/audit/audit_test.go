@@ -0,0 +1,153 @@
+package audit
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"familyplan/src/internal/testapp"
+
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+func TestFindFiltersEventsForOwnersAndMembers(t *testing.T) {
+	app := testapp.New(t)
+
+	events := []Event{
+		{PlanID: "plan-1", ActorID: "owner", Action: ActionPlanUpdated, Target: "family_plans", TargetID: "plan-1"},
+		{PlanID: "plan-1", ActorID: "owner", Action: ActionPaymentApproved, After: map[string]any{"user_id": "member-a", "status": "approved"}},
+		{PlanID: "plan-1", ActorID: "owner", Action: ActionMemberPaused, UserID: "member-b"},
+		{PlanID: "plan-1", ActorID: "member-a", Action: ActionPaymentClaimed, Before: map[string]any{"user_id": "member-a"}},
+		{PlanID: "plan-2", ActorID: "owner", Action: ActionPaymentApproved, UserID: "member-a"},
+	}
+	for _, event := range events {
+		if err := RecordWithDao(app.Dao(), event); err != nil {
+			t.Fatalf("RecordWithDao(%s) returned error: %v", event.Action, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{name: "everything", want: []string{ActionPaymentClaimed, ActionMemberPaused, ActionPaymentApproved, ActionPlanUpdated}},
+		{name: "category", filter: Filter{Category: "payment"}, want: []string{ActionPaymentClaimed, ActionPaymentApproved}},
+		{name: "member", filter: Filter{UserID: "member-a"}, want: []string{ActionPaymentClaimed, ActionPaymentApproved}},
+		{name: "actor", filter: Filter{ActorID: "member-a"}, want: []string{ActionPaymentClaimed}},
+		{name: "category and member", filter: Filter{Category: "membership", UserID: "member-a"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := FindWithDao(app.Dao(), "plan-1", tt.filter, 50)
+			if err != nil {
+				t.Fatalf("FindWithDao returned error: %v", err)
+			}
+			assertActions(t, records, tt.want)
+		})
+	}
+
+	records, err := FindForMemberWithDao(app.Dao(), "plan-1", "member-b", 50)
+	if err != nil {
+		t.Fatalf("FindForMemberWithDao returned error: %v", err)
+	}
+	assertActions(t, records, []string{ActionMemberPaused, ActionPlanUpdated})
+}
+
+func TestTrackRecordsChangesOnlyWhenTheChangeIsKept(t *testing.T) {
+	app := testapp.New(t)
+
+	collection, err := app.Dao().FindCollectionByNameOrId("memberships")
+	if err != nil {
+		t.Fatalf("failed to find memberships collection: %v", err)
+	}
+	membership := pbmodels.NewRecord(collection)
+	membership.Set("plan_id", "plan-1")
+	membership.Set("user_id", "member-a")
+	membership.Set("leave_requested", false)
+	if err := app.Dao().SaveRecord(membership); err != nil {
+		t.Fatalf("failed to save membership: %v", err)
+	}
+
+	event := Event{PlanID: "plan-1", ActorID: "member-a", Action: ActionLeaveRequested, Target: "memberships", TargetID: membership.Id}
+	failed := errors.New("change failed")
+	err = TrackWithDao(app.Dao(), event, func(txDao *daos.Dao) error {
+		membership.Set("leave_requested", true)
+		if err := txDao.SaveRecord(membership); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("TrackWithDao error = %v, want the change's error", err)
+	}
+
+	records, err := FindWithDao(app.Dao(), "plan-1", Filter{}, 50)
+	if err != nil {
+		t.Fatalf("FindWithDao returned error: %v", err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("events after failed change = %d, want 0", len(records))
+	}
+
+	err = TrackWithDao(app.Dao(), event, func(txDao *daos.Dao) error {
+		membership.Set("leave_requested", true)
+		return txDao.SaveRecord(membership)
+	})
+	if err != nil {
+		t.Fatalf("TrackWithDao returned error: %v", err)
+	}
+
+	records, err = FindWithDao(app.Dao(), "plan-1", Filter{UserID: "member-a"}, 50)
+	if err != nil {
+		t.Fatalf("FindWithDao returned error: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("events = %d, want 1", len(records))
+	}
+
+	changes := Changes(records[0])
+	if len(changes) != 1 || changes[0] != (Change{Field: "leave_requested", Before: "false", After: "true"}) {
+		t.Fatalf("Changes = %+v, want leave_requested false -> true", changes)
+	}
+}
+
+func TestSnapshotLeavesOutSecrets(t *testing.T) {
+	app := testapp.New(t)
+
+	collection, err := app.Dao().FindCollectionByNameOrId("member_claim_links")
+	if err != nil {
+		t.Fatalf("failed to find member_claim_links collection: %v", err)
+	}
+	link := pbmodels.NewRecord(collection)
+	link.Set("token", "secret-token")
+	link.Set("artificial_member_id", "artificial-1")
+
+	snapshot := Snapshot(link)
+	if _, ok := snapshot["token"]; ok {
+		t.Fatalf("Snapshot kept the claim token: %v", snapshot)
+	}
+	if snapshot["artificial_member_id"] != "artificial-1" {
+		t.Fatalf("Snapshot artificial_member_id = %v, want artificial-1", snapshot["artificial_member_id"])
+	}
+}
+
+func assertActions(t *testing.T, records []*pbmodels.Record, want []string) {
+	t.Helper()
+
+	got := make([]string, 0, len(records))
+	for _, record := range records {
+		got = append(got, record.GetString("action"))
+	}
+
+	// Events saved in the same millisecond share a created time, so only the set is compared.
+	sort.Strings(got)
+	want = append([]string(nil), want...)
+	sort.Strings(want)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("actions = %v, want %v", got, want)
+	}
+}
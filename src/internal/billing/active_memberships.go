@@ -15,7 +15,25 @@ func GetActiveMembershipsForMonth(app *pocketbase.PocketBase, planID string, tar
 	return getActiveMembershipsForMonth(app.Dao(), planID, targetMonth)
 }
 
+// GetServedMembershipsForMonth returns memberships that had the plan in the target month,
+// including members on a free trial or grace period who are not charged for it.
+func GetServedMembershipsForMonth(app *pocketbase.PocketBase, planID string, targetMonth time.Time) ([]*pbmodels.Record, error) {
+	return findMembershipsForMonth(app.Dao(), planID, targetMonth, func(membership *pbmodels.Record, monthStart time.Time) bool {
+		if _, free := FreePeriodForMonth(membership, monthStart); free {
+			return false
+		}
+
+		return IsInactiveInMonth(membership, monthStart)
+	})
+}
+
 func getActiveMembershipsForMonth(dao *daos.Dao, planID string, targetMonth time.Time) ([]*pbmodels.Record, error) {
+	return findMembershipsForMonth(dao, planID, targetMonth, IsInactiveInMonth)
+}
+
+// findMembershipsForMonth returns memberships that had started and not ended by the target month,
+// leaving out the ones skip rejects.
+func findMembershipsForMonth(dao *daos.Dao, planID string, targetMonth time.Time, skip func(*pbmodels.Record, time.Time) bool) ([]*pbmodels.Record, error) {
 	membershipsCollection, err := dao.FindCollectionByNameOrId("memberships")
 	if err != nil {
 		return nil, err
@@ -59,6 +77,10 @@ func getActiveMembershipsForMonth(dao *daos.Dao, planID string, targetMonth time
 			}
 		}
 
+		if skip(membership, monthStart) {
+			continue
+		}
+
 		activeMemberships = append(activeMemberships, membership)
 	}
 
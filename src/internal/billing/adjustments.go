@@ -0,0 +1,150 @@
+package billing
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// AdjustmentsCollection is the PocketBase collection that stores credits and charges.
+const AdjustmentsCollection = "adjustments"
+
+// AdjustmentKindLateFee marks a charge generated by the plan's late fee rule.
+// Owner-entered adjustments have no kind.
+const AdjustmentKindLateFee = "late_fee"
+
+var (
+	// ErrInvalidAdjustment indicates that an adjustment has no amount or no reason.
+	ErrInvalidAdjustment = errors.New("adjustment needs a non-zero amount and a reason")
+	// ErrAdjustmentNotFound indicates that the adjustment does not belong to the plan.
+	ErrAdjustmentNotFound = errors.New("adjustment not found")
+	// ErrGeneratedAdjustment indicates that a generated adjustment can only be waived, not deleted.
+	ErrGeneratedAdjustment = errors.New("generated adjustments can only be waived")
+)
+
+// Adjustment is a credit (positive amount) or charge (negative amount) applied to a member's balance.
+// An empty UserID applies it to every member active in ForMonth.
+type Adjustment struct {
+	PlanID    string
+	UserID    string
+	Amount    money.Amount
+	Reason    string
+	ForMonth  time.Time
+	CreatedBy string
+	Kind      string
+}
+
+// AppliesToAllMembers reports whether an adjustment record is shared by every active member.
+func AppliesToAllMembers(adjustment *pbmodels.Record) bool {
+	return adjustment.GetString("user_id") == ""
+}
+
+// IsLateFee reports whether an adjustment record was generated by the plan's late fee rule.
+func IsLateFee(adjustment *pbmodels.Record) bool {
+	return adjustment.GetString("kind") == AdjustmentKindLateFee
+}
+
+// IsWaived reports whether the owner waived an adjustment. Waived adjustments do not affect balances.
+func IsWaived(adjustment *pbmodels.Record) bool {
+	return adjustment.GetBool("waived")
+}
+
+// CreateAdjustmentWithDao stores an adjustment for the start of its effective month.
+func CreateAdjustmentWithDao(dao *daos.Dao, adjustment Adjustment) (*pbmodels.Record, error) {
+	reason := strings.TrimSpace(adjustment.Reason)
+	if adjustment.Amount.IsZero() || reason == "" {
+		return nil, ErrInvalidAdjustment
+	}
+
+	collection, err := dao.FindCollectionByNameOrId(AdjustmentsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	forMonth := time.Date(adjustment.ForMonth.Year(), adjustment.ForMonth.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	record := pbmodels.NewRecord(collection)
+	record.Set("plan_id", adjustment.PlanID)
+	record.Set("user_id", adjustment.UserID)
+	record.Set("amount", adjustment.Amount.Minor)
+	record.Set("reason", reason)
+	record.Set("for_month", forMonth)
+	record.Set("created_by", adjustment.CreatedBy)
+	record.Set("kind", adjustment.Kind)
+	if err := dao.SaveRecord(record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// DeleteAdjustmentWithDao removes an owner-entered adjustment that belongs to the plan.
+// Generated adjustments would be recreated, so they are waived instead.
+func DeleteAdjustmentWithDao(dao *daos.Dao, planID, adjustmentID string) error {
+	record, err := findPlanAdjustmentWithDao(dao, planID, adjustmentID)
+	if err != nil {
+		return err
+	}
+	if record.GetString("kind") != "" {
+		return ErrGeneratedAdjustment
+	}
+
+	return dao.DeleteRecord(record)
+}
+
+// WaiveAdjustmentWithDao waives an adjustment so it no longer counts toward the member's balance.
+func WaiveAdjustmentWithDao(dao *daos.Dao, planID, adjustmentID string) error {
+	record, err := findPlanAdjustmentWithDao(dao, planID, adjustmentID)
+	if err != nil {
+		return err
+	}
+	if IsWaived(record) {
+		return nil
+	}
+
+	record.Set("waived", true)
+	return dao.SaveRecord(record)
+}
+
+func findPlanAdjustmentWithDao(dao *daos.Dao, planID, adjustmentID string) (*pbmodels.Record, error) {
+	if adjustmentID == "" {
+		return nil, ErrAdjustmentNotFound
+	}
+
+	record, err := dao.FindRecordById(AdjustmentsCollection, adjustmentID)
+	if err != nil || record == nil || record.GetString("plan_id") != planID {
+		return nil, ErrAdjustmentNotFound
+	}
+
+	return record, nil
+}
+
+// FindAdjustmentsWithDao returns a plan's adjustments, newest effective month first.
+func FindAdjustmentsWithDao(dao *daos.Dao, planID string) ([]*pbmodels.Record, error) {
+	filter, err := planutil.BuildEqualsFilter(
+		planutil.FilterTerm{Field: "plan_id", Value: planID},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return dao.FindRecordsByFilter(
+		AdjustmentsCollection,
+		filter.Expression,
+		"-for_month,-created",
+		-1,
+		0,
+		filter.Params,
+	)
+}
+
+// AdjustmentAmount returns an adjustment's amount in minor units of the plan currency.
+func AdjustmentAmount(adjustment *pbmodels.Record, currency string) money.Amount {
+	return money.New(int64(adjustment.GetInt("amount")), currency)
+}
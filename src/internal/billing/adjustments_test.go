@@ -0,0 +1,79 @@
+package billing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/testapp"
+)
+
+func TestAdjustmentsApplyToMemberBalance(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	other := testapp.SaveUser(t, app, "other")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 3000)
+
+	membership := testapp.SaveMembership(t, app, plan.Id, member.Id, time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC))
+	membership.Set("date_ended", testapp.DateTime(t, time.Date(2026, time.February, 10, 0, 0, 0, 0, time.UTC)))
+	if err := app.Dao().SaveRecord(membership); err != nil {
+		t.Fatalf("failed to end membership: %v", err)
+	}
+
+	adjustments := []Adjustment{
+		{UserID: "", Amount: money.New(500, "USD"), Reason: "Promo month", ForMonth: time.Date(2026, time.January, 20, 0, 0, 0, 0, time.UTC)},
+		{UserID: "", Amount: money.New(700, "USD"), Reason: "After leaving", ForMonth: time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC)},
+		{UserID: member.Id, Amount: money.New(-200, "USD"), Reason: "Extra screen", ForMonth: time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{UserID: member.Id, Amount: money.New(-900, "USD"), Reason: "Too late", ForMonth: time.Date(2026, time.June, 1, 0, 0, 0, 0, time.UTC)},
+		{UserID: other.Id, Amount: money.New(-400, "USD"), Reason: "Someone else", ForMonth: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, adjustment := range adjustments {
+		adjustment.PlanID = plan.Id
+		adjustment.CreatedBy = owner.Id
+		if _, err := CreateAdjustmentWithDao(app.Dao(), adjustment); err != nil {
+			t.Fatalf("CreateAdjustmentWithDao(%q) returned error: %v", adjustment.Reason, err)
+		}
+	}
+
+	balance, err := CalculateMemberBalanceWithDao(app.Dao(), plan.Id, member.Id, time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("CalculateMemberBalanceWithDao returned error: %v", err)
+	}
+
+	// Two months at 1500 each, the January credit and the February charge.
+	if got, want := balance.Minor, int64(-3000+500-200); got != want {
+		t.Fatalf("balance = %d cents, want %d", got, want)
+	}
+}
+
+func TestCreateAdjustmentRejectsMissingAmountOrReason(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 1000)
+
+	tests := []Adjustment{
+		{PlanID: plan.Id, Amount: money.New(0, "USD"), Reason: "Nothing", ForMonth: time.Now()},
+		{PlanID: plan.Id, Amount: money.New(100, "USD"), Reason: "  ", ForMonth: time.Now()},
+	}
+
+	for _, adjustment := range tests {
+		if _, err := CreateAdjustmentWithDao(app.Dao(), adjustment); !errors.Is(err, ErrInvalidAdjustment) {
+			t.Fatalf("CreateAdjustmentWithDao(%+v) error = %v, want ErrInvalidAdjustment", adjustment, err)
+		}
+	}
+
+	record, err := CreateAdjustmentWithDao(app.Dao(), Adjustment{PlanID: plan.Id, Amount: money.New(100, "USD"), Reason: "Refund", ForMonth: time.Now()})
+	if err != nil {
+		t.Fatalf("CreateAdjustmentWithDao returned error: %v", err)
+	}
+	if err := DeleteAdjustmentWithDao(app.Dao(), "another-plan", record.Id); !errors.Is(err, ErrAdjustmentNotFound) {
+		t.Fatalf("DeleteAdjustmentWithDao(another plan) error = %v, want ErrAdjustmentNotFound", err)
+	}
+	if err := DeleteAdjustmentWithDao(app.Dao(), plan.Id, record.Id); err != nil {
+		t.Fatalf("DeleteAdjustmentWithDao returned error: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// allocationFields are the fields of each collection allocations are derived from. Updates that
+// leave all of them alone, such as renaming a plan or editing a payment's notes, skip the rebuild.
+var allocationFields = map[string][]string{
+	"payments":            {"plan_id", "user_id", "amount", "status", "date", "for_month", "created"},
+	AdjustmentsCollection: {"plan_id", "user_id", "amount", "for_month", "waived"},
+	"memberships":         {"plan_id", "user_id", "created", "date_ended", "inactive_periods"},
+	"family_plans":        {"cost", "currency", "owner", "archived_at"},
+}
+
+// RegisterAllocationHooks rebuilds allocations as of the clock's time whenever a record they are
+// derived from changes. The hooks run once the change is saved, so a failed rebuild cannot undo it.
+// Instead the plan is marked stale and rebuilt by ReallocateStaleWithDao, which the recurring claims
+// run calls first.
+func RegisterAllocationHooks(app *pocketbase.PocketBase, clock Clock) {
+	reallocate := func(e *core.ModelEvent, isUpdate bool) error {
+		record, ok := e.Model.(*pbmodels.Record)
+		if !ok {
+			return nil
+		}
+		if isUpdate && !changesAllocations(record) {
+			return nil
+		}
+
+		now := clock.Now()
+		err := reallocateForRecordWithDao(e.Dao, record, now)
+		if original := record.OriginalCopy(); err == nil && isUpdate && movedBetweenMembers(record, original) {
+			// A payment or adjustment moved to another member also changes the old member's cover.
+			err = reallocateForRecordWithDao(e.Dao, original, now)
+		}
+		if err == nil {
+			return nil
+		}
+
+		app.Logger().Error("Failed to rebuild payment allocations", "collection", record.Collection().Name, "id", record.Id, "error", err)
+		if markErr := markAllocationsStaleWithDao(e.Dao, allocationPlanID(record)); markErr != nil {
+			return errors.Join(err, markErr)
+		}
+
+		return nil
+	}
+
+	sources := make([]string, 0, len(allocationFields))
+	for collectionName := range allocationFields {
+		sources = append(sources, collectionName)
+	}
+
+	app.OnModelAfterCreate(sources...).Add(func(e *core.ModelEvent) error {
+		return reallocate(e, false)
+	})
+	app.OnModelAfterUpdate(sources...).Add(func(e *core.ModelEvent) error {
+		return reallocate(e, true)
+	})
+	app.OnModelAfterDelete(sources...).Add(func(e *core.ModelEvent) error {
+		return reallocate(e, false)
+	})
+}
+
+// ReallocateAllWithDao rebuilds the allocations of every plan as of now.
+func ReallocateAllWithDao(dao *daos.Dao, now time.Time) error {
+	plans, err := dao.FindRecordsByFilter("family_plans", "id != ''", "", -1, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, plan := range plans {
+		if err := ReallocatePlanWithDao(dao, plan.Id, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReallocateStaleWithDao rebuilds, as of now, the allocations of the plans whose last rebuild failed.
+func ReallocateStaleWithDao(dao *daos.Dao, now time.Time) error {
+	plans, err := dao.FindRecordsByFilter("family_plans", "allocations_stale = true", "", -1, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, plan := range plans {
+		if err := ReallocatePlanWithDao(dao, plan.Id, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// changesAllocations reports whether an update touched a field allocations are derived from.
+func changesAllocations(record *pbmodels.Record) bool {
+	original := record.OriginalCopy()
+	for _, field := range allocationFields[record.Collection().Name] {
+		if fmt.Sprint(record.Get(field)) != fmt.Sprint(original.Get(field)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// movedBetweenMembers reports whether a payment or adjustment now belongs to a different member or plan.
+func movedBetweenMembers(record, original *pbmodels.Record) bool {
+	switch record.Collection().Name {
+	case "payments", AdjustmentsCollection:
+		if original.GetString("plan_id") == "" {
+			return false
+		}
+
+		return record.GetString("plan_id") != original.GetString("plan_id") ||
+			record.GetString("user_id") != original.GetString("user_id")
+	default:
+		return false
+	}
+}
+
+func allocationPlanID(record *pbmodels.Record) string {
+	if record.Collection().Name == "family_plans" {
+		return record.Id
+	}
+
+	return record.GetString("plan_id")
+}
+
+// markAllocationsStaleWithDao flags a plan for ReallocateStaleWithDao. Deleted plans have nothing to
+// rebuild.
+func markAllocationsStaleWithDao(dao *daos.Dao, planID string) error {
+	plan, err := dao.FindRecordById("family_plans", planID)
+	if err != nil {
+		return nil
+	}
+
+	plan.Set("allocations_stale", true)
+	return dao.SaveRecord(plan)
+}
+
+func reallocateForRecordWithDao(dao *daos.Dao, record *pbmodels.Record, now time.Time) error {
+	switch record.Collection().Name {
+	case "payments":
+		return ReallocateMemberWithDao(dao, record.GetString("plan_id"), record.GetString("user_id"), now)
+	case AdjustmentsCollection:
+		if AppliesToAllMembers(record) {
+			return ReallocatePlanWithDao(dao, record.GetString("plan_id"), now)
+		}
+		return ReallocateMemberWithDao(dao, record.GetString("plan_id"), record.GetString("user_id"), now)
+	case "memberships":
+		// Joining, leaving or pausing changes everyone's share of the cost.
+		return ReallocatePlanWithDao(dao, record.GetString("plan_id"), now)
+	case "family_plans":
+		return ReallocatePlanWithDao(dao, record.Id, now)
+	default:
+		return nil
+	}
+}
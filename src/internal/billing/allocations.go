@@ -0,0 +1,467 @@
+package billing
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// AllocationsCollection is the PocketBase collection that records which credit covers which month.
+const AllocationsCollection = "allocations"
+
+// maxPrepaidMonths caps how far ahead unattributed credit is spread.
+const maxPrepaidMonths = 120
+
+// MonthCharge is what a member owes for one month before any payment or credit.
+type MonthCharge struct {
+	Month    string
+	DueCents int64
+}
+
+// Allocation assigns part of a payment or credit adjustment to one month of a member's charges.
+type Allocation struct {
+	Month        string
+	AmountCents  int64
+	PaymentID    string
+	AdjustmentID string
+}
+
+// MonthCoverage is how much of one month's charge a member has covered.
+type MonthCoverage struct {
+	Month        string
+	DueCents     int64
+	CoveredCents int64
+}
+
+// allocationCredit is money available to cover charges. A credit with a Month settles that month first.
+type allocationCredit struct {
+	PaymentID    string
+	AdjustmentID string
+	Month        string
+	AmountCents  int64
+	at           time.Time
+}
+
+func (c allocationCredit) allocation(month string, amountCents int64) Allocation {
+	return Allocation{
+		Month:        month,
+		AmountCents:  amountCents,
+		PaymentID:    c.PaymentID,
+		AdjustmentID: c.AdjustmentID,
+	}
+}
+
+// allocateCredits settles month-attributed credits against their own month, then spreads what is
+// left over the oldest months that are still unpaid. Credits are used in the order given and
+// charges must be in month order.
+func allocateCredits(charges []MonthCharge, credits []allocationCredit) []Allocation {
+	remaining := make(map[string]int64, len(charges))
+	for _, charge := range charges {
+		remaining[charge.Month] += charge.DueCents
+	}
+
+	allocations := []Allocation{}
+	unattributed := make([]allocationCredit, 0, len(credits))
+	for _, credit := range credits {
+		if due := remaining[credit.Month]; credit.Month != "" && due > 0 {
+			applied := min(due, credit.AmountCents)
+			allocations = append(allocations, credit.allocation(credit.Month, applied))
+			remaining[credit.Month] -= applied
+			credit.AmountCents -= applied
+		}
+
+		if credit.AmountCents > 0 {
+			unattributed = append(unattributed, credit)
+		}
+	}
+
+	next := 0
+	for _, credit := range unattributed {
+		for credit.AmountCents > 0 && next < len(charges) {
+			month := charges[next].Month
+			due := remaining[month]
+			if due <= 0 {
+				next++
+				continue
+			}
+
+			applied := min(due, credit.AmountCents)
+			allocations = append(allocations, credit.allocation(month, applied))
+			remaining[month] -= applied
+			credit.AmountCents -= applied
+		}
+	}
+
+	return allocations
+}
+
+// ReallocatePlanWithDao rebuilds the allocations of every member of a plan as of now and clears its
+// stale mark. Allocations of a deleted plan are removed.
+func ReallocatePlanWithDao(dao *daos.Dao, planID string, now time.Time) error {
+	plan, err := dao.FindRecordById("family_plans", planID)
+	if err != nil {
+		return deleteAllocationsWithDao(dao, planutil.FilterTerm{Field: "plan_id", Value: planID})
+	}
+
+	filter, err := planutil.BuildEqualsFilter(
+		planutil.FilterTerm{Field: "plan_id", Value: planID},
+	)
+	if err != nil {
+		return err
+	}
+
+	memberships, err := dao.FindRecordsByFilter("memberships", filter.Expression, "", -1, 0, filter.Params)
+	if err != nil {
+		return err
+	}
+
+	if err := deleteAllocationsWithDao(dao, planutil.FilterTerm{Field: "plan_id", Value: planID}); err != nil {
+		return err
+	}
+
+	for _, membership := range memberships {
+		if membership.GetString("user_id") == planutil.OwnerID(plan) {
+			continue
+		}
+
+		if err := saveMemberAllocationsWithDao(dao, plan, membership, now); err != nil {
+			return err
+		}
+	}
+
+	if plan.GetBool("allocations_stale") {
+		plan.Set("allocations_stale", false)
+		return dao.SaveRecord(plan)
+	}
+
+	return nil
+}
+
+// ReallocateMemberWithDao rebuilds a member's allocations from their charges, payments and adjustments
+// as of now.
+func ReallocateMemberWithDao(dao *daos.Dao, planID, userID string, now time.Time) error {
+	if err := deleteAllocationsWithDao(dao,
+		planutil.FilterTerm{Field: "plan_id", Value: planID},
+		planutil.FilterTerm{Field: "user_id", Value: userID},
+	); err != nil {
+		return err
+	}
+
+	plan, err := dao.FindRecordById("family_plans", planID)
+	if err != nil {
+		return nil
+	}
+
+	membership, err := planutil.FindMembershipWithDao(dao, planID, userID)
+	if err != nil {
+		return err
+	}
+	if membership == nil || userID == planutil.OwnerID(plan) {
+		return nil
+	}
+
+	return saveMemberAllocationsWithDao(dao, plan, membership, now)
+}
+
+// CoverageWithDao returns a member's charge and allocated cover for each month from first through
+// last. Months after now's are charged at the split as it stands.
+func CoverageWithDao(dao *daos.Dao, planID, userID string, first, last, now time.Time) ([]MonthCoverage, error) {
+	plan, err := dao.FindRecordById("family_plans", planID)
+	if err != nil {
+		return nil, err
+	}
+
+	membership, err := planutil.FindMembershipWithDao(dao, planID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if membership == nil {
+		return nil, errors.New("membership not found")
+	}
+
+	charges, _, err := memberChargesAndCreditsWithDao(dao, plan, membership, nil, now)
+	if err != nil {
+		return nil, err
+	}
+
+	dueByMonth := make(map[string]int64, len(charges))
+	for _, charge := range charges {
+		dueByMonth[charge.Month] = charge.DueCents
+	}
+
+	filter, err := planutil.BuildEqualsFilter(
+		planutil.FilterTerm{Field: "plan_id", Value: planID},
+		planutil.FilterTerm{Field: "user_id", Value: userID},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	allocations, err := dao.FindRecordsByFilter(AllocationsCollection, filter.Expression, "", -1, 0, filter.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	coveredByMonth := make(map[string]int64, len(allocations))
+	for _, allocation := range allocations {
+		coveredByMonth[allocation.GetString("month")] += int64(allocation.GetInt("amount"))
+	}
+
+	startMonth, endMonth := billedMonths(plan, membership, now)
+	open := billedAhead(plan, membership)
+
+	coverage := []MonthCoverage{}
+	for month := monthStart(first); !month.After(last); month = month.AddDate(0, 1, 0) {
+		monthKey := month.Format(monthKeyLayout)
+		entry := MonthCoverage{Month: monthKey, CoveredCents: coveredByMonth[monthKey]}
+
+		switch {
+		case month.Before(startMonth):
+		case !month.After(endMonth):
+			entry.DueCents = dueByMonth[monthKey]
+		case open:
+			// Future months are charged at the split as it stands today.
+			entry.DueCents, _, err = memberShareForMonthWithDao(dao, plan, userID, month)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		coverage = append(coverage, entry)
+	}
+
+	return coverage, nil
+}
+
+func saveMemberAllocationsWithDao(dao *daos.Dao, plan, membership *pbmodels.Record, now time.Time) error {
+	allocations, err := memberAllocationsWithDao(dao, plan, membership, now)
+	if err != nil {
+		return err
+	}
+
+	collection, err := dao.FindCollectionByNameOrId(AllocationsCollection)
+	if err != nil {
+		return err
+	}
+
+	for _, allocation := range allocations {
+		record := pbmodels.NewRecord(collection)
+		record.Set("plan_id", plan.Id)
+		record.Set("user_id", membership.GetString("user_id"))
+		record.Set("month", allocation.Month)
+		record.Set("amount", allocation.AmountCents)
+		record.Set("payment_id", allocation.PaymentID)
+		record.Set("adjustment_id", allocation.AdjustmentID)
+		if err := dao.SaveRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// memberAllocationsWithDao spreads a member's credit over their billed months and, while the
+// membership is open, over the months ahead that the remaining credit pays for.
+func memberAllocationsWithDao(dao *daos.Dao, plan, membership *pbmodels.Record, now time.Time) ([]Allocation, error) {
+	charges, credits, err := memberChargesAndCreditsWithDao(dao, plan, membership, nil, now)
+	if err != nil {
+		return nil, err
+	}
+
+	allocations := allocateCredits(charges, credits)
+	if !billedAhead(plan, membership) {
+		return allocations, nil
+	}
+
+	leftoverCents := int64(0)
+	for _, credit := range credits {
+		leftoverCents += credit.AmountCents
+	}
+	for _, allocation := range allocations {
+		leftoverCents -= allocation.AmountCents
+	}
+	if leftoverCents <= 0 {
+		return allocations, nil
+	}
+
+	_, endMonth := billedMonths(plan, membership, now)
+	userID := membership.GetString("user_id")
+	month := endMonth.AddDate(0, 1, 0)
+	for i := 0; leftoverCents > 0 && i < maxPrepaidMonths; i++ {
+		shareCents, _, err := memberShareForMonthWithDao(dao, plan, userID, month)
+		if err != nil {
+			return nil, err
+		}
+
+		charges = append(charges, MonthCharge{Month: month.Format(monthKeyLayout), DueCents: shareCents})
+		leftoverCents -= shareCents
+		month = month.AddDate(0, 1, 0)
+	}
+
+	return allocateCredits(charges, credits), nil
+}
+
+// memberChargesAndCreditsWithDao returns what a member was charged each billed month and every
+// payment or credit adjustment available to cover it, oldest first. It mirrors
+// CalculateMemberBalanceWithDao, so credits minus charges is the member's balance.
+// Waived adjustments and those matched by skipAdjustment are left out.
+func memberChargesAndCreditsWithDao(dao *daos.Dao, plan, membership *pbmodels.Record, skipAdjustment func(*pbmodels.Record) bool, now time.Time) ([]MonthCharge, []allocationCredit, error) {
+	userID := membership.GetString("user_id")
+	currency := planutil.Currency(plan)
+	startMonth, endMonth := billedMonths(plan, membership, now)
+	startKey := startMonth.Format(monthKeyLayout)
+	endKey := endMonth.Format(monthKeyLayout)
+
+	credits := []allocationCredit{}
+
+	payments, err := approvedPaymentsWithDao(dao, plan.Id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, payment := range payments {
+		credit := allocationCredit{
+			PaymentID:   payment.Id,
+			AmountCents: PaymentAmount(payment, currency).Minor,
+			at:          payment.GetDateTime("date").Time(),
+		}
+		if forMonth := payment.GetDateTime("for_month"); !forMonth.IsZero() {
+			credit.Month = forMonth.Time().Format(monthKeyLayout)
+		}
+		if credit.at.IsZero() {
+			credit.at = payment.GetDateTime("created").Time()
+		}
+
+		credits = append(credits, credit)
+	}
+
+	adjustments, err := FindAdjustmentsWithDao(dao, plan.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	extraDueByMonth := make(map[string]int64)
+	sharedAdjustments := make(map[string][]*pbmodels.Record)
+	addAdjustment := func(adjustment *pbmodels.Record, monthKey string) {
+		amountCents := AdjustmentAmount(adjustment, currency).Minor
+		if amountCents < 0 {
+			extraDueByMonth[monthKey] -= amountCents
+			return
+		}
+
+		credits = append(credits, allocationCredit{
+			AdjustmentID: adjustment.Id,
+			Month:        monthKey,
+			AmountCents:  amountCents,
+			at:           adjustment.GetDateTime("for_month").Time(),
+		})
+	}
+
+	for _, adjustment := range adjustments {
+		if IsWaived(adjustment) || (skipAdjustment != nil && skipAdjustment(adjustment)) {
+			continue
+		}
+
+		monthKey := adjustment.GetDateTime("for_month").Time().Format(monthKeyLayout)
+
+		switch {
+		case AppliesToAllMembers(adjustment):
+			sharedAdjustments[monthKey] = append(sharedAdjustments[monthKey], adjustment)
+		case adjustment.GetString("user_id") == userID && monthKey <= endKey:
+			addAdjustment(adjustment, max(monthKey, startKey))
+		}
+	}
+
+	charges := []MonthCharge{}
+	for month := startMonth; !month.After(endMonth); month = month.AddDate(0, 1, 0) {
+		monthKey := month.Format(monthKeyLayout)
+
+		shareCents, userActive, err := memberShareForMonthWithDao(dao, plan, userID, month)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if userActive {
+			for _, adjustment := range sharedAdjustments[monthKey] {
+				addAdjustment(adjustment, monthKey)
+			}
+		}
+
+		charges = append(charges, MonthCharge{Month: monthKey, DueCents: shareCents})
+	}
+
+	for i := range charges {
+		charges[i].DueCents += extraDueByMonth[charges[i].Month]
+	}
+
+	sort.SliceStable(credits, func(i, j int) bool {
+		return credits[i].at.Before(credits[j].at)
+	})
+
+	return charges, credits, nil
+}
+
+func approvedPaymentsWithDao(dao *daos.Dao, planID, userID string) ([]*pbmodels.Record, error) {
+	// Payments credit their beneficiary (user_id) regardless of who paid them.
+	filter, err := planutil.BuildEqualsFilter(
+		planutil.FilterTerm{Field: "plan_id", Value: planID},
+		planutil.FilterTerm{Field: "user_id", Value: userID},
+		planutil.FilterTerm{Field: "status", Value: "approved"},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return dao.FindRecordsByFilter("payments", filter.Expression, "created", -1, 0, filter.Params)
+}
+
+// approvedPaymentsAsOfWithDao returns the approved payments dated on or before asOf. A payment
+// without a date counts from when it was recorded.
+func approvedPaymentsAsOfWithDao(dao *daos.Dao, planID, userID string, asOf time.Time) ([]*pbmodels.Record, error) {
+	payments, err := approvedPaymentsWithDao(dao, planID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	paid := make([]*pbmodels.Record, 0, len(payments))
+	for _, payment := range payments {
+		if !paymentDate(payment).After(asOf) {
+			paid = append(paid, payment)
+		}
+	}
+
+	return paid, nil
+}
+
+// paymentDate returns the day a payment was made, or when it was recorded if it has no date.
+func paymentDate(payment *pbmodels.Record) time.Time {
+	if date := payment.GetDateTime("date"); !date.IsZero() {
+		return date.Time()
+	}
+
+	return payment.GetDateTime("created").Time()
+}
+
+func deleteAllocationsWithDao(dao *daos.Dao, terms ...planutil.FilterTerm) error {
+	filter, err := planutil.BuildEqualsFilter(terms...)
+	if err != nil {
+		return err
+	}
+
+	records, err := dao.FindRecordsByFilter(AllocationsCollection, filter.Expression, "", -1, 0, filter.Params)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := dao.DeleteRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
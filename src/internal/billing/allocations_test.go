@@ -0,0 +1,189 @@
+package billing
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/testapp"
+
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+func TestAllocateCreditsFillsOldestMonthsFirst(t *testing.T) {
+	t.Parallel()
+
+	charges := []MonthCharge{
+		{Month: "2026-01", DueCents: 1000},
+		{Month: "2026-02", DueCents: 1000},
+		{Month: "2026-03", DueCents: 1000},
+		{Month: "2026-04", DueCents: 0},
+		{Month: "2026-05", DueCents: 1000},
+	}
+
+	tests := []struct {
+		name    string
+		credits []allocationCredit
+		want    []Allocation
+	}{
+		{
+			name:    "unattributed credit spreads oldest first and skips free months",
+			credits: []allocationCredit{{PaymentID: "p1", AmountCents: 3500}},
+			want: []Allocation{
+				{Month: "2026-01", AmountCents: 1000, PaymentID: "p1"},
+				{Month: "2026-02", AmountCents: 1000, PaymentID: "p1"},
+				{Month: "2026-03", AmountCents: 1000, PaymentID: "p1"},
+				{Month: "2026-05", AmountCents: 500, PaymentID: "p1"},
+			},
+		},
+		{
+			name: "attributed payment settles its own month before unattributed credit",
+			credits: []allocationCredit{
+				{PaymentID: "p1", AmountCents: 1500},
+				{PaymentID: "p2", Month: "2026-01", AmountCents: 1200},
+			},
+			want: []Allocation{
+				{Month: "2026-01", AmountCents: 1000, PaymentID: "p2"},
+				{Month: "2026-02", AmountCents: 1000, PaymentID: "p1"},
+				{Month: "2026-03", AmountCents: 500, PaymentID: "p1"},
+				{Month: "2026-03", AmountCents: 200, PaymentID: "p2"},
+			},
+		},
+		{
+			name:    "credit adjustments allocate like payments",
+			credits: []allocationCredit{{AdjustmentID: "a1", Month: "2026-02", AmountCents: 400}},
+			want: []Allocation{
+				{Month: "2026-02", AmountCents: 400, AdjustmentID: "a1"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := allocateCredits(charges, test.credits); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("allocateCredits() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReallocateMemberPrepaysFutureMonths(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+
+	now := time.Now()
+	thisMonth := monthStart(now)
+	lastMonth := thisMonth.AddDate(0, -1, 0)
+	testapp.SaveMembership(t, app, plan.Id, member.Id, lastMonth.AddDate(0, 0, 3))
+
+	paymentsCollection, err := app.Dao().FindCollectionByNameOrId("payments")
+	if err != nil {
+		t.Fatalf("failed to find payments collection: %v", err)
+	}
+
+	// Last month and this month cost 1000 each, so 3500 also covers the next month and half of the one after.
+	payment := pbmodels.NewRecord(paymentsCollection)
+	payment.Set("plan_id", plan.Id)
+	payment.Set("user_id", member.Id)
+	payment.Set("amount", 3500)
+	payment.Set("date", now)
+	payment.Set("status", "approved")
+	if err := app.Dao().SaveRecord(payment); err != nil {
+		t.Fatalf("failed to save payment: %v", err)
+	}
+
+	if err := ReallocateMemberWithDao(app.Dao(), plan.Id, member.Id, now); err != nil {
+		t.Fatalf("ReallocateMemberWithDao returned error: %v", err)
+	}
+
+	coverage, err := CoverageWithDao(app.Dao(), plan.Id, member.Id, lastMonth.AddDate(0, -1, 0), thisMonth.AddDate(0, 3, 0), now)
+	if err != nil {
+		t.Fatalf("CoverageWithDao returned error: %v", err)
+	}
+
+	want := []MonthCoverage{
+		{Month: lastMonth.AddDate(0, -1, 0).Format("2006-01"), DueCents: 0, CoveredCents: 0},
+		{Month: lastMonth.Format("2006-01"), DueCents: 1000, CoveredCents: 1000},
+		{Month: thisMonth.Format("2006-01"), DueCents: 1000, CoveredCents: 1000},
+		{Month: thisMonth.AddDate(0, 1, 0).Format("2006-01"), DueCents: 1000, CoveredCents: 1000},
+		{Month: thisMonth.AddDate(0, 2, 0).Format("2006-01"), DueCents: 1000, CoveredCents: 500},
+		{Month: thisMonth.AddDate(0, 3, 0).Format("2006-01"), DueCents: 1000, CoveredCents: 0},
+	}
+	if !reflect.DeepEqual(coverage, want) {
+		t.Fatalf("CoverageWithDao() = %+v, want %+v", coverage, want)
+	}
+
+	if err := ReallocatePlanWithDao(app.Dao(), plan.Id, now); err != nil {
+		t.Fatalf("ReallocatePlanWithDao returned error: %v", err)
+	}
+	records, err := app.Dao().FindRecordsByFilter(AllocationsCollection, "id != ''", "", -1, 0)
+	if err != nil {
+		t.Fatalf("failed to load allocations: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("allocations after rebuilding the plan = %d, want 4", len(records))
+	}
+}
+
+func TestChangesAllocationsIgnoresFieldsOutsideTheCostSplit(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	saved := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+
+	tests := []struct {
+		name  string
+		field string
+		value any
+		want  bool
+	}{
+		{name: "rename", field: "name", value: "Renamed", want: false},
+		{name: "stale mark", field: "allocations_stale", value: true, want: false},
+		{name: "cost", field: "cost", value: 3000, want: true},
+		{name: "archive", field: "archived_at", value: time.Now(), want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan := testapp.FindRecord(t, app, "family_plans", saved.Id)
+			plan.Set(test.field, test.value)
+
+			if got := changesAllocations(plan); got != test.want {
+				t.Fatalf("changesAllocations() after setting %s = %v, want %v", test.field, got, test.want)
+			}
+		})
+	}
+}
+
+func TestReallocateStaleRebuildsMarkedPlans(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+	now := time.Now()
+	testapp.SaveMembership(t, app, plan.Id, member.Id, monthStart(now))
+	testapp.SavePayment(t, app, plan.Id, member.Id, 1000, now, "approved")
+
+	if err := markAllocationsStaleWithDao(app.Dao(), plan.Id); err != nil {
+		t.Fatalf("markAllocationsStaleWithDao returned error: %v", err)
+	}
+	if err := ReallocateStaleWithDao(app.Dao(), now); err != nil {
+		t.Fatalf("ReallocateStaleWithDao returned error: %v", err)
+	}
+
+	if testapp.FindRecord(t, app, "family_plans", plan.Id).GetBool("allocations_stale") {
+		t.Fatal("expected the stale mark to be cleared")
+	}
+
+	records, err := app.Dao().FindRecordsByFilter(AllocationsCollection, "id != ''", "", -1, 0)
+	if err != nil {
+		t.Fatalf("failed to load allocations: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("allocations after the stale rebuild = %d, want 1", len(records))
+	}
+}
@@ -0,0 +1,80 @@
+package billing
+
+import (
+	"errors"
+	"time"
+
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+var (
+	// ErrPlanArchived indicates a change to a plan that has been archived.
+	ErrPlanArchived = errors.New("plan is archived")
+	// ErrPlanNotArchived indicates restoring or purging a plan that is still in use.
+	ErrPlanNotArchived = errors.New("plan is not archived")
+)
+
+// ArchivePlanWithDao makes a plan read-only. Members are billed through the month it is archived
+// and no later; standing orders and late fees stop with it.
+func ArchivePlanWithDao(dao *daos.Dao, planID string, archivedAt time.Time) error {
+	plan, err := dao.FindRecordById("family_plans", planID)
+	if err != nil {
+		return err
+	}
+	if planutil.IsArchived(plan) {
+		return ErrPlanArchived
+	}
+
+	plan.Set("archived_at", archivedAt)
+	if err := dao.SaveRecord(plan); err != nil {
+		return err
+	}
+
+	return ReallocatePlanWithDao(dao, planID, archivedAt)
+}
+
+// RestorePlanWithDao puts an archived plan back in use. Billing restarts with the month of
+// restoredAt, so open memberships are not charged for the months the plan spent archived.
+func RestorePlanWithDao(dao *daos.Dao, planID string, restoredAt time.Time) error {
+	plan, err := dao.FindRecordById("family_plans", planID)
+	if err != nil {
+		return err
+	}
+	if !planutil.IsArchived(plan) {
+		return ErrPlanNotArchived
+	}
+
+	filter, err := planutil.BuildEqualsFilter(
+		planutil.FilterTerm{Field: "plan_id", Value: planID},
+	)
+	if err != nil {
+		return err
+	}
+
+	memberships, err := dao.FindRecordsByFilter("memberships", filter.Expression, "", -1, 0, filter.Params)
+	if err != nil {
+		return err
+	}
+
+	if gap, ok := gapBetween(planutil.ArchivedAt(plan), restoredAt, InactiveReasonArchived); ok {
+		for _, membership := range memberships {
+			if !membership.GetDateTime("date_ended").IsZero() {
+				continue
+			}
+
+			membership.Set("inactive_periods", append(InactivePeriods(membership), gap))
+			if err := dao.SaveRecord(membership); err != nil {
+				return err
+			}
+		}
+	}
+
+	plan.Set("archived_at", nil)
+	if err := dao.SaveRecord(plan); err != nil {
+		return err
+	}
+
+	return ReallocatePlanWithDao(dao, planID, restoredAt)
+}
@@ -0,0 +1,66 @@
+package billing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/planutil"
+	"familyplan/src/internal/testapp"
+)
+
+func TestArchivePlanStopsBillingUntilRestored(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+	testapp.SaveMembership(t, app, plan.Id, member.Id, time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC))
+
+	archivedAt := time.Date(2026, time.February, 10, 0, 0, 0, 0, time.UTC)
+	if err := ArchivePlanWithDao(app.Dao(), plan.Id, archivedAt); err != nil {
+		t.Fatalf("ArchivePlanWithDao returned error: %v", err)
+	}
+	if err := ArchivePlanWithDao(app.Dao(), plan.Id, archivedAt); !errors.Is(err, ErrPlanArchived) {
+		t.Fatalf("ArchivePlanWithDao(archived plan) error = %v, want ErrPlanArchived", err)
+	}
+
+	archived, err := app.Dao().FindRecordById("family_plans", plan.Id)
+	if err != nil {
+		t.Fatalf("failed to reload plan: %v", err)
+	}
+	if !planutil.IsArchived(archived) {
+		t.Fatal("expected plan to be archived")
+	}
+
+	shareCents, _, err := memberShareForMonthWithDao(app.Dao(), archived, member.Id, archivedAt)
+	if err != nil {
+		t.Fatalf("memberShareForMonthWithDao returned error: %v", err)
+	}
+
+	balance, err := CalculateMemberBalanceWithDao(app.Dao(), plan.Id, member.Id, archivedAt.AddDate(0, 2, 0))
+	if err != nil {
+		t.Fatalf("CalculateMemberBalanceWithDao returned error: %v", err)
+	}
+	if balance.Minor != -2*shareCents {
+		t.Fatalf("archived balance = %d, want January and February only (%d)", balance.Minor, -2*shareCents)
+	}
+
+	restoredAt := time.Date(2026, time.May, 3, 0, 0, 0, 0, time.UTC)
+	if err := RestorePlanWithDao(app.Dao(), plan.Id, restoredAt); err != nil {
+		t.Fatalf("RestorePlanWithDao returned error: %v", err)
+	}
+	if err := RestorePlanWithDao(app.Dao(), plan.Id, restoredAt); !errors.Is(err, ErrPlanNotArchived) {
+		t.Fatalf("RestorePlanWithDao(plan in use) error = %v, want ErrPlanNotArchived", err)
+	}
+
+	membership, err := planutil.FindMembershipWithDao(app.Dao(), plan.Id, member.Id)
+	if err != nil {
+		t.Fatalf("FindMembershipWithDao returned error: %v", err)
+	}
+
+	periods := InactivePeriods(membership)
+	if len(periods) != 1 || periods[0].Start != "2026-03" || periods[0].End != "2026-04" || periods[0].Reason != InactiveReasonArchived {
+		t.Fatalf("inactive periods = %+v, want archived 2026-03 through 2026-04", periods)
+	}
+}
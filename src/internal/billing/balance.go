@@ -10,66 +10,48 @@ import (
 
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
 )
 
-// CalculateMemberBalance calculates the current balance for a member.
-func CalculateMemberBalance(app *pocketbase.PocketBase, planID, userID string) (float64, error) {
-	return CalculateMemberBalanceWithDao(app.Dao(), planID, userID)
+// CalculateMemberBalance calculates a member's balance at asOf in the plan's currency.
+func CalculateMemberBalance(app *pocketbase.PocketBase, planID, userID string, asOf time.Time) (money.Amount, error) {
+	return CalculateMemberBalanceWithDao(app.Dao(), planID, userID, asOf)
 }
 
-// CalculateMemberBalanceWithDao calculates the current balance using the provided dao.
-func CalculateMemberBalanceWithDao(dao *daos.Dao, planID, userID string) (float64, error) {
+// CalculateMemberBalanceWithDao calculates what a member's balance was at asOf: the months billed
+// through asOf's month, less the approved payments dated on or before it. Pass the current time
+// for the balance as it stands.
+func CalculateMemberBalanceWithDao(dao *daos.Dao, planID, userID string, asOf time.Time) (money.Amount, error) {
 	plansCollection, err := dao.FindCollectionByNameOrId("family_plans")
 	if err != nil {
-		return 0, err
+		return money.Amount{}, err
 	}
 
 	plan, err := dao.FindRecordById(plansCollection.Id, planID)
 	if err != nil {
-		return 0, err
+		return money.Amount{}, err
 	}
 
-	monthlyCostCents := money.ToCents(plan.GetFloat("cost"))
+	currency := planutil.Currency(plan)
 
 	membership, err := planutil.FindMembershipWithDao(dao, planID, userID)
 	if err != nil {
-		return 0, err
+		return money.Amount{}, err
 	}
 	if membership == nil {
-		return 0, fmt.Errorf("membership not found")
+		return money.Amount{}, fmt.Errorf("membership not found")
 	}
 
-	paymentsCollection, err := dao.FindCollectionByNameOrId("payments")
+	userPayments, err := approvedPaymentsAsOfWithDao(dao, planID, userID, asOf)
 	if err != nil {
-		return 0, err
-	}
-
-	filter, err := planutil.BuildEqualsFilter(
-		planutil.FilterTerm{Field: "plan_id", Value: planID},
-		planutil.FilterTerm{Field: "user_id", Value: userID},
-		planutil.FilterTerm{Field: "status", Value: "approved"},
-	)
-	if err != nil {
-		return 0, err
-	}
-
-	userPayments, err := dao.FindRecordsByFilter(
-		paymentsCollection.Id,
-		filter.Expression,
-		"",
-		-1,
-		0,
-		filter.Params,
-	)
-	if err != nil {
-		return 0, err
+		return money.Amount{}, err
 	}
 
 	totalPaidCents := int64(0)
 	paymentsByMonth := make(map[string]int64)
 
 	for _, payment := range userPayments {
-		amountCents := money.ToCents(payment.GetFloat("amount"))
+		amountCents := PaymentAmount(payment, currency).Minor
 		totalPaidCents += amountCents
 
 		forMonth := payment.GetDateTime("for_month")
@@ -79,44 +61,31 @@ func CalculateMemberBalanceWithDao(dao *daos.Dao, planID, userID string) (float6
 		}
 	}
 
-	membershipStartDate := membership.GetDateTime("created").Time()
-	membershipEndDate := membership.GetDateTime("date_ended")
-
-	startMonth := time.Date(
-		membershipStartDate.Year(),
-		membershipStartDate.Month(),
-		1,
-		0,
-		0,
-		0,
-		0,
-		membershipStartDate.Location(),
-	)
-
-	var endMonth time.Time
-	if !membershipEndDate.IsZero() {
-		endMonth = time.Date(
-			membershipEndDate.Time().Year(),
-			membershipEndDate.Time().Month(),
-			1,
-			0,
-			0,
-			0,
-			0,
-			membershipEndDate.Time().Location(),
-		)
-	} else {
-		now := time.Now()
-		endMonth = time.Date(
-			now.Year(),
-			now.Month(),
-			1,
-			0,
-			0,
-			0,
-			0,
-			now.Location(),
-		)
+	startMonth, endMonth := billedMonths(plan, membership, asOf)
+
+	adjustments, err := FindAdjustmentsWithDao(dao, planID)
+	if err != nil {
+		return money.Amount{}, err
+	}
+
+	// Member adjustments count once their month arrives; shared ones only for months the member was active.
+	adjustmentCents := int64(0)
+	sharedAdjustmentsByMonth := make(map[string]int64)
+	endMonthKey := endMonth.Format("2006-01")
+	for _, adjustment := range adjustments {
+		if IsWaived(adjustment) {
+			continue
+		}
+
+		monthKey := adjustment.GetDateTime("for_month").Time().Format("2006-01")
+		amountCents := AdjustmentAmount(adjustment, currency).Minor
+
+		switch {
+		case AppliesToAllMembers(adjustment):
+			sharedAdjustmentsByMonth[monthKey] += amountCents
+		case adjustment.GetString("user_id") == userID && monthKey <= endMonthKey:
+			adjustmentCents += amountCents
+		}
 	}
 
 	amountDueCents := int64(0)
@@ -125,30 +94,16 @@ func CalculateMemberBalanceWithDao(dao *daos.Dao, planID, userID string) (float6
 	for !currentMonth.After(endMonth) {
 		monthKey := currentMonth.Format("2006-01")
 
-		activeMemberships, err := getActiveMembershipsForMonth(dao, planID, currentMonth)
+		shareCents, userActive, err := memberShareForMonthWithDao(dao, plan, userID, currentMonth)
 		if err != nil {
-			return 0, err
+			return money.Amount{}, err
 		}
 
-		memberIDs := make([]string, 0, len(activeMemberships)+1)
-		ownerIncluded := false
-		ownerID := planutil.OwnerID(plan)
-
-		for _, activeMembership := range activeMemberships {
-			memberID := activeMembership.GetString("user_id")
-			if memberID == ownerID {
-				ownerIncluded = true
-			}
-			memberIDs = append(memberIDs, memberID)
+		if userActive {
+			adjustmentCents += sharedAdjustmentsByMonth[monthKey]
 		}
 
-		if !ownerIncluded {
-			memberIDs = append(memberIDs, ownerID)
-		}
-
-		if len(memberIDs) > 0 {
-			amountDueCents += memberShareCents(monthlyCostCents, memberIDs, userID)
-		}
+		amountDueCents += shareCents
 
 		if paidAmount, exists := paymentsByMonth[monthKey]; exists {
 			totalPaidCents, amountDueCents = applyAttributedPayment(totalPaidCents, amountDueCents, paidAmount)
@@ -157,7 +112,58 @@ func CalculateMemberBalanceWithDao(dao *daos.Dao, planID, userID string) (float6
 		currentMonth = currentMonth.AddDate(0, 1, 0)
 	}
 
-	return money.FromCents(totalPaidCents - amountDueCents), nil
+	return money.New(totalPaidCents-amountDueCents+adjustmentCents, currency), nil
+}
+
+// billedMonths returns the first and last month a membership is billed for by asOf.
+// An open membership is billed through asOf's month, or the month its plan was archived.
+func billedMonths(plan, membership *pbmodels.Record, asOf time.Time) (time.Time, time.Time) {
+	startMonth := monthStart(membership.GetDateTime("created").Time())
+
+	if membershipEndDate := membership.GetDateTime("date_ended"); !membershipEndDate.IsZero() && membershipEndDate.Time().Before(asOf) {
+		return startMonth, monthStart(membershipEndDate.Time())
+	}
+	if archivedAt := planutil.ArchivedAt(plan); !archivedAt.IsZero() && archivedAt.Before(asOf) {
+		return startMonth, monthStart(archivedAt)
+	}
+
+	return startMonth, monthStart(asOf)
+}
+
+// billedAhead reports whether a membership will keep being billed, so credit can pay for months ahead.
+func billedAhead(plan, membership *pbmodels.Record) bool {
+	return membership.GetDateTime("date_ended").IsZero() && !planutil.IsArchived(plan)
+}
+
+// memberShareForMonthWithDao returns a member's share of the plan cost for a month, split
+// among the members billed that month and the owner, and whether the member was billed.
+func memberShareForMonthWithDao(dao *daos.Dao, plan *pbmodels.Record, userID string, month time.Time) (int64, bool, error) {
+	activeMemberships, err := getActiveMembershipsForMonth(dao, plan.Id, month)
+	if err != nil {
+		return 0, false, err
+	}
+
+	memberIDs := make([]string, 0, len(activeMemberships)+1)
+	ownerIncluded := false
+	ownerID := planutil.OwnerID(plan)
+
+	userActive := false
+	for _, activeMembership := range activeMemberships {
+		memberID := activeMembership.GetString("user_id")
+		if memberID == ownerID {
+			ownerIncluded = true
+		}
+		if memberID == userID {
+			userActive = true
+		}
+		memberIDs = append(memberIDs, memberID)
+	}
+
+	if !ownerIncluded {
+		memberIDs = append(memberIDs, ownerID)
+	}
+
+	return memberShareCents(planutil.Cost(plan).Minor, memberIDs, userID), userActive, nil
 }
 
 func applyAttributedPayment(totalPaidCents, amountDueCents, paidAmount int64) (int64, int64) {
@@ -210,11 +216,11 @@ func EndMembershipIfSettledWithDao(dao *daos.Dao, planID, userID string, endedAt
 		return nil
 	}
 
-	balance, err := CalculateMemberBalanceWithDao(dao, planID, userID)
+	balance, err := CalculateMemberBalanceWithDao(dao, planID, userID, endedAt)
 	if err != nil {
 		return err
 	}
-	if balance < 0 {
+	if balance.IsNegative() {
 		return nil
 	}
 
@@ -0,0 +1,28 @@
+package billing
+
+import "time"
+
+// Clock tells billing what time it is. The app reads the wall clock through SystemClock once, at
+// the edge: the scheduler, the hooks and each handler take a Clock and pass the time it reads into
+// the billing functions, which never read the time themselves. Tests pass a FixedClock instead.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to a Clock.
+type ClockFunc func() time.Time
+
+// Now calls f.
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock reads the wall clock.
+var SystemClock Clock = ClockFunc(time.Now)
+
+// FixedClock returns a clock that always reads at.
+func FixedClock(at time.Time) Clock {
+	return ClockFunc(func() time.Time {
+		return at
+	})
+}
@@ -0,0 +1,98 @@
+package billing
+
+import (
+	"errors"
+	"time"
+
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+const (
+	// PaymentKindPayment marks money received from a member.
+	PaymentKindPayment = "payment"
+	// PaymentKindWriteOff marks an owner-recorded forgiveness entry rather than money received.
+	PaymentKindWriteOff = "write_off"
+)
+
+var (
+	// ErrMembershipNotEnded indicates that the membership is still active.
+	ErrMembershipNotEnded = errors.New("membership has not ended")
+	// ErrNothingToWriteOff indicates that the member does not owe anything.
+	ErrNothingToWriteOff = errors.New("member has no outstanding balance")
+)
+
+// IsWriteOff reports whether a payment record is a forgiveness entry.
+func IsWriteOff(payment *pbmodels.Record) bool {
+	return payment.GetString("kind") == PaymentKindWriteOff
+}
+
+// ReinstateMembershipWithDao reactivates an ended membership without billing the months it was removed.
+func ReinstateMembershipWithDao(dao *daos.Dao, planID, userID string, reinstatedAt time.Time) error {
+	membership, err := planutil.FindMembershipWithDao(dao, planID, userID)
+	if err != nil {
+		return err
+	}
+	if membership == nil {
+		return errors.New("membership not found")
+	}
+
+	dateEnded := membership.GetDateTime("date_ended")
+	if dateEnded.IsZero() {
+		return ErrMembershipNotEnded
+	}
+
+	if gap, ok := RemovalGap(dateEnded.Time(), reinstatedAt); ok {
+		membership.Set("inactive_periods", append(InactivePeriods(membership), gap))
+	}
+
+	membership.Set("date_ended", nil)
+	membership.Set("leave_requested", false)
+	return dao.SaveRecord(membership)
+}
+
+// WriteOffBalanceWithDao records a forgiveness entry that settles a former member's outstanding balance.
+func WriteOffBalanceWithDao(dao *daos.Dao, planID, userID, notes string, writtenOffAt time.Time) (money.Amount, error) {
+	membership, err := planutil.FindMembershipWithDao(dao, planID, userID)
+	if err != nil {
+		return money.Amount{}, err
+	}
+	if membership == nil {
+		return money.Amount{}, errors.New("membership not found")
+	}
+	if membership.GetDateTime("date_ended").IsZero() {
+		return money.Amount{}, ErrMembershipNotEnded
+	}
+
+	balance, err := CalculateMemberBalanceWithDao(dao, planID, userID, writtenOffAt)
+	if err != nil {
+		return money.Amount{}, err
+	}
+	if !balance.IsNegative() {
+		return money.Amount{}, ErrNothingToWriteOff
+	}
+
+	paymentsCollection, err := dao.FindCollectionByNameOrId("payments")
+	if err != nil {
+		return money.Amount{}, err
+	}
+
+	amount := balance.Neg()
+
+	writeOff := pbmodels.NewRecord(paymentsCollection)
+	writeOff.Set("plan_id", planID)
+	writeOff.Set("user_id", userID)
+	writeOff.Set("amount", amount.Minor)
+	writeOff.Set("date", writtenOffAt)
+	writeOff.Set("status", "approved")
+	writeOff.Set("kind", PaymentKindWriteOff)
+	writeOff.Set("notes", notes)
+	if err := dao.SaveRecord(writeOff); err != nil {
+		return money.Amount{}, err
+	}
+
+	return amount, nil
+}
@@ -0,0 +1,106 @@
+package billing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/planutil"
+	"familyplan/src/internal/testapp"
+)
+
+func TestReinstateMembershipSkipsRemovedMonths(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+
+	created := time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC)
+	ended := time.Date(2026, time.February, 10, 0, 0, 0, 0, time.UTC)
+	membership := testapp.SaveMembership(t, app, plan.Id, member.Id, created)
+	membership.Set("date_ended", testapp.DateTime(t, ended))
+	if err := app.Dao().SaveRecord(membership); err != nil {
+		t.Fatalf("failed to end membership: %v", err)
+	}
+
+	balanceBefore, err := CalculateMemberBalanceWithDao(app.Dao(), plan.Id, member.Id, ended)
+	if err != nil {
+		t.Fatalf("CalculateMemberBalanceWithDao returned error: %v", err)
+	}
+
+	reinstatedAt := time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC)
+	if err := ReinstateMembershipWithDao(app.Dao(), plan.Id, member.Id, reinstatedAt); err != nil {
+		t.Fatalf("ReinstateMembershipWithDao returned error: %v", err)
+	}
+
+	reinstated, err := planutil.FindMembershipWithDao(app.Dao(), plan.Id, member.Id)
+	if err != nil {
+		t.Fatalf("FindMembershipWithDao returned error: %v", err)
+	}
+	if !reinstated.GetDateTime("date_ended").IsZero() {
+		t.Fatal("expected date_ended to be cleared")
+	}
+
+	periods := InactivePeriods(reinstated)
+	if len(periods) != 1 || periods[0].Start != "2026-03" || periods[0].End != "2026-04" {
+		t.Fatalf("inactive periods = %+v, want 2026-03 through 2026-04", periods)
+	}
+
+	for _, month := range []time.Month{time.March, time.April} {
+		active, err := getActiveMembershipsForMonth(app.Dao(), plan.Id, time.Date(2026, month, 1, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("getActiveMembershipsForMonth returned error: %v", err)
+		}
+		for _, record := range active {
+			if record.GetString("user_id") == member.Id {
+				t.Fatalf("reinstated member should not be billed for %s", month)
+			}
+		}
+	}
+
+	if !balanceBefore.IsNegative() {
+		t.Fatalf("balance before reinstating = %v, want outstanding debt", balanceBefore)
+	}
+}
+
+func TestWriteOffBalanceSettlesFormerMember(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+
+	created := time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC)
+	membership := testapp.SaveMembership(t, app, plan.Id, member.Id, created)
+	now := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, err := WriteOffBalanceWithDao(app.Dao(), plan.Id, member.Id, "", now); !errors.Is(err, ErrMembershipNotEnded) {
+		t.Fatalf("WriteOffBalanceWithDao error = %v, want ErrMembershipNotEnded", err)
+	}
+
+	membership.Set("date_ended", testapp.DateTime(t, time.Date(2026, time.February, 10, 0, 0, 0, 0, time.UTC)))
+	if err := app.Dao().SaveRecord(membership); err != nil {
+		t.Fatalf("failed to end membership: %v", err)
+	}
+
+	writtenOff, err := WriteOffBalanceWithDao(app.Dao(), plan.Id, member.Id, "forgiven", now)
+	if err != nil {
+		t.Fatalf("WriteOffBalanceWithDao returned error: %v", err)
+	}
+	if got := writtenOff.Minor; got != 2000 {
+		t.Fatalf("written off = %d cents, want 2000", got)
+	}
+
+	balance, err := CalculateMemberBalanceWithDao(app.Dao(), plan.Id, member.Id, now)
+	if err != nil {
+		t.Fatalf("CalculateMemberBalanceWithDao returned error: %v", err)
+	}
+	if balance.Minor != 0 {
+		t.Fatalf("balance after write-off = %v, want 0", balance)
+	}
+
+	if _, err := WriteOffBalanceWithDao(app.Dao(), plan.Id, member.Id, "", now); !errors.Is(err, ErrNothingToWriteOff) {
+		t.Fatalf("second WriteOffBalanceWithDao error = %v, want ErrNothingToWriteOff", err)
+	}
+}
@@ -0,0 +1,106 @@
+package billing
+
+import (
+	"time"
+
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+const monthKeyLayout = "2006-01"
+
+const (
+	// InactiveReasonRemoved marks months between a member's removal and reinstatement.
+	InactiveReasonRemoved = "removed"
+	// InactiveReasonTrial marks a new member's free first months.
+	InactiveReasonTrial = "trial"
+	// InactiveReasonGrace marks free months granted to a member by the owner.
+	InactiveReasonGrace = "grace"
+	// InactiveReasonPaused marks months a member has put their seat on hold.
+	InactiveReasonPaused = "paused"
+	// InactiveReasonArchived marks months the plan spent archived.
+	InactiveReasonArchived = "archived"
+)
+
+// InactivePeriod marks an inclusive range of months in which a membership is not billed.
+// An empty End leaves the period open.
+type InactivePeriod struct {
+	Start  string `json:"start"`
+	End    string `json:"end,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Contains reports whether the month falls within the period.
+func (p InactivePeriod) Contains(month time.Time) bool {
+	monthKey := month.Format(monthKeyLayout)
+	if p.Start == "" || monthKey < p.Start {
+		return false
+	}
+
+	return p.End == "" || monthKey <= p.End
+}
+
+// InactivePeriods returns the stored inactive periods for a membership.
+func InactivePeriods(membership *pbmodels.Record) []InactivePeriod {
+	periods := []InactivePeriod{}
+	if err := membership.UnmarshalJSONField("inactive_periods", &periods); err != nil {
+		return []InactivePeriod{}
+	}
+
+	return periods
+}
+
+// IsInactiveInMonth reports whether the membership is excluded from billing for the month.
+func IsInactiveInMonth(membership *pbmodels.Record, month time.Time) bool {
+	_, inactive := inactivePeriodForMonth(membership, month)
+	return inactive
+}
+
+// IsFree reports whether the period still gives the member the plan, just without charging them.
+func (p InactivePeriod) IsFree() bool {
+	return p.Reason == InactiveReasonTrial || p.Reason == InactiveReasonGrace
+}
+
+// FreePeriodForMonth returns the trial or grace period covering the month, if any.
+func FreePeriodForMonth(membership *pbmodels.Record, month time.Time) (InactivePeriod, bool) {
+	period, inactive := inactivePeriodForMonth(membership, month)
+	if !inactive || !period.IsFree() {
+		return InactivePeriod{}, false
+	}
+
+	return period, true
+}
+
+func inactivePeriodForMonth(membership *pbmodels.Record, month time.Time) (InactivePeriod, bool) {
+	for _, period := range InactivePeriods(membership) {
+		if period.Contains(month) {
+			return period, true
+		}
+	}
+
+	return InactivePeriod{}, false
+}
+
+// RemovalGap returns the unbilled months between a membership ending and being reinstated.
+// The ended month and the reinstated month both stay billable.
+func RemovalGap(endedAt, reinstatedAt time.Time) (InactivePeriod, bool) {
+	return gapBetween(endedAt, reinstatedAt, InactiveReasonRemoved)
+}
+
+// gapBetween returns the months strictly between the months of stoppedAt and resumedAt.
+func gapBetween(stoppedAt, resumedAt time.Time, reason string) (InactivePeriod, bool) {
+	gapStart := monthStart(stoppedAt).AddDate(0, 1, 0)
+	gapEnd := monthStart(resumedAt).AddDate(0, -1, 0)
+	if gapEnd.Before(gapStart) {
+		return InactivePeriod{}, false
+	}
+
+	return InactivePeriod{
+		Start:  gapStart.Format(monthKeyLayout),
+		End:    gapEnd.Format(monthKeyLayout),
+		Reason: reason,
+	}, true
+}
+
+func monthStart(value time.Time) time.Time {
+	return time.Date(value.Year(), value.Month(), 1, 0, 0, 0, 0, value.Location())
+}
@@ -0,0 +1,61 @@
+package billing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRemovalGapSkipsMonthsBetweenEndingAndReinstating(t *testing.T) {
+	t.Parallel()
+
+	endedAt := time.Date(2026, time.January, 20, 0, 0, 0, 0, time.UTC)
+	reinstatedAt := time.Date(2026, time.April, 3, 0, 0, 0, 0, time.UTC)
+
+	gap, ok := RemovalGap(endedAt, reinstatedAt)
+	if !ok {
+		t.Fatal("expected a removal gap")
+	}
+	if gap.Start != "2026-02" || gap.End != "2026-03" {
+		t.Fatalf("gap = %+v, want 2026-02 through 2026-03", gap)
+	}
+}
+
+func TestRemovalGapIsEmptyForAdjacentMonths(t *testing.T) {
+	t.Parallel()
+
+	endedAt := time.Date(2026, time.January, 20, 0, 0, 0, 0, time.UTC)
+
+	if _, ok := RemovalGap(endedAt, endedAt.AddDate(0, 0, 5)); ok {
+		t.Fatal("expected no gap when reinstated in the same month")
+	}
+	if _, ok := RemovalGap(endedAt, time.Date(2026, time.February, 2, 0, 0, 0, 0, time.UTC)); ok {
+		t.Fatal("expected no gap when reinstated the following month")
+	}
+}
+
+func TestInactivePeriodContains(t *testing.T) {
+	t.Parallel()
+
+	closed := InactivePeriod{Start: "2026-02", End: "2026-03"}
+	open := InactivePeriod{Start: "2026-02"}
+
+	tests := []struct {
+		name   string
+		period InactivePeriod
+		month  time.Time
+		want   bool
+	}{
+		{name: "before closed period", period: closed, month: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC), want: false},
+		{name: "inside closed period", period: closed, month: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), want: true},
+		{name: "after closed period", period: closed, month: time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC), want: false},
+		{name: "open period", period: open, month: time.Date(2027, time.June, 1, 0, 0, 0, 0, time.UTC), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.period.Contains(tt.month); got != tt.want {
+				t.Fatalf("Contains(%s) = %v, want %v", tt.month.Format("2006-01"), got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// MaxDueDay is the latest day of the month a plan's charges can fall due, so every month has one.
+const MaxDueDay = 28
+
+// ErrInvalidLateFeeRule indicates that a plan's due day or late fee settings are out of range.
+var ErrInvalidLateFeeRule = errors.New("due day must be between 1 and 28 and late fees cannot be negative")
+
+// LateFeeRule describes when a month's unpaid share becomes late and what that costs.
+// A month is late once it is still unpaid DaysOverdue days after its due date.
+type LateFeeRule struct {
+	DueDay      int
+	DaysOverdue int
+	Fixed       money.Amount
+	Percent     float64
+}
+
+// LateFeeRuleFor reads a plan's late fee rule.
+func LateFeeRuleFor(plan *pbmodels.Record) LateFeeRule {
+	return LateFeeRule{
+		DueDay:      DueDay(plan),
+		DaysOverdue: max(plan.GetInt("late_fee_days"), 0),
+		Fixed:       money.New(int64(plan.GetInt("late_fee_amount")), planutil.Currency(plan)),
+		Percent:     max(plan.GetFloat("late_fee_percent"), 0),
+	}
+}
+
+// DueDay returns the day of the month a plan's charges fall due, defaulting to the first.
+func DueDay(plan *pbmodels.Record) int {
+	day := plan.GetInt("due_day")
+	if day < 1 || day > MaxDueDay {
+		return 1
+	}
+
+	return day
+}
+
+// Validate checks that the rule can be stored on a plan.
+func (r LateFeeRule) Validate() error {
+	if r.DueDay < 1 || r.DueDay > MaxDueDay || r.DaysOverdue < 0 || r.Fixed.IsNegative() || !(r.Percent >= 0 && r.Percent <= 100) {
+		return ErrInvalidLateFeeRule
+	}
+
+	return nil
+}
+
+// Enabled reports whether the rule charges anything.
+func (r LateFeeRule) Enabled() bool {
+	return r.Fixed.IsPositive() || r.Percent > 0
+}
+
+// DueDate returns when a month's share falls due.
+func (r LateFeeRule) DueDate(month time.Time) time.Time {
+	return time.Date(month.Year(), month.Month(), r.DueDay, 0, 0, 0, 0, time.UTC)
+}
+
+// Deadline returns the moment a month's unpaid share becomes late: the end of the
+// DaysOverdue-th day after its due date.
+func (r LateFeeRule) Deadline(month time.Time) time.Time {
+	return r.DueDate(month).AddDate(0, 0, r.DaysOverdue+1)
+}
+
+// Fee returns the late fee for a month with outstandingCents still unpaid at its deadline.
+func (r LateFeeRule) Fee(outstandingCents int64) int64 {
+	if outstandingCents <= 0 {
+		return 0
+	}
+
+	return r.Fixed.Minor + int64(math.Round(float64(outstandingCents)*r.Percent/100))
+}
+
+// SyncLateFeesWithDao brings every member's late fee adjustments in line with the plan's rule as of now.
+// Fees are derived from payment dates rather than from when this runs, so syncing again never changes
+// the result. Waived fees are kept as they are, and an archived plan's fees are left alone. The sync
+// runs in one transaction, and a unique index stops concurrent syncs from filing the same fee twice.
+func SyncLateFeesWithDao(dao *daos.Dao, planID string, now time.Time) error {
+	return dao.RunInTransaction(func(txDao *daos.Dao) error {
+		plan, err := txDao.FindRecordById("family_plans", planID)
+		if err != nil {
+			return err
+		}
+		if planutil.IsArchived(plan) {
+			return nil
+		}
+
+		filter, err := planutil.BuildEqualsFilter(
+			planutil.FilterTerm{Field: "plan_id", Value: planID},
+		)
+		if err != nil {
+			return err
+		}
+
+		memberships, err := txDao.FindRecordsByFilter("memberships", filter.Expression, "", -1, 0, filter.Params)
+		if err != nil {
+			return err
+		}
+
+		adjustments, err := FindAdjustmentsWithDao(txDao, planID)
+		if err != nil {
+			return err
+		}
+
+		feesByUser := make(map[string]map[string]*pbmodels.Record)
+		for _, adjustment := range adjustments {
+			if !IsLateFee(adjustment) {
+				continue
+			}
+
+			userID := adjustment.GetString("user_id")
+			if feesByUser[userID] == nil {
+				feesByUser[userID] = make(map[string]*pbmodels.Record)
+			}
+			feesByUser[userID][adjustment.GetDateTime("for_month").Time().Format(monthKeyLayout)] = adjustment
+		}
+
+		for _, membership := range memberships {
+			userID := membership.GetString("user_id")
+			if userID == planutil.OwnerID(plan) {
+				continue
+			}
+
+			if err := syncMemberLateFeesWithDao(txDao, plan, membership, feesByUser[userID], now); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// SyncAllLateFeesWithDao syncs the late fees of every plan.
+func SyncAllLateFeesWithDao(dao *daos.Dao, now time.Time) error {
+	plans, err := dao.FindRecordsByFilter("family_plans", "id != ''", "", -1, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, plan := range plans {
+		if err := SyncLateFeesWithDao(dao, plan.Id, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func syncMemberLateFeesWithDao(dao *daos.Dao, plan, membership *pbmodels.Record, existing map[string]*pbmodels.Record, now time.Time) error {
+	rule := LateFeeRuleFor(plan)
+	expected := map[string]int64{}
+	if rule.Enabled() {
+		// Earlier fees must not make later months look unpaid, so they are left out of the charges.
+		charges, credits, err := memberChargesAndCreditsWithDao(dao, plan, membership, IsLateFee, now)
+		if err != nil {
+			return err
+		}
+
+		expected = lateFees(charges, credits, rule, now)
+	}
+
+	for monthKey, record := range existing {
+		if IsWaived(record) {
+			continue
+		}
+
+		feeCents, ok := expected[monthKey]
+		switch {
+		case !ok:
+			if err := dao.DeleteRecord(record); err != nil {
+				return err
+			}
+		case int64(record.GetInt("amount")) != -feeCents:
+			record.Set("amount", -feeCents)
+			if err := dao.SaveRecord(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	userID := membership.GetString("user_id")
+	for monthKey, feeCents := range expected {
+		if _, ok := existing[monthKey]; ok {
+			continue
+		}
+
+		month, err := time.Parse(monthKeyLayout, monthKey)
+		if err != nil {
+			return err
+		}
+
+		_, err = CreateAdjustmentWithDao(dao, Adjustment{
+			PlanID:   plan.Id,
+			UserID:   userID,
+			Amount:   money.New(-feeCents, planutil.Currency(plan)),
+			Reason:   fmt.Sprintf("Late fee: %s unpaid %d days after its due date", month.Format("January 2006"), rule.DaysOverdue),
+			ForMonth: month,
+			Kind:     AdjustmentKindLateFee,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lateFees returns the fee for each month that was not covered by the payments and credits
+// dated before its deadline. Credits attributed to a later month do not count toward it.
+func lateFees(charges []MonthCharge, credits []allocationCredit, rule LateFeeRule, now time.Time) map[string]int64 {
+	fees := map[string]int64{}
+
+	for i, charge := range charges {
+		if charge.DueCents <= 0 {
+			continue
+		}
+
+		month, err := time.Parse(monthKeyLayout, charge.Month)
+		if err != nil {
+			continue
+		}
+
+		deadline := rule.Deadline(month)
+		if deadline.After(now) {
+			continue
+		}
+
+		paidInTime := []allocationCredit{}
+		for _, credit := range credits {
+			if credit.at.Before(deadline) && credit.Month <= charge.Month {
+				paidInTime = append(paidInTime, credit)
+			}
+		}
+
+		outstandingCents := charge.DueCents
+		for _, allocation := range allocateCredits(charges[:i+1], paidInTime) {
+			if allocation.Month == charge.Month {
+				outstandingCents -= allocation.AmountCents
+			}
+		}
+
+		if fee := rule.Fee(outstandingCents); fee > 0 {
+			fees[charge.Month] = fee
+		}
+	}
+
+	return fees
+}
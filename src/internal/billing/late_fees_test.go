@@ -0,0 +1,216 @@
+package billing
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/testapp"
+
+	"github.com/pocketbase/pocketbase"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+func TestLateFeesChargeMonthsUnpaidAtTheirDeadline(t *testing.T) {
+	t.Parallel()
+
+	charges := []MonthCharge{
+		{Month: "2026-01", DueCents: 1000},
+		{Month: "2026-02", DueCents: 1000},
+		{Month: "2026-03", DueCents: 0},
+	}
+	rule := LateFeeRule{DueDay: 5, DaysOverdue: 3, Fixed: money.New(200, "USD"), Percent: 10}
+	now := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		credits []allocationCredit
+		now     time.Time
+		want    map[string]int64
+	}{
+		{
+			name:    "nothing paid",
+			credits: nil,
+			now:     now,
+			want:    map[string]int64{"2026-01": 300, "2026-02": 300},
+		},
+		{
+			name: "paid on the last day before the deadline",
+			credits: []allocationCredit{
+				{PaymentID: "p1", AmountCents: 1000, at: time.Date(2026, time.January, 8, 23, 0, 0, 0, time.UTC)},
+			},
+			now:  now,
+			want: map[string]int64{"2026-02": 300},
+		},
+		{
+			name: "partly paid late pays interest on what was unpaid",
+			credits: []allocationCredit{
+				{PaymentID: "p1", AmountCents: 1500, at: time.Date(2026, time.January, 2, 0, 0, 0, 0, time.UTC)},
+				{PaymentID: "p2", AmountCents: 500, at: time.Date(2026, time.February, 20, 0, 0, 0, 0, time.UTC)},
+			},
+			now:  now,
+			want: map[string]int64{"2026-02": 250},
+		},
+		{
+			name: "credit attributed to a later month does not count",
+			credits: []allocationCredit{
+				{PaymentID: "p1", Month: "2026-02", AmountCents: 1000, at: time.Date(2026, time.January, 2, 0, 0, 0, 0, time.UTC)},
+			},
+			now:  now,
+			want: map[string]int64{"2026-01": 300},
+		},
+		{
+			name:    "deadline not reached yet",
+			credits: nil,
+			now:     time.Date(2026, time.February, 8, 23, 59, 0, 0, time.UTC),
+			want:    map[string]int64{"2026-01": 300},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := lateFees(charges, test.credits, rule, test.now); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("lateFees() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSyncLateFeesIsIdempotentAndKeepsWaivedFees(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+	plan.Set("due_day", 5)
+	plan.Set("late_fee_days", 3)
+	plan.Set("late_fee_amount", 200)
+	plan.Set("late_fee_percent", 10)
+	if err := app.Dao().SaveRecord(plan); err != nil {
+		t.Fatalf("failed to save late fee rule: %v", err)
+	}
+
+	membership := testapp.SaveMembership(t, app, plan.Id, member.Id, time.Date(2026, time.January, 2, 0, 0, 0, 0, time.UTC))
+	membership.Set("date_ended", testapp.DateTime(t, time.Date(2026, time.March, 10, 0, 0, 0, 0, time.UTC)))
+	if err := app.Dao().SaveRecord(membership); err != nil {
+		t.Fatalf("failed to end membership: %v", err)
+	}
+
+	paymentsCollection, err := app.Dao().FindCollectionByNameOrId("payments")
+	if err != nil {
+		t.Fatalf("failed to find payments collection: %v", err)
+	}
+
+	// January is paid on time, February only after its deadline and March not at all.
+	for _, paid := range []struct {
+		amount int64
+		date   time.Time
+	}{
+		{1000, time.Date(2026, time.January, 7, 0, 0, 0, 0, time.UTC)},
+		{500, time.Date(2026, time.February, 20, 0, 0, 0, 0, time.UTC)},
+	} {
+		payment := pbmodels.NewRecord(paymentsCollection)
+		payment.Set("plan_id", plan.Id)
+		payment.Set("user_id", member.Id)
+		payment.Set("amount", paid.amount)
+		payment.Set("date", paid.date)
+		payment.Set("status", "approved")
+		if err := app.Dao().SaveRecord(payment); err != nil {
+			t.Fatalf("failed to save payment: %v", err)
+		}
+	}
+
+	now := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if err := SyncLateFeesWithDao(app.Dao(), plan.Id, now); err != nil {
+			t.Fatalf("SyncLateFeesWithDao returned error: %v", err)
+		}
+	}
+
+	fees := lateFeeAmountsByMonth(t, app, plan.Id)
+	if want := map[string]int64{"2026-02": -300, "2026-03": -300}; !reflect.DeepEqual(fees, want) {
+		t.Fatalf("late fees = %v, want %v", fees, want)
+	}
+
+	// A second copy of a fee, as a concurrent sync would file, is refused.
+	adjustmentsCollection, err := app.Dao().FindCollectionByNameOrId(AdjustmentsCollection)
+	if err != nil {
+		t.Fatalf("failed to find adjustments collection: %v", err)
+	}
+	duplicate := pbmodels.NewRecord(adjustmentsCollection)
+	duplicate.Set("plan_id", plan.Id)
+	duplicate.Set("user_id", member.Id)
+	duplicate.Set("amount", -300)
+	duplicate.Set("for_month", time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC))
+	duplicate.Set("kind", AdjustmentKindLateFee)
+	duplicate.Set("reason", "Late fee")
+	if err := app.Dao().SaveRecord(duplicate); err == nil {
+		t.Fatal("expected a duplicate late fee to be rejected")
+	}
+
+	balance, err := CalculateMemberBalanceWithDao(app.Dao(), plan.Id, member.Id, now)
+	if err != nil {
+		t.Fatalf("CalculateMemberBalanceWithDao returned error: %v", err)
+	}
+	if got, want := balance.Minor, int64(1500-3000-600); got != want {
+		t.Fatalf("balance = %d cents, want %d", got, want)
+	}
+
+	adjustments, err := FindAdjustmentsWithDao(app.Dao(), plan.Id)
+	if err != nil {
+		t.Fatalf("FindAdjustmentsWithDao returned error: %v", err)
+	}
+	for _, adjustment := range adjustments {
+		if err := DeleteAdjustmentWithDao(app.Dao(), plan.Id, adjustment.Id); !errors.Is(err, ErrGeneratedAdjustment) {
+			t.Fatalf("DeleteAdjustmentWithDao(late fee) error = %v, want ErrGeneratedAdjustment", err)
+		}
+		if adjustment.GetDateTime("for_month").Time().Month() == time.February {
+			if err := WaiveAdjustmentWithDao(app.Dao(), plan.Id, adjustment.Id); err != nil {
+				t.Fatalf("WaiveAdjustmentWithDao returned error: %v", err)
+			}
+		}
+	}
+
+	// Turning the rule off removes unwaived fees but keeps the record of the waived one.
+	plan.Set("late_fee_amount", 0)
+	plan.Set("late_fee_percent", 0)
+	if err := app.Dao().SaveRecord(plan); err != nil {
+		t.Fatalf("failed to clear late fee rule: %v", err)
+	}
+	if err := SyncLateFeesWithDao(app.Dao(), plan.Id, now); err != nil {
+		t.Fatalf("SyncLateFeesWithDao returned error: %v", err)
+	}
+
+	fees = lateFeeAmountsByMonth(t, app, plan.Id)
+	if want := map[string]int64{"2026-02": -300}; !reflect.DeepEqual(fees, want) {
+		t.Fatalf("late fees after disabling the rule = %v, want %v", fees, want)
+	}
+
+	balance, err = CalculateMemberBalanceWithDao(app.Dao(), plan.Id, member.Id, now)
+	if err != nil {
+		t.Fatalf("CalculateMemberBalanceWithDao returned error: %v", err)
+	}
+	if got, want := balance.Minor, int64(1500-3000); got != want {
+		t.Fatalf("balance with the fee waived = %d cents, want %d", got, want)
+	}
+}
+
+func lateFeeAmountsByMonth(t *testing.T, app *pocketbase.PocketBase, planID string) map[string]int64 {
+	t.Helper()
+
+	adjustments, err := FindAdjustmentsWithDao(app.Dao(), planID)
+	if err != nil {
+		t.Fatalf("FindAdjustmentsWithDao returned error: %v", err)
+	}
+
+	amounts := map[string]int64{}
+	for _, adjustment := range adjustments {
+		if IsLateFee(adjustment) {
+			amounts[adjustment.GetDateTime("for_month").Time().Format(monthKeyLayout)] = int64(adjustment.GetInt("amount"))
+		}
+	}
+
+	return amounts
+}
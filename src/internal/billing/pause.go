@@ -0,0 +1,111 @@
+package billing
+
+import (
+	"errors"
+	"time"
+
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+var (
+	// ErrAlreadyPaused indicates that the membership already has a current or upcoming pause.
+	ErrAlreadyPaused = errors.New("membership is already paused")
+	// ErrNotPaused indicates that the membership has no current or upcoming pause to resume from.
+	ErrNotPaused = errors.New("membership is not paused")
+	// ErrInvalidPause indicates that a pause ends before it starts.
+	ErrInvalidPause = errors.New("pause must end on or after its first month")
+)
+
+// CurrentPause returns the pause that covers the month of now or starts after it, if any.
+func CurrentPause(membership *pbmodels.Record, now time.Time) (InactivePeriod, bool) {
+	nowKey := now.Format(monthKeyLayout)
+	for _, period := range InactivePeriods(membership) {
+		if period.Reason != InactiveReasonPaused {
+			continue
+		}
+		if period.End == "" || period.End >= nowKey {
+			return period, true
+		}
+	}
+
+	return InactivePeriod{}, false
+}
+
+// PauseMembershipWithDao stops billing a member from the start month until the end month.
+// A zero end leaves the pause open until the member is resumed.
+func PauseMembershipWithDao(dao *daos.Dao, planID, userID string, start, end, now time.Time) error {
+	membership, err := findOpenMembershipWithDao(dao, planID, userID)
+	if err != nil {
+		return err
+	}
+
+	if _, paused := CurrentPause(membership, now); paused {
+		return ErrAlreadyPaused
+	}
+
+	period := InactivePeriod{
+		Start:  monthStart(start).Format(monthKeyLayout),
+		Reason: InactiveReasonPaused,
+	}
+	if !end.IsZero() {
+		period.End = monthStart(end).Format(monthKeyLayout)
+		if period.End < period.Start {
+			return ErrInvalidPause
+		}
+	}
+
+	membership.Set("inactive_periods", append(InactivePeriods(membership), period))
+	return dao.SaveRecord(membership)
+}
+
+// ResumeMembershipWithDao ends a member's current pause so billing restarts with the month of resumedAt.
+// A pause that has not started yet is cancelled.
+func ResumeMembershipWithDao(dao *daos.Dao, planID, userID string, resumedAt time.Time) error {
+	membership, err := findOpenMembershipWithDao(dao, planID, userID)
+	if err != nil {
+		return err
+	}
+
+	current, paused := CurrentPause(membership, resumedAt)
+	if !paused {
+		return ErrNotPaused
+	}
+
+	lastPausedMonth := monthStart(resumedAt).AddDate(0, -1, 0).Format(monthKeyLayout)
+
+	periods := make([]InactivePeriod, 0, len(InactivePeriods(membership)))
+	for _, period := range InactivePeriods(membership) {
+		if period != current {
+			periods = append(periods, period)
+			continue
+		}
+
+		if lastPausedMonth < period.Start {
+			continue
+		}
+
+		period.End = lastPausedMonth
+		periods = append(periods, period)
+	}
+
+	membership.Set("inactive_periods", periods)
+	return dao.SaveRecord(membership)
+}
+
+func findOpenMembershipWithDao(dao *daos.Dao, planID, userID string) (*pbmodels.Record, error) {
+	membership, err := planutil.FindMembershipWithDao(dao, planID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if membership == nil {
+		return nil, errors.New("membership not found")
+	}
+	if !membership.GetDateTime("date_ended").IsZero() {
+		return nil, errors.New("membership has ended")
+	}
+
+	return membership, nil
+}
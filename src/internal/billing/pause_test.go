@@ -0,0 +1,81 @@
+package billing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/testapp"
+)
+
+func TestPausedMonthsAreLeftOutOfTheSplit(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	traveller := testapp.SaveUser(t, app, "traveller")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+
+	testapp.SaveMembership(t, app, plan.Id, traveller.Id, time.Date(2026, time.January, 3, 0, 0, 0, 0, time.UTC))
+
+	now := time.Date(2026, time.January, 10, 0, 0, 0, 0, time.UTC)
+	march := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	may := time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC)
+
+	if err := PauseMembershipWithDao(app.Dao(), plan.Id, traveller.Id, may, march, now); !errors.Is(err, ErrInvalidPause) {
+		t.Fatalf("PauseMembershipWithDao(end before start) error = %v, want ErrInvalidPause", err)
+	}
+	if err := PauseMembershipWithDao(app.Dao(), plan.Id, traveller.Id, march, time.Time{}, now); err != nil {
+		t.Fatalf("PauseMembershipWithDao returned error: %v", err)
+	}
+	if err := PauseMembershipWithDao(app.Dao(), plan.Id, traveller.Id, march, may, now); !errors.Is(err, ErrAlreadyPaused) {
+		t.Fatalf("second PauseMembershipWithDao error = %v, want ErrAlreadyPaused", err)
+	}
+
+	if err := ResumeMembershipWithDao(app.Dao(), plan.Id, traveller.Id, time.Date(2026, time.June, 2, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("ResumeMembershipWithDao returned error: %v", err)
+	}
+
+	for month, wantActive := range map[time.Month]bool{
+		time.February: true,
+		time.March:    false,
+		time.May:      false,
+		time.June:     true,
+	} {
+		active, err := getActiveMembershipsForMonth(app.Dao(), plan.Id, time.Date(2026, month, 1, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("getActiveMembershipsForMonth returned error: %v", err)
+		}
+		if gotActive := len(active) == 1; gotActive != wantActive {
+			t.Fatalf("traveller active in %s = %v, want %v", month, gotActive, wantActive)
+		}
+	}
+
+	if err := ResumeMembershipWithDao(app.Dao(), plan.Id, traveller.Id, time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC)); !errors.Is(err, ErrNotPaused) {
+		t.Fatalf("ResumeMembershipWithDao after resuming error = %v, want ErrNotPaused", err)
+	}
+}
+
+func TestResumeCancelsPauseThatHasNotStarted(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+	testapp.SaveMembership(t, app, plan.Id, member.Id, time.Date(2026, time.January, 3, 0, 0, 0, 0, time.UTC))
+
+	now := time.Date(2026, time.February, 10, 0, 0, 0, 0, time.UTC)
+	if err := PauseMembershipWithDao(app.Dao(), plan.Id, member.Id, time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC), time.Time{}, now); err != nil {
+		t.Fatalf("PauseMembershipWithDao returned error: %v", err)
+	}
+	if err := ResumeMembershipWithDao(app.Dao(), plan.Id, member.Id, now); err != nil {
+		t.Fatalf("ResumeMembershipWithDao returned error: %v", err)
+	}
+
+	membership, err := app.Dao().FindFirstRecordByData("memberships", "user_id", member.Id)
+	if err != nil {
+		t.Fatalf("failed to reload membership: %v", err)
+	}
+	if periods := InactivePeriods(membership); len(periods) != 0 {
+		t.Fatalf("inactive periods = %+v, want none", periods)
+	}
+}
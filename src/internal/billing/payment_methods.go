@@ -0,0 +1,136 @@
+package billing
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// Payment methods a payment can arrive by.
+const (
+	PaymentMethodBankTransfer = "bank_transfer"
+	PaymentMethodPayPal       = "paypal"
+	PaymentMethodCash         = "cash"
+	PaymentMethodCard         = "card"
+	PaymentMethodOther        = "other"
+)
+
+// PaymentMethods lists every payment method in the order they are offered.
+var PaymentMethods = []string{
+	PaymentMethodBankTransfer,
+	PaymentMethodPayPal,
+	PaymentMethodCash,
+	PaymentMethodCard,
+	PaymentMethodOther,
+}
+
+var paymentMethodLabels = map[string]string{
+	PaymentMethodBankTransfer: "Bank transfer",
+	PaymentMethodPayPal:       "PayPal",
+	PaymentMethodCash:         "Cash",
+	PaymentMethodCard:         "Card",
+	PaymentMethodOther:        "Other",
+}
+
+const (
+	// MaxPaymentReferenceLength matches the reference field in the payments collection.
+	MaxPaymentReferenceLength = 100
+	// MaxPaymentInstructionsLength caps the instructions an owner gives for one method.
+	MaxPaymentInstructionsLength = 500
+)
+
+var (
+	// ErrUnknownPaymentMethod indicates a payment method that is not in PaymentMethods.
+	ErrUnknownPaymentMethod = errors.New("unknown payment method")
+	// ErrPaymentMethodNotAccepted indicates that the plan owner does not take payments that way.
+	ErrPaymentMethodNotAccepted = errors.New("payment method is not accepted by this plan")
+	// ErrPaymentReferenceTooLong indicates that a payment reference is longer than MaxPaymentReferenceLength.
+	ErrPaymentReferenceTooLong = errors.New("payment reference is too long")
+	// ErrPaymentInstructionsTooLong indicates that instructions are longer than MaxPaymentInstructionsLength.
+	ErrPaymentInstructionsTooLong = errors.New("payment instructions are too long")
+)
+
+// AcceptedPaymentMethod is a method the plan owner takes payments by, with how to pay that way.
+type AcceptedPaymentMethod struct {
+	Method       string `json:"method"`
+	Instructions string `json:"instructions"`
+}
+
+// PaymentMethodLabel returns the display name of a payment method, or "" for an unrecorded one.
+func PaymentMethodLabel(method string) string {
+	return paymentMethodLabels[method]
+}
+
+// AcceptedPaymentMethods returns the methods a plan accepts. An empty list means the owner has not
+// restricted them.
+func AcceptedPaymentMethods(plan *pbmodels.Record) []AcceptedPaymentMethod {
+	methods := []AcceptedPaymentMethod{}
+	if err := plan.UnmarshalJSONField("payment_methods", &methods); err != nil {
+		return []AcceptedPaymentMethod{}
+	}
+
+	return methods
+}
+
+// SetAcceptedPaymentMethods validates and stores a plan's accepted methods in PaymentMethods order.
+// It only updates the record; the caller saves it.
+func SetAcceptedPaymentMethods(plan *pbmodels.Record, methods []AcceptedPaymentMethod) error {
+	byMethod := make(map[string]AcceptedPaymentMethod, len(methods))
+	for _, method := range methods {
+		if _, ok := paymentMethodLabels[method.Method]; !ok {
+			return ErrUnknownPaymentMethod
+		}
+
+		method.Instructions = strings.TrimSpace(method.Instructions)
+		if utf8.RuneCountInString(method.Instructions) > MaxPaymentInstructionsLength {
+			return ErrPaymentInstructionsTooLong
+		}
+
+		byMethod[method.Method] = method
+	}
+
+	ordered := []AcceptedPaymentMethod{}
+	for _, method := range PaymentMethods {
+		if accepted, ok := byMethod[method]; ok {
+			ordered = append(ordered, accepted)
+		}
+	}
+
+	plan.Set("payment_methods", ordered)
+	return nil
+}
+
+// ValidatePaymentMethod checks a method submitted with a claim. An empty method is allowed and
+// leaves the payment without one; any other must be known and accepted by the plan.
+func ValidatePaymentMethod(plan *pbmodels.Record, method string) error {
+	if method == "" {
+		return nil
+	}
+	if _, ok := paymentMethodLabels[method]; !ok {
+		return ErrUnknownPaymentMethod
+	}
+
+	accepted := AcceptedPaymentMethods(plan)
+	if len(accepted) == 0 {
+		return nil
+	}
+	for _, acceptedMethod := range accepted {
+		if acceptedMethod.Method == method {
+			return nil
+		}
+	}
+
+	return ErrPaymentMethodNotAccepted
+}
+
+// NormalizePaymentReference trims a payment reference and checks its length.
+func NormalizePaymentReference(value string) (string, error) {
+	reference := strings.TrimSpace(value)
+	if utf8.RuneCountInString(reference) > MaxPaymentReferenceLength {
+		return "", ErrPaymentReferenceTooLong
+	}
+
+	return reference, nil
+}
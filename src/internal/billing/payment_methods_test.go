@@ -0,0 +1,61 @@
+package billing
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	pbmodels "github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func TestValidatePaymentMethod(t *testing.T) {
+	t.Parallel()
+
+	collection := &pbmodels.Collection{
+		Schema: schema.NewSchema(&schema.SchemaField{Name: "payment_methods", Type: schema.FieldTypeJson}),
+	}
+	unrestricted := pbmodels.NewRecord(collection)
+	restricted := pbmodels.NewRecord(collection)
+	if err := SetAcceptedPaymentMethods(restricted, []AcceptedPaymentMethod{
+		{Method: PaymentMethodPayPal, Instructions: "  family@example.com "},
+		{Method: PaymentMethodBankTransfer, Instructions: "IBAN DE00 1234"},
+	}); err != nil {
+		t.Fatalf("SetAcceptedPaymentMethods returned error: %v", err)
+	}
+
+	want := []AcceptedPaymentMethod{
+		{Method: PaymentMethodBankTransfer, Instructions: "IBAN DE00 1234"},
+		{Method: PaymentMethodPayPal, Instructions: "family@example.com"},
+	}
+	if got := AcceptedPaymentMethods(restricted); !reflect.DeepEqual(got, want) {
+		t.Fatalf("AcceptedPaymentMethods() = %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		name    string
+		plan    *pbmodels.Record
+		method  string
+		wantErr error
+	}{
+		{name: "no method", plan: restricted, method: "", wantErr: nil},
+		{name: "any method before the owner chooses", plan: unrestricted, method: PaymentMethodCash, wantErr: nil},
+		{name: "accepted method", plan: restricted, method: PaymentMethodPayPal, wantErr: nil},
+		{name: "method the owner does not take", plan: restricted, method: PaymentMethodCash, wantErr: ErrPaymentMethodNotAccepted},
+		{name: "unknown method", plan: unrestricted, method: "cheque", wantErr: ErrUnknownPaymentMethod},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := ValidatePaymentMethod(test.plan, test.method); !errors.Is(err, test.wantErr) {
+				t.Fatalf("ValidatePaymentMethod(%q) error = %v, want %v", test.method, err, test.wantErr)
+			}
+		})
+	}
+
+	tooLong := []AcceptedPaymentMethod{{Method: PaymentMethodCash, Instructions: strings.Repeat("x", MaxPaymentInstructionsLength+1)}}
+	if err := SetAcceptedPaymentMethods(restricted, tooLong); !errors.Is(err, ErrPaymentInstructionsTooLong) {
+		t.Fatalf("SetAcceptedPaymentMethods(long instructions) error = %v, want ErrPaymentInstructionsTooLong", err)
+	}
+}
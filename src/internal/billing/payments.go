@@ -0,0 +1,22 @@
+package billing
+
+import (
+	"familyplan/src/internal/money"
+
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// PaymentAmount returns the amount credited by a payment, stored in minor units of the plan currency.
+func PaymentAmount(payment *pbmodels.Record, currency string) money.Amount {
+	return money.New(int64(payment.GetInt("amount")), currency)
+}
+
+// PayerID returns who handed over the money for a payment.
+// Payments without a recorded payer were made by the member they credit.
+func PayerID(payment *pbmodels.Record) string {
+	if payerID := payment.GetString("payer_id"); payerID != "" {
+		return payerID
+	}
+
+	return payment.GetString("user_id")
+}
@@ -0,0 +1,71 @@
+package billing
+
+import (
+	"testing"
+	"time"
+
+	"familyplan/src/internal/testapp"
+
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+func TestPaymentOnBehalfCreditsBeneficiary(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	parent := testapp.SaveUser(t, app, "parent")
+	kid := testapp.SaveUser(t, app, "kid")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 3000)
+
+	now := time.Now()
+	testapp.SaveMembership(t, app, plan.Id, owner.Id, now)
+	testapp.SaveMembership(t, app, plan.Id, parent.Id, now)
+	testapp.SaveMembership(t, app, plan.Id, kid.Id, now)
+
+	paymentsCollection, err := app.Dao().FindCollectionByNameOrId("payments")
+	if err != nil {
+		t.Fatalf("failed to find payments collection: %v", err)
+	}
+
+	payment := pbmodels.NewRecord(paymentsCollection)
+	payment.Set("plan_id", plan.Id)
+	payment.Set("user_id", kid.Id)
+	payment.Set("payer_id", parent.Id)
+	payment.Set("amount", 1000)
+	payment.Set("date", now)
+	payment.Set("status", "approved")
+	if err := app.Dao().SaveRecord(payment); err != nil {
+		t.Fatalf("failed to save payment: %v", err)
+	}
+
+	if got := PayerID(payment); got != parent.Id {
+		t.Fatalf("PayerID() = %q, want %q", got, parent.Id)
+	}
+
+	kidBalance, err := CalculateMemberBalanceWithDao(app.Dao(), plan.Id, kid.Id, now)
+	if err != nil {
+		t.Fatalf("CalculateMemberBalanceWithDao(kid) returned error: %v", err)
+	}
+	if kidBalance.Minor != 0 {
+		t.Fatalf("kid balance = %v, want 0", kidBalance)
+	}
+
+	parentBalance, err := CalculateMemberBalanceWithDao(app.Dao(), plan.Id, parent.Id, now)
+	if err != nil {
+		t.Fatalf("CalculateMemberBalanceWithDao(parent) returned error: %v", err)
+	}
+	if parentBalance.Minor != -1000 {
+		t.Fatalf("parent balance = %v, want -10", parentBalance)
+	}
+}
+
+func TestPayerIDDefaultsToBeneficiary(t *testing.T) {
+	t.Parallel()
+
+	payment := pbmodels.NewRecord(&pbmodels.Collection{})
+	payment.Set("user_id", "member-1")
+
+	if got := PayerID(payment); got != "member-1" {
+		t.Fatalf("PayerID() = %q, want %q", got, "member-1")
+	}
+}
@@ -0,0 +1,319 @@
+package billing
+
+import (
+	"errors"
+	"time"
+
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// RecurringClaimsCollection is the PocketBase collection that stores members' standing orders.
+const RecurringClaimsCollection = "recurring_claims"
+
+var (
+	// ErrInvalidRecurringClaim indicates that a standing order has no amount, a bad day or its months out of order.
+	ErrInvalidRecurringClaim = errors.New("standing order needs a positive amount, a day between 1 and 28 and an end after its start")
+	// ErrRecurringClaimNotFound indicates that the standing order does not belong to the member.
+	ErrRecurringClaimNotFound = errors.New("standing order not found")
+)
+
+// RecurringClaim is a member's promise to pay Amount by Method on DayOfMonth of every month from
+// StartMonth. A zero EndMonth keeps it going until it is cancelled, and an empty Method files claims
+// without one.
+type RecurringClaim struct {
+	PlanID     string
+	UserID     string
+	Amount     money.Amount
+	Method     string
+	DayOfMonth int
+	StartMonth time.Time
+	EndMonth   time.Time
+}
+
+// CreateRecurringClaimWithDao stores a standing order. Its method must be one the plan accepts.
+func CreateRecurringClaimWithDao(dao *daos.Dao, claim RecurringClaim) (*pbmodels.Record, error) {
+	startMonth := monthStart(claim.StartMonth)
+	if !claim.Amount.IsPositive() || claim.DayOfMonth < 1 || claim.DayOfMonth > MaxDueDay || startMonth.IsZero() {
+		return nil, ErrInvalidRecurringClaim
+	}
+
+	plan, err := dao.FindRecordById("family_plans", claim.PlanID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidatePaymentMethod(plan, claim.Method); err != nil {
+		return nil, err
+	}
+
+	endMonth := ""
+	if !claim.EndMonth.IsZero() {
+		if monthStart(claim.EndMonth).Before(startMonth) {
+			return nil, ErrInvalidRecurringClaim
+		}
+		endMonth = claim.EndMonth.Format(monthKeyLayout)
+	}
+
+	collection, err := dao.FindCollectionByNameOrId(RecurringClaimsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	record := pbmodels.NewRecord(collection)
+	record.Set("plan_id", claim.PlanID)
+	record.Set("user_id", claim.UserID)
+	record.Set("amount", claim.Amount.Minor)
+	record.Set("method", claim.Method)
+	record.Set("day_of_month", claim.DayOfMonth)
+	record.Set("start_month", startMonth.Format(monthKeyLayout))
+	record.Set("end_month", endMonth)
+	if err := dao.SaveRecord(record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// CancelRecurringClaimWithDao stops a member's standing order. Claims it already filed are kept.
+func CancelRecurringClaimWithDao(dao *daos.Dao, planID, userID, claimID string, cancelledAt time.Time) error {
+	if claimID == "" {
+		return ErrRecurringClaimNotFound
+	}
+
+	record, err := dao.FindRecordById(RecurringClaimsCollection, claimID)
+	if err != nil || record == nil || record.GetString("plan_id") != planID || record.GetString("user_id") != userID {
+		return ErrRecurringClaimNotFound
+	}
+	if !record.GetDateTime("cancelled_at").IsZero() {
+		return nil
+	}
+
+	record.Set("cancelled_at", cancelledAt)
+	return dao.SaveRecord(record)
+}
+
+// FindRecurringClaimsWithDao returns a plan's standing orders that have not been cancelled, oldest first.
+func FindRecurringClaimsWithDao(dao *daos.Dao, planID string) ([]*pbmodels.Record, error) {
+	filter, err := planutil.BuildEqualsFilter(
+		planutil.FilterTerm{Field: "plan_id", Value: planID},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return dao.FindRecordsByFilter(
+		RecurringClaimsCollection,
+		filter.Expression+" && cancelled_at = ''",
+		"created",
+		-1,
+		0,
+		filter.Params,
+	)
+}
+
+// SetAutoApproveRecurringWithDao sets whether a member's standing order claims skip owner approval.
+func SetAutoApproveRecurringWithDao(dao *daos.Dao, planID, userID string, autoApprove bool) error {
+	membership, err := planutil.FindMembershipWithDao(dao, planID, userID)
+	if err != nil {
+		return err
+	}
+	if membership == nil {
+		return errors.New("membership not found")
+	}
+
+	membership.Set("auto_approve_recurring", autoApprove)
+	return dao.SaveRecord(membership)
+}
+
+// RunRecurringClaimsWithDao files the claims that standing orders owe as of now and returns how many
+// it filed. A month is skipped when the order already filed it, the member has another claim for it,
+// or it is already paid. Orders only file months from when they were set up, never earlier ones, and
+// orders on archived plans file nothing.
+func RunRecurringClaimsWithDao(dao *daos.Dao, now time.Time) (int, error) {
+	// Claims are only filed for months the allocations leave uncovered, so they must be current.
+	if err := ReallocateStaleWithDao(dao, now); err != nil {
+		return 0, err
+	}
+
+	claims, err := dao.FindRecordsByFilter(RecurringClaimsCollection, "cancelled_at = ''", "created", -1, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	filed := 0
+	for _, claim := range claims {
+		count, err := runRecurringClaimWithDao(dao, claim, now)
+		if err != nil {
+			return filed, err
+		}
+		filed += count
+	}
+
+	return filed, nil
+}
+
+func runRecurringClaimWithDao(dao *daos.Dao, claim *pbmodels.Record, now time.Time) (int, error) {
+	planID := claim.GetString("plan_id")
+	userID := claim.GetString("user_id")
+
+	plan, err := dao.FindRecordById("family_plans", planID)
+	if err != nil || planutil.IsArchived(plan) {
+		return 0, nil
+	}
+
+	membership, err := planutil.FindMembershipWithDao(dao, planID, userID)
+	if err != nil {
+		return 0, err
+	}
+	if membership == nil || !membership.GetDateTime("date_ended").IsZero() {
+		return 0, nil
+	}
+
+	filed := 0
+	for _, month := range recurringClaimMonths(claim, now) {
+		due, err := recurringClaimDueWithDao(dao, plan, claim, month, now)
+		if err != nil {
+			return filed, err
+		}
+		if !due {
+			continue
+		}
+
+		if err := fileRecurringClaimWithDao(dao, plan, membership, claim, month, now); err != nil {
+			return filed, err
+		}
+		filed++
+	}
+
+	return filed, nil
+}
+
+// recurringClaimMonths returns the months a standing order should have filed by now.
+func recurringClaimMonths(claim *pbmodels.Record, now time.Time) []time.Time {
+	startMonth, err := time.Parse(monthKeyLayout, claim.GetString("start_month"))
+	if err != nil {
+		return nil
+	}
+	if createdMonth := monthStart(claim.GetDateTime("created").Time()); createdMonth.After(startMonth) {
+		startMonth = createdMonth
+	}
+
+	endMonth := monthStart(now)
+	if value := claim.GetString("end_month"); value != "" {
+		if lastMonth, err := time.Parse(monthKeyLayout, value); err == nil && lastMonth.Before(endMonth) {
+			endMonth = lastMonth
+		}
+	}
+
+	day := claim.GetInt("day_of_month")
+	months := []time.Time{}
+	for month := startMonth; !month.After(endMonth); month = month.AddDate(0, 1, 0) {
+		if time.Date(month.Year(), month.Month(), day, 0, 0, 0, 0, time.UTC).After(now) {
+			break
+		}
+		months = append(months, month)
+	}
+
+	return months
+}
+
+// recurringClaimDueWithDao reports whether a standing order still has to file a month. Another claim
+// for the month counts unless it was rejected; a rejected claim this order filed is not filed again.
+func recurringClaimDueWithDao(dao *daos.Dao, plan, claim *pbmodels.Record, month, now time.Time) (bool, error) {
+	userID := claim.GetString("user_id")
+
+	filter, err := planutil.BuildEqualsFilter(
+		planutil.FilterTerm{Field: "plan_id", Value: plan.Id},
+		planutil.FilterTerm{Field: "user_id", Value: userID},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	payments, err := dao.FindRecordsByFilter("payments", filter.Expression, "", -1, 0, filter.Params)
+	if err != nil {
+		return false, err
+	}
+
+	monthKey := month.Format(monthKeyLayout)
+	for _, payment := range payments {
+		forMonth := payment.GetDateTime("for_month")
+		if forMonth.IsZero() || forMonth.Time().Format(monthKeyLayout) != monthKey {
+			continue
+		}
+
+		if payment.GetString("status") != "rejected" || payment.GetString("recurring_claim_id") == claim.Id {
+			return false, nil
+		}
+	}
+
+	coverage, err := CoverageWithDao(dao, plan.Id, userID, month, month, now)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range coverage {
+		if entry.CoveredCents >= entry.DueCents {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// fileRecurringClaimWithDao files a standing order's claim for a month and records it in the plan's
+// activity. A claim whose method the plan no longer accepts is filed without one and left for the
+// owner to approve, even when the member's claims are otherwise approved automatically.
+func fileRecurringClaimWithDao(dao *daos.Dao, plan, membership, claim *pbmodels.Record, month, now time.Time) error {
+	collection, err := dao.FindCollectionByNameOrId("payments")
+	if err != nil {
+		return err
+	}
+
+	userID := claim.GetString("user_id")
+	method := claim.GetString("method")
+	status := "pending"
+	if ValidatePaymentMethod(plan, method) != nil {
+		method = ""
+	} else if membership.GetBool("auto_approve_recurring") {
+		status = "approved"
+	}
+
+	payment := pbmodels.NewRecord(collection)
+	payment.Set("plan_id", plan.Id)
+	payment.Set("user_id", userID)
+	payment.Set("payer_id", userID)
+	payment.Set("amount", claim.GetInt("amount"))
+	payment.Set("date", time.Date(month.Year(), month.Month(), claim.GetInt("day_of_month"), 0, 0, 0, 0, time.UTC))
+	payment.Set("status", status)
+	payment.Set("notes", "Standing order")
+	payment.Set("method", method)
+	payment.Set("for_month", month)
+	payment.Set("recurring_claim_id", claim.Id)
+
+	return dao.RunInTransaction(func(txDao *daos.Dao) error {
+		if err := txDao.SaveRecord(payment); err != nil {
+			return err
+		}
+
+		err := audit.RecordWithDao(txDao, audit.Event{
+			PlanID:   plan.Id,
+			Action:   audit.ActionPaymentFiled,
+			Target:   "payments",
+			TargetID: payment.Id,
+			After:    audit.Snapshot(payment),
+		})
+		if err != nil {
+			return err
+		}
+
+		if status != "approved" {
+			return nil
+		}
+
+		return EndMembershipIfSettledWithDao(txDao, plan.Id, userID, now)
+	})
+}
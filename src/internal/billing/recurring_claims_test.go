@@ -0,0 +1,231 @@
+package billing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/testapp"
+
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+func TestRunRecurringClaimsSkipsClaimedAndPaidMonths(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+	testapp.SaveMembership(t, app, plan.Id, member.Id, time.Date(2026, time.January, 3, 0, 0, 0, 0, time.UTC))
+
+	if err := SetAutoApproveRecurringWithDao(app.Dao(), plan.Id, member.Id, true); err != nil {
+		t.Fatalf("SetAutoApproveRecurringWithDao returned error: %v", err)
+	}
+
+	claim, err := CreateRecurringClaimWithDao(app.Dao(), RecurringClaim{
+		PlanID:     plan.Id,
+		UserID:     member.Id,
+		Amount:     money.New(1000, "USD"),
+		DayOfMonth: 10,
+		StartMonth: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("CreateRecurringClaimWithDao returned error: %v", err)
+	}
+	claim.Set("created", testapp.DateTime(t, time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)))
+	if err := app.Dao().SaveRecord(claim); err != nil {
+		t.Fatalf("failed to backdate standing order: %v", err)
+	}
+
+	paymentsCollection, err := app.Dao().FindCollectionByNameOrId("payments")
+	if err != nil {
+		t.Fatalf("failed to find payments collection: %v", err)
+	}
+
+	// February already has a claim of its own and March is paid.
+	for _, existing := range []struct {
+		month  time.Month
+		status string
+	}{
+		{time.February, "pending"},
+		{time.March, "approved"},
+	} {
+		payment := pbmodels.NewRecord(paymentsCollection)
+		payment.Set("plan_id", plan.Id)
+		payment.Set("user_id", member.Id)
+		payment.Set("amount", 1000)
+		payment.Set("date", time.Date(2026, existing.month, 2, 0, 0, 0, 0, time.UTC))
+		payment.Set("for_month", time.Date(2026, existing.month, 1, 0, 0, 0, 0, time.UTC))
+		payment.Set("status", existing.status)
+		if err := app.Dao().SaveRecord(payment); err != nil {
+			t.Fatalf("failed to save payment: %v", err)
+		}
+	}
+
+	now := time.Date(2026, time.April, 15, 0, 0, 0, 0, time.UTC)
+	filed, err := RunRecurringClaimsWithDao(app.Dao(), now)
+	if err != nil {
+		t.Fatalf("RunRecurringClaimsWithDao returned error: %v", err)
+	}
+	if filed != 2 {
+		t.Fatalf("filed claims = %d, want 2 (January and April)", filed)
+	}
+
+	filedClaims, err := app.Dao().FindRecordsByFilter("payments", "recurring_claim_id != ''", "for_month", -1, 0)
+	if err != nil {
+		t.Fatalf("failed to load filed claims: %v", err)
+	}
+	for i, month := range []time.Month{time.January, time.April} {
+		payment := filedClaims[i]
+		if got := payment.GetDateTime("for_month").Time().Month(); got != month {
+			t.Fatalf("filed claim %d is for %s, want %s", i, got, month)
+		}
+		if got := payment.GetDateTime("date").Time().Day(); got != 10 {
+			t.Fatalf("filed claim %d is dated day %d, want 10", i, got)
+		}
+		if got := payment.GetString("status"); got != "approved" {
+			t.Fatalf("filed claim %d status = %q, want approved for a trusted member", i, got)
+		}
+	}
+
+	// A rejected claim from the order is not filed again.
+	filedClaims[0].Set("status", "rejected")
+	if err := app.Dao().SaveRecord(filedClaims[0]); err != nil {
+		t.Fatalf("failed to reject claim: %v", err)
+	}
+	if filed, err := RunRecurringClaimsWithDao(app.Dao(), now); err != nil || filed != 0 {
+		t.Fatalf("second RunRecurringClaimsWithDao = %d, %v; want 0, nil", filed, err)
+	}
+
+	if err := CancelRecurringClaimWithDao(app.Dao(), plan.Id, owner.Id, claim.Id, now); !errors.Is(err, ErrRecurringClaimNotFound) {
+		t.Fatalf("CancelRecurringClaimWithDao(someone else) error = %v, want ErrRecurringClaimNotFound", err)
+	}
+	if err := CancelRecurringClaimWithDao(app.Dao(), plan.Id, member.Id, claim.Id, now); err != nil {
+		t.Fatalf("CancelRecurringClaimWithDao returned error: %v", err)
+	}
+	if filed, err := RunRecurringClaimsWithDao(app.Dao(), now.AddDate(0, 1, 0)); err != nil || filed != 0 {
+		t.Fatalf("RunRecurringClaimsWithDao after cancelling = %d, %v; want 0, nil", filed, err)
+	}
+}
+
+func TestCreateRecurringClaimRejectsInvalidSchedules(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 1000)
+	start := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []RecurringClaim{
+		{PlanID: plan.Id, Amount: money.New(0, "USD"), DayOfMonth: 1, StartMonth: start},
+		{PlanID: plan.Id, Amount: money.New(500, "USD"), DayOfMonth: 31, StartMonth: start},
+		{PlanID: plan.Id, Amount: money.New(500, "USD"), DayOfMonth: 1, StartMonth: start, EndMonth: start.AddDate(0, -1, 0)},
+	}
+
+	for _, claim := range tests {
+		if _, err := CreateRecurringClaimWithDao(app.Dao(), claim); !errors.Is(err, ErrInvalidRecurringClaim) {
+			t.Fatalf("CreateRecurringClaimWithDao(%+v) error = %v, want ErrInvalidRecurringClaim", claim, err)
+		}
+	}
+}
+
+func TestRunRecurringClaimsFilesTheOrdersMethodAndRecordsIt(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+	testapp.SaveMembership(t, app, plan.Id, member.Id, time.Date(2026, time.January, 3, 0, 0, 0, 0, time.UTC))
+	acceptMethods(t, app.Dao(), plan, PaymentMethodBankTransfer, PaymentMethodPayPal)
+
+	if err := SetAutoApproveRecurringWithDao(app.Dao(), plan.Id, member.Id, true); err != nil {
+		t.Fatalf("SetAutoApproveRecurringWithDao returned error: %v", err)
+	}
+
+	order := RecurringClaim{
+		PlanID:     plan.Id,
+		UserID:     member.Id,
+		Amount:     money.New(1000, "USD"),
+		Method:     PaymentMethodCash,
+		DayOfMonth: 10,
+		StartMonth: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if _, err := CreateRecurringClaimWithDao(app.Dao(), order); !errors.Is(err, ErrPaymentMethodNotAccepted) {
+		t.Fatalf("CreateRecurringClaimWithDao(cash) error = %v, want ErrPaymentMethodNotAccepted", err)
+	}
+
+	order.Method = PaymentMethodPayPal
+	claim, err := CreateRecurringClaimWithDao(app.Dao(), order)
+	if err != nil {
+		t.Fatalf("CreateRecurringClaimWithDao returned error: %v", err)
+	}
+	claim.Set("created", testapp.DateTime(t, time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)))
+	if err := app.Dao().SaveRecord(claim); err != nil {
+		t.Fatalf("failed to backdate standing order: %v", err)
+	}
+
+	if filed, err := RunRecurringClaimsWithDao(app.Dao(), time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC)); err != nil || filed != 1 {
+		t.Fatalf("RunRecurringClaimsWithDao(January) = %d, %v; want 1, nil", filed, err)
+	}
+
+	// The owner stops taking PayPal, so February's claim waits for them with no method.
+	acceptMethods(t, app.Dao(), plan, PaymentMethodBankTransfer)
+	if filed, err := RunRecurringClaimsWithDao(app.Dao(), time.Date(2026, time.February, 15, 0, 0, 0, 0, time.UTC)); err != nil || filed != 1 {
+		t.Fatalf("RunRecurringClaimsWithDao(February) = %d, %v; want 1, nil", filed, err)
+	}
+
+	filedClaims, err := app.Dao().FindRecordsByFilter("payments", "recurring_claim_id != ''", "for_month", -1, 0)
+	if err != nil {
+		t.Fatalf("failed to load filed claims: %v", err)
+	}
+	want := []struct {
+		method string
+		status string
+	}{
+		{method: PaymentMethodPayPal, status: "approved"},
+		{method: "", status: "pending"},
+	}
+	if len(filedClaims) != len(want) {
+		t.Fatalf("filed claims = %d, want %d", len(filedClaims), len(want))
+	}
+
+	events, err := audit.FindWithDao(app.Dao(), plan.Id, audit.Filter{}, -1)
+	if err != nil {
+		t.Fatalf("FindWithDao returned error: %v", err)
+	}
+	recorded := map[string]bool{}
+	for _, event := range events {
+		if event.GetString("action") == audit.ActionPaymentFiled {
+			recorded[event.GetString("target_id")] = true
+		}
+	}
+
+	for i, payment := range filedClaims {
+		if got := payment.GetString("method"); got != want[i].method {
+			t.Fatalf("filed claim %d method = %q, want %q", i, got, want[i].method)
+		}
+		if got := payment.GetString("status"); got != want[i].status {
+			t.Fatalf("filed claim %d status = %q, want %q", i, got, want[i].status)
+		}
+		if !recorded[payment.Id] {
+			t.Fatalf("filed claim %d has no %s audit event", i, audit.ActionPaymentFiled)
+		}
+	}
+}
+
+func acceptMethods(t *testing.T, dao *daos.Dao, plan *pbmodels.Record, methods ...string) {
+	t.Helper()
+
+	accepted := make([]AcceptedPaymentMethod, 0, len(methods))
+	for _, method := range methods {
+		accepted = append(accepted, AcceptedPaymentMethod{Method: method})
+	}
+	if err := SetAcceptedPaymentMethods(plan, accepted); err != nil {
+		t.Fatalf("SetAcceptedPaymentMethods returned error: %v", err)
+	}
+	if err := dao.SaveRecord(plan); err != nil {
+		t.Fatalf("failed to save accepted methods: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package billing
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// Kinds of statement entry.
+const (
+	StatementCharge     = "charge"
+	StatementPayment    = "payment"
+	StatementAdjustment = "adjustment"
+)
+
+// StatementEntry is one dated movement on a member's account. Charges are negative and payments
+// positive, so a statement's entries add up to the member's balance.
+type StatementEntry struct {
+	// ID stays the same every time the statement is built, so exports can be re-imported safely.
+	ID          string
+	Kind        string
+	Date        time.Time
+	Month       string
+	Description string
+	Amount      money.Amount
+}
+
+// MemberStatementWithDao lists a member's monthly charges, adjustments and approved payments in
+// date order as they stood at asOf, following the same rules as CalculateMemberBalanceWithDao, so
+// its entries add up to the balance at asOf.
+func MemberStatementWithDao(dao *daos.Dao, planID, userID string, asOf time.Time) ([]StatementEntry, error) {
+	plan, err := dao.FindRecordById("family_plans", planID)
+	if err != nil {
+		return nil, err
+	}
+
+	membership, err := planutil.FindMembershipWithDao(dao, planID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if membership == nil {
+		return nil, errors.New("membership not found")
+	}
+
+	currency := planutil.Currency(plan)
+	planName := plan.GetString("name")
+	entries := []StatementEntry{}
+
+	payments, err := approvedPaymentsAsOfWithDao(dao, planID, userID, asOf)
+	if err != nil {
+		return nil, err
+	}
+	for _, payment := range payments {
+		date := paymentDate(payment)
+
+		month := ""
+		if forMonth := payment.GetDateTime("for_month"); !forMonth.IsZero() {
+			month = forMonth.Time().Format(monthKeyLayout)
+		}
+
+		entries = append(entries, StatementEntry{
+			ID:          payment.Id,
+			Kind:        StatementPayment,
+			Date:        date,
+			Month:       month,
+			Description: paymentDescription(planName, payment),
+			Amount:      PaymentAmount(payment, currency),
+		})
+	}
+
+	startMonth, endMonth := billedMonths(plan, membership, asOf)
+	endMonthKey := endMonth.Format(monthKeyLayout)
+
+	adjustments, err := FindAdjustmentsWithDao(dao, planID)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedAdjustmentsByMonth := map[string][]*pbmodels.Record{}
+	for _, adjustment := range adjustments {
+		if IsWaived(adjustment) {
+			continue
+		}
+
+		monthKey := adjustment.GetDateTime("for_month").Time().Format(monthKeyLayout)
+		switch {
+		case AppliesToAllMembers(adjustment):
+			sharedAdjustmentsByMonth[monthKey] = append(sharedAdjustmentsByMonth[monthKey], adjustment)
+		case adjustment.GetString("user_id") == userID && monthKey <= endMonthKey:
+			entries = append(entries, adjustmentEntry(adjustment, currency))
+		}
+	}
+
+	for month := startMonth; !month.After(endMonth); month = month.AddDate(0, 1, 0) {
+		monthKey := month.Format(monthKeyLayout)
+
+		shareCents, userActive, err := memberShareForMonthWithDao(dao, plan, userID, month)
+		if err != nil {
+			return nil, err
+		}
+
+		if shareCents != 0 {
+			entries = append(entries, StatementEntry{
+				ID:          membership.Id + "-" + monthKey,
+				Kind:        StatementCharge,
+				Date:        month,
+				Month:       monthKey,
+				Description: planName + " share for " + month.Format("January 2006"),
+				Amount:      money.New(-shareCents, currency),
+			})
+		}
+
+		if !userActive {
+			continue
+		}
+		for _, adjustment := range sharedAdjustmentsByMonth[monthKey] {
+			entries = append(entries, adjustmentEntry(adjustment, currency))
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date.Before(entries[j].Date)
+	})
+
+	return entries, nil
+}
+
+// StatementTotal adds up a statement's entries.
+func StatementTotal(entries []StatementEntry, currency string) money.Amount {
+	total := money.New(0, currency)
+	for _, entry := range entries {
+		total = total.Add(entry.Amount)
+	}
+
+	return total
+}
+
+func adjustmentEntry(adjustment *pbmodels.Record, currency string) StatementEntry {
+	month := adjustment.GetDateTime("for_month").Time()
+
+	return StatementEntry{
+		ID:          adjustment.Id,
+		Kind:        StatementAdjustment,
+		Date:        month,
+		Month:       month.Format(monthKeyLayout),
+		Description: adjustment.GetString("reason"),
+		Amount:      AdjustmentAmount(adjustment, currency),
+	}
+}
+
+func paymentDescription(planName string, payment *pbmodels.Record) string {
+	description := planName + " payment"
+	if label := PaymentMethodLabel(payment.GetString("method")); label != "" {
+		description += " by " + label
+	}
+	if reference := payment.GetString("reference"); reference != "" {
+		description += " (" + reference + ")"
+	}
+
+	return description
+}
@@ -0,0 +1,142 @@
+package billing
+
+import (
+	"testing"
+	"time"
+
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/testapp"
+
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+func TestMemberStatementAddsUpToBalance(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	other := testapp.SaveUser(t, app, "other")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+	joined := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	testapp.SaveMembership(t, app, plan.Id, member.Id, joined)
+	testapp.SaveMembership(t, app, plan.Id, other.Id, joined.AddDate(0, 1, 0))
+
+	paymentsCollection, err := app.Dao().FindCollectionByNameOrId("payments")
+	if err != nil {
+		t.Fatalf("failed to find payments collection: %v", err)
+	}
+	for _, status := range []string{"approved", "pending"} {
+		payment := pbmodels.NewRecord(paymentsCollection)
+		payment.Set("plan_id", plan.Id)
+		payment.Set("user_id", member.Id)
+		payment.Set("amount", 1500)
+		payment.Set("date", joined.AddDate(0, 0, 4))
+		payment.Set("for_month", joined)
+		payment.Set("status", status)
+		if err := app.Dao().SaveRecord(payment); err != nil {
+			t.Fatalf("failed to save payment: %v", err)
+		}
+	}
+
+	adjustments := []Adjustment{
+		{PlanID: plan.Id, Amount: money.New(-300, "USD"), Reason: "Price rise", ForMonth: joined.AddDate(0, 1, 0)},
+		{PlanID: plan.Id, UserID: member.Id, Amount: money.New(250, "USD"), Reason: "Refund", ForMonth: joined},
+	}
+	for _, adjustment := range adjustments {
+		if _, err := CreateAdjustmentWithDao(app.Dao(), adjustment); err != nil {
+			t.Fatalf("CreateAdjustmentWithDao returned error: %v", err)
+		}
+	}
+
+	now := time.Date(2026, time.April, 15, 12, 0, 0, 0, time.UTC)
+	entries, err := MemberStatementWithDao(app.Dao(), plan.Id, member.Id, now)
+	if err != nil {
+		t.Fatalf("MemberStatementWithDao returned error: %v", err)
+	}
+
+	balance, err := CalculateMemberBalanceWithDao(app.Dao(), plan.Id, member.Id, now)
+	if err != nil {
+		t.Fatalf("CalculateMemberBalanceWithDao returned error: %v", err)
+	}
+	if got := StatementTotal(entries, "USD"); got != balance {
+		t.Fatalf("statement total = %v, want balance %v", got, balance)
+	}
+
+	kinds := map[string]int{}
+	ids := map[string]bool{}
+	for i, entry := range entries {
+		kinds[entry.Kind]++
+		if ids[entry.ID] {
+			t.Fatalf("entry id %q is used twice", entry.ID)
+		}
+		ids[entry.ID] = true
+		if i > 0 && entry.Date.Before(entries[i-1].Date) {
+			t.Fatalf("entries are not in date order: %v before %v", entries[i-1].Date, entry.Date)
+		}
+	}
+	if kinds[StatementPayment] != 1 || kinds[StatementAdjustment] != 2 || kinds[StatementCharge] == 0 {
+		t.Fatalf("entry kinds = %v, want one approved payment, both adjustments and the monthly charges", kinds)
+	}
+}
+
+func TestMemberBalanceAsOf(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+	testapp.SaveMembership(t, app, plan.Id, member.Id, time.Date(2026, time.January, 10, 0, 0, 0, 0, time.UTC))
+
+	paymentsCollection, err := app.Dao().FindCollectionByNameOrId("payments")
+	if err != nil {
+		t.Fatalf("failed to find payments collection: %v", err)
+	}
+	for _, paid := range []time.Time{
+		time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, time.February, 20, 0, 0, 0, 0, time.UTC),
+	} {
+		payment := pbmodels.NewRecord(paymentsCollection)
+		payment.Set("plan_id", plan.Id)
+		payment.Set("user_id", member.Id)
+		payment.Set("amount", 1000)
+		payment.Set("date", paid)
+		payment.Set("for_month", monthStart(paid))
+		payment.Set("status", "approved")
+		if err := app.Dao().SaveRecord(payment); err != nil {
+			t.Fatalf("failed to save payment: %v", err)
+		}
+	}
+
+	// The member and the owner split the cost, so each month charges the member 1000.
+	tests := []struct {
+		name string
+		asOf time.Time
+		want int64
+	}{
+		{name: "before joining", asOf: time.Date(2025, time.December, 31, 23, 0, 0, 0, time.UTC), want: 0},
+		{name: "end of the first month", asOf: time.Date(2026, time.January, 31, 23, 0, 0, 0, time.UTC), want: 0},
+		{name: "before the second payment", asOf: time.Date(2026, time.February, 10, 0, 0, 0, 0, time.UTC), want: -1000},
+		{name: "end of March", asOf: time.Date(2026, time.March, 31, 23, 0, 0, 0, time.UTC), want: -1000},
+		{name: "mid April", asOf: time.Date(2026, time.April, 15, 12, 0, 0, 0, time.UTC), want: -2000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balance, err := CalculateMemberBalanceWithDao(app.Dao(), plan.Id, member.Id, tt.asOf)
+			if err != nil {
+				t.Fatalf("CalculateMemberBalanceWithDao returned error: %v", err)
+			}
+			entries, err := MemberStatementWithDao(app.Dao(), plan.Id, member.Id, tt.asOf)
+			if err != nil {
+				t.Fatalf("MemberStatementWithDao returned error: %v", err)
+			}
+
+			if balance.Minor != tt.want {
+				t.Fatalf("balance = %d, want %d", balance.Minor, tt.want)
+			}
+			if got := StatementTotal(entries, "USD"); got != balance {
+				t.Fatalf("statement total = %v, want balance %v", got, balance)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package billing
+
+import (
+	"errors"
+	"time"
+
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// MaxFreeMonths caps a trial or grace period so a typo cannot waive years of charges.
+const MaxFreeMonths = 24
+
+// ErrInvalidFreeMonths indicates that a trial or grace period length is out of range.
+var ErrInvalidFreeMonths = errors.New("free months must be between 0 and 24")
+
+// TrialMonths returns how many free months a plan gives new members.
+func TrialMonths(plan *pbmodels.Record) int {
+	months := plan.GetInt("trial_months")
+	if months < 0 {
+		return 0
+	}
+
+	return months
+}
+
+// StartTrial gives a new membership the plan's free first months, starting with the month it joins.
+// It only updates the record; the caller saves it.
+func StartTrial(membership, plan *pbmodels.Record, joinedAt time.Time) {
+	period, ok := freePeriod(joinedAt, TrialMonths(plan), InactiveReasonTrial)
+	if !ok {
+		return
+	}
+
+	membership.Set("inactive_periods", append(InactivePeriods(membership), period))
+}
+
+// GrantGracePeriodWithDao waives a member's charges for the given number of months from start.
+func GrantGracePeriodWithDao(dao *daos.Dao, planID, userID string, start time.Time, months int) error {
+	if months < 1 || months > MaxFreeMonths {
+		return ErrInvalidFreeMonths
+	}
+
+	membership, err := planutil.FindMembershipWithDao(dao, planID, userID)
+	if err != nil {
+		return err
+	}
+	if membership == nil {
+		return errors.New("membership not found")
+	}
+
+	period, _ := freePeriod(start, months, InactiveReasonGrace)
+	membership.Set("inactive_periods", append(InactivePeriods(membership), period))
+	return dao.SaveRecord(membership)
+}
+
+func freePeriod(start time.Time, months int, reason string) (InactivePeriod, bool) {
+	if months <= 0 {
+		return InactivePeriod{}, false
+	}
+
+	first := monthStart(start)
+	return InactivePeriod{
+		Start:  first.Format(monthKeyLayout),
+		End:    first.AddDate(0, months-1, 0).Format(monthKeyLayout),
+		Reason: reason,
+	}, true
+}
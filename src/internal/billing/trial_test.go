@@ -0,0 +1,83 @@
+package billing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/testapp"
+)
+
+func TestTrialAndGraceMonthsAreNotCharged(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	trialist := testapp.SaveUser(t, app, "trialist")
+	other := testapp.SaveUser(t, app, "other")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 3000)
+	plan.Set("trial_months", 2)
+	if err := app.Dao().SaveRecord(plan); err != nil {
+		t.Fatalf("failed to save trial months: %v", err)
+	}
+
+	joined := time.Date(2026, time.January, 20, 0, 0, 0, 0, time.UTC)
+	ended := testapp.DateTime(t, time.Date(2026, time.March, 15, 0, 0, 0, 0, time.UTC))
+
+	trialMembership := testapp.SaveMembership(t, app, plan.Id, trialist.Id, joined)
+	StartTrial(trialMembership, plan, joined)
+	trialMembership.Set("date_ended", ended)
+	if err := app.Dao().SaveRecord(trialMembership); err != nil {
+		t.Fatalf("failed to save trial membership: %v", err)
+	}
+
+	otherMembership := testapp.SaveMembership(t, app, plan.Id, other.Id, joined)
+	otherMembership.Set("date_ended", ended)
+	if err := app.Dao().SaveRecord(otherMembership); err != nil {
+		t.Fatalf("failed to end membership: %v", err)
+	}
+
+	march := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	if err := GrantGracePeriodWithDao(app.Dao(), plan.Id, other.Id, march, 1); err != nil {
+		t.Fatalf("GrantGracePeriodWithDao returned error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		userID string
+		want   int64
+	}{
+		// January and February are free, March is split with the owner.
+		{name: "trial member", userID: trialist.Id, want: -1500},
+		// January and February are split with the owner, March is waived.
+		{name: "grace member", userID: other.Id, want: -3000},
+	}
+
+	now := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+	for _, test := range tests {
+		balance, err := CalculateMemberBalanceWithDao(app.Dao(), plan.Id, test.userID, now)
+		if err != nil {
+			t.Fatalf("%s: CalculateMemberBalanceWithDao returned error: %v", test.name, err)
+		}
+		if balance.Minor != test.want {
+			t.Fatalf("%s: balance = %d cents, want %d", test.name, balance.Minor, test.want)
+		}
+	}
+
+	january := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	served, err := GetServedMembershipsForMonth(app, plan.Id, january)
+	if err != nil {
+		t.Fatalf("GetServedMembershipsForMonth returned error: %v", err)
+	}
+	if len(served) != 2 {
+		t.Fatalf("served memberships in January = %d, want 2", len(served))
+	}
+
+	period, free := FreePeriodForMonth(trialMembership, january)
+	if !free || period.Reason != InactiveReasonTrial || period.End != "2026-02" {
+		t.Fatalf("FreePeriodForMonth(January) = %+v, %v, want trial through 2026-02", period, free)
+	}
+
+	if err := GrantGracePeriodWithDao(app.Dao(), plan.Id, other.Id, march, 0); !errors.Is(err, ErrInvalidFreeMonths) {
+		t.Fatalf("GrantGracePeriodWithDao(0 months) error = %v, want ErrInvalidFreeMonths", err)
+	}
+}
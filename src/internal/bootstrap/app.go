@@ -2,10 +2,16 @@ package bootstrap
 
 import (
 	"familyplan/src/internal/assets"
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/cli"
+	"familyplan/src/internal/fxrates"
 	"familyplan/src/internal/http/router"
+	"familyplan/src/internal/integrity"
+	"fmt"
 	"io/fs"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/apis"
@@ -33,20 +39,103 @@ func Run() error {
 	app.Settings().Logs.MaxDays = 7
 	app.Settings().Smtp.Enabled = false
 
+	clock := billing.SystemClock
+	billing.RegisterAllocationHooks(app, clock)
+	cli.Register(app, clock)
+
 	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
 		staticFS, err := fs.Sub(assets.StaticFS, "static")
 		if err != nil {
 			return err
 		}
 
+		if err := loadExchangeRatesFile(app); err != nil {
+			return err
+		}
+
+		now := clock.Now()
+		if err := checkIntegrity(app, now); err != nil {
+			return err
+		}
+
+		// Allocations are derived data, so rebuild them in case records changed outside the app.
+		if err := billing.ReallocateAllWithDao(app.Dao(), now); err != nil {
+			return fmt.Errorf("failed to rebuild payment allocations: %w", err)
+		}
+
+		if err := billing.SyncAllLateFeesWithDao(app.Dao(), now); err != nil {
+			return fmt.Errorf("failed to apply late fees: %w", err)
+		}
+
+		// Catch up on standing orders that came due while the server was down.
+		runRecurringClaims(app, now)
+		startScheduler(app, clock)
+
 		e.Router.GET("/static/*", apis.StaticDirectoryHandler(staticFS, false))
-		router.Setup(app, e.Router)
+		router.Setup(app, e.Router, clock)
 		return nil
 	})
 
 	return app.Start()
 }
 
+// loadExchangeRatesFile refreshes the shared exchange rates from FAMILYPLAN_RATES_FILE, when set.
+func loadExchangeRatesFile(app *pocketbase.PocketBase) error {
+	path := strings.TrimSpace(os.Getenv("FAMILYPLAN_RATES_FILE"))
+	if path == "" {
+		return nil
+	}
+
+	loaded, err := fxrates.LoadFileWithDao(app.Dao(), path)
+	if err != nil {
+		return fmt.Errorf("failed to load exchange rates from %s: %w", path, err)
+	}
+
+	app.Logger().Info("Loaded exchange rates", "file", path, "count", loaded)
+	return nil
+}
+
+// checkIntegrity runs the integrity checks when FAMILYPLAN_INTEGRITY_CHECK is set, logging the
+// problems found for "report" and also repairing them for "fix".
+func checkIntegrity(app *pocketbase.PocketBase, now time.Time) error {
+	mode := strings.TrimSpace(os.Getenv("FAMILYPLAN_INTEGRITY_CHECK"))
+	if mode == "" {
+		return nil
+	}
+	if mode != "report" && mode != "fix" {
+		return fmt.Errorf("FAMILYPLAN_INTEGRITY_CHECK must be report or fix, not %q", mode)
+	}
+
+	problems, err := integrity.CheckWithDao(app.Dao(), now)
+	if err != nil {
+		return fmt.Errorf("failed to check data integrity: %w", err)
+	}
+
+	for _, problem := range problems {
+		app.Logger().Warn(
+			"Integrity problem",
+			"check", problem.Check,
+			"collection", problem.Collection,
+			"record", problem.RecordID,
+			"problem", problem.Message,
+			"fix", problem.Fix,
+		)
+	}
+
+	if mode != "fix" || len(problems) == 0 {
+		app.Logger().Info("Checked data integrity", "problems", len(problems))
+		return nil
+	}
+
+	fixed, err := integrity.FixWithDao(app.Dao(), problems, now)
+	if err != nil {
+		return fmt.Errorf("failed to fix integrity problems: %w", err)
+	}
+
+	app.Logger().Info("Fixed integrity problems", "problems", len(problems), "fixed", fixed)
+	return nil
+}
+
 // defaultToServeCommand preserves explicit PocketBase subcommands but restores
 // the historical "run the server by default" behavior for bare binary launches.
 func defaultToServeCommand() {
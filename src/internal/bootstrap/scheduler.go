@@ -0,0 +1,51 @@
+package bootstrap
+
+import (
+	"time"
+
+	"familyplan/src/internal/billing"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/cron"
+)
+
+// startScheduler runs the billing jobs that depend on the date rather than on a record changing.
+// The jobs are idempotent, so a missed or repeated run only delays or repeats work already done.
+func startScheduler(app *pocketbase.PocketBase, clock billing.Clock) {
+	scheduler := cron.New()
+
+	scheduler.MustAdd("recurring_claims", "*/15 * * * *", func() {
+		runRecurringClaims(app, clock.Now())
+	})
+
+	scheduler.MustAdd("late_fees", "5 * * * *", func() {
+		if err := billing.SyncAllLateFeesWithDao(app.Dao(), clock.Now()); err != nil {
+			app.Logger().Error("Failed to apply late fees", "error", err)
+		}
+	})
+
+	// Allocations are built as of a moment, so a new month's charges stay uncovered until they are rebuilt.
+	scheduler.MustAdd("allocations", "0 0 1 * *", func() {
+		if err := billing.ReallocateAllWithDao(app.Dao(), clock.Now()); err != nil {
+			app.Logger().Error("Failed to rebuild payment allocations for the new month", "error", err)
+		}
+	})
+
+	scheduler.Start()
+
+	app.OnTerminate().Add(func(e *core.TerminateEvent) error {
+		scheduler.Stop()
+		return nil
+	})
+}
+
+func runRecurringClaims(app *pocketbase.PocketBase, now time.Time) {
+	filed, err := billing.RunRecurringClaimsWithDao(app.Dao(), now)
+	if err != nil {
+		app.Logger().Error("Failed to file standing order claims", "error", err)
+	}
+	if filed > 0 {
+		app.Logger().Info("Filed standing order claims", "count", filed)
+	}
+}
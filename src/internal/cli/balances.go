@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"fmt"
+
+	"familyplan/src/internal/billing"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/spf13/cobra"
+)
+
+func newBalancesCommand(app *pocketbase.PocketBase, clock billing.Clock) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "balances",
+		Short: "Maintain member balances",
+	}
+
+	command.AddCommand(newBalancesRecomputeCommand(app, clock))
+	return command
+}
+
+func newBalancesRecomputeCommand(app *pocketbase.PocketBase, clock billing.Clock) *cobra.Command {
+	var joinCode string
+
+	command := &cobra.Command{
+		Use:   "recompute",
+		Short: "Rebuild payment allocations and late fees",
+		Long: "Rebuild payment allocations and bring late fees up to date, for every plan or for the " +
+			"plan given with --plan. Run it after changing records outside the app.",
+		Args: cobra.NoArgs,
+		RunE: func(command *cobra.Command, args []string) error {
+			now := clock.Now()
+
+			if joinCode == "" {
+				err := app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+					if err := billing.ReallocateAllWithDao(txDao, now); err != nil {
+						return err
+					}
+					return billing.SyncAllLateFeesWithDao(txDao, now)
+				})
+				if err != nil {
+					return err
+				}
+
+				fmt.Fprintln(command.OutOrStdout(), "Recomputed balances for every plan")
+				return nil
+			}
+
+			plan, err := findPlanWithDao(app.Dao(), joinCode)
+			if err != nil {
+				return err
+			}
+
+			err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+				if err := billing.ReallocatePlanWithDao(txDao, plan.Id, now); err != nil {
+					return err
+				}
+				return billing.SyncLateFeesWithDao(txDao, plan.Id, now)
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(command.OutOrStdout(), "Recomputed balances for %q\n", plan.GetString("name"))
+			return nil
+		},
+	}
+
+	command.Flags().StringVar(&joinCode, "plan", "", "join code of the only plan to recompute")
+	return command
+}
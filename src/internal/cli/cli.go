@@ -0,0 +1,20 @@
+// Package cli adds FamilyPlan's own subcommands to the PocketBase command line.
+package cli
+
+import (
+	"familyplan/src/internal/billing"
+
+	"github.com/pocketbase/pocketbase"
+)
+
+// Register adds the app's subcommands to the PocketBase root command. Commands that bill, check
+// or stamp records read the time from clock.
+func Register(app *pocketbase.PocketBase, clock billing.Clock) {
+	app.RootCmd.AddCommand(
+		newPlansCommand(app, clock),
+		newBalancesCommand(app, clock),
+		newUsersCommand(app),
+		newDoctorCommand(app, clock),
+		newSeedCommand(app, clock),
+	)
+}
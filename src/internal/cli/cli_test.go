@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/testapp"
+
+	"github.com/spf13/cobra"
+)
+
+func TestPlansShowListsMembersWithBalances(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+	now := time.Date(2026, time.March, 10, 0, 0, 0, 0, time.UTC)
+	testapp.SaveMembership(t, app, plan.Id, owner.Id, now.AddDate(0, -2, 0))
+	testapp.SaveMembership(t, app, plan.Id, member.Id, now.AddDate(0, -2, 0))
+	testapp.SavePayment(t, app, plan.Id, member.Id, 400, now, "approved")
+
+	balance, err := billing.CalculateMemberBalanceWithDao(app.Dao(), plan.Id, member.Id, now)
+	if err != nil {
+		t.Fatalf("CalculateMemberBalanceWithDao returned error: %v", err)
+	}
+
+	out := runCommand(t, newPlansCommand(app, billing.FixedClock(now)), "", "show", "ABC123")
+
+	for _, want := range []string{"Name:            Streaming", "Owner:           owner", member.Id, balance.String()} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("plans show output is missing %q:\n%s", want, out)
+		}
+	}
+
+	if _, err := executeCommand(newPlansCommand(app, billing.FixedClock(now)), "", "show", "NOPE00"); err == nil {
+		t.Fatal("expected plans show to fail for an unknown join code")
+	}
+}
+
+func TestBalancesRecomputeRebuildsAllocations(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+	now := time.Date(2026, time.March, 10, 0, 0, 0, 0, time.UTC)
+	testapp.SaveMembership(t, app, plan.Id, member.Id, now)
+	testapp.SavePayment(t, app, plan.Id, member.Id, 1000, now, "approved")
+
+	for _, args := range [][]string{{"recompute", "--plan", "ABC123"}, {"recompute"}} {
+		if _, err := app.Dao().DB().NewQuery("DELETE FROM " + billing.AllocationsCollection).Execute(); err != nil {
+			t.Fatalf("failed to clear allocations: %v", err)
+		}
+
+		out := runCommand(t, newBalancesCommand(app, billing.FixedClock(now)), "", args...)
+		if !strings.HasPrefix(out, "Recomputed balances") {
+			t.Fatalf("balances %v output = %q", args, out)
+		}
+
+		allocations, err := app.Dao().FindRecordsByFilter(billing.AllocationsCollection, "id != ''", "", -1, 0)
+		if err != nil {
+			t.Fatalf("failed to load allocations: %v", err)
+		}
+		if len(allocations) != 1 || allocations[0].GetInt("amount") != 1000 {
+			t.Fatalf("balances %v left %d allocations, want one of 1000", args, len(allocations))
+		}
+	}
+}
+
+func TestUsersResetPasswordReadsStdin(t *testing.T) {
+	app := testapp.New(t)
+
+	testapp.SaveUser(t, app, "alice")
+
+	out := runCommand(t, newUsersCommand(app), "correct horse battery\n", "reset-password", "alice", "--password-stdin")
+	if strings.Contains(out, "correct horse battery") {
+		t.Fatalf("reset-password printed the password: %q", out)
+	}
+
+	user, err := app.Dao().FindAuthRecordByUsername("users", "alice")
+	if err != nil {
+		t.Fatalf("failed to load user: %v", err)
+	}
+	if !user.ValidatePassword("correct horse battery") {
+		t.Fatal("expected the password read from stdin to be set")
+	}
+
+	if _, err := executeCommand(newUsersCommand(app), "short\n", "reset-password", "alice", "--password-stdin"); err == nil {
+		t.Fatal("expected a short password to be rejected")
+	}
+
+	out = runCommand(t, newUsersCommand(app), "", "reset-password", "alice")
+	if !strings.HasPrefix(out, "New password for alice: ") {
+		t.Fatalf("reset-password without stdin output = %q", out)
+	}
+}
+
+func runCommand(t *testing.T, command *cobra.Command, stdin string, args ...string) string {
+	t.Helper()
+
+	out, err := executeCommand(command, stdin, args...)
+	if err != nil {
+		t.Fatalf("%s %v returned error: %v", command.Name(), args, err)
+	}
+
+	return out
+}
+
+func executeCommand(command *cobra.Command, stdin string, args ...string) (string, error) {
+	var out bytes.Buffer
+	command.SetIn(strings.NewReader(stdin))
+	command.SetOut(&out)
+	command.SetErr(&out)
+	command.SetArgs(args)
+
+	err := command.Execute()
+	return out.String(), err
+}
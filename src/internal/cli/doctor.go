@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/integrity"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/spf13/cobra"
+)
+
+func newDoctorCommand(app *pocketbase.PocketBase, clock billing.Clock) *cobra.Command {
+	var fix bool
+
+	command := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the data for integrity problems",
+		Args:  cobra.NoArgs,
+		RunE: func(command *cobra.Command, args []string) error {
+			now := clock.Now()
+			problems, err := integrity.CheckWithDao(app.Dao(), now)
+			if err != nil {
+				return err
+			}
+
+			out := command.OutOrStdout()
+			if len(problems) == 0 {
+				fmt.Fprintln(out, "No problems found")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "CHECK\tCOLLECTION\tRECORD\tPROBLEM\tFIX")
+			for _, problem := range problems {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", problem.Check, problem.Collection, problem.RecordID, problem.Message, problem.Fix)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+
+			if !fix {
+				return fmt.Errorf("found %d problems", len(problems))
+			}
+
+			fixed, err := integrity.FixWithDao(app.Dao(), problems, now)
+			if err != nil {
+				return fmt.Errorf("failed to fix problems: %w", err)
+			}
+
+			fmt.Fprintf(out, "Fixed %d of %d problems\n", fixed, len(problems))
+			if fixed < len(problems) {
+				return fmt.Errorf("%d problems need fixing by hand", len(problems)-fixed)
+			}
+
+			return nil
+		},
+	}
+
+	command.Flags().BoolVar(&fix, "fix", false, "apply the suggested fixes in one transaction")
+	return command
+}
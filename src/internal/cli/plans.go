@@ -0,0 +1,295 @@
+package cli
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"familyplan/src/internal/archive"
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+	"github.com/spf13/cobra"
+)
+
+func newPlansCommand(app *pocketbase.PocketBase, clock billing.Clock) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "plans",
+		Short: "Manage family plans",
+	}
+
+	command.AddCommand(
+		newPlansListCommand(app),
+		newPlansShowCommand(app, clock),
+		newPlansExportCommand(app, clock),
+		newPlansImportCommand(app, clock),
+	)
+	return command
+}
+
+func newPlansListCommand(app *pocketbase.PocketBase) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List every plan with its owner and member count",
+		Args:  cobra.NoArgs,
+		RunE: func(command *cobra.Command, args []string) error {
+			plans, err := app.Dao().FindRecordsByFilter("family_plans", "id != ''", "created", -1, 0)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(command.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "JOIN CODE\tNAME\tOWNER\tMEMBERS\tCOST\tSTATUS")
+			for _, plan := range plans {
+				memberships, err := findMembershipsWithDao(app.Dao(), plan.Id)
+				if err != nil {
+					return err
+				}
+
+				active := 0
+				for _, membership := range memberships {
+					if membership.GetDateTime("date_ended").IsZero() {
+						active++
+					}
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n",
+					plan.GetString("join_code"),
+					plan.GetString("name"),
+					usernameWithDao(app.Dao(), planutil.OwnerID(plan)),
+					active,
+					planutil.Cost(plan),
+					planStatus(plan),
+				)
+			}
+
+			return w.Flush()
+		},
+	}
+}
+
+func newPlansShowCommand(app *pocketbase.PocketBase, clock billing.Clock) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <join_code>",
+		Short: "Show a plan's settings and its members' balances",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			plan, err := findPlanWithDao(app.Dao(), args[0])
+			if err != nil {
+				return err
+			}
+
+			memberships, err := findMembershipsWithDao(app.Dao(), plan.Id)
+			if err != nil {
+				return err
+			}
+
+			userIDs := make([]string, 0, len(memberships))
+			for _, membership := range memberships {
+				userIDs = append(userIDs, membership.GetString("user_id"))
+			}
+			identities, err := planutil.FindIdentitiesWithDao(app.Dao(), plan.Id, userIDs)
+			if err != nil {
+				return err
+			}
+
+			now := clock.Now()
+			out := command.OutOrStdout()
+			fmt.Fprintf(out, "Name:            %s\n", plan.GetString("name"))
+			fmt.Fprintf(out, "Join code:       %s\n", plan.GetString("join_code"))
+			fmt.Fprintf(out, "ID:              %s\n", plan.Id)
+			fmt.Fprintf(out, "Owner:           %s\n", usernameWithDao(app.Dao(), planutil.OwnerID(plan)))
+			fmt.Fprintf(out, "Cost:            %s\n", planutil.Cost(plan))
+			fmt.Fprintf(out, "Individual cost: %s\n", planutil.IndividualCost(plan))
+			fmt.Fprintf(out, "Created:         %s\n", plan.Created.Time().Format("2006-01-02"))
+			fmt.Fprintf(out, "Status:          %s\n\n", planStatus(plan))
+
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "MEMBER\tUSER ID\tJOINED\tENDED\tBALANCE")
+			for _, membership := range memberships {
+				userID := membership.GetString("user_id")
+				name := identities[userID].DisplayName()
+				if membership.GetBool("is_artificial") {
+					name += " (artificial)"
+				}
+
+				balance := "owner"
+				if userID != planutil.OwnerID(plan) {
+					amount, err := billing.CalculateMemberBalanceWithDao(app.Dao(), plan.Id, userID, now)
+					if err != nil {
+						return err
+					}
+					balance = amount.String()
+				}
+
+				ended := "-"
+				if dateEnded := membership.GetDateTime("date_ended"); !dateEnded.IsZero() {
+					ended = dateEnded.Time().Format("2006-01-02")
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, userID, membership.Created.Time().Format("2006-01-02"), ended, balance)
+			}
+
+			return w.Flush()
+		},
+	}
+}
+
+func newPlansExportCommand(app *pocketbase.PocketBase, clock billing.Clock) *cobra.Command {
+	var output string
+
+	command := &cobra.Command{
+		Use:   "export <join_code>",
+		Short: "Write a plan archive as JSON",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			planRecord, err := findPlanWithDao(app.Dao(), args[0])
+			if err != nil {
+				return err
+			}
+
+			planArchive, err := archive.ExportPlanWithDao(app.Dao(), planRecord.Id, clock.Now())
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = command.OutOrStdout()
+			if output != "" {
+				file, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				w = file
+			}
+
+			return archive.Write(w, planArchive)
+		},
+	}
+
+	command.Flags().StringVarP(&output, "output", "o", "", "file to write instead of standard output")
+	return command
+}
+
+func newPlansImportCommand(app *pocketbase.PocketBase, clock billing.Clock) *cobra.Command {
+	var owner string
+	var mappings []string
+
+	command := &cobra.Command{
+		Use:   "import <archive.json>",
+		Short: "Recreate a plan from an archive under a new owner",
+		Long: "Recreate a plan from an archive under a new owner. Archived users can be mapped to " +
+			"existing users with --map <archived_user_id>=<username or id>; real members who are " +
+			"not mapped come back as artificial members.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			planArchive, err := archive.Read(file)
+			if err != nil {
+				return err
+			}
+
+			ownerRecord, err := findUserWithDao(app.Dao(), owner)
+			if err != nil {
+				return fmt.Errorf("owner: %w", err)
+			}
+
+			userMap := map[string]string{}
+			for _, mapping := range mappings {
+				archivedID, target, ok := strings.Cut(mapping, "=")
+				if !ok || archivedID == "" {
+					return fmt.Errorf("mapping %q is not <archived_user_id>=<user>", mapping)
+				}
+
+				userRecord, err := findUserWithDao(app.Dao(), target)
+				if err != nil {
+					return fmt.Errorf("mapping %q: %w", mapping, err)
+				}
+				userMap[archivedID] = userRecord.Id
+			}
+
+			planRecord, err := archive.ImportPlanWithDao(app.Dao(), planArchive, ownerRecord.Id, userMap, clock.Now())
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(command.OutOrStdout(), "Imported %q with join code %s\n", planRecord.GetString("name"), planRecord.GetString("join_code"))
+			return nil
+		},
+	}
+
+	command.Flags().StringVar(&owner, "owner", "", "username or id of the new owner")
+	command.Flags().StringArrayVar(&mappings, "map", nil, "map an archived user to an existing one, as <archived_user_id>=<username or id>")
+	_ = command.MarkFlagRequired("owner")
+	return command
+}
+
+// findPlanWithDao looks a plan up by join code.
+func findPlanWithDao(dao *daos.Dao, joinCode string) (*pbmodels.Record, error) {
+	plan, err := planutil.FindPlanByJoinCodeWithDao(dao, joinCode)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil {
+		return nil, fmt.Errorf("no plan has join code %q", joinCode)
+	}
+
+	return plan, nil
+}
+
+func findMembershipsWithDao(dao *daos.Dao, planID string) ([]*pbmodels.Record, error) {
+	filter, err := planutil.BuildEqualsFilter(planutil.FilterTerm{Field: "plan_id", Value: planID})
+	if err != nil {
+		return nil, err
+	}
+
+	return dao.FindRecordsByFilter("memberships", filter.Expression, "created", -1, 0, filter.Params)
+}
+
+// usernameWithDao returns a user's username, or their id when the account is gone.
+func usernameWithDao(dao *daos.Dao, userID string) string {
+	record, err := dao.FindRecordById("users", userID)
+	if err != nil {
+		return userID
+	}
+
+	return record.GetString("username")
+}
+
+func planStatus(plan *pbmodels.Record) string {
+	if planutil.IsArchived(plan) {
+		return "archived " + planutil.ArchivedAt(plan).Format("2006-01-02")
+	}
+
+	return "active"
+}
+
+// findUserWithDao looks a user up by username, then by id.
+func findUserWithDao(dao *daos.Dao, usernameOrID string) (*pbmodels.Record, error) {
+	record, err := dao.FindAuthRecordByUsername("users", usernameOrID)
+	if err == nil {
+		return record, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	record, err = dao.FindRecordById("users", usernameOrID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no user %q", usernameOrID)
+	}
+
+	return record, err
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/seed"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/spf13/cobra"
+)
+
+func newSeedCommand(app *pocketbase.PocketBase, clock billing.Clock) *cobra.Command {
+	var (
+		seedValue int64
+		date      string
+	)
+
+	command := &cobra.Command{
+		Use:   "seed",
+		Short: "Fill an empty database with demo users, plans and payments",
+		Args:  cobra.NoArgs,
+		RunE: func(command *cobra.Command, args []string) error {
+			now := clock.Now()
+			if date != "" {
+				parsed, err := time.Parse("2006-01-02", date)
+				if err != nil {
+					return fmt.Errorf("--now must be a date like 2024-06-15: %w", err)
+				}
+				now = parsed
+			}
+
+			summary, err := seed.RunWithDao(app.Dao(), seed.Options{Seed: seedValue, Now: now})
+			if err != nil {
+				return err
+			}
+
+			out := command.OutOrStdout()
+			fmt.Fprintf(out, "Created plans %s\n", strings.Join(summary.Plans, ", "))
+			fmt.Fprintf(out, "with %d memberships, %d payments, %d claim links and %d join requests\n",
+				summary.Memberships, summary.Payments, summary.ClaimLinks, summary.JoinRequests)
+			fmt.Fprintf(out, "Log in as %s with password %s\n", strings.Join(summary.Users, ", "), seed.Password)
+			return nil
+		},
+	}
+
+	command.Flags().Int64Var(&seedValue, "seed", seed.DefaultSeed, "random seed; the same seed and date give the same data")
+	command.Flags().StringVar(&date, "now", "", "date to build the data around, as YYYY-MM-DD, instead of today")
+	return command
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"familyplan/src/internal/support/random"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/spf13/cobra"
+)
+
+// minPasswordLength matches the minimum the users collection accepts.
+const minPasswordLength = 8
+
+func newUsersCommand(app *pocketbase.PocketBase) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "users",
+		Short: "Manage user accounts",
+	}
+
+	command.AddCommand(newUsersResetPasswordCommand(app))
+	return command
+}
+
+func newUsersResetPasswordCommand(app *pocketbase.PocketBase) *cobra.Command {
+	var passwordStdin bool
+
+	command := &cobra.Command{
+		Use:   "reset-password <username|id>",
+		Short: "Set a new password for a user",
+		Long: "Set a new password for a user and sign them out everywhere. With --password-stdin the " +
+			"password is read from the first line of standard input, so it stays out of shell history " +
+			"and the process list; otherwise a random password is generated and printed.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			user, err := findUserWithDao(app.Dao(), args[0])
+			if err != nil {
+				return err
+			}
+
+			var password string
+			if passwordStdin {
+				if password, err = readPassword(command.InOrStdin()); err != nil {
+					return err
+				}
+			} else if password, err = random.GenerateToken(); err != nil {
+				return err
+			}
+			if utf8.RuneCountInString(password) < minPasswordLength {
+				return errors.New("passwords must be at least 8 characters")
+			}
+
+			// Setting the password also refreshes the token key, which ends the user's sessions.
+			if err := user.SetPassword(password); err != nil {
+				return err
+			}
+			if err := app.Dao().SaveRecord(user); err != nil {
+				return err
+			}
+
+			if passwordStdin {
+				fmt.Fprintf(command.OutOrStdout(), "Password reset for %s\n", user.GetString("username"))
+			} else {
+				fmt.Fprintf(command.OutOrStdout(), "New password for %s: %s\n", user.GetString("username"), password)
+			}
+			return nil
+		},
+	}
+
+	command.Flags().BoolVar(&passwordStdin, "password-stdin", false, "read the new password from standard input instead of generating one")
+	return command
+}
+
+// readPassword reads the first line of in, without its line ending.
+func readPassword(in io.Reader) (string, error) {
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
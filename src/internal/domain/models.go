@@ -1,5 +1,7 @@
 package domain
 
+import "familyplan/src/internal/money"
+
 // SessionData holds user session information.
 type SessionData struct {
 	IsAuthenticated bool
@@ -11,28 +13,79 @@ type SessionData struct {
 
 // FamilyPlan represents a subscription plan that can be shared among family/friends.
 type FamilyPlan struct {
-	ID             string  `json:"id"`
-	Name           string  `json:"name"`
-	Description    string  `json:"description"`
-	Cost           float64 `json:"cost"`
-	IndividualCost float64 `json:"individual_cost"`
-	Owner          string  `json:"owner"`
-	JoinCode       string  `json:"join_code"`
-	CreatedAt      string  `json:"created_at"`
-	MembersCount   int     `json:"members_count"`
-	Balance        float64 `json:"balance"`
+	ID             string       `json:"id"`
+	Name           string       `json:"name"`
+	Description    string       `json:"description"`
+	Currency       string       `json:"currency"`
+	Cost           money.Amount `json:"cost"`
+	IndividualCost money.Amount `json:"individual_cost"`
+	TrialMonths    int          `json:"trial_months"`
+	DueDay         int          `json:"due_day"`
+	LateFeeDays    int          `json:"late_fee_days"`
+	LateFeeAmount  money.Amount `json:"late_fee_amount"`
+	LateFeePercent float64      `json:"late_fee_percent"`
+	Owner          string       `json:"owner"`
+	JoinCode       string       `json:"join_code"`
+	CreatedAt      string       `json:"created_at"`
+	MembersCount   int          `json:"members_count"`
+	Balance        money.Amount `json:"balance"`
+	IsArchived     bool         `json:"is_archived"`
+	ArchivedAt     string       `json:"archived_at"`
 }
 
 // Member represents a user who is part of a family plan.
 type Member struct {
-	ID             string  `json:"id"`
-	Username       string  `json:"username"`
-	Name           string  `json:"name"`
-	AvatarURL      string  `json:"avatar_url"`
-	Balance        float64 `json:"balance"`
-	LeaveRequested bool    `json:"leave_requested"`
-	DateEnded      string  `json:"date_ended"`
-	IsArtificial   bool    `json:"is_artificial"`
+	ID             string       `json:"id"`
+	Username       string       `json:"username"`
+	Name           string       `json:"name"`
+	AvatarURL      string       `json:"avatar_url"`
+	Balance        money.Amount `json:"balance"`
+	LeaveRequested bool         `json:"leave_requested"`
+	DateEnded      string       `json:"date_ended"`
+	IsArtificial   bool         `json:"is_artificial"`
+	FreeReason     string       `json:"free_reason"`
+	FreeUntil      string       `json:"free_until"`
+	Paused         bool         `json:"paused"`
+	PausedFrom     string       `json:"paused_from"`
+	PausedUntil    string       `json:"paused_until"`
+}
+
+// ClaimLink describes an active public claim link for an artificial member.
+type ClaimLink struct {
+	URL       string `json:"url"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// ClaimAttempt records a try at claiming an artificial member through a public link.
+type ClaimAttempt struct {
+	ArtificialName string `json:"artificial_name"`
+	Username       string `json:"username"`
+	Succeeded      bool   `json:"succeeded"`
+	Reason         string `json:"reason"`
+	AttemptedAt    string `json:"attempted_at"`
+}
+
+// AuditEvent is one entry in a plan's activity feed.
+type AuditEvent struct {
+	Action     string        `json:"action"`
+	Label      string        `json:"label"`
+	Actor      string        `json:"actor"`
+	Member     string        `json:"member"`
+	OccurredAt string        `json:"occurred_at"`
+	Changes    []AuditChange `json:"changes"`
+}
+
+// AuditCategory is a kind of audit event the owner can filter the activity feed by.
+type AuditCategory struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// AuditChange is a field an audited change modified, with its old and new value.
+type AuditChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
 }
 
 // JoinRequest represents a user's request to join a family plan.
@@ -45,16 +98,109 @@ type JoinRequest struct {
 
 // Payment represents a payment made by a member for a family plan.
 type Payment struct {
-	ID       string  `json:"id"`
-	PlanID   string  `json:"plan_id"`
-	UserID   string  `json:"user_id"`
-	Amount   float64 `json:"amount"`
-	Date     string  `json:"date"`
-	Status   string  `json:"status"`
-	Notes    string  `json:"notes"`
-	ForMonth string  `json:"for_month"`
-	Username string  `json:"username"`
-	Name     string  `json:"name"`
+	ID             string       `json:"id"`
+	PlanID         string       `json:"plan_id"`
+	UserID         string       `json:"user_id"`
+	Amount         money.Amount `json:"amount"`
+	OriginalAmount money.Amount `json:"original_amount"`
+	ExchangeRate   float64      `json:"exchange_rate"`
+	IsConverted    bool         `json:"is_converted"`
+	Date           string       `json:"date"`
+	Status         string       `json:"status"`
+	Notes          string       `json:"notes"`
+	ForMonth       string       `json:"for_month"`
+	Kind           string       `json:"kind"`
+	Method         string       `json:"method"`
+	MethodLabel    string       `json:"method_label"`
+	Reference      string       `json:"reference"`
+	PayerID        string       `json:"payer_id"`
+	PayerName      string       `json:"payer_name"`
+	Username       string       `json:"username"`
+	Name           string       `json:"name"`
+}
+
+// PaymentMethodOption is a payment method as the plan offers it. Accepted is set when the owner chose
+// the method; Offered when members may pay that way, which is every method until the owner chooses.
+type PaymentMethodOption struct {
+	Method       string `json:"method"`
+	Label        string `json:"label"`
+	Accepted     bool   `json:"accepted"`
+	Offered      bool   `json:"offered"`
+	Instructions string `json:"instructions"`
+}
+
+// RecurringClaim is a member's standing order, filed as a payment claim every month.
+type RecurringClaim struct {
+	ID          string       `json:"id"`
+	UserID      string       `json:"user_id"`
+	Name        string       `json:"name"`
+	Amount      money.Amount `json:"amount"`
+	Method      string       `json:"method"`
+	DayOfMonth  int          `json:"day_of_month"`
+	StartMonth  string       `json:"start_month"`
+	EndMonth    string       `json:"end_month"`
+	AutoApprove bool         `json:"auto_approve"`
+}
+
+// Adjustment is a credit (positive) or charge (negative) on a member's balance.
+type Adjustment struct {
+	ID         string       `json:"id"`
+	UserID     string       `json:"user_id"`
+	Name       string       `json:"name"`
+	Amount     money.Amount `json:"amount"`
+	Reason     string       `json:"reason"`
+	ForMonth   string       `json:"for_month"`
+	AllMembers bool         `json:"all_members"`
+	LateFee    bool         `json:"late_fee"`
+	Waived     bool         `json:"waived"`
+}
+
+// CoverageMonth is one cell of the paid/unpaid grid.
+type CoverageMonth struct {
+	Month   string       `json:"month"`
+	Label   string       `json:"label"`
+	Due     money.Amount `json:"due"`
+	Covered money.Amount `json:"covered"`
+	Status  string       `json:"status"`
+}
+
+// MemberCoverage is one member's row in the paid/unpaid grid.
+type MemberCoverage struct {
+	MemberID string          `json:"member_id"`
+	Name     string          `json:"name"`
+	Months   []CoverageMonth `json:"months"`
+}
+
+// Household groups family plans whose balances are settled together.
+type Household struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Owner     string       `json:"owner"`
+	Plans     []FamilyPlan `json:"plans"`
+	PlanCount int          `json:"plan_count"`
+}
+
+// HouseholdPosition is a person's net amount across every plan in a household.
+type HouseholdPosition struct {
+	PersonID string       `json:"person_id"`
+	Name     string       `json:"name"`
+	Net      money.Amount `json:"net"`
+}
+
+// SettleUpTransfer is a suggested payment that settles household balances.
+type SettleUpTransfer struct {
+	FromName string       `json:"from_name"`
+	ToName   string       `json:"to_name"`
+	Amount   money.Amount `json:"amount"`
+}
+
+// ExchangeRate is how many units of Quote one unit of Base buys.
+type ExchangeRate struct {
+	Base   string  `json:"base"`
+	Quote  string  `json:"quote"`
+	Rate   float64 `json:"rate"`
+	Source string  `json:"source"`
+	Shared bool    `json:"shared"`
 }
 
 // MemberPaymentsPagination describes the owner payments table pagination state.
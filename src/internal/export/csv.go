@@ -0,0 +1,254 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+const monthLayout = "2006-01"
+
+// textCell quotes member-entered text that a spreadsheet would otherwise run as a formula.
+func textCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@", rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
+
+// WritePaymentsCSV writes one row per plan payment, oldest first, keeping those whose date and
+// status pass the filter.
+func WritePaymentsCSV(w io.Writer, dao *daos.Dao, plan *pbmodels.Record, filter Filter) error {
+	terms := []planutil.FilterTerm{{Field: "plan_id", Value: plan.Id}}
+	if filter.Status != "" {
+		terms = append(terms, planutil.FilterTerm{Field: "status", Value: filter.Status})
+	}
+
+	query, err := planutil.BuildEqualsFilter(terms...)
+	if err != nil {
+		return err
+	}
+
+	paymentRecords, err := dao.FindRecordsByFilter("payments", query.Expression, "date,created", -1, 0, query.Params)
+	if err != nil {
+		return err
+	}
+
+	userIDs := make([]string, 0, len(paymentRecords)*2)
+	for _, paymentRecord := range paymentRecords {
+		userIDs = append(userIDs, paymentRecord.GetString("user_id"), billing.PayerID(paymentRecord))
+	}
+
+	identities, err := planutil.FindIdentitiesWithDao(dao, plan.Id, userIDs)
+	if err != nil {
+		return err
+	}
+
+	currency := planutil.Currency(plan)
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"id", "date", "for_month", "member", "payer", "amount", "currency", "status", "method", "reference", "notes"}); err != nil {
+		return err
+	}
+
+	for _, paymentRecord := range paymentRecords {
+		date := paymentRecord.GetDateTime("date").Time()
+		if !filter.Includes(date) {
+			continue
+		}
+
+		forMonth := ""
+		if value := paymentRecord.GetDateTime("for_month"); !value.IsZero() {
+			forMonth = value.Time().Format(monthLayout)
+		}
+
+		err := writer.Write([]string{
+			paymentRecord.Id,
+			date.Format(dateLayout),
+			forMonth,
+			textCell(identities[paymentRecord.GetString("user_id")].DisplayName()),
+			textCell(identities[billing.PayerID(paymentRecord)].DisplayName()),
+			billing.PaymentAmount(paymentRecord, currency).Decimal(),
+			currency,
+			paymentRecord.GetString("status"),
+			textCell(paymentRecord.GetString("method")),
+			textCell(paymentRecord.GetString("reference")),
+			textCell(paymentRecord.GetString("notes")),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteMembersCSV writes one row per member, current and former, with their balance as of now.
+// The filter's dates narrow members to those who joined within them.
+func WriteMembersCSV(w io.Writer, dao *daos.Dao, plan *pbmodels.Record, filter Filter, now time.Time) error {
+	memberships, identities, err := planMembershipsWithDao(dao, plan)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"member_id", "name", "username", "artificial", "status", "joined", "ended", "balance", "currency"}); err != nil {
+		return err
+	}
+
+	for _, membership := range memberships {
+		status := memberStatus(membership, now)
+		joined := membership.GetDateTime("created").Time()
+		if !filter.MatchesStatus(status) || !filter.Includes(joined) {
+			continue
+		}
+
+		userID := membership.GetString("user_id")
+		balance, err := billing.CalculateMemberBalanceWithDao(dao, plan.Id, userID, now)
+		if err != nil {
+			return err
+		}
+
+		ended := ""
+		if value := membership.GetDateTime("date_ended"); !value.IsZero() {
+			ended = value.Time().Format(dateLayout)
+		}
+
+		identity := identities[userID]
+		err = writer.Write([]string{
+			userID,
+			textCell(identity.DisplayName()),
+			textCell(identity.Username),
+			strconv.FormatBool(membership.GetBool("is_artificial")),
+			status,
+			joined.Format(dateLayout),
+			ended,
+			balance.Decimal(),
+			balance.CurrencyCode(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteChargesCSV writes a matrix of what each member was charged per month, with a total column.
+// Months run from the filter's first to last day, defaulting to the first membership and the month of now.
+func WriteChargesCSV(w io.Writer, dao *daos.Dao, plan *pbmodels.Record, filter Filter, now time.Time) error {
+	memberships, identities, err := planMembershipsWithDao(dao, plan)
+	if err != nil {
+		return err
+	}
+
+	first, last := filter.From, filter.To
+	if first.IsZero() {
+		first = now
+		for _, membership := range memberships {
+			if joined := membership.GetDateTime("created").Time(); joined.Before(first) {
+				first = joined
+			}
+		}
+	}
+	if last.IsZero() {
+		last = now
+	}
+	first = time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC)
+	last = time.Date(last.Year(), last.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	header := []string{"member_id", "name", "status"}
+	for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+		header = append(header, month.Format(monthLayout))
+	}
+	header = append(header, "total")
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	currency := planutil.Currency(plan)
+	for _, membership := range memberships {
+		status := memberStatus(membership, now)
+		if !filter.MatchesStatus(status) {
+			continue
+		}
+
+		userID := membership.GetString("user_id")
+		coverage, err := billing.CoverageWithDao(dao, plan.Id, userID, first, last, now)
+		if err != nil {
+			return err
+		}
+
+		row := []string{userID, textCell(identities[userID].DisplayName()), status}
+		var totalCents int64
+		for _, month := range coverage {
+			row = append(row, money.New(month.DueCents, currency).Decimal())
+			totalCents += month.DueCents
+		}
+		row = append(row, money.New(totalCents, currency).Decimal())
+
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// planMembershipsWithDao loads every membership of the plan other than the owner's, oldest first,
+// with the identities of their members.
+func planMembershipsWithDao(dao *daos.Dao, plan *pbmodels.Record) ([]*pbmodels.Record, map[string]planutil.Identity, error) {
+	query, err := planutil.BuildEqualsFilter(planutil.FilterTerm{Field: "plan_id", Value: plan.Id})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	records, err := dao.FindRecordsByFilter("memberships", query.Expression, "created", -1, 0, query.Params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ownerID := planutil.OwnerID(plan)
+	memberships := make([]*pbmodels.Record, 0, len(records))
+	userIDs := make([]string, 0, len(records))
+	for _, record := range records {
+		if record.GetString("user_id") == ownerID {
+			continue
+		}
+
+		memberships = append(memberships, record)
+		userIDs = append(userIDs, record.GetString("user_id"))
+	}
+
+	identities, err := planutil.FindIdentitiesWithDao(dao, plan.Id, userIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return memberships, identities, nil
+}
+
+func memberStatus(membership *pbmodels.Record, now time.Time) string {
+	if !membership.GetDateTime("date_ended").IsZero() {
+		return MemberStatusFormer
+	}
+	if pause, ok := billing.CurrentPause(membership, now); ok && pause.Contains(now) {
+		return MemberStatusPaused
+	}
+
+	return MemberStatusActive
+}
@@ -0,0 +1,179 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/testapp"
+)
+
+func TestParseFilter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		status  string
+		wantErr error
+	}{
+		{name: "no filter"},
+		{name: "range and status", from: "2026-01-01", to: "2026-03-31", status: "approved"},
+		{name: "malformed day", from: "01/02/2026", wantErr: ErrInvalidFilter},
+		{name: "range ends before it starts", from: "2026-03-01", to: "2026-02-01", wantErr: ErrInvalidFilter},
+		{name: "unknown status", status: "paid", wantErr: ErrInvalidFilter},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseFilter(test.from, test.to, test.status, PaymentStatuses); !errors.Is(err, test.wantErr) {
+				t.Fatalf("ParseFilter(%q, %q, %q) error = %v, want %v", test.from, test.to, test.status, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestWritePaymentsCSVFiltersAndNamesMembers(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveNamedUser(t, app, "owner", "")
+	member := testapp.SaveNamedUser(t, app, "member", "Mia Member")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+	testapp.SaveMembership(t, app, plan.Id, member.Id, time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC))
+	grandma := testapp.SaveMembership(t, app, plan.Id, "artificial-grandma", time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC))
+	grandma.Set("is_artificial", true)
+	grandma.Set("name", "Grandma")
+	if err := app.Dao().SaveRecord(grandma); err != nil {
+		t.Fatalf("failed to save artificial member: %v", err)
+	}
+
+	testapp.SavePayment(t, app, plan.Id, member.Id, 1000, time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC), "approved")
+	payment := testapp.SavePayment(t, app, plan.Id, grandma.GetString("user_id"), 1000, time.Date(2026, time.February, 5, 0, 0, 0, 0, time.UTC), "approved")
+	payment.Set("payer_id", member.Id)
+	if err := app.Dao().SaveRecord(payment); err != nil {
+		t.Fatalf("failed to record payer: %v", err)
+	}
+	testapp.SavePayment(t, app, plan.Id, member.Id, 500, time.Date(2026, time.February, 28, 12, 0, 0, 0, time.UTC), "pending")
+	testapp.SavePayment(t, app, plan.Id, member.Id, 700, time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), "approved")
+
+	filter, err := ParseFilter("2026-02-01", "2026-02-28", "approved", PaymentStatuses)
+	if err != nil {
+		t.Fatalf("ParseFilter returned error: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := WritePaymentsCSV(&out, app.Dao(), plan, filter); err != nil {
+		t.Fatalf("WritePaymentsCSV returned error: %v", err)
+	}
+
+	rows := readCSV(t, &out)
+	if len(rows) != 2 {
+		t.Fatalf("rows = %v, want a header and one payment", rows)
+	}
+	if got, want := rows[1][1:8], []string{"2026-02-05", "", "Grandma", "Mia Member", "10.00", "USD", "approved"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("payment row = %v, want %v", got, want)
+	}
+}
+
+func TestCSVExportsQuoteFormulas(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveNamedUser(t, app, "owner", "")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+	placeholder := testapp.SaveArtificialMembership(t, app, plan.Id, "artificial-member", "=HYPERLINK(\"http://evil\")", time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	payment := testapp.SavePayment(t, app, plan.Id, placeholder.GetString("user_id"), 1000, time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC), "approved")
+	payment.Set("reference", "+1 555 0100")
+	payment.Set("notes", "@SUM(A1:A9)")
+	if err := app.Dao().SaveRecord(payment); err != nil {
+		t.Fatalf("failed to save payment details: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := WritePaymentsCSV(&out, app.Dao(), plan, Filter{}); err != nil {
+		t.Fatalf("WritePaymentsCSV returned error: %v", err)
+	}
+
+	rows := readCSV(t, &out)
+	if len(rows) != 2 {
+		t.Fatalf("rows = %v, want a header and one payment", rows)
+	}
+	if got, want := rows[1][3], "'=HYPERLINK(\"http://evil\")"; got != want {
+		t.Fatalf("member = %q, want %q", got, want)
+	}
+	if got, want := rows[1][9:11], []string{"'+1 555 0100", "'@SUM(A1:A9)"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("reference and notes = %v, want %v", got, want)
+	}
+	if got := rows[1][5]; got != "10.00" {
+		t.Fatalf("amount = %q, want it left as a number", got)
+	}
+
+	out.Reset()
+	if err := WriteMembersCSV(&out, app.Dao(), plan, Filter{}, time.Date(2026, time.February, 15, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("WriteMembersCSV returned error: %v", err)
+	}
+	quoted := false
+	for _, row := range readCSV(t, &out)[1:] {
+		if row[0] == placeholder.GetString("user_id") {
+			quoted = row[1] == "'=HYPERLINK(\"http://evil\")"
+		}
+	}
+	if !quoted {
+		t.Fatalf("members export = %q, want the placeholder's name quoted", out.String())
+	}
+}
+
+func TestWriteMembersCSVIncludesBalances(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveNamedUser(t, app, "owner", "")
+	member := testapp.SaveNamedUser(t, app, "member", "")
+	former := testapp.SaveNamedUser(t, app, "former", "")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+	joined := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	testapp.SaveMembership(t, app, plan.Id, member.Id, joined)
+	ended := testapp.SaveMembership(t, app, plan.Id, former.Id, joined)
+	ended.Set("date_ended", time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC))
+	if err := app.Dao().SaveRecord(ended); err != nil {
+		t.Fatalf("failed to end membership: %v", err)
+	}
+
+	testapp.SavePayment(t, app, plan.Id, member.Id, 3000, time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC), "approved")
+
+	var out bytes.Buffer
+	now := time.Date(2026, time.February, 15, 0, 0, 0, 0, time.UTC)
+	if err := WriteMembersCSV(&out, app.Dao(), plan, Filter{Status: MemberStatusActive}, now); err != nil {
+		t.Fatalf("WriteMembersCSV returned error: %v", err)
+	}
+
+	rows := readCSV(t, &out)
+	if len(rows) != 2 {
+		t.Fatalf("rows = %v, want a header and the active member", rows)
+	}
+	if got, want := rows[1][2:6], []string{"member", "false", MemberStatusActive, "2026-01-01"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("member row = %v, want %v", got, want)
+	}
+	balance, err := billing.CalculateMemberBalanceWithDao(app.Dao(), plan.Id, member.Id, now)
+	if err != nil {
+		t.Fatalf("CalculateMemberBalanceWithDao returned error: %v", err)
+	}
+	if got := rows[1][7]; got != balance.Decimal() {
+		t.Fatalf("balance = %q, want %q", got, balance.Decimal())
+	}
+}
+
+func readCSV(t *testing.T, out *bytes.Buffer) [][]string {
+	t.Helper()
+
+	rows, err := csv.NewReader(out).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV: %v", err)
+	}
+
+	return rows
+}
@@ -0,0 +1,86 @@
+package export
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Member statuses an export can be narrowed to.
+const (
+	MemberStatusActive = "active"
+	MemberStatusPaused = "paused"
+	MemberStatusFormer = "former"
+)
+
+var (
+	// PaymentStatuses lists the statuses a payments export can be narrowed to.
+	PaymentStatuses = []string{"pending", "approved", "rejected"}
+	// MemberStatuses lists the statuses a members or charges export can be narrowed to.
+	MemberStatuses = []string{MemberStatusActive, MemberStatusPaused, MemberStatusFormer}
+)
+
+// ErrInvalidFilter indicates an export filter with a malformed date, an empty range or an unknown status.
+var ErrInvalidFilter = errors.New("invalid export filter")
+
+// Filter narrows an export to a date range and a status. Zero dates and an empty status match everything.
+type Filter struct {
+	From   time.Time
+	To     time.Time
+	Status string
+}
+
+// ParseFilter reads from and to as YYYY-MM-DD days, both inclusive, and checks status against the allowed ones.
+func ParseFilter(from, to, status string, statuses []string) (Filter, error) {
+	filter := Filter{Status: strings.TrimSpace(status)}
+
+	var err error
+	if filter.From, err = parseDay(from); err != nil {
+		return Filter{}, ErrInvalidFilter
+	}
+	if filter.To, err = parseDay(to); err != nil {
+		return Filter{}, ErrInvalidFilter
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return Filter{}, ErrInvalidFilter
+	}
+
+	if filter.Status == "" {
+		return filter, nil
+	}
+	for _, allowed := range statuses {
+		if filter.Status == allowed {
+			return filter, nil
+		}
+	}
+
+	return Filter{}, ErrInvalidFilter
+}
+
+// Includes reports whether a moment falls inside the filter's days.
+func (f Filter) Includes(at time.Time) bool {
+	if !f.From.IsZero() && at.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !at.Before(f.To.AddDate(0, 0, 1)) {
+		return false
+	}
+
+	return true
+}
+
+// MatchesStatus reports whether a status passes the filter.
+func (f Filter) MatchesStatus(status string) bool {
+	return f.Status == "" || f.Status == status
+}
+
+func parseDay(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(dateLayout, value)
+}
@@ -0,0 +1,201 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/planutil"
+
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// Journal formats a member can download. Ledger files also load in hledger.
+const (
+	JournalFormatLedger    = "ledger"
+	JournalFormatBeancount = "beancount"
+)
+
+// PlanAccountPlaceholder is replaced with the plan's name in account names.
+const PlanAccountPlaceholder = "{plan}"
+
+// DefaultJournalAccounts are the account names used when a member does not choose their own.
+var DefaultJournalAccounts = JournalAccounts{
+	Liability: "Liabilities:FamilyPlan:" + PlanAccountPlaceholder,
+	Expense:   "Expenses:Subscriptions:" + PlanAccountPlaceholder,
+	Funding:   "Assets:Checking",
+}
+
+var (
+	// ErrInvalidAccount indicates an account name that ledger or beancount would not accept.
+	ErrInvalidAccount = errors.New("invalid account name")
+	// ErrUnknownJournalFormat indicates a journal format other than ledger or beancount.
+	ErrUnknownJournalFormat = errors.New("unknown journal format")
+)
+
+// Account names must satisfy beancount, whose rules are stricter than ledger's.
+var accountPattern = regexp.MustCompile(`^(Assets|Liabilities|Equity|Income|Expenses)(:[A-Z0-9][A-Za-z0-9-]*)+$`)
+
+// JournalAccounts names the accounts a member's plan history is booked against. The liability
+// account carries the member's balance with the plan; charges and adjustments are booked against
+// the expense account and payments come out of the funding account.
+type JournalAccounts struct {
+	Liability string
+	Expense   string
+	Funding   string
+}
+
+// WithDefaults fills in any empty account from DefaultJournalAccounts.
+func (a JournalAccounts) WithDefaults() JournalAccounts {
+	if strings.TrimSpace(a.Liability) == "" {
+		a.Liability = DefaultJournalAccounts.Liability
+	}
+	if strings.TrimSpace(a.Expense) == "" {
+		a.Expense = DefaultJournalAccounts.Expense
+	}
+	if strings.TrimSpace(a.Funding) == "" {
+		a.Funding = DefaultJournalAccounts.Funding
+	}
+
+	return a
+}
+
+// ForPlan substitutes the plan's name into the accounts and checks that every one is valid.
+func (a JournalAccounts) ForPlan(planName string) (JournalAccounts, error) {
+	segment := accountSegment(planName)
+	resolved := JournalAccounts{
+		Liability: strings.ReplaceAll(strings.TrimSpace(a.Liability), PlanAccountPlaceholder, segment),
+		Expense:   strings.ReplaceAll(strings.TrimSpace(a.Expense), PlanAccountPlaceholder, segment),
+		Funding:   strings.ReplaceAll(strings.TrimSpace(a.Funding), PlanAccountPlaceholder, segment),
+	}
+
+	for _, account := range []string{resolved.Liability, resolved.Expense, resolved.Funding} {
+		if !accountPattern.MatchString(account) {
+			return JournalAccounts{}, ErrInvalidAccount
+		}
+	}
+
+	return resolved, nil
+}
+
+// WriteJournal writes a member's statement for the plan in the given format.
+func WriteJournal(w io.Writer, format string, plan *pbmodels.Record, entries []billing.StatementEntry, accounts JournalAccounts) error {
+	resolved, err := accounts.WithDefaults().ForPlan(plan.GetString("name"))
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case JournalFormatLedger:
+		return writeLedger(w, plan, entries, resolved)
+	case JournalFormatBeancount:
+		return writeBeancount(w, plan, entries, resolved)
+	default:
+		return ErrUnknownJournalFormat
+	}
+}
+
+func writeLedger(w io.Writer, plan *pbmodels.Record, entries []billing.StatementEntry, accounts JournalAccounts) error {
+	currency := planutil.Currency(plan)
+	if _, err := fmt.Fprintf(w, "; %s (%s)\n", journalText(plan.GetString("name")), currency); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		counter := counterAccount(entry, accounts)
+		amount := entry.Amount.Decimal()
+		_, err := fmt.Fprintf(w, "\n%s * %s\n    ; id: %s\n    %s  %s %s\n    %s\n",
+			entry.Date.Format(dateLayout),
+			journalText(entry.Description),
+			entry.ID,
+			accounts.Liability, amount, currency,
+			counter,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeBeancount(w io.Writer, plan *pbmodels.Record, entries []billing.StatementEntry, accounts JournalAccounts) error {
+	currency := planutil.Currency(plan)
+	if _, err := fmt.Fprintf(w, "; %s (%s)\n", journalText(plan.GetString("name")), currency); err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	opened := entries[0].Date.Format(dateLayout)
+	for _, account := range []string{accounts.Liability, accounts.Expense, accounts.Funding} {
+		if _, err := fmt.Fprintf(w, "%s open %s %s\n", opened, account, currency); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		_, err := fmt.Fprintf(w, "\n%s * %s\n  id: %s\n  %s  %s %s\n  %s\n",
+			entry.Date.Format(dateLayout),
+			beancountString(entry.Description),
+			beancountString(entry.ID),
+			accounts.Liability, entry.Amount.Decimal(), currency,
+			counterAccount(entry, accounts),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// counterAccount is where the other side of an entry is booked: payments leave the funding
+// account, everything else is an expense.
+func counterAccount(entry billing.StatementEntry, accounts JournalAccounts) string {
+	if entry.Kind == billing.StatementPayment {
+		return accounts.Funding
+	}
+
+	return accounts.Expense
+}
+
+// accountSegment turns a plan name into a single account segment such as "NetflixPremium".
+func accountSegment(name string) string {
+	var segment strings.Builder
+	upperNext := true
+	for _, r := range name {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			upperNext = true
+			continue
+		}
+		if upperNext {
+			r = unicode.ToUpper(r)
+			upperNext = false
+		}
+		segment.WriteRune(r)
+	}
+
+	if segment.Len() == 0 {
+		return "Plan"
+	}
+
+	return segment.String()
+}
+
+// journalText keeps a payee or comment on one line and out of ledger's comment syntax.
+func journalText(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	return strings.ReplaceAll(value, ";", ",")
+}
+
+func beancountString(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
@@ -0,0 +1,102 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/money"
+
+	pbmodels "github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func TestJournalAccountsForPlan(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		accounts      JournalAccounts
+		wantLiability string
+		wantErr       error
+	}{
+		{name: "defaults", wantLiability: "Liabilities:FamilyPlan:NetflixPremium4k"},
+		{name: "custom", accounts: JournalAccounts{Liability: "Liabilities:Family:{plan}:Share"}, wantLiability: "Liabilities:Family:NetflixPremium4k:Share"},
+		{name: "unknown root", accounts: JournalAccounts{Liability: "Debts:Netflix"}, wantErr: ErrInvalidAccount},
+		{name: "lowercase segment", accounts: JournalAccounts{Funding: "Assets:checking"}, wantErr: ErrInvalidAccount},
+		{name: "space in name", accounts: JournalAccounts{Expense: "Expenses:TV Shows"}, wantErr: ErrInvalidAccount},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.accounts.WithDefaults().ForPlan("netflix premium (4k)")
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("ForPlan error = %v, want %v", err, test.wantErr)
+			}
+			if got.Liability != test.wantLiability {
+				t.Fatalf("ForPlan liability = %q, want %q", got.Liability, test.wantLiability)
+			}
+		})
+	}
+}
+
+func TestWriteJournal(t *testing.T) {
+	t.Parallel()
+
+	collection := &pbmodels.Collection{
+		Schema: schema.NewSchema(
+			&schema.SchemaField{Name: "name", Type: schema.FieldTypeText},
+			&schema.SchemaField{Name: "currency", Type: schema.FieldTypeText},
+		),
+	}
+	plan := pbmodels.NewRecord(collection)
+	plan.Set("name", "Netflix")
+	plan.Set("currency", "USD")
+
+	entries := []billing.StatementEntry{
+		{ID: "m1-2026-01", Kind: billing.StatementCharge, Date: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC), Description: "Netflix share for January 2026", Amount: money.New(-667, "USD")},
+		{ID: "pay1", Kind: billing.StatementPayment, Date: time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC), Description: `Netflix payment (ref "A"; 1)`, Amount: money.New(700, "USD")},
+	}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{
+			format: JournalFormatLedger,
+			want: []string{
+				"2026-01-01 * Netflix share for January 2026\n    ; id: m1-2026-01\n    Liabilities:FamilyPlan:Netflix  -6.67 USD\n    Expenses:Subscriptions:Netflix\n",
+				"2026-01-05 * Netflix payment (ref \"A\", 1)\n    ; id: pay1\n    Liabilities:FamilyPlan:Netflix  7.00 USD\n    Assets:Checking\n",
+			},
+		},
+		{
+			format: JournalFormatBeancount,
+			want: []string{
+				"2026-01-01 open Liabilities:FamilyPlan:Netflix USD\n",
+				"2026-01-05 * \"Netflix payment (ref \\\"A\\\"; 1)\"\n  id: \"pay1\"\n  Liabilities:FamilyPlan:Netflix  7.00 USD\n  Assets:Checking\n",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := WriteJournal(&out, test.format, plan, entries, JournalAccounts{}); err != nil {
+				t.Fatalf("WriteJournal returned error: %v", err)
+			}
+
+			for _, want := range test.want {
+				if !strings.Contains(out.String(), want) {
+					t.Fatalf("journal is missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+
+	if err := WriteJournal(&bytes.Buffer{}, "qif", plan, entries, JournalAccounts{}); !errors.Is(err, ErrUnknownJournalFormat) {
+		t.Fatalf("WriteJournal(qif) error = %v, want ErrUnknownJournalFormat", err)
+	}
+}
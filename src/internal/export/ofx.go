@@ -0,0 +1,111 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/planutil"
+)
+
+const (
+	ofxDateLayout = "20060102"
+	ofxTimeLayout = "20060102150405"
+	// ofxNameLength is the longest payee name OFX allows.
+	ofxNameLength = 32
+)
+
+// ofxHeader starts an OFX 1.02 (SGML) file, the version most personal finance apps import.
+const ofxHeader = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:UTF-8
+CHARSET:NONE
+COMPRESSION:NONE
+OLDFILEUID:NONE
+NEWFILEUID:NONE
+
+`
+
+// writeOFX writes one bank statement per plan. Each plan is an account named by its join code
+// and every transaction's FITID is its statement entry ID, so importing again matches the
+// transactions already there instead of duplicating them.
+func writeOFX(w io.Writer, statements []PlanStatement, now time.Time) error {
+	var b strings.Builder
+	b.WriteString(ofxHeader)
+	b.WriteString("<OFX>\n")
+	b.WriteString("<SIGNONMSGSRSV1><SONRS>\n")
+	b.WriteString("<STATUS><CODE>0<SEVERITY>INFO</STATUS>\n")
+	fmt.Fprintf(&b, "<DTSERVER>%s\n", now.UTC().Format(ofxTimeLayout))
+	b.WriteString("<LANGUAGE>ENG\n")
+	b.WriteString("</SONRS></SIGNONMSGSRSV1>\n")
+	b.WriteString("<BANKMSGSRSV1>\n")
+
+	for _, statement := range statements {
+		currency := planutil.Currency(statement.Plan)
+		start, end := statementPeriod(statement.Entries, now)
+
+		b.WriteString("<STMTTRNRS>\n")
+		fmt.Fprintf(&b, "<TRNUID>%s\n", ofxText(statement.Plan.Id))
+		b.WriteString("<STATUS><CODE>0<SEVERITY>INFO</STATUS>\n")
+		b.WriteString("<STMTRS>\n")
+		fmt.Fprintf(&b, "<CURDEF>%s\n", currency)
+		fmt.Fprintf(&b, "<BANKACCTFROM><BANKID>FAMILYPLAN<ACCTID>%s<ACCTTYPE>CHECKING</BANKACCTFROM>\n", ofxText(statement.Plan.GetString("join_code")))
+		fmt.Fprintf(&b, "<BANKTRANLIST><DTSTART>%s<DTEND>%s\n", start.Format(ofxDateLayout), end.Format(ofxDateLayout))
+
+		for _, entry := range statement.Entries {
+			transactionType := "CREDIT"
+			if entry.Amount.IsNegative() {
+				transactionType = "DEBIT"
+			}
+
+			b.WriteString("<STMTTRN>\n")
+			fmt.Fprintf(&b, "<TRNTYPE>%s\n", transactionType)
+			fmt.Fprintf(&b, "<DTPOSTED>%s\n", entry.Date.Format(ofxDateLayout))
+			fmt.Fprintf(&b, "<TRNAMT>%s\n", entry.Amount.Decimal())
+			fmt.Fprintf(&b, "<FITID>%s\n", ofxText(entry.ID))
+			fmt.Fprintf(&b, "<NAME>%s\n", ofxText(truncateRunes(entry.Description, ofxNameLength)))
+			fmt.Fprintf(&b, "<MEMO>%s\n", ofxText(entry.Description))
+			b.WriteString("</STMTTRN>\n")
+		}
+
+		b.WriteString("</BANKTRANLIST>\n")
+		fmt.Fprintf(&b, "<LEDGERBAL><BALAMT>%s<DTASOF>%s</LEDGERBAL>\n",
+			billing.StatementTotal(statement.Entries, currency).Decimal(), now.UTC().Format(ofxTimeLayout))
+		b.WriteString("</STMTRS>\n")
+		b.WriteString("</STMTTRNRS>\n")
+	}
+
+	b.WriteString("</BANKMSGSRSV1>\n")
+	b.WriteString("</OFX>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// statementPeriod returns the days of the first and last entries, or now for an empty statement.
+func statementPeriod(entries []billing.StatementEntry, now time.Time) (time.Time, time.Time) {
+	if len(entries) == 0 {
+		return now, now
+	}
+
+	return entries[0].Date, entries[len(entries)-1].Date
+}
+
+// ofxText keeps a value on one line and escapes the characters SGML reserves.
+func ofxText(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(value)
+}
+
+func truncateRunes(value string, limit int) string {
+	runes := []rune(value)
+	if len(runes) <= limit {
+		return value
+	}
+
+	return string(runes[:limit])
+}
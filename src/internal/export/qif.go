@@ -0,0 +1,39 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const qifDateLayout = "01/02/2006"
+
+// writeQIF writes one bank account per plan. QIF has no transaction ID field, so each entry's
+// stable ID goes in the number field, which importers use when matching duplicates.
+func writeQIF(w io.Writer, statements []PlanStatement) error {
+	var b strings.Builder
+	for _, statement := range statements {
+		b.WriteString("!Account\n")
+		fmt.Fprintf(&b, "N%s (%s)\n", qifText(statement.Plan.GetString("name")), qifText(statement.Plan.GetString("join_code")))
+		b.WriteString("TBank\n")
+		b.WriteString("^\n")
+		b.WriteString("!Type:Bank\n")
+
+		for _, entry := range statement.Entries {
+			fmt.Fprintf(&b, "D%s\n", entry.Date.Format(qifDateLayout))
+			fmt.Fprintf(&b, "T%s\n", entry.Amount.Decimal())
+			fmt.Fprintf(&b, "N%s\n", qifText(entry.ID))
+			fmt.Fprintf(&b, "P%s\n", qifText(entry.Description))
+			b.WriteString("C*\n")
+			b.WriteString("^\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// qifText keeps a value on one line, since every QIF field is a single line.
+func qifText(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
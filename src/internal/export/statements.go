@@ -0,0 +1,122 @@
+package export
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// Statement formats a member's personal finance app can import.
+const (
+	StatementFormatOFX = "ofx"
+	StatementFormatQIF = "qif"
+)
+
+// ErrUnknownStatementFormat indicates a statement format other than OFX or QIF.
+var ErrUnknownStatementFormat = errors.New("unknown statement format")
+
+// ErrInvalidAsOf indicates an as-of date that is not a day, or is a day after today.
+var ErrInvalidAsOf = errors.New("as-of date must be a day no later than today")
+
+// PlanStatement is a member's statement for one plan. Each plan becomes its own account in
+// OFX and QIF files.
+type PlanStatement struct {
+	Plan    *pbmodels.Record
+	Entries []billing.StatementEntry
+}
+
+// FindPlanStatementWithDao builds a member's statement for one plan as it stood at asOf.
+func FindPlanStatementWithDao(dao *daos.Dao, plan *pbmodels.Record, userID string, asOf time.Time) (PlanStatement, error) {
+	entries, err := billing.MemberStatementWithDao(dao, plan.Id, userID, asOf)
+	if err != nil {
+		return PlanStatement{}, err
+	}
+
+	return PlanStatement{Plan: plan, Entries: entries}, nil
+}
+
+// FindMemberStatementsWithDao builds a member's statement as of asOf for every plan they belong to,
+// current or former.
+func FindMemberStatementsWithDao(dao *daos.Dao, userID string, asOf time.Time) ([]PlanStatement, error) {
+	filter, err := planutil.BuildEqualsFilter(planutil.FilterTerm{Field: "user_id", Value: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	memberships, err := dao.FindRecordsByFilter("memberships", filter.Expression, "created", -1, 0, filter.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	statements := []PlanStatement{}
+	seen := map[string]bool{}
+	for _, membership := range memberships {
+		planID := membership.GetString("plan_id")
+		if seen[planID] {
+			continue
+		}
+		seen[planID] = true
+
+		plan, err := dao.FindRecordById("family_plans", planID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		statement, err := FindPlanStatementWithDao(dao, plan, userID, asOf)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+	}
+
+	return statements, nil
+}
+
+// WriteStatements writes statements as one OFX or QIF file. The time of now stamps OFX files.
+func WriteStatements(w io.Writer, format string, statements []PlanStatement, now time.Time) error {
+	switch format {
+	case StatementFormatOFX:
+		return writeOFX(w, statements, now)
+	case StatementFormatQIF:
+		return writeQIF(w, statements)
+	default:
+		return ErrUnknownStatementFormat
+	}
+}
+
+// ParseAsOf reads the day a statement should be built as of, in YYYY-MM-DD form, and returns the
+// end of that day. An empty value means now.
+func ParseAsOf(value string, now time.Time) (time.Time, error) {
+	day, err := parseDay(value)
+	if err != nil {
+		return time.Time{}, ErrInvalidAsOf
+	}
+	if day.IsZero() {
+		return now, nil
+	}
+	if day.After(now) {
+		return time.Time{}, ErrInvalidAsOf
+	}
+
+	endOfDay := day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	if endOfDay.After(now) {
+		return now, nil
+	}
+
+	return endOfDay, nil
+}
+
+// ValidStatementFormat reports whether a statement can be written in the format.
+func ValidStatementFormat(format string) bool {
+	return format == StatementFormatOFX || format == StatementFormatQIF
+}
@@ -0,0 +1,106 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/testapp"
+)
+
+func TestWriteStatementsUsesStableIDs(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveNamedUser(t, app, "owner", "")
+	member := testapp.SaveNamedUser(t, app, "member", "")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 2000)
+	membership := testapp.SaveMembership(t, app, plan.Id, member.Id, time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC))
+	payment := testapp.SavePayment(t, app, plan.Id, member.Id, 1000, time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC), "approved")
+	testapp.SavePayment(t, app, plan.Id, member.Id, 500, time.Date(2026, time.January, 6, 0, 0, 0, 0, time.UTC), "rejected")
+
+	now := time.Date(2026, time.February, 15, 9, 30, 0, 0, time.UTC)
+	statements, err := FindMemberStatementsWithDao(app.Dao(), member.Id, now)
+	if err != nil {
+		t.Fatalf("FindMemberStatementsWithDao returned error: %v", err)
+	}
+	if len(statements) != 1 {
+		t.Fatalf("statements = %d, want 1", len(statements))
+	}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{
+			format: StatementFormatOFX,
+			want: []string{
+				"<ACCTID>ABC123<ACCTTYPE>CHECKING",
+				"<TRNTYPE>CREDIT\n<DTPOSTED>20260105\n<TRNAMT>10.00\n<FITID>" + payment.Id + "\n",
+				"<TRNTYPE>DEBIT\n<DTPOSTED>20260101\n<TRNAMT>-10.00\n<FITID>" + membership.Id + "-2026-01\n",
+			},
+		},
+		{
+			format: StatementFormatQIF,
+			want: []string{
+				"!Account\nNStreaming (ABC123)\nTBank\n^\n!Type:Bank\n",
+				"D01/05/2026\nT10.00\nN" + payment.Id + "\n",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var first, second bytes.Buffer
+			if err := WriteStatements(&first, test.format, statements, now); err != nil {
+				t.Fatalf("WriteStatements returned error: %v", err)
+			}
+			if err := WriteStatements(&second, test.format, statements, now); err != nil {
+				t.Fatalf("WriteStatements returned error: %v", err)
+			}
+			if first.String() != second.String() {
+				t.Fatalf("exporting twice produced different files")
+			}
+
+			for _, want := range test.want {
+				if !strings.Contains(first.String(), want) {
+					t.Fatalf("%s statement is missing %q:\n%s", test.format, want, first.String())
+				}
+			}
+			if strings.Contains(first.String(), "<TRNAMT>5.00\n") || strings.Contains(first.String(), "\nT5.00\n") {
+				t.Fatalf("%s statement includes the rejected payment:\n%s", test.format, first.String())
+			}
+		})
+	}
+}
+
+func TestParseAsOf(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, time.April, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr error
+	}{
+		{name: "empty is now", want: now},
+		{name: "past day ends at midnight", value: "2026-03-31", want: time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)},
+		{name: "today is now", value: "2026-04-15", want: now},
+		{name: "future day", value: "2026-04-16", wantErr: ErrInvalidAsOf},
+		{name: "malformed day", value: "31/03/2026", wantErr: ErrInvalidAsOf},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseAsOf(test.value, now)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("ParseAsOf(%q) error = %v, want %v", test.value, err, test.wantErr)
+			}
+			if !got.Equal(test.want) {
+				t.Fatalf("ParseAsOf(%q) = %v, want %v", test.value, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package fxrates
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// CollectionName is the PocketBase collection that stores exchange rates.
+const CollectionName = "exchange_rates"
+
+const (
+	// SourceManual marks a rate entered by a plan owner.
+	SourceManual = "manual"
+	// SourceFile marks a rate loaded from the local rates file.
+	SourceFile = "file"
+)
+
+var (
+	// ErrRateNotFound indicates that no rate is known for a currency pair.
+	ErrRateNotFound = errors.New("exchange rate not found")
+	// ErrInvalidRate indicates that a rate is not a positive number between two different currencies.
+	ErrInvalidRate = errors.New("exchange rate is invalid")
+)
+
+// Rate is how many units of Quote one unit of Base buys.
+type Rate struct {
+	Base  string
+	Quote string
+	Value float64
+}
+
+// NewRate validates a rate and normalizes its currency codes.
+func NewRate(base, quote string, value float64) (Rate, error) {
+	base, err := money.NormalizeCurrency(base)
+	if err != nil {
+		return Rate{}, err
+	}
+
+	quote, err = money.NormalizeCurrency(quote)
+	if err != nil {
+		return Rate{}, err
+	}
+
+	if base == quote || value <= 0 {
+		return Rate{}, ErrInvalidRate
+	}
+
+	return Rate{Base: base, Quote: quote, Value: value}, nil
+}
+
+// LookupWithDao finds the rate that converts base into quote for a plan.
+// Rates entered for the plan win over rates loaded from the rates file, and a
+// stored rate for the opposite direction is inverted when needed.
+func LookupWithDao(dao *daos.Dao, planID, base, quote string) (float64, error) {
+	if base == quote {
+		return 1, nil
+	}
+
+	record, err := findPairWithDao(dao, planID, base, quote)
+	if err != nil {
+		return 0, err
+	}
+	if record != nil {
+		return record.GetFloat("rate"), nil
+	}
+
+	record, err = findPairWithDao(dao, planID, quote, base)
+	if err != nil {
+		return 0, err
+	}
+	if record != nil {
+		return 1 / record.GetFloat("rate"), nil
+	}
+
+	return 0, ErrRateNotFound
+}
+
+// SaveWithDao stores a rate for a plan, or as a shared rate when planID is empty.
+// An existing rate for the same pair and scope is replaced.
+func SaveWithDao(dao *daos.Dao, planID string, rate Rate, source string) error {
+	record, err := findScopedPairWithDao(dao, planID, rate.Base, rate.Quote)
+	if err != nil {
+		return err
+	}
+
+	if record == nil {
+		collection, err := dao.FindCollectionByNameOrId(CollectionName)
+		if err != nil {
+			return err
+		}
+
+		record = pbmodels.NewRecord(collection)
+		record.Set("plan_id", planID)
+		record.Set("base", rate.Base)
+		record.Set("quote", rate.Quote)
+	}
+
+	record.Set("rate", rate.Value)
+	record.Set("source", source)
+	return dao.SaveRecord(record)
+}
+
+// ListWithDao returns the rates available to a plan, plan rates first.
+func ListWithDao(dao *daos.Dao, planID string) ([]*pbmodels.Record, error) {
+	records, err := dao.FindRecordsByFilter(CollectionName, "id != ''", "base,quote", -1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	planRates := []*pbmodels.Record{}
+	sharedRates := []*pbmodels.Record{}
+	for _, record := range records {
+		switch scope := record.GetString("plan_id"); {
+		case scope == "":
+			sharedRates = append(sharedRates, record)
+		case scope == planID:
+			planRates = append(planRates, record)
+		}
+	}
+
+	return append(planRates, sharedRates...), nil
+}
+
+// ParseFile reads "base,quote,rate" lines, such as "GBP,EUR,1.17".
+// Blank lines, lines starting with "#" and a leading "base,quote,rate" header are skipped.
+func ParseFile(r io.Reader) ([]Rate, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	rates := []Rate{}
+	for line := 1; ; line++ {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rates, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("rates file line %d: want base,quote,rate", line)
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(fields[0]), "base") {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(fields[2]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("rates file line %d: %w", line, ErrInvalidRate)
+		}
+
+		rate, err := NewRate(fields[0], fields[1], value)
+		if err != nil {
+			return nil, fmt.Errorf("rates file line %d: %w", line, err)
+		}
+
+		rates = append(rates, rate)
+	}
+}
+
+// LoadFileWithDao stores every rate in the file as a shared rate and returns how many were loaded.
+func LoadFileWithDao(dao *daos.Dao, path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	rates, err := ParseFile(file)
+	if err != nil {
+		return 0, err
+	}
+
+	err = dao.RunInTransaction(func(txDao *daos.Dao) error {
+		for _, rate := range rates {
+			if err := SaveWithDao(txDao, "", rate, SourceFile); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(rates), nil
+}
+
+func findPairWithDao(dao *daos.Dao, planID, base, quote string) (*pbmodels.Record, error) {
+	if planID != "" {
+		record, err := findScopedPairWithDao(dao, planID, base, quote)
+		if err != nil || record != nil {
+			return record, err
+		}
+	}
+
+	return findScopedPairWithDao(dao, "", base, quote)
+}
+
+func findScopedPairWithDao(dao *daos.Dao, planID, base, quote string) (*pbmodels.Record, error) {
+	filter, err := planutil.BuildEqualsFilter(
+		planutil.FilterTerm{Field: "base", Value: base},
+		planutil.FilterTerm{Field: "quote", Value: quote},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := dao.FindRecordsByFilter(CollectionName, filter.Expression, "-updated", -1, 0, filter.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if record.GetString("plan_id") == planID {
+			return record, nil
+		}
+	}
+
+	return nil, nil
+}
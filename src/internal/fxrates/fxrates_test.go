@@ -0,0 +1,104 @@
+package fxrates
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"familyplan/src/internal/testapp"
+)
+
+func TestParseFileReadsRatesAndSkipsHeader(t *testing.T) {
+	t.Parallel()
+
+	rates, err := ParseFile(strings.NewReader("base,quote,rate\n# refreshed monthly\ngbp, EUR, 1.17\n\nUSD,JPY,151.2\n"))
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	want := []Rate{
+		{Base: "GBP", Quote: "EUR", Value: 1.17},
+		{Base: "USD", Quote: "JPY", Value: 151.2},
+	}
+	if len(rates) != len(want) {
+		t.Fatalf("ParseFile returned %d rates, want %d", len(rates), len(want))
+	}
+	for i := range want {
+		if rates[i] != want[i] {
+			t.Fatalf("rate %d = %+v, want %+v", i, rates[i], want[i])
+		}
+	}
+}
+
+func TestParseFileRejectsInvalidRates(t *testing.T) {
+	t.Parallel()
+
+	tests := []string{
+		"GBP,EUR\n",
+		"GBP,EUR,abc\n",
+		"GBP,EUR,0\n",
+		"EUR,EUR,1\n",
+		"GBP,XX,1.2\n",
+	}
+
+	for _, input := range tests {
+		if _, err := ParseFile(strings.NewReader(input)); err == nil {
+			t.Fatalf("ParseFile(%q) returned nil error", input)
+		}
+	}
+}
+
+func TestLookupWithDaoPrefersPlanRatesAndInvertsPairs(t *testing.T) {
+	app := testapp.New(t)
+
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	if err := os.WriteFile(path, []byte("GBP,EUR,1.15\nEUR,USD,1.08\n"), 0o600); err != nil {
+		t.Fatalf("failed to write rates file: %v", err)
+	}
+
+	loaded, err := LoadFileWithDao(app.Dao(), path)
+	if err != nil {
+		t.Fatalf("LoadFileWithDao returned error: %v", err)
+	}
+	if loaded != 2 {
+		t.Fatalf("LoadFileWithDao loaded %d rates, want 2", loaded)
+	}
+
+	manual, err := NewRate("GBP", "EUR", 1.2)
+	if err != nil {
+		t.Fatalf("NewRate returned error: %v", err)
+	}
+	if err := SaveWithDao(app.Dao(), "plan_1", manual, SourceManual); err != nil {
+		t.Fatalf("SaveWithDao returned error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		planID string
+		base   string
+		quote  string
+		want   float64
+	}{
+		{name: "plan rate wins", planID: "plan_1", base: "GBP", quote: "EUR", want: 1.2},
+		{name: "shared rate for other plans", planID: "plan_2", base: "GBP", quote: "EUR", want: 1.15},
+		{name: "inverted shared rate", planID: "plan_1", base: "USD", quote: "EUR", want: 1 / 1.08},
+		{name: "same currency", planID: "plan_1", base: "EUR", quote: "EUR", want: 1},
+	}
+
+	for _, test := range tests {
+		got, err := LookupWithDao(app.Dao(), test.planID, test.base, test.quote)
+		if err != nil {
+			t.Fatalf("%s: LookupWithDao returned error: %v", test.name, err)
+		}
+		if math.Abs(got-test.want) > 1e-9 {
+			t.Fatalf("%s: LookupWithDao = %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	if _, err := LookupWithDao(app.Dao(), "plan_1", "CHF", "EUR"); !errors.Is(err, ErrRateNotFound) {
+		t.Fatalf("LookupWithDao(CHF) error = %v, want ErrRateNotFound", err)
+	}
+}
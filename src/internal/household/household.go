@@ -0,0 +1,387 @@
+package household
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// CollectionName is the PocketBase collection that stores households.
+const CollectionName = "households"
+
+var (
+	// ErrHouseholdNotFound indicates that the household does not exist.
+	ErrHouseholdNotFound = errors.New("household not found")
+	// ErrPlanUnavailable indicates that the plan cannot be added to the household.
+	ErrPlanUnavailable = errors.New("plan is unavailable")
+)
+
+// PlanIDs returns the plans grouped by a household.
+func PlanIDs(household *pbmodels.Record) []string {
+	planIDs := []string{}
+	if err := household.UnmarshalJSONField("plan_ids", &planIDs); err != nil {
+		return []string{}
+	}
+
+	return planIDs
+}
+
+// FindByIDWithDao loads a household by id, returning nil when it does not exist.
+func FindByIDWithDao(dao *daos.Dao, householdID string) (*pbmodels.Record, error) {
+	if householdID == "" {
+		return nil, nil
+	}
+
+	record, err := dao.FindRecordById(CollectionName, householdID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// CreateWithDao stores a new empty household owned by the user.
+func CreateWithDao(dao *daos.Dao, name, ownerID string) (*pbmodels.Record, error) {
+	collection, err := dao.FindCollectionByNameOrId(CollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	record := pbmodels.NewRecord(collection)
+	record.Set("name", strings.TrimSpace(name))
+	record.Set("owner", ownerID)
+	record.Set("plan_ids", []string{})
+	if err := dao.SaveRecord(record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// AddPlanWithDao adds a plan into the household when one of the household's members belongs to it.
+// The plans may have different owners; a plan shared with nobody in the household stays out.
+func AddPlanWithDao(dao *daos.Dao, household *pbmodels.Record, planID string) error {
+	if household == nil {
+		return ErrHouseholdNotFound
+	}
+
+	plan, err := dao.FindRecordById("family_plans", planID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPlanUnavailable
+	}
+	if err != nil {
+		return err
+	}
+
+	memberIDs, err := MemberIDsWithDao(dao, household)
+	if err != nil {
+		return err
+	}
+
+	participantIDs, err := planParticipantIDsWithDao(dao, plan)
+	if err != nil {
+		return err
+	}
+
+	shared := false
+	for _, participantID := range participantIDs {
+		if memberIDs[participantID] {
+			shared = true
+			break
+		}
+	}
+	if !shared {
+		return ErrPlanUnavailable
+	}
+
+	planIDs := PlanIDs(household)
+	for _, existing := range planIDs {
+		if existing == planID {
+			return nil
+		}
+	}
+
+	household.Set("plan_ids", append(planIDs, planID))
+	return dao.SaveRecord(household)
+}
+
+// RemovePlanWithDao drops a plan from the household.
+func RemovePlanWithDao(dao *daos.Dao, household *pbmodels.Record, planID string) error {
+	if household == nil {
+		return ErrHouseholdNotFound
+	}
+
+	planIDs := PlanIDs(household)
+	kept := make([]string, 0, len(planIDs))
+	for _, existing := range planIDs {
+		if existing != planID {
+			kept = append(kept, existing)
+		}
+	}
+
+	household.Set("plan_ids", kept)
+	return dao.SaveRecord(household)
+}
+
+// CanView reports whether the user owns the household or belongs to one of its plans.
+func CanView(dao *daos.Dao, household *pbmodels.Record, userID string) (bool, error) {
+	if household.GetString("owner") == userID {
+		return true, nil
+	}
+
+	for _, planID := range PlanIDs(household) {
+		belongs, err := belongsToPlanWithDao(dao, planID, userID)
+		if err != nil {
+			return false, err
+		}
+		if belongs {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// MemberIDsWithDao returns the household's members: its owner and everyone who owns or currently
+// belongs to one of its plans.
+func MemberIDsWithDao(dao *daos.Dao, household *pbmodels.Record) (map[string]bool, error) {
+	memberIDs := map[string]bool{household.GetString("owner"): true}
+	for _, planID := range PlanIDs(household) {
+		plan, err := dao.FindRecordById("family_plans", planID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		participantIDs, err := planParticipantIDsWithDao(dao, plan)
+		if err != nil {
+			return nil, err
+		}
+		for _, participantID := range participantIDs {
+			memberIDs[participantID] = true
+		}
+	}
+
+	return memberIDs, nil
+}
+
+// FindForUser loads the households a user owns or can see through their plans.
+func FindForUser(app *pocketbase.PocketBase, userID string) ([]*pbmodels.Record, error) {
+	return FindForUserWithDao(app.Dao(), userID)
+}
+
+// FindForUserWithDao loads the households a user owns or can see through their plans using the provided dao.
+func FindForUserWithDao(dao *daos.Dao, userID string) ([]*pbmodels.Record, error) {
+	records, err := dao.FindRecordsByFilter(CollectionName, "id != ''", "name", -1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]*pbmodels.Record, 0, len(records))
+	for _, record := range records {
+		canView, err := CanView(dao, record, userID)
+		if err != nil {
+			return nil, err
+		}
+		if canView {
+			visible = append(visible, record)
+		}
+	}
+
+	return visible, nil
+}
+
+// ConsolidateWithDao nets every person's balance at now across all of the household's plans, per
+// currency, so every member sees the same positions. Members carry their plan balance, and each
+// plan owner carries the opposite of what their members owe.
+func ConsolidateWithDao(dao *daos.Dao, household *pbmodels.Record, now time.Time) ([]Position, error) {
+	type positionKey struct {
+		personID string
+		currency string
+	}
+
+	net := map[positionKey]int64{}
+	names := map[string]string{}
+
+	for _, planID := range PlanIDs(household) {
+		plan, err := dao.FindRecordById("family_plans", planID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		currency := planutil.Currency(plan)
+		ownerKey := positionKey{personID: planutil.OwnerID(plan), currency: currency}
+		if _, exists := net[ownerKey]; !exists {
+			net[ownerKey] = 0
+		}
+
+		memberships, err := findMembershipsWithDao(dao, planID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, membership := range memberships {
+			memberID := membership.GetString("user_id")
+			if memberID == "" || memberID == ownerKey.personID {
+				continue
+			}
+
+			balance, err := billing.CalculateMemberBalanceWithDao(dao, planID, memberID, now)
+			if err != nil {
+				return nil, err
+			}
+
+			net[positionKey{personID: memberID, currency: currency}] += balance.Minor
+			net[ownerKey] -= balance.Minor
+
+			if membership.GetBool("is_artificial") {
+				names[memberID] = membership.GetString("name")
+			}
+		}
+
+		// Someone who paid on another member's behalf is owed that amount by the beneficiary.
+		onBehalf, err := findOnBehalfPaymentsWithDao(dao, planID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, payment := range onBehalf {
+			minor := billing.PaymentAmount(payment, currency).Minor
+			net[positionKey{personID: billing.PayerID(payment), currency: currency}] += minor
+			net[positionKey{personID: payment.GetString("user_id"), currency: currency}] -= minor
+		}
+	}
+
+	positions := make([]Position, 0, len(net))
+	for key, cents := range net {
+		name, ok := names[key.personID]
+		if !ok {
+			name = displayNameWithDao(dao, key.personID)
+			names[key.personID] = name
+		}
+
+		positions = append(positions, Position{PersonID: key.personID, Name: name, Currency: key.currency, Cents: cents})
+	}
+
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Currency != positions[j].Currency {
+			return positions[i].Currency < positions[j].Currency
+		}
+		if positions[i].Name != positions[j].Name {
+			return positions[i].Name < positions[j].Name
+		}
+
+		return positions[i].PersonID < positions[j].PersonID
+	})
+
+	return positions, nil
+}
+
+func findMembershipsWithDao(dao *daos.Dao, planID string) ([]*pbmodels.Record, error) {
+	filter, err := planutil.BuildEqualsFilter(
+		planutil.FilterTerm{Field: "plan_id", Value: planID},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return dao.FindRecordsByFilter(
+		"memberships",
+		filter.Expression,
+		"",
+		-1,
+		0,
+		filter.Params,
+	)
+}
+
+func findOnBehalfPaymentsWithDao(dao *daos.Dao, planID string) ([]*pbmodels.Record, error) {
+	filter, err := planutil.BuildEqualsFilter(
+		planutil.FilterTerm{Field: "plan_id", Value: planID},
+		planutil.FilterTerm{Field: "status", Value: "approved"},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := dao.FindRecordsByFilter(
+		"payments",
+		filter.Expression+" && payer_id != '' && payer_id != user_id",
+		"",
+		-1,
+		0,
+		filter.Params,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+// planParticipantIDsWithDao returns the plan's owner and its current members.
+func planParticipantIDsWithDao(dao *daos.Dao, plan *pbmodels.Record) ([]string, error) {
+	memberships, err := findMembershipsWithDao(dao, plan.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	participantIDs := []string{planutil.OwnerID(plan)}
+	for _, membership := range memberships {
+		if membership.GetDateTime("date_ended").IsZero() {
+			participantIDs = append(participantIDs, membership.GetString("user_id"))
+		}
+	}
+
+	return participantIDs, nil
+}
+
+func belongsToPlanWithDao(dao *daos.Dao, planID, userID string) (bool, error) {
+	plan, err := dao.FindRecordById("family_plans", planID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if planutil.IsOwner(plan, userID) {
+		return true, nil
+	}
+
+	membership, err := planutil.FindMembershipWithDao(dao, planID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return membership != nil && membership.GetDateTime("date_ended").IsZero(), nil
+}
+
+func displayNameWithDao(dao *daos.Dao, userID string) string {
+	user, err := dao.FindRecordById("users", userID)
+	if err != nil || user == nil {
+		return userID
+	}
+
+	if name := strings.TrimSpace(user.GetString("name")); name != "" {
+		return name
+	}
+
+	return user.GetString("username")
+}
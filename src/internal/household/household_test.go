@@ -0,0 +1,238 @@
+package household
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/testapp"
+
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+func TestConsolidateWithDaoNetsBalancesAcrossPlans(t *testing.T) {
+	app := testapp.New(t)
+
+	alice := testapp.SaveUser(t, app, "alice")
+	bob := testapp.SaveUser(t, app, "bob")
+	carol := testapp.SaveUser(t, app, "carol")
+
+	now := time.Now()
+	streaming := testapp.SavePlan(t, app, alice.Id, "STREAM", 3000)
+	testapp.SaveMembership(t, app, streaming.Id, alice.Id, now)
+	testapp.SaveMembership(t, app, streaming.Id, carol.Id, now)
+
+	music := testapp.SavePlan(t, app, alice.Id, "MUSIC1", 3000)
+	testapp.SaveMembership(t, app, music.Id, alice.Id, now)
+	testapp.SaveMembership(t, app, music.Id, bob.Id, now)
+	testapp.SaveMembership(t, app, music.Id, carol.Id, now)
+
+	record, err := CreateWithDao(app.Dao(), "Family", alice.Id)
+	if err != nil {
+		t.Fatalf("CreateWithDao returned error: %v", err)
+	}
+	if err := AddPlanWithDao(app.Dao(), record, streaming.Id); err != nil {
+		t.Fatalf("AddPlanWithDao(streaming) returned error: %v", err)
+	}
+	if err := AddPlanWithDao(app.Dao(), record, music.Id); err != nil {
+		t.Fatalf("AddPlanWithDao(music) returned error: %v", err)
+	}
+
+	positions, err := ConsolidateWithDao(app.Dao(), record, now)
+	if err != nil {
+		t.Fatalf("ConsolidateWithDao returned error: %v", err)
+	}
+
+	want := map[string]int64{alice.Id: 3500, bob.Id: -1000, carol.Id: -2500}
+	if len(positions) != len(want) {
+		t.Fatalf("ConsolidateWithDao returned %d positions, want %d", len(positions), len(want))
+	}
+	for _, position := range positions {
+		if position.Cents != want[position.PersonID] {
+			t.Fatalf("%s net = %d cents, want %d", position.Name, position.Cents, want[position.PersonID])
+		}
+	}
+
+	transfers := SettleUp(positions)
+	if len(transfers) != 2 {
+		t.Fatalf("SettleUp returned %d transfers, want 2", len(transfers))
+	}
+	for _, transfer := range transfers {
+		if transfer.ToID != alice.Id {
+			t.Fatalf("transfer %+v should be paid to alice", transfer)
+		}
+	}
+}
+
+func TestConsolidateWithDaoChargesBeneficiaryForOnBehalfPayments(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	parent := testapp.SaveUser(t, app, "parent")
+	kid := testapp.SaveUser(t, app, "kid")
+
+	now := time.Now()
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 3000)
+	testapp.SaveMembership(t, app, plan.Id, owner.Id, now)
+	testapp.SaveMembership(t, app, plan.Id, parent.Id, now)
+	testapp.SaveMembership(t, app, plan.Id, kid.Id, now)
+
+	paymentsCollection, err := app.Dao().FindCollectionByNameOrId("payments")
+	if err != nil {
+		t.Fatalf("failed to find payments collection: %v", err)
+	}
+
+	payment := pbmodels.NewRecord(paymentsCollection)
+	payment.Set("plan_id", plan.Id)
+	payment.Set("user_id", kid.Id)
+	payment.Set("payer_id", parent.Id)
+	payment.Set("amount", 1000)
+	payment.Set("date", now)
+	payment.Set("status", "approved")
+	if err := app.Dao().SaveRecord(payment); err != nil {
+		t.Fatalf("failed to save payment: %v", err)
+	}
+
+	record, err := CreateWithDao(app.Dao(), "Family", owner.Id)
+	if err != nil {
+		t.Fatalf("CreateWithDao returned error: %v", err)
+	}
+	if err := AddPlanWithDao(app.Dao(), record, plan.Id); err != nil {
+		t.Fatalf("AddPlanWithDao returned error: %v", err)
+	}
+
+	positions, err := ConsolidateWithDao(app.Dao(), record, now)
+	if err != nil {
+		t.Fatalf("ConsolidateWithDao returned error: %v", err)
+	}
+
+	// The parent still owes their own share but is owed the kid's share they covered.
+	want := map[string]int64{
+		owner.Id:  1000,
+		parent.Id: 0,
+		kid.Id:    -1000,
+	}
+	for _, position := range positions {
+		if position.Cents != want[position.PersonID] {
+			t.Fatalf("%s net = %d cents, want %d", position.Name, position.Cents, want[position.PersonID])
+		}
+	}
+}
+
+func TestCanViewRequiresPlanMembership(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	outsider := testapp.SaveUser(t, app, "outsider")
+
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 1000)
+	testapp.SaveMembership(t, app, plan.Id, member.Id, time.Now())
+
+	record, err := CreateWithDao(app.Dao(), "Family", owner.Id)
+	if err != nil {
+		t.Fatalf("CreateWithDao returned error: %v", err)
+	}
+	if err := AddPlanWithDao(app.Dao(), record, plan.Id); err != nil {
+		t.Fatalf("AddPlanWithDao returned error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		userID string
+		want   bool
+	}{
+		{name: "owner", userID: owner.Id, want: true},
+		{name: "member", userID: member.Id, want: true},
+		{name: "outsider", userID: outsider.Id, want: false},
+	} {
+		got, err := CanView(app.Dao(), record, tc.userID)
+		if err != nil {
+			t.Fatalf("CanView(%s) returned error: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Fatalf("CanView(%s) = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestConsolidateWithDaoGivesEveryMemberTheSameSettleUpAcrossOwners(t *testing.T) {
+	app := testapp.New(t)
+
+	alice := testapp.SaveUser(t, app, "alice")
+	bob := testapp.SaveUser(t, app, "bob")
+
+	now := time.Now()
+	streaming := testapp.SavePlan(t, app, alice.Id, "STREAM", 2000)
+	testapp.SaveMembership(t, app, streaming.Id, alice.Id, now)
+	testapp.SaveMembership(t, app, streaming.Id, bob.Id, now)
+
+	storage := testapp.SavePlan(t, app, bob.Id, "CLOUD1", 600)
+	testapp.SaveMembership(t, app, storage.Id, bob.Id, now)
+	testapp.SaveMembership(t, app, storage.Id, alice.Id, now)
+
+	record, err := CreateWithDao(app.Dao(), "Family", alice.Id)
+	if err != nil {
+		t.Fatalf("CreateWithDao returned error: %v", err)
+	}
+	for _, plan := range []*pbmodels.Record{streaming, storage} {
+		if err := AddPlanWithDao(app.Dao(), record, plan.Id); err != nil {
+			t.Fatalf("AddPlanWithDao(%s) returned error: %v", plan.GetString("join_code"), err)
+		}
+	}
+
+	for _, viewer := range []*pbmodels.Record{alice, bob} {
+		canView, err := CanView(app.Dao(), record, viewer.Id)
+		if err != nil {
+			t.Fatalf("CanView(%s) returned error: %v", viewer.Username(), err)
+		}
+		if !canView {
+			t.Fatalf("CanView(%s) = false, want true", viewer.Username())
+		}
+	}
+
+	// Bob owes alice half of streaming and alice owes bob half of storage.
+	positions, err := ConsolidateWithDao(app.Dao(), record, now)
+	if err != nil {
+		t.Fatalf("ConsolidateWithDao returned error: %v", err)
+	}
+
+	transfers := SettleUp(positions)
+	if len(transfers) != 1 || transfers[0].FromID != bob.Id || transfers[0].ToID != alice.Id || transfers[0].Cents != 700 {
+		t.Fatalf("SettleUp = %+v, want bob to pay alice 700", transfers)
+	}
+}
+
+func TestAddPlanWithDaoRequiresAHouseholdMemberInThePlan(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	stranger := testapp.SaveUser(t, app, "stranger")
+
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 1000)
+	testapp.SaveMembership(t, app, plan.Id, member.Id, time.Now())
+	membersPlan := testapp.SavePlan(t, app, member.Id, "MEMBER", 1000)
+	strangersPlan := testapp.SavePlan(t, app, stranger.Id, "STRNGR", 1000)
+
+	record, err := CreateWithDao(app.Dao(), "Family", owner.Id)
+	if err != nil {
+		t.Fatalf("CreateWithDao returned error: %v", err)
+	}
+
+	for _, planID := range []string{strangersPlan.Id, "missing", membersPlan.Id} {
+		if err := AddPlanWithDao(app.Dao(), record, planID); !errors.Is(err, ErrPlanUnavailable) {
+			t.Fatalf("AddPlanWithDao(%s) before the member joined error = %v, want %v", planID, err, ErrPlanUnavailable)
+		}
+	}
+
+	// Once the owner's plan brings the member into the household, the member's own plan can join it.
+	for _, planID := range []string{plan.Id, membersPlan.Id} {
+		if err := AddPlanWithDao(app.Dao(), record, planID); err != nil {
+			t.Fatalf("AddPlanWithDao(%s) returned error: %v", planID, err)
+		}
+	}
+	if got := PlanIDs(record); len(got) != 2 {
+		t.Fatalf("PlanIDs = %v, want the owner's and the member's plans", got)
+	}
+}
@@ -0,0 +1,99 @@
+package household
+
+import "sort"
+
+// Position is a person's net amount in one currency across a household, in minor units.
+// Positive means the person is owed money; negative means they owe.
+type Position struct {
+	PersonID string
+	Name     string
+	Currency string
+	Cents    int64
+}
+
+// Transfer is a single payment that moves money from a debtor to a creditor.
+type Transfer struct {
+	FromID   string
+	FromName string
+	ToID     string
+	ToName   string
+	Currency string
+	Cents    int64
+}
+
+// SettleUp returns transfers that bring every position to zero.
+// Each currency is settled separately. Largest debts are matched against largest
+// credits, which needs at most one transfer fewer than the number of people with
+// a non-zero position in that currency.
+func SettleUp(positions []Position) []Transfer {
+	currencies := []string{}
+	byCurrency := map[string][]Position{}
+	for _, position := range positions {
+		if _, ok := byCurrency[position.Currency]; !ok {
+			currencies = append(currencies, position.Currency)
+		}
+		byCurrency[position.Currency] = append(byCurrency[position.Currency], position)
+	}
+	sort.Strings(currencies)
+
+	transfers := []Transfer{}
+	for _, code := range currencies {
+		transfers = append(transfers, settleCurrency(code, byCurrency[code])...)
+	}
+
+	return transfers
+}
+
+func settleCurrency(code string, positions []Position) []Transfer {
+	debtors := make([]Position, 0, len(positions))
+	creditors := make([]Position, 0, len(positions))
+	for _, position := range positions {
+		switch {
+		case position.Cents < 0:
+			debtors = append(debtors, Position{PersonID: position.PersonID, Name: position.Name, Currency: code, Cents: -position.Cents})
+		case position.Cents > 0:
+			creditors = append(creditors, position)
+		}
+	}
+
+	sortLargestFirst(debtors)
+	sortLargestFirst(creditors)
+
+	transfers := []Transfer{}
+	for d, c := 0, 0; d < len(debtors) && c < len(creditors); {
+		amount := debtors[d].Cents
+		if creditors[c].Cents < amount {
+			amount = creditors[c].Cents
+		}
+
+		transfers = append(transfers, Transfer{
+			FromID:   debtors[d].PersonID,
+			FromName: debtors[d].Name,
+			ToID:     creditors[c].PersonID,
+			ToName:   creditors[c].Name,
+			Currency: code,
+			Cents:    amount,
+		})
+
+		debtors[d].Cents -= amount
+		creditors[c].Cents -= amount
+		if debtors[d].Cents == 0 {
+			d++
+		}
+		if creditors[c].Cents == 0 {
+			c++
+		}
+	}
+
+	return transfers
+}
+
+func sortLargestFirst(positions []Position) {
+	sort.SliceStable(positions, func(i, j int) bool {
+		if positions[i].Cents != positions[j].Cents {
+			return positions[i].Cents > positions[j].Cents
+		}
+
+		return positions[i].PersonID < positions[j].PersonID
+	})
+}
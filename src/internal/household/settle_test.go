@@ -0,0 +1,96 @@
+package household
+
+import "testing"
+
+func TestSettleUpClearsEveryPosition(t *testing.T) {
+	t.Parallel()
+
+	positions := []Position{
+		{PersonID: "owner-a", Name: "Alice", Cents: 4500},
+		{PersonID: "owner-b", Name: "Bob", Cents: 1500},
+		{PersonID: "carol", Name: "Carol", Cents: -3000},
+		{PersonID: "dave", Name: "Dave", Cents: -2000},
+		{PersonID: "erin", Name: "Erin", Cents: -1000},
+		{PersonID: "frank", Name: "Frank", Cents: 0},
+	}
+
+	transfers := SettleUp(positions)
+	if len(transfers) > 4 {
+		t.Fatalf("SettleUp returned %d transfers, want at most 4", len(transfers))
+	}
+
+	net := map[string]int64{}
+	for _, position := range positions {
+		net[position.PersonID] = position.Cents
+	}
+	for _, transfer := range transfers {
+		if transfer.Cents <= 0 {
+			t.Fatalf("transfer %+v has a non-positive amount", transfer)
+		}
+		net[transfer.FromID] += transfer.Cents
+		net[transfer.ToID] -= transfer.Cents
+	}
+
+	for personID, cents := range net {
+		if cents != 0 {
+			t.Fatalf("%s is left with %d cents after settling", personID, cents)
+		}
+	}
+}
+
+func TestSettleUpMatchesLargestDebtFirst(t *testing.T) {
+	t.Parallel()
+
+	transfers := SettleUp([]Position{
+		{PersonID: "small", Cents: -500},
+		{PersonID: "large", Cents: -2500},
+		{PersonID: "owner", Cents: 3000},
+	})
+
+	if len(transfers) != 2 {
+		t.Fatalf("SettleUp returned %d transfers, want 2", len(transfers))
+	}
+	if transfers[0].FromID != "large" || transfers[0].Cents != 2500 {
+		t.Fatalf("first transfer = %+v, want 2500 cents from large", transfers[0])
+	}
+	if transfers[1].FromID != "small" || transfers[1].Cents != 500 {
+		t.Fatalf("second transfer = %+v, want 500 cents from small", transfers[1])
+	}
+}
+
+func TestSettleUpKeepsCurrenciesSeparate(t *testing.T) {
+	t.Parallel()
+
+	transfers := SettleUp([]Position{
+		{PersonID: "alice", Currency: "USD", Cents: 1000},
+		{PersonID: "bob", Currency: "USD", Cents: -1000},
+		{PersonID: "alice", Currency: "EUR", Cents: -700},
+		{PersonID: "bob", Currency: "EUR", Cents: 700},
+	})
+
+	if len(transfers) != 2 {
+		t.Fatalf("SettleUp returned %d transfers, want 2", len(transfers))
+	}
+	for _, transfer := range transfers {
+		switch transfer.Currency {
+		case "EUR":
+			if transfer.FromID != "alice" || transfer.Cents != 700 {
+				t.Fatalf("EUR transfer = %+v, want 700 from alice", transfer)
+			}
+		case "USD":
+			if transfer.FromID != "bob" || transfer.Cents != 1000 {
+				t.Fatalf("USD transfer = %+v, want 1000 from bob", transfer)
+			}
+		default:
+			t.Fatalf("unexpected transfer currency %q", transfer.Currency)
+		}
+	}
+}
+
+func TestSettleUpWithNoBalancesReturnsNothing(t *testing.T) {
+	t.Parallel()
+
+	if transfers := SettleUp([]Position{{PersonID: "a"}, {PersonID: "b"}}); len(transfers) != 0 {
+		t.Fatalf("SettleUp returned %d transfers, want 0", len(transfers))
+	}
+}
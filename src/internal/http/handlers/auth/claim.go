@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"familyplan/src/internal/memberclaim"
 
@@ -56,12 +57,12 @@ func buildPathWithQuery(path string, values url.Values) string {
 	return path + "?" + encoded
 }
 
-func redirectAfterAuth(c echo.Context, app *pocketbase.PocketBase, userID, claimToken string) error {
+func redirectAfterAuth(c echo.Context, app *pocketbase.PocketBase, userID, claimToken string, now time.Time) error {
 	if strings.TrimSpace(claimToken) == "" {
 		return c.Redirect(http.StatusSeeOther, "/family-plans")
 	}
 
-	result, err := memberclaim.Claim(app, claimToken, userID)
+	result, err := memberclaim.Claim(app, claimToken, userID, now)
 	if err != nil {
 		if message := memberclaim.ErrorMessage(err); message != "" {
 			values := url.Values{}
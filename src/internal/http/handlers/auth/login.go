@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"familyplan/src/internal/billing"
 	"familyplan/src/internal/http/sessionutil"
 	"familyplan/src/internal/memberclaim"
 	"familyplan/src/internal/support/random"
@@ -34,8 +35,10 @@ func HandleLoginPage() echo.HandlerFunc {
 }
 
 // HandleLoginSubmit authenticates a user and stores an auth token cookie.
-func HandleLoginSubmit(app *pocketbase.PocketBase) echo.HandlerFunc {
+func HandleLoginSubmit(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		claimToken := currentClaimToken(c)
 		username := c.FormValue("username")
 		password := c.FormValue("password")
@@ -63,7 +66,7 @@ func HandleLoginSubmit(app *pocketbase.PocketBase) echo.HandlerFunc {
 			return c.Redirect(http.StatusSeeOther, buildAuthPagePath("/login", claimToken, "Authentication failed"))
 		}
 
-		c.SetCookie(newAuthCookie(token, time.Now().Add(30*24*time.Hour)))
-		return redirectAfterAuth(c, app, authRecord.Id, claimToken)
+		c.SetCookie(newAuthCookie(token, now.Add(30*24*time.Hour)))
+		return redirectAfterAuth(c, app, authRecord.Id, claimToken, now)
 	}
 }
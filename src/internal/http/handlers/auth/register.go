@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"familyplan/src/internal/billing"
 	"familyplan/src/internal/http/sessionutil"
 	"familyplan/src/internal/memberclaim"
 	"familyplan/src/internal/support/random"
@@ -34,8 +35,10 @@ func HandleRegisterPage() echo.HandlerFunc {
 }
 
 // HandleRegisterSubmit registers a user and signs them in immediately.
-func HandleRegisterSubmit(app *pocketbase.PocketBase) echo.HandlerFunc {
+func HandleRegisterSubmit(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		claimToken := currentClaimToken(c)
 		username := c.FormValue("username")
 		password := c.FormValue("password")
@@ -85,7 +88,7 @@ func HandleRegisterSubmit(app *pocketbase.PocketBase) echo.HandlerFunc {
 			return c.Redirect(http.StatusSeeOther, buildPathWithQuery("/login", mapSuccessAndClaim(claimToken, "Registration successful. Please login.")))
 		}
 
-		c.SetCookie(newAuthCookie(token, time.Now().Add(30*24*time.Hour)))
-		return redirectAfterAuth(c, app, record.Id, claimToken)
+		c.SetCookie(newAuthCookie(token, now.Add(30*24*time.Hour)))
+		return redirectAfterAuth(c, app, record.Id, claimToken, now)
 	}
 }
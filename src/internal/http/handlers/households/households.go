@@ -0,0 +1,291 @@
+package households
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/domain"
+	"familyplan/src/internal/household"
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/planutil"
+	"familyplan/src/internal/view"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// HandleHouseholdsList renders the households visible to the current user.
+func HandleHouseholdsList(app *pocketbase.PocketBase) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+
+		records, err := household.FindForUser(app, session.UserID)
+		if err != nil {
+			return err
+		}
+
+		households := make([]domain.Household, 0, len(records))
+		for _, record := range records {
+			households = append(households, buildHousehold(app, record))
+		}
+
+		return view.RenderPage(c, "households.html", map[string]interface{}{
+			"title":      "Households",
+			"households": households,
+			"error":      c.QueryParam("error"),
+		})
+	}
+}
+
+// HandleCreateHousehold creates an empty household owned by the current user.
+func HandleCreateHousehold(app *pocketbase.PocketBase) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimSpace(c.FormValue("name"))
+		if name == "" {
+			return c.Redirect(http.StatusSeeOther, "/households")
+		}
+
+		record, err := household.CreateWithDao(app.Dao(), name, session.UserID)
+		if err != nil {
+			return err
+		}
+
+		return c.Redirect(http.StatusSeeOther, "/households/"+record.Id)
+	}
+}
+
+// HandleHouseholdDetails renders consolidated balances and settle-up transfers for a household.
+func HandleHouseholdDetails(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		now := clock.Now()
+
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+
+		record, err := household.FindByIDWithDao(app.Dao(), c.PathParam("household_id"))
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			return c.Redirect(http.StatusSeeOther, "/households")
+		}
+
+		canView, err := household.CanView(app.Dao(), record, session.UserID)
+		if err != nil {
+			return err
+		}
+		if !canView {
+			return c.Redirect(http.StatusSeeOther, "/households")
+		}
+
+		positions, err := household.ConsolidateWithDao(app.Dao(), record, now)
+		if err != nil {
+			return err
+		}
+
+		householdPositions := make([]domain.HouseholdPosition, 0, len(positions))
+		for _, position := range positions {
+			householdPositions = append(householdPositions, domain.HouseholdPosition{
+				PersonID: position.PersonID,
+				Name:     position.Name,
+				Net:      money.New(position.Cents, position.Currency),
+			})
+		}
+
+		transfers := household.SettleUp(positions)
+		settleUp := make([]domain.SettleUpTransfer, 0, len(transfers))
+		for _, transfer := range transfers {
+			settleUp = append(settleUp, domain.SettleUpTransfer{
+				FromName: transfer.FromName,
+				ToName:   transfer.ToName,
+				Amount:   money.New(transfer.Cents, transfer.Currency),
+			})
+		}
+
+		isOwner := record.GetString("owner") == session.UserID
+		availablePlans := []domain.FamilyPlan{}
+		if isOwner {
+			availablePlans, err = loadAvailablePlans(app, record, session.UserID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return view.RenderPage(c, "household_details.html", map[string]interface{}{
+			"title":           record.GetString("name"),
+			"household":       buildHousehold(app, record),
+			"is_owner":        isOwner,
+			"positions":       householdPositions,
+			"transfers":       settleUp,
+			"available_plans": availablePlans,
+			"error":           c.QueryParam("error"),
+		})
+	}
+}
+
+// HandleAddHouseholdPlan adds a plan the owner belongs to into the household.
+func HandleAddHouseholdPlan(app *pocketbase.PocketBase) echo.HandlerFunc {
+	return handleOwnedHouseholdAction(app, func(txDao *daos.Dao, record *pbmodels.Record, planID string) error {
+		return household.AddPlanWithDao(txDao, record, planID)
+	})
+}
+
+// HandleRemoveHouseholdPlan removes a plan from the household.
+func HandleRemoveHouseholdPlan(app *pocketbase.PocketBase) echo.HandlerFunc {
+	return handleOwnedHouseholdAction(app, func(txDao *daos.Dao, record *pbmodels.Record, planID string) error {
+		return household.RemovePlanWithDao(txDao, record, planID)
+	})
+}
+
+// HandleDeleteHousehold deletes a household without touching its plans.
+func HandleDeleteHousehold(app *pocketbase.PocketBase) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+
+		record, err := household.FindByIDWithDao(app.Dao(), c.PathParam("household_id"))
+		if err != nil {
+			return err
+		}
+		if record == nil || record.GetString("owner") != session.UserID {
+			return c.Redirect(http.StatusSeeOther, "/households")
+		}
+
+		if err := app.Dao().DeleteRecord(record); err != nil {
+			return err
+		}
+
+		return c.Redirect(http.StatusSeeOther, "/households")
+	}
+}
+
+func handleOwnedHouseholdAction(app *pocketbase.PocketBase, action func(txDao *daos.Dao, record *pbmodels.Record, planID string) error) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+
+		householdID := c.PathParam("household_id")
+		planID := strings.TrimSpace(c.FormValue("plan_id"))
+
+		err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+			record, err := household.FindByIDWithDao(txDao, householdID)
+			if err != nil {
+				return err
+			}
+			if record == nil || record.GetString("owner") != session.UserID {
+				return household.ErrHouseholdNotFound
+			}
+			if planID == "" {
+				return household.ErrPlanUnavailable
+			}
+
+			return action(txDao, record, planID)
+		})
+		if err != nil {
+			if errors.Is(err, household.ErrHouseholdNotFound) {
+				return c.Redirect(http.StatusSeeOther, "/households")
+			}
+			if errors.Is(err, household.ErrPlanUnavailable) {
+				values := url.Values{}
+				values.Set("error", "That plan can't be added to this household.")
+				return c.Redirect(http.StatusSeeOther, "/households/"+householdID+"?"+values.Encode())
+			}
+			return err
+		}
+
+		return c.Redirect(http.StatusSeeOther, "/households/"+householdID)
+	}
+}
+
+func buildHousehold(app *pocketbase.PocketBase, record *pbmodels.Record) domain.Household {
+	planIDs := household.PlanIDs(record)
+	plans := make([]domain.FamilyPlan, 0, len(planIDs))
+	for _, planID := range planIDs {
+		planRecord, err := app.Dao().FindRecordById("family_plans", planID)
+		if err != nil || planRecord == nil {
+			continue
+		}
+
+		plans = append(plans, domain.FamilyPlan{
+			ID:       planRecord.Id,
+			Name:     planRecord.GetString("name"),
+			Owner:    planutil.OwnerID(planRecord),
+			JoinCode: planRecord.GetString("join_code"),
+		})
+	}
+
+	return domain.Household{
+		ID:        record.Id,
+		Name:      record.GetString("name"),
+		Owner:     record.GetString("owner"),
+		Plans:     plans,
+		PlanCount: len(plans),
+	}
+}
+
+func loadAvailablePlans(app *pocketbase.PocketBase, record *pbmodels.Record, userID string) ([]domain.FamilyPlan, error) {
+	included := map[string]bool{}
+	for _, planID := range household.PlanIDs(record) {
+		included[planID] = true
+	}
+
+	membershipFilter, err := planutil.BuildEqualsFilter(
+		planutil.FilterTerm{Field: "user_id", Value: userID},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	memberships, err := app.Dao().FindRecordsByFilter(
+		"memberships",
+		membershipFilter.Expression,
+		"",
+		-1,
+		0,
+		membershipFilter.Params,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	plans := []domain.FamilyPlan{}
+	for _, membership := range memberships {
+		planID := membership.GetString("plan_id")
+		if included[planID] || !membership.GetDateTime("date_ended").IsZero() {
+			continue
+		}
+
+		planRecord, err := app.Dao().FindRecordById("family_plans", planID)
+		if err != nil || planRecord == nil {
+			continue
+		}
+
+		included[planID] = true
+		plans = append(plans, domain.FamilyPlan{
+			ID:       planRecord.Id,
+			Name:     planRecord.GetString("name"),
+			JoinCode: planRecord.GetString("join_code"),
+		})
+	}
+
+	return plans, nil
+}
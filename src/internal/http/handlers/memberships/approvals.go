@@ -4,6 +4,8 @@ import (
 	"errors"
 	"net/http"
 
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
 	"familyplan/src/internal/planutil"
 
 	"github.com/labstack/echo/v5"
@@ -13,8 +15,10 @@ import (
 )
 
 // HandleApproveRequest approves a pending join request.
-func HandleApproveRequest(app *pocketbase.PocketBase) echo.HandlerFunc {
+func HandleApproveRequest(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		session, err := sessionOrRedirect(c)
 		if err != nil {
 			return err
@@ -49,21 +53,34 @@ func HandleApproveRequest(app *pocketbase.PocketBase) echo.HandlerFunc {
 				return err
 			}
 
-			existingMembership, err := planutil.FindMembershipWithDao(txDao, planRecord.Id, userID)
+			membership, err := planutil.FindMembershipWithDao(txDao, planRecord.Id, userID)
 			if err != nil {
 				return err
 			}
-			if existingMembership == nil {
-				newMembership := pbmodels.NewRecord(membershipsCollection)
-				newMembership.Set("plan_id", planRecord.Id)
-				newMembership.Set("user_id", userID)
-				newMembership.Set("is_artificial", false)
-				if err := txDao.SaveRecord(newMembership); err != nil {
+			if membership == nil {
+				membership = pbmodels.NewRecord(membershipsCollection)
+				membership.Set("plan_id", planRecord.Id)
+				membership.Set("user_id", userID)
+				membership.Set("is_artificial", false)
+				billing.StartTrial(membership, planRecord, now)
+				if err := txDao.SaveRecord(membership); err != nil {
 					return err
 				}
 			}
 
-			return txDao.DeleteRecord(request)
+			if err := txDao.DeleteRecord(request); err != nil {
+				return err
+			}
+
+			return audit.RecordWithDao(txDao, audit.Event{
+				PlanID:   planRecord.Id,
+				ActorID:  session.UserID,
+				Action:   audit.ActionJoinApproved,
+				Target:   "memberships",
+				TargetID: membership.Id,
+				Before:   audit.Snapshot(request),
+				After:    audit.Snapshot(membership),
+			})
 		})
 		if err != nil {
 			if errors.Is(err, requestNotFound) {
@@ -106,7 +123,21 @@ func HandleDenyRequest(app *pocketbase.PocketBase) echo.HandlerFunc {
 			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
 		}
 
-		if err := app.Dao().DeleteRecord(request); err != nil {
+		err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+			if err := txDao.DeleteRecord(request); err != nil {
+				return err
+			}
+
+			return audit.RecordWithDao(txDao, audit.Event{
+				PlanID:   planRecord.Id,
+				ActorID:  session.UserID,
+				Action:   audit.ActionJoinDenied,
+				Target:   "join_requests",
+				TargetID: request.Id,
+				Before:   audit.Snapshot(request),
+			})
+		})
+		if err != nil {
 			return err
 		}
 
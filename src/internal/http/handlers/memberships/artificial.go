@@ -5,19 +5,24 @@ import (
 	"strings"
 	"unicode/utf8"
 
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
 	"familyplan/src/internal/planutil"
 	"familyplan/src/internal/support/random"
 
 	"github.com/labstack/echo/v5"
 	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
 	pbmodels "github.com/pocketbase/pocketbase/models"
 )
 
 const maxArtificialMemberNameLength = 80
 
 // HandleAddArtificialMember creates an artificial member record.
-func HandleAddArtificialMember(app *pocketbase.PocketBase) echo.HandlerFunc {
+func HandleAddArtificialMember(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		session, err := sessionOrRedirect(c)
 		if err != nil {
 			return err
@@ -85,7 +90,22 @@ func HandleAddArtificialMember(app *pocketbase.PocketBase) echo.HandlerFunc {
 		newMembership.Set("user_id", artificialUserID)
 		newMembership.Set("is_artificial", true)
 		newMembership.Set("name", memberName)
-		if err := app.Dao().SaveRecord(newMembership); err != nil {
+		billing.StartTrial(newMembership, planRecord, now)
+		err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+			if err := txDao.SaveRecord(newMembership); err != nil {
+				return err
+			}
+
+			return audit.RecordWithDao(txDao, audit.Event{
+				PlanID:   planRecord.Id,
+				ActorID:  session.UserID,
+				Action:   audit.ActionMemberAdded,
+				Target:   "memberships",
+				TargetID: newMembership.Id,
+				After:    audit.Snapshot(newMembership),
+			})
+		})
+		if err != nil {
 			return err
 		}
 
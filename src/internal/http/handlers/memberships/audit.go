@@ -0,0 +1,46 @@
+package memberships
+
+import (
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+// recordMembershipChangeWithDao makes a change to a member's membership in a transaction and
+// records it with the membership's values before and after.
+func recordMembershipChangeWithDao(dao *daos.Dao, planID, userID, actorID, action string, change func(txDao *daos.Dao) error) error {
+	return dao.RunInTransaction(func(txDao *daos.Dao) error {
+		before, err := planutil.FindMembershipWithDao(txDao, planID, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := change(txDao); err != nil {
+			return err
+		}
+
+		after, err := planutil.FindMembershipWithDao(txDao, planID, userID)
+		if err != nil {
+			return err
+		}
+
+		targetID := ""
+		if after != nil {
+			targetID = after.Id
+		} else if before != nil {
+			targetID = before.Id
+		}
+
+		return audit.RecordWithDao(txDao, audit.Event{
+			PlanID:   planID,
+			ActorID:  actorID,
+			Action:   action,
+			Target:   "memberships",
+			TargetID: targetID,
+			UserID:   userID,
+			Before:   audit.Snapshot(before),
+			After:    audit.Snapshot(after),
+		})
+	})
+}
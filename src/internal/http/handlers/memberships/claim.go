@@ -5,7 +5,10 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
 	"familyplan/src/internal/http/sessionutil"
 	"familyplan/src/internal/memberclaim"
 	"familyplan/src/internal/planutil"
@@ -19,8 +22,30 @@ import (
 var errClaimLinkUnavailable = errors.New("member claim link unavailable")
 
 // HandleCreateMemberClaimLink creates or reuses a public claim link for an artificial member.
-func HandleCreateMemberClaimLink(app *pocketbase.PocketBase) echo.HandlerFunc {
+func HandleCreateMemberClaimLink(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return handleMemberClaimLinkAction(app, clock, audit.ActionClaimLinkCreated, func(txDao *daos.Dao, planID, artificialMemberID string, now time.Time) error {
+		_, err := memberclaim.EnsureWithDao(txDao, planID, artificialMemberID, now)
+		return err
+	})
+}
+
+// HandleRegenerateMemberClaimLink revokes the current claim link and issues a fresh one.
+func HandleRegenerateMemberClaimLink(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return handleMemberClaimLinkAction(app, clock, audit.ActionClaimLinkRegenerated, func(txDao *daos.Dao, planID, artificialMemberID string, now time.Time) error {
+		_, err := memberclaim.RegenerateWithDao(txDao, planID, artificialMemberID, now)
+		return err
+	})
+}
+
+// HandleRevokeMemberClaimLink revokes the active claim link for an artificial member.
+func HandleRevokeMemberClaimLink(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return handleMemberClaimLinkAction(app, clock, audit.ActionClaimLinkRevoked, memberclaim.RevokeWithDao)
+}
+
+func handleMemberClaimLinkAction(app *pocketbase.PocketBase, clock billing.Clock, auditAction string, action func(txDao *daos.Dao, planID, artificialMemberID string, now time.Time) error) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		session, err := sessionOrRedirect(c)
 		if err != nil {
 			return err
@@ -53,8 +78,44 @@ func HandleCreateMemberClaimLink(app *pocketbase.PocketBase) echo.HandlerFunc {
 				return errClaimLinkUnavailable
 			}
 
-			_, err = memberclaim.EnsureWithDao(txDao, planRecord.Id, artificialMemberID)
-			return err
+			before, err := memberclaim.FindForArtificialMemberWithDao(txDao, planRecord.Id, artificialMemberID, now)
+			if err != nil {
+				return err
+			}
+
+			if err := action(txDao, planRecord.Id, artificialMemberID, now); err != nil {
+				return err
+			}
+
+			after, err := memberclaim.FindForArtificialMemberWithDao(txDao, planRecord.Id, artificialMemberID, now)
+			if err != nil {
+				return err
+			}
+
+			// An unchanged link means the owner asked for one that already existed.
+			if before != nil && after != nil && before.Id == after.Id {
+				return nil
+			}
+
+			target := after
+			if target == nil {
+				target = before
+			}
+			targetID := ""
+			if target != nil {
+				targetID = target.Id
+			}
+
+			return audit.RecordWithDao(txDao, audit.Event{
+				PlanID:   planRecord.Id,
+				ActorID:  session.UserID,
+				Action:   auditAction,
+				Target:   memberclaim.CollectionName,
+				TargetID: targetID,
+				UserID:   artificialMemberID,
+				Before:   audit.Snapshot(before),
+				After:    audit.Snapshot(after),
+			})
 		})
 		if err != nil {
 			if errors.Is(err, errClaimLinkUnavailable) {
@@ -68,14 +129,16 @@ func HandleCreateMemberClaimLink(app *pocketbase.PocketBase) echo.HandlerFunc {
 }
 
 // HandleClaimMemberPage renders the public claim page for a member-takeover link.
-func HandleClaimMemberPage(app *pocketbase.PocketBase) echo.HandlerFunc {
+func HandleClaimMemberPage(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		token := strings.TrimSpace(c.PathParam("token"))
 		errorMessage := strings.TrimSpace(c.QueryParam("error"))
 
-		info, err := memberclaim.Lookup(app, token)
+		info, err := memberclaim.Lookup(app, token, now)
 		if err != nil {
-			if !errors.Is(err, memberclaim.ErrClaimLinkNotFound) && !errors.Is(err, memberclaim.ErrArtificialMemberUnavailable) {
+			if memberclaim.ErrorMessage(err) == "" {
 				return err
 			}
 
@@ -124,15 +187,17 @@ func HandleClaimMemberPage(app *pocketbase.PocketBase) echo.HandlerFunc {
 }
 
 // HandleClaimMember completes a public artificial-member claim for the signed-in user.
-func HandleClaimMember(app *pocketbase.PocketBase) echo.HandlerFunc {
+func HandleClaimMember(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		token := strings.TrimSpace(c.PathParam("token"))
 		session, ok := sessionutil.Current(c)
 		if !ok || !session.IsAuthenticated {
 			return c.Redirect(http.StatusSeeOther, loginPathWithClaim(token))
 		}
 
-		result, err := memberclaim.Claim(app, token, session.UserID)
+		result, err := memberclaim.Claim(app, token, session.UserID, now)
 		if err != nil {
 			if message := memberclaim.ErrorMessage(err); message != "" {
 				return c.Redirect(http.StatusSeeOther, claimPathWithError(token, message))
@@ -0,0 +1,69 @@
+package memberships
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/planutil"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+// HandleGrantGracePeriod waives a member's charges for a number of months.
+func HandleGrantGracePeriod(app *pocketbase.PocketBase) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+		memberID := strings.TrimSpace(c.FormValue("user_id"))
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil {
+			return err
+		}
+		if planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		if !planutil.IsOwner(planRecord, session.UserID) || memberID == "" {
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+		}
+
+		values := url.Values{}
+		start, err := time.Parse("2006-01", c.FormValue("start_month"))
+		if err != nil {
+			values.Set("error", "Choose the first month of the grace period.")
+			return c.Redirect(http.StatusSeeOther, pathWithQuery("/"+joinCode, values))
+		}
+
+		months, err := strconv.Atoi(strings.TrimSpace(c.FormValue("months")))
+		if err != nil {
+			months = 0
+		}
+
+		err = recordMembershipChangeWithDao(app.Dao(), planRecord.Id, memberID, session.UserID, audit.ActionGraceGranted, func(txDao *daos.Dao) error {
+			return billing.GrantGracePeriodWithDao(txDao, planRecord.Id, memberID, start, months)
+		})
+		if errors.Is(err, billing.ErrInvalidFreeMonths) {
+			values.Set("error", fmt.Sprintf("A grace period must be between 1 and %d months.", billing.MaxFreeMonths))
+			return c.Redirect(http.StatusSeeOther, pathWithQuery("/"+joinCode, values))
+		}
+		if err != nil {
+			return err
+		}
+
+		values.Set("success", "Grace period granted.")
+		return c.Redirect(http.StatusSeeOther, pathWithQuery("/"+joinCode, values))
+	}
+}
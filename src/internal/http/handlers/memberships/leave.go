@@ -3,8 +3,8 @@ package memberships
 import (
 	"errors"
 	"net/http"
-	"time"
 
+	"familyplan/src/internal/audit"
 	"familyplan/src/internal/billing"
 	"familyplan/src/internal/planutil"
 
@@ -14,8 +14,10 @@ import (
 )
 
 // HandleLeavePlan either leaves immediately or marks leave_requested.
-func HandleLeavePlan(app *pocketbase.PocketBase) echo.HandlerFunc {
+func HandleLeavePlan(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		session, err := sessionOrRedirect(c)
 		if err != nil {
 			return err
@@ -44,19 +46,34 @@ func HandleLeavePlan(app *pocketbase.PocketBase) echo.HandlerFunc {
 				return membershipNotFound
 			}
 
-			balance, err := billing.CalculateMemberBalanceWithDao(txDao, planRecord.Id, session.UserID)
+			balance, err := billing.CalculateMemberBalanceWithDao(txDao, planRecord.Id, session.UserID, now)
 			if err != nil {
 				return err
 			}
 
-			if balance >= 0 {
-				existingMembership.Set("date_ended", time.Now())
+			before := audit.Snapshot(existingMembership)
+			action := audit.ActionMemberLeft
+			if !balance.IsNegative() {
+				existingMembership.Set("date_ended", now)
 				existingMembership.Set("leave_requested", false)
 			} else {
 				existingMembership.Set("leave_requested", true)
+				action = audit.ActionLeaveRequested
+			}
+
+			if err := txDao.SaveRecord(existingMembership); err != nil {
+				return err
 			}
 
-			return txDao.SaveRecord(existingMembership)
+			return audit.RecordWithDao(txDao, audit.Event{
+				PlanID:   planRecord.Id,
+				ActorID:  session.UserID,
+				Action:   action,
+				Target:   "memberships",
+				TargetID: existingMembership.Id,
+				Before:   before,
+				After:    audit.Snapshot(existingMembership),
+			})
 		})
 		if err != nil {
 			if errors.Is(err, membershipNotFound) {
@@ -1,18 +1,27 @@
 package memberships
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
-	"time"
+	"net/url"
+	"strings"
 
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/money"
 	"familyplan/src/internal/planutil"
 
 	"github.com/labstack/echo/v5"
 	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
 )
 
 // HandleRemoveMember ends another member's membership.
-func HandleRemoveMember(app *pocketbase.PocketBase) echo.HandlerFunc {
+func HandleRemoveMember(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		session, err := sessionOrRedirect(c)
 		if err != nil {
 			return err
@@ -40,11 +49,125 @@ func HandleRemoveMember(app *pocketbase.PocketBase) echo.HandlerFunc {
 			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
 		}
 
-		membership.Set("date_ended", time.Now())
-		if err := app.Dao().SaveRecord(membership); err != nil {
+		balance, err := billing.CalculateMemberBalance(app, planRecord.Id, memberID, now)
+		if err != nil {
+			return err
+		}
+
+		err = recordMembershipChangeWithDao(app.Dao(), planRecord.Id, memberID, session.UserID, audit.ActionMemberRemoved, func(txDao *daos.Dao) error {
+			membership.Set("date_ended", now)
+			return txDao.SaveRecord(membership)
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.Redirect(http.StatusSeeOther, pathWithQuery("/"+joinCode, removalNotice(balance)))
+	}
+}
+
+// HandleReinstateMember restores a removed member, keeping their outstanding balance.
+func HandleReinstateMember(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		now := clock.Now()
+
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+		memberID := strings.TrimSpace(c.FormValue("user_id"))
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil {
+			return err
+		}
+		if planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		if !planutil.IsOwner(planRecord, session.UserID) || memberID == "" {
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+		}
+
+		err = recordMembershipChangeWithDao(app.Dao(), planRecord.Id, memberID, session.UserID, audit.ActionMemberReinstated, func(txDao *daos.Dao) error {
+			return billing.ReinstateMembershipWithDao(txDao, planRecord.Id, memberID, now)
+		})
+		if err != nil {
+			if errors.Is(err, billing.ErrMembershipNotEnded) {
+				return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+			}
+			return err
+		}
+
+		values := url.Values{}
+		values.Set("success", "Member reinstated.")
+		return c.Redirect(http.StatusSeeOther, pathWithQuery("/"+joinCode, values))
+	}
+}
+
+// HandleWriteOffMember forgives a former member's outstanding balance.
+func HandleWriteOffMember(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		now := clock.Now()
+
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+		memberID := strings.TrimSpace(c.FormValue("user_id"))
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil {
+			return err
+		}
+		if planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		if !planutil.IsOwner(planRecord, session.UserID) || memberID == "" {
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+		}
+
+		var writtenOff money.Amount
+		err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+			writtenOff, err = billing.WriteOffBalanceWithDao(txDao, planRecord.Id, memberID, "Balance written off by owner", now)
+			if err != nil {
+				return err
+			}
+
+			return audit.RecordWithDao(txDao, audit.Event{
+				PlanID:  planRecord.Id,
+				ActorID: session.UserID,
+				Action:  audit.ActionBalanceWrittenOff,
+				Target:  "memberships",
+				UserID:  memberID,
+				After:   map[string]any{"written_off": writtenOff.String()},
+			})
+		})
+		if err != nil {
+			if errors.Is(err, billing.ErrMembershipNotEnded) || errors.Is(err, billing.ErrNothingToWriteOff) {
+				return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+			}
 			return err
 		}
 
-		return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+		values := url.Values{}
+		values.Set("success", fmt.Sprintf("Wrote off %s.", writtenOff))
+		return c.Redirect(http.StatusSeeOther, pathWithQuery("/"+joinCode, values))
+	}
+}
+
+func removalNotice(balance money.Amount) url.Values {
+	values := url.Values{}
+
+	switch {
+	case balance.IsNegative():
+		values.Set("notice", fmt.Sprintf("Member removed while still owing %s. Their balance is kept under Former Members.", balance.Neg()))
+	case balance.IsPositive():
+		values.Set("notice", fmt.Sprintf("Member removed with %s in unused credit. Their balance is kept under Former Members.", balance))
 	}
+
+	return values
 }
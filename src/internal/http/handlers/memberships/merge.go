@@ -0,0 +1,87 @@
+package memberships
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/memberclaim"
+	"familyplan/src/internal/planutil"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+// HandleMergeArtificialMember folds an artificial member into another existing member.
+func HandleMergeArtificialMember(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		now := clock.Now()
+
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+		artificialMemberID := strings.TrimSpace(c.FormValue("artificial_member_id"))
+		targetMemberID := strings.TrimSpace(c.FormValue("target_member_id"))
+
+		if artificialMemberID == "" || targetMemberID == "" {
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+		}
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil {
+			return err
+		}
+		if planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		if !planutil.IsOwner(planRecord, session.UserID) {
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+		}
+
+		err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+			before, err := planutil.FindMembershipWithDao(txDao, planRecord.Id, artificialMemberID)
+			if err != nil {
+				return err
+			}
+
+			if err := memberclaim.MergeArtificialMembership(txDao, planRecord, artificialMemberID, targetMemberID, now); err != nil {
+				return err
+			}
+
+			after, err := planutil.FindMembershipWithDao(txDao, planRecord.Id, targetMemberID)
+			if err != nil {
+				return err
+			}
+
+			return audit.RecordWithDao(txDao, audit.Event{
+				PlanID:   planRecord.Id,
+				ActorID:  session.UserID,
+				Action:   audit.ActionMemberMerged,
+				Target:   "memberships",
+				TargetID: after.Id,
+				UserID:   targetMemberID,
+				Before:   audit.Snapshot(before),
+				After:    audit.Snapshot(after),
+			})
+		})
+		if err != nil {
+			if errors.Is(err, memberclaim.ErrArtificialMemberUnavailable) || errors.Is(err, memberclaim.ErrMergeTargetUnavailable) {
+				values := url.Values{}
+				values.Set("error", "Those members can't be merged.")
+				return c.Redirect(http.StatusSeeOther, pathWithQuery("/"+joinCode, values))
+			}
+			return err
+		}
+
+		values := url.Values{}
+		values.Set("success", "Members merged.")
+		return c.Redirect(http.StatusSeeOther, pathWithQuery("/"+joinCode, values))
+	}
+}
@@ -0,0 +1,116 @@
+package memberships
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/planutil"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+// HandlePauseMember puts a member's seat on hold so they are not charged while away.
+func HandlePauseMember(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		now := clock.Now()
+
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+		memberID := strings.TrimSpace(c.FormValue("user_id"))
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil {
+			return err
+		}
+		if planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		if !planutil.IsOwner(planRecord, session.UserID) || memberID == "" || memberID == planutil.OwnerID(planRecord) {
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+		}
+
+		values := url.Values{}
+		start, err := time.Parse("2006-01", c.FormValue("start_month"))
+		if err != nil {
+			values.Set("error", "Choose the first month of the pause.")
+			return c.Redirect(http.StatusSeeOther, pathWithQuery("/"+joinCode, values))
+		}
+
+		// Leaving the last month empty keeps the pause open until the member is resumed.
+		var end time.Time
+		if endValue := strings.TrimSpace(c.FormValue("end_month")); endValue != "" {
+			end, err = time.Parse("2006-01", endValue)
+			if err != nil {
+				values.Set("error", "The last paused month is not a valid month.")
+				return c.Redirect(http.StatusSeeOther, pathWithQuery("/"+joinCode, values))
+			}
+		}
+
+		err = recordMembershipChangeWithDao(app.Dao(), planRecord.Id, memberID, session.UserID, audit.ActionMemberPaused, func(txDao *daos.Dao) error {
+			return billing.PauseMembershipWithDao(txDao, planRecord.Id, memberID, start, end, now)
+		})
+		switch {
+		case errors.Is(err, billing.ErrAlreadyPaused):
+			values.Set("error", "This member already has a pause. Resume them first.")
+		case errors.Is(err, billing.ErrInvalidPause):
+			values.Set("error", "A pause must end on or after its first month.")
+		case err != nil:
+			return err
+		default:
+			values.Set("success", "Membership paused.")
+		}
+
+		return c.Redirect(http.StatusSeeOther, pathWithQuery("/"+joinCode, values))
+	}
+}
+
+// HandleResumeMember ends a member's pause so billing restarts this month.
+func HandleResumeMember(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		now := clock.Now()
+
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+		memberID := strings.TrimSpace(c.FormValue("user_id"))
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil {
+			return err
+		}
+		if planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		if !planutil.IsOwner(planRecord, session.UserID) || memberID == "" {
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+		}
+
+		err = recordMembershipChangeWithDao(app.Dao(), planRecord.Id, memberID, session.UserID, audit.ActionMemberResumed, func(txDao *daos.Dao) error {
+			return billing.ResumeMembershipWithDao(txDao, planRecord.Id, memberID, now)
+		})
+		if err != nil {
+			if errors.Is(err, billing.ErrNotPaused) {
+				return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+			}
+			return err
+		}
+
+		values := url.Values{}
+		values.Set("success", "Membership resumed.")
+		return c.Redirect(http.StatusSeeOther, pathWithQuery("/"+joinCode, values))
+	}
+}
@@ -3,10 +3,12 @@ package memberships
 import (
 	"net/http"
 
+	"familyplan/src/internal/audit"
 	"familyplan/src/internal/planutil"
 
 	"github.com/labstack/echo/v5"
 	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
 	pbmodels "github.com/pocketbase/pocketbase/models"
 )
 
@@ -32,15 +34,29 @@ func HandleRequestJoin(app *pocketbase.PocketBase) echo.HandlerFunc {
 			return err
 		}
 		if existingRequest == nil {
-			joinRequestsCollection, err := app.Dao().FindCollectionByNameOrId("join_requests")
-			if err != nil {
-				return err
-			}
+			err := app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+				joinRequestsCollection, err := txDao.FindCollectionByNameOrId("join_requests")
+				if err != nil {
+					return err
+				}
 
-			newRequest := pbmodels.NewRecord(joinRequestsCollection)
-			newRequest.Set("plan_id", planRecord.Id)
-			newRequest.Set("user_id", session.UserID)
-			if err := app.Dao().SaveRecord(newRequest); err != nil {
+				newRequest := pbmodels.NewRecord(joinRequestsCollection)
+				newRequest.Set("plan_id", planRecord.Id)
+				newRequest.Set("user_id", session.UserID)
+				if err := txDao.SaveRecord(newRequest); err != nil {
+					return err
+				}
+
+				return audit.RecordWithDao(txDao, audit.Event{
+					PlanID:   planRecord.Id,
+					ActorID:  session.UserID,
+					Action:   audit.ActionJoinRequested,
+					Target:   "join_requests",
+					TargetID: newRequest.Id,
+					After:    audit.Snapshot(newRequest),
+				})
+			})
+			if err != nil {
 				return err
 			}
 		}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"net/http"
 
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
 	"familyplan/src/internal/memberclaim"
 	"familyplan/src/internal/planutil"
 
@@ -13,8 +15,10 @@ import (
 )
 
 // HandleTransferMembership converts an artificial member into a real user membership.
-func HandleTransferMembership(app *pocketbase.PocketBase) echo.HandlerFunc {
+func HandleTransferMembership(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		session, err := sessionOrRedirect(c)
 		if err != nil {
 			return err
@@ -73,14 +77,33 @@ func HandleTransferMembership(app *pocketbase.PocketBase) echo.HandlerFunc {
 				return missingTransferPrerequisite
 			}
 
-			if err := memberclaim.TransferArtificialMembership(txDao, planRecord, artificialMemberID, realUserID); err != nil {
+			before, err := planutil.FindMembershipWithDao(txDao, planRecord.Id, artificialMemberID)
+			if err != nil {
+				return err
+			}
+
+			if err := memberclaim.TransferArtificialMembership(txDao, planRecord, artificialMemberID, realUserID, now); err != nil {
 				if errors.Is(err, memberclaim.ErrArtificialMemberUnavailable) || errors.Is(err, memberclaim.ErrAlreadyMember) {
 					return missingTransferPrerequisite
 				}
 				return err
 			}
 
-			return nil
+			after, err := planutil.FindMembershipWithDao(txDao, planRecord.Id, realUserID)
+			if err != nil {
+				return err
+			}
+
+			return audit.RecordWithDao(txDao, audit.Event{
+				PlanID:   planRecord.Id,
+				ActorID:  session.UserID,
+				Action:   audit.ActionMemberTransferred,
+				Target:   "memberships",
+				TargetID: after.Id,
+				UserID:   realUserID,
+				Before:   audit.Snapshot(before),
+				After:    audit.Snapshot(after),
+			})
 		})
 		if err != nil {
 			if errors.Is(err, missingTransferPrerequisite) {
@@ -0,0 +1,177 @@
+package payments
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/planutil"
+	"familyplan/src/internal/view"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+// HandleAddAdjustment records an owner-entered credit or charge for one member or the whole plan.
+func HandleAddAdjustment(app *pocketbase.PocketBase) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil {
+			return err
+		}
+		if planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		if !planutil.IsOwner(planRecord, session.UserID) {
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+		}
+
+		// An empty user_id applies the adjustment to every member active that month.
+		userID := strings.TrimSpace(c.FormValue("user_id"))
+		if userID != "" {
+			membership, err := planutil.FindMembership(app, planRecord.Id, userID)
+			if err != nil {
+				return err
+			}
+			if membership == nil {
+				return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+			}
+		}
+
+		amount, err := money.ParseInput(c.FormValue("amount"), planutil.Currency(planRecord), view.RequestLocale(c))
+		if err != nil || !amount.IsPositive() {
+			return redirectWithError(c, joinCode, "Adjustment amount must be a positive number.")
+		}
+		if c.FormValue("direction") == "charge" {
+			amount = amount.Neg()
+		}
+
+		forMonth, err := time.Parse("2006-01", c.FormValue("for_month"))
+		if err != nil {
+			return redirectWithError(c, joinCode, "Choose the month the adjustment applies to.")
+		}
+
+		err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+			adjustment, err := billing.CreateAdjustmentWithDao(txDao, billing.Adjustment{
+				PlanID:    planRecord.Id,
+				UserID:    userID,
+				Amount:    amount,
+				Reason:    c.FormValue("reason"),
+				ForMonth:  forMonth,
+				CreatedBy: session.UserID,
+			})
+			if err != nil {
+				return err
+			}
+
+			return audit.RecordWithDao(txDao, audit.Event{
+				PlanID:   planRecord.Id,
+				ActorID:  session.UserID,
+				Action:   audit.ActionAdjustmentAdded,
+				Target:   billing.AdjustmentsCollection,
+				TargetID: adjustment.Id,
+				After:    audit.Snapshot(adjustment),
+			})
+		})
+		if errors.Is(err, billing.ErrInvalidAdjustment) {
+			return redirectWithError(c, joinCode, "Adjustments need an amount and a reason.")
+		}
+		if err != nil {
+			return err
+		}
+
+		return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+	}
+}
+
+// HandleDeleteAdjustment removes an adjustment from the plan.
+func HandleDeleteAdjustment(app *pocketbase.PocketBase) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil {
+			return err
+		}
+		if planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		if !planutil.IsOwner(planRecord, session.UserID) {
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+		}
+
+		adjustmentID := c.FormValue("adjustment_id")
+		err = audit.TrackWithDao(app.Dao(), adjustmentEvent(planRecord.Id, session.UserID, audit.ActionAdjustmentDeleted, adjustmentID), func(txDao *daos.Dao) error {
+			return billing.DeleteAdjustmentWithDao(txDao, planRecord.Id, adjustmentID)
+		})
+		if errors.Is(err, billing.ErrGeneratedAdjustment) {
+			return redirectWithError(c, joinCode, "Late fees are added automatically. Waive them instead of removing them.")
+		}
+		if err != nil && !errors.Is(err, billing.ErrAdjustmentNotFound) {
+			return err
+		}
+
+		return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+	}
+}
+
+// HandleWaiveAdjustment waives a late fee so it no longer counts toward the member's balance.
+func HandleWaiveAdjustment(app *pocketbase.PocketBase) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil {
+			return err
+		}
+		if planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		if !planutil.IsOwner(planRecord, session.UserID) {
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+		}
+
+		adjustmentID := c.FormValue("adjustment_id")
+		err = audit.TrackWithDao(app.Dao(), adjustmentEvent(planRecord.Id, session.UserID, audit.ActionAdjustmentWaived, adjustmentID), func(txDao *daos.Dao) error {
+			return billing.WaiveAdjustmentWithDao(txDao, planRecord.Id, adjustmentID)
+		})
+		if err != nil && !errors.Is(err, billing.ErrAdjustmentNotFound) {
+			return err
+		}
+
+		return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+	}
+}
+
+// adjustmentEvent describes a change to an existing adjustment.
+func adjustmentEvent(planID, actorID, action, adjustmentID string) audit.Event {
+	return audit.Event{
+		PlanID:   planID,
+		ActorID:  actorID,
+		Action:   action,
+		Target:   billing.AdjustmentsCollection,
+		TargetID: adjustmentID,
+	}
+}
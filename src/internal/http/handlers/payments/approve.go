@@ -3,8 +3,8 @@ package payments
 import (
 	"errors"
 	"net/http"
-	"time"
 
+	"familyplan/src/internal/audit"
 	"familyplan/src/internal/billing"
 	"familyplan/src/internal/planutil"
 
@@ -14,8 +14,10 @@ import (
 )
 
 // HandleApprovePayment approves a pending payment.
-func HandleApprovePayment(app *pocketbase.PocketBase) echo.HandlerFunc {
+func HandleApprovePayment(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		session, err := sessionOrRedirect(c)
 		if err != nil {
 			return err
@@ -51,12 +53,17 @@ func HandleApprovePayment(app *pocketbase.PocketBase) echo.HandlerFunc {
 				return paymentNotApprovable
 			}
 
+			before := audit.Snapshot(payment)
 			payment.Set("status", "approved")
 			if err := txDao.SaveRecord(payment); err != nil {
 				return err
 			}
 
-			return billing.EndMembershipIfSettledWithDao(txDao, planRecord.Id, payment.GetString("user_id"), time.Now())
+			if err := audit.RecordWithDao(txDao, paymentEvent(payment, session.UserID, audit.ActionPaymentApproved, before)); err != nil {
+				return err
+			}
+
+			return billing.EndMembershipIfSettledWithDao(txDao, planRecord.Id, payment.GetString("user_id"), now)
 		})
 		if err != nil {
 			if errors.Is(err, paymentNotApprovable) {
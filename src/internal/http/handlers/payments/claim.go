@@ -2,19 +2,23 @@ package payments
 
 import (
 	"net/http"
-	"time"
 
-	"familyplan/src/internal/money"
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
 	"familyplan/src/internal/planutil"
+	"familyplan/src/internal/view"
 
 	"github.com/labstack/echo/v5"
 	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
 	pbmodels "github.com/pocketbase/pocketbase/models"
 )
 
 // HandleClaimPayment submits a pending payment claim.
-func HandleClaimPayment(app *pocketbase.PocketBase) echo.HandlerFunc {
+func HandleClaimPayment(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		session, err := sessionOrRedirect(c)
 		if err != nil {
 			return err
@@ -37,11 +41,33 @@ func HandleClaimPayment(app *pocketbase.PocketBase) echo.HandlerFunc {
 			return c.Redirect(http.StatusSeeOther, "/family-plans")
 		}
 
-		amount, err := money.ParseAmount(c.FormValue("amount"))
-		if err != nil || amount <= 0 {
+		payerID, beneficiaryID, ok := resolveClaimParties(session.UserID, c.FormValue("payer_id"), c.FormValue("beneficiary_id"))
+		if !ok {
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+		}
+
+		beneficiaryMembership, err := planutil.FindMembership(app, planRecord.Id, beneficiaryID)
+		if err != nil {
+			return err
+		}
+		if beneficiaryMembership == nil || !beneficiaryMembership.GetDateTime("date_ended").IsZero() {
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+		}
+
+		payerAllowed, err := isPlanParticipant(app, planRecord, payerID)
+		if err != nil {
+			return err
+		}
+		if !payerAllowed {
 			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
 		}
 
+		// Claimants cannot pick their own exchange rate; only the plan's stored rates apply.
+		amounts, err := resolvePaymentAmounts(app.Dao(), planRecord, view.RequestLocale(c), c.FormValue("amount"), c.FormValue("currency"), "")
+		if err != nil {
+			return redirectWithError(c, joinCode, paymentAmountError(err, planRecord, c.FormValue("currency")))
+		}
+
 		paymentsCollection, err := app.Dao().FindCollectionByNameOrId("payments")
 		if err != nil {
 			return err
@@ -52,19 +78,34 @@ func HandleClaimPayment(app *pocketbase.PocketBase) echo.HandlerFunc {
 			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
 		}
 
+		method, reference, message := readPaymentMethod(c, planRecord)
+		if message != "" {
+			return redirectWithError(c, joinCode, message)
+		}
+
 		payment := pbmodels.NewRecord(paymentsCollection)
 		payment.Set("plan_id", planRecord.Id)
-		payment.Set("user_id", session.UserID)
-		payment.Set("amount", amount)
-		payment.Set("date", time.Now())
+		payment.Set("user_id", beneficiaryID)
+		payment.Set("payer_id", payerID)
+		amounts.apply(payment)
+		payment.Set("date", now)
 		payment.Set("status", "pending")
 		payment.Set("notes", notes)
+		payment.Set("method", method)
+		payment.Set("reference", reference)
 
 		if forMonth := parseForMonth(c.FormValue("for_month")); forMonth != "" {
 			payment.Set("for_month", forMonth)
 		}
 
-		if err := app.Dao().SaveRecord(payment); err != nil {
+		err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+			if err := txDao.SaveRecord(payment); err != nil {
+				return err
+			}
+
+			return audit.RecordWithDao(txDao, paymentEvent(payment, session.UserID, audit.ActionPaymentClaimed, nil))
+		})
+		if err != nil {
 			return err
 		}
 
@@ -1,10 +1,26 @@
 package payments
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
+
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/fxrates"
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/planutil"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+	"golang.org/x/text/language"
 )
 
 const maxPaymentNotesLength = 500
@@ -22,6 +38,95 @@ func parseForMonth(value string) string {
 	return forMonthDate.Format("2006-01-02")
 }
 
+// paymentAmounts is what was handed over and what it is worth in the plan currency.
+type paymentAmounts struct {
+	Original money.Amount
+	Rate     float64
+	Credited money.Amount
+}
+
+// resolvePaymentAmounts reads a positive amount from a number input and converts it into the plan currency.
+// Browsers always submit number inputs with a "." decimal separator, whatever the page locale.
+// A manual rate, when given, is used instead of the stored exchange rates.
+func resolvePaymentAmounts(dao *daos.Dao, plan *pbmodels.Record, locale language.Tag, amountValue, currencyValue, rateValue string) (paymentAmounts, error) {
+	planCurrency := planutil.Currency(plan)
+
+	paidCurrency := planCurrency
+	if strings.TrimSpace(currencyValue) != "" {
+		normalized, err := money.NormalizeCurrency(currencyValue)
+		if err != nil {
+			return paymentAmounts{}, err
+		}
+		paidCurrency = normalized
+	}
+
+	original, err := money.ParseInput(amountValue, paidCurrency, locale)
+	if err != nil {
+		return paymentAmounts{}, err
+	}
+	if !original.IsPositive() {
+		return paymentAmounts{}, fmt.Errorf("amount must be positive")
+	}
+
+	if paidCurrency == planCurrency {
+		return paymentAmounts{Original: original, Rate: 1, Credited: original}, nil
+	}
+
+	var rate float64
+	if strings.TrimSpace(rateValue) != "" {
+		manual, err := strconv.ParseFloat(strings.TrimSpace(rateValue), 64)
+		if err != nil || manual <= 0 {
+			return paymentAmounts{}, fxrates.ErrInvalidRate
+		}
+		rate = manual
+	} else {
+		rate, err = fxrates.LookupWithDao(dao, plan.Id, paidCurrency, planCurrency)
+		if err != nil {
+			return paymentAmounts{}, err
+		}
+	}
+
+	credited := original.Convert(rate, planCurrency)
+	if !credited.IsPositive() {
+		return paymentAmounts{}, fmt.Errorf("amount must be positive")
+	}
+
+	return paymentAmounts{Original: original, Rate: rate, Credited: credited}, nil
+}
+
+// apply stores the credited amount and, for a foreign-currency payment, what was originally paid.
+func (amounts paymentAmounts) apply(payment *pbmodels.Record) {
+	payment.Set("amount", amounts.Credited.Minor)
+	if amounts.Original.Currency == amounts.Credited.Currency {
+		return
+	}
+
+	payment.Set("original_amount", amounts.Original.Minor)
+	payment.Set("original_currency", amounts.Original.Currency)
+	payment.Set("exchange_rate", amounts.Rate)
+}
+
+// paymentAmountError explains a rejected amount, or returns an empty string for a plain redirect.
+func paymentAmountError(err error, plan *pbmodels.Record, currencyValue string) string {
+	switch {
+	case errors.Is(err, fxrates.ErrRateNotFound):
+		return fmt.Sprintf("No exchange rate from %s to %s is set up yet. Ask the plan owner to add one.", strings.ToUpper(strings.TrimSpace(currencyValue)), planutil.Currency(plan))
+	case errors.Is(err, fxrates.ErrInvalidRate):
+		return "Exchange rate must be a positive number."
+	default:
+		return ""
+	}
+}
+
+func redirectWithError(c echo.Context, joinCode, message string) error {
+	path := "/" + joinCode
+	if message != "" {
+		path += "?" + url.Values{"error": {message}}.Encode()
+	}
+
+	return c.Redirect(http.StatusSeeOther, path)
+}
+
 func normalizeNotes(value string) (string, error) {
 	notes := strings.TrimSpace(value)
 	if utf8.RuneCountInString(notes) > maxPaymentNotesLength {
@@ -30,3 +135,68 @@ func normalizeNotes(value string) (string, error) {
 
 	return notes, nil
 }
+
+// readPaymentMethod reads the method and reference submitted with a payment.
+// It returns a message for the payer when either is not acceptable.
+func readPaymentMethod(c echo.Context, plan *pbmodels.Record) (string, string, string) {
+	method := strings.TrimSpace(c.FormValue("method"))
+	switch err := billing.ValidatePaymentMethod(plan, method); {
+	case errors.Is(err, billing.ErrPaymentMethodNotAccepted):
+		return "", "", "This plan does not accept payments by " + billing.PaymentMethodLabel(method) + "."
+	case err != nil:
+		return "", "", "Choose how the payment was made."
+	}
+
+	reference, err := billing.NormalizePaymentReference(c.FormValue("reference"))
+	if err != nil {
+		return "", "", fmt.Sprintf("Payment references must be %d characters or fewer.", billing.MaxPaymentReferenceLength)
+	}
+
+	return method, reference, ""
+}
+
+// resolveClaimParties fills in the claimant for a missing payer or beneficiary.
+// A claimant may only report payments they made or payments made for them.
+func resolveClaimParties(claimantID, payerID, beneficiaryID string) (string, string, bool) {
+	payerID = strings.TrimSpace(payerID)
+	if payerID == "" {
+		payerID = claimantID
+	}
+
+	beneficiaryID = strings.TrimSpace(beneficiaryID)
+	if beneficiaryID == "" {
+		beneficiaryID = claimantID
+	}
+
+	if payerID != claimantID && beneficiaryID != claimantID {
+		return "", "", false
+	}
+
+	return payerID, beneficiaryID, true
+}
+
+func isPlanParticipant(app *pocketbase.PocketBase, planRecord *pbmodels.Record, userID string) (bool, error) {
+	if planutil.IsOwner(planRecord, userID) {
+		return true, nil
+	}
+
+	membership, err := planutil.FindMembership(app, planRecord.Id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return membership != nil, nil
+}
+
+// paymentEvent describes a change to a payment; before is empty for a new payment.
+func paymentEvent(payment *pbmodels.Record, actorID, action string, before map[string]any) audit.Event {
+	return audit.Event{
+		PlanID:   payment.GetString("plan_id"),
+		ActorID:  actorID,
+		Action:   action,
+		Target:   "payments",
+		TargetID: payment.Id,
+		Before:   before,
+		After:    audit.Snapshot(payment),
+	}
+}
@@ -1,8 +1,16 @@
 package payments
 
 import (
+	"errors"
 	"strings"
 	"testing"
+
+	"familyplan/src/internal/fxrates"
+	"familyplan/src/internal/money"
+
+	pbmodels "github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"golang.org/x/text/language"
 )
 
 func TestParseForMonth(t *testing.T) {
@@ -45,3 +53,72 @@ func TestNormalizeNotes(t *testing.T) {
 		t.Fatal("expected normalizeNotes to reject oversized notes")
 	}
 }
+
+func TestResolveClaimParties(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		payerID         string
+		beneficiaryID   string
+		wantPayer       string
+		wantBeneficiary string
+		wantOK          bool
+	}{
+		{name: "defaults to claimant", wantPayer: "me", wantBeneficiary: "me", wantOK: true},
+		{name: "partner paid for me", payerID: "partner", wantPayer: "partner", wantBeneficiary: "me", wantOK: true},
+		{name: "I paid for my kid", beneficiaryID: "kid", wantPayer: "me", wantBeneficiary: "kid", wantOK: true},
+		{name: "unrelated parties", payerID: "partner", beneficiaryID: "kid", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payer, beneficiary, ok := resolveClaimParties("me", tt.payerID, tt.beneficiaryID)
+			if ok != tt.wantOK || payer != tt.wantPayer || beneficiary != tt.wantBeneficiary {
+				t.Fatalf("resolveClaimParties() = (%q, %q, %v), want (%q, %q, %v)", payer, beneficiary, ok, tt.wantPayer, tt.wantBeneficiary, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestResolvePaymentAmounts(t *testing.T) {
+	t.Parallel()
+
+	collection := &pbmodels.Collection{
+		Schema: schema.NewSchema(&schema.SchemaField{Name: "currency", Type: schema.FieldTypeText}),
+	}
+	plan := pbmodels.NewRecord(collection)
+	plan.Set("currency", "EUR")
+
+	tests := []struct {
+		name         string
+		amount       string
+		currency     string
+		rate         string
+		wantCredited money.Amount
+		wantOriginal money.Amount
+		wantErr      error
+	}{
+		{name: "plan currency", amount: "12.50", wantCredited: money.New(1250, "EUR"), wantOriginal: money.New(1250, "EUR")},
+		{name: "manual rate", amount: "20", currency: "gbp", rate: "1.17", wantCredited: money.New(2340, "EUR"), wantOriginal: money.New(2000, "GBP")},
+		{name: "invalid manual rate", amount: "20", currency: "GBP", rate: "-1", wantErr: fxrates.ErrInvalidRate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolvePaymentAmounts(nil, plan, language.Und, tt.amount, tt.currency, tt.rate)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("resolvePaymentAmounts() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolvePaymentAmounts() returned error: %v", err)
+			}
+			if got.Credited != tt.wantCredited || got.Original != tt.wantOriginal {
+				t.Fatalf("resolvePaymentAmounts() = %+v, want credited %+v from %+v", got, tt.wantCredited, tt.wantOriginal)
+			}
+		})
+	}
+}
@@ -2,11 +2,12 @@ package payments
 
 import (
 	"net/http"
-	"time"
+	"strings"
 
+	"familyplan/src/internal/audit"
 	"familyplan/src/internal/billing"
-	"familyplan/src/internal/money"
 	"familyplan/src/internal/planutil"
+	"familyplan/src/internal/view"
 
 	"github.com/labstack/echo/v5"
 	"github.com/pocketbase/pocketbase"
@@ -15,8 +16,10 @@ import (
 )
 
 // HandleAddManualPayment adds an owner-entered approved payment.
-func HandleAddManualPayment(app *pocketbase.PocketBase) echo.HandlerFunc {
+func HandleAddManualPayment(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		session, err := sessionOrRedirect(c)
 		if err != nil {
 			return err
@@ -44,11 +47,29 @@ func HandleAddManualPayment(app *pocketbase.PocketBase) echo.HandlerFunc {
 			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
 		}
 
-		amount, err := money.ParseAmount(c.FormValue("amount"))
-		if err != nil || amount <= 0 {
+		payerID := strings.TrimSpace(c.FormValue("payer_id"))
+		if payerID == "" {
+			payerID = userID
+		}
+
+		payerAllowed, err := isPlanParticipant(app, planRecord, payerID)
+		if err != nil {
+			return err
+		}
+		if !payerAllowed {
 			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
 		}
 
+		amounts, err := resolvePaymentAmounts(app.Dao(), planRecord, view.RequestLocale(c), c.FormValue("amount"), c.FormValue("currency"), c.FormValue("exchange_rate"))
+		if err != nil {
+			return redirectWithError(c, joinCode, paymentAmountError(err, planRecord, c.FormValue("currency")))
+		}
+
+		method, reference, message := readPaymentMethod(c, planRecord)
+		if message != "" {
+			return redirectWithError(c, joinCode, message)
+		}
+
 		paymentsCollection, err := app.Dao().FindCollectionByNameOrId("payments")
 		if err != nil {
 			return err
@@ -57,14 +78,17 @@ func HandleAddManualPayment(app *pocketbase.PocketBase) echo.HandlerFunc {
 		payment := pbmodels.NewRecord(paymentsCollection)
 		payment.Set("plan_id", planRecord.Id)
 		payment.Set("user_id", userID)
-		payment.Set("amount", amount)
-		payment.Set("date", time.Now())
+		payment.Set("payer_id", payerID)
+		amounts.apply(payment)
+		payment.Set("date", now)
 		payment.Set("status", "approved")
 		notes, err := normalizeNotes(c.FormValue("notes"))
 		if err != nil {
 			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
 		}
 		payment.Set("notes", notes)
+		payment.Set("method", method)
+		payment.Set("reference", reference)
 
 		if forMonth := parseForMonth(c.FormValue("for_month")); forMonth != "" {
 			payment.Set("for_month", forMonth)
@@ -75,7 +99,11 @@ func HandleAddManualPayment(app *pocketbase.PocketBase) echo.HandlerFunc {
 				return err
 			}
 
-			return billing.EndMembershipIfSettledWithDao(txDao, planRecord.Id, userID, time.Now())
+			if err := audit.RecordWithDao(txDao, paymentEvent(payment, session.UserID, audit.ActionPaymentAdded, nil)); err != nil {
+				return err
+			}
+
+			return billing.EndMembershipIfSettledWithDao(txDao, planRecord.Id, userID, now)
 		})
 		if err != nil {
 			return err
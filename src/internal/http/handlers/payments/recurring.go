@@ -0,0 +1,192 @@
+package payments
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/planutil"
+	"familyplan/src/internal/view"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+// HandleAddRecurringClaim sets up a standing order that files the member's claim every month.
+func HandleAddRecurringClaim(app *pocketbase.PocketBase) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil {
+			return err
+		}
+		if planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		membership, err := planutil.FindMembership(app, planRecord.Id, session.UserID)
+		if err != nil {
+			return err
+		}
+		if membership == nil || !membership.GetDateTime("date_ended").IsZero() {
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+		}
+
+		amount, err := money.ParseInput(c.FormValue("amount"), planutil.Currency(planRecord), view.RequestLocale(c))
+		if err != nil {
+			return redirectWithError(c, joinCode, "Standing order amount must be a positive number.")
+		}
+
+		dayOfMonth, err := strconv.Atoi(strings.TrimSpace(c.FormValue("day_of_month")))
+		if err != nil {
+			return redirectWithError(c, joinCode, "Choose the day of the month your standing order pays.")
+		}
+
+		startMonth, err := time.Parse("2006-01", c.FormValue("start_month"))
+		if err != nil {
+			return redirectWithError(c, joinCode, "Choose the month your standing order starts.")
+		}
+
+		var endMonth time.Time
+		if value := strings.TrimSpace(c.FormValue("end_month")); value != "" {
+			endMonth, err = time.Parse("2006-01", value)
+			if err != nil {
+				return redirectWithError(c, joinCode, "Choose a valid last month, or leave it empty.")
+			}
+		}
+
+		method, _, message := readPaymentMethod(c, planRecord)
+		if message != "" {
+			return redirectWithError(c, joinCode, message)
+		}
+
+		err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+			order, err := billing.CreateRecurringClaimWithDao(txDao, billing.RecurringClaim{
+				PlanID:     planRecord.Id,
+				UserID:     session.UserID,
+				Amount:     amount,
+				Method:     method,
+				DayOfMonth: dayOfMonth,
+				StartMonth: startMonth,
+				EndMonth:   endMonth,
+			})
+			if err != nil {
+				return err
+			}
+
+			return audit.RecordWithDao(txDao, audit.Event{
+				PlanID:   planRecord.Id,
+				ActorID:  session.UserID,
+				Action:   audit.ActionStandingOrderCreated,
+				Target:   billing.RecurringClaimsCollection,
+				TargetID: order.Id,
+				After:    audit.Snapshot(order),
+			})
+		})
+		if errors.Is(err, billing.ErrInvalidRecurringClaim) {
+			return redirectWithError(c, joinCode, "Standing orders need a positive amount, a day between 1 and 28, and a last month no earlier than the first.")
+		}
+		if err != nil {
+			return err
+		}
+
+		return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+	}
+}
+
+// HandleCancelRecurringClaim stops one of the member's standing orders.
+func HandleCancelRecurringClaim(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		now := clock.Now()
+
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil {
+			return err
+		}
+		if planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		orderID := c.FormValue("recurring_claim_id")
+		event := audit.Event{
+			PlanID:   planRecord.Id,
+			ActorID:  session.UserID,
+			Action:   audit.ActionStandingOrderEnded,
+			Target:   billing.RecurringClaimsCollection,
+			TargetID: orderID,
+		}
+		err = audit.TrackWithDao(app.Dao(), event, func(txDao *daos.Dao) error {
+			return billing.CancelRecurringClaimWithDao(txDao, planRecord.Id, session.UserID, orderID, now)
+		})
+		if err != nil && !errors.Is(err, billing.ErrRecurringClaimNotFound) {
+			return err
+		}
+
+		return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+	}
+}
+
+// HandleTrustRecurringClaims lets the owner approve a member's standing order claims automatically.
+func HandleTrustRecurringClaims(app *pocketbase.PocketBase) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil {
+			return err
+		}
+		if planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		if !planutil.IsOwner(planRecord, session.UserID) {
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+		}
+
+		membership, err := planutil.FindMembership(app, planRecord.Id, c.FormValue("user_id"))
+		if err != nil {
+			return err
+		}
+		if membership == nil {
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+		}
+
+		trusted := c.FormValue("auto_approve") == "true"
+		event := audit.Event{
+			PlanID:   planRecord.Id,
+			ActorID:  session.UserID,
+			Action:   audit.ActionStandingOrderTrusted,
+			Target:   "memberships",
+			TargetID: membership.Id,
+		}
+		err = audit.TrackWithDao(app.Dao(), event, func(txDao *daos.Dao) error {
+			return billing.SetAutoApproveRecurringWithDao(txDao, planRecord.Id, membership.GetString("user_id"), trusted)
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.Redirect(http.StatusSeeOther, "/"+joinCode)
+	}
+}
@@ -3,10 +3,12 @@ package payments
 import (
 	"net/http"
 
+	"familyplan/src/internal/audit"
 	"familyplan/src/internal/planutil"
 
 	"github.com/labstack/echo/v5"
 	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
 )
 
 // HandleRejectPayment rejects a pending payment.
@@ -46,8 +48,16 @@ func HandleRejectPayment(app *pocketbase.PocketBase) echo.HandlerFunc {
 			return c.Redirect(http.StatusSeeOther, "/"+joinCode)
 		}
 
+		before := audit.Snapshot(payment)
 		payment.Set("status", "rejected")
-		if err := app.Dao().SaveRecord(payment); err != nil {
+		err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+			if err := txDao.SaveRecord(payment); err != nil {
+				return err
+			}
+
+			return audit.RecordWithDao(txDao, paymentEvent(payment, session.UserID, audit.ActionPaymentRejected, before))
+		})
+		if err != nil {
 			return err
 		}
 
@@ -3,19 +3,47 @@ package plans
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/fxrates"
+	"familyplan/src/internal/memberclaim"
 	"familyplan/src/internal/money"
 	"familyplan/src/internal/planutil"
 
 	"github.com/labstack/echo/v5"
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
 )
 
-// HandleDeletePlan deletes a plan and its related records.
-func HandleDeletePlan(app *pocketbase.PocketBase) echo.HandlerFunc {
+// purgeRetention is how long a plan stays archived before its owner can purge it.
+const purgeRetention = 30 * 24 * time.Hour
+
+// planRecordCollections hold the records that belong to a plan and go with it when it is purged.
+// Payments, adjustments and memberships come before allocations, which are rebuilt from them.
+var planRecordCollections = []string{
+	"payments",
+	billing.AdjustmentsCollection,
+	billing.RecurringClaimsCollection,
+	"join_requests",
+	memberclaim.CollectionName,
+	memberclaim.AttemptsCollectionName,
+	fxrates.CollectionName,
+	"memberships",
+	billing.AllocationsCollection,
+	audit.CollectionName,
+}
+
+// HandleArchivePlan makes a plan read-only and stops billing it. Nothing is deleted.
+func HandleArchivePlan(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		session, err := sessionOrRedirect(c)
 		if err != nil {
 			return err
@@ -31,104 +59,129 @@ func HandleDeletePlan(app *pocketbase.PocketBase) echo.HandlerFunc {
 			return redirectToPlan(c, joinCode)
 		}
 
-		err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
-			membershipsCollection, err := txDao.FindCollectionByNameOrId("memberships")
-			if err != nil {
-				return err
-			}
+		err = audit.TrackWithDao(app.Dao(), planEvent(planRecord, session.UserID, audit.ActionPlanArchived), func(txDao *daos.Dao) error {
+			return billing.ArchivePlanWithDao(txDao, planRecord.Id, now)
+		})
+		if err != nil {
+			return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/%s?error=Failed+to+archive+plan", joinCode))
+		}
 
-			membershipFilter, err := planutil.BuildEqualsFilter(
-				planutil.FilterTerm{Field: "plan_id", Value: planRecord.Id},
-			)
-			if err != nil {
-				return err
-			}
+		return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/%s?success=Plan+archived.+It+is+read-only+and+no+longer+billed.", joinCode))
+	}
+}
 
-			memberships, err := txDao.FindRecordsByFilter(
-				membershipsCollection.Id,
-				membershipFilter.Expression,
-				"",
-				-1,
-				0,
-				membershipFilter.Params,
-			)
-			if err == nil {
-				for _, membership := range memberships {
-					if err := txDao.DeleteRecord(membership); err != nil {
-						return err
-					}
-				}
-			}
+// HandleRestorePlan puts an archived plan back in use. Members are not billed for the archived months.
+func HandleRestorePlan(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		now := clock.Now()
 
-			joinRequestsCollection, err := txDao.FindCollectionByNameOrId("join_requests")
-			if err != nil {
-				return err
-			}
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
 
-			joinRequestFilter, err := planutil.BuildEqualsFilter(
-				planutil.FilterTerm{Field: "plan_id", Value: planRecord.Id},
-			)
-			if err != nil {
-				return err
-			}
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil || planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
 
-			joinRequests, err := txDao.FindRecordsByFilter(
-				joinRequestsCollection.Id,
-				joinRequestFilter.Expression,
-				"",
-				-1,
-				0,
-				joinRequestFilter.Params,
-			)
-			if err == nil {
-				for _, request := range joinRequests {
-					if err := txDao.DeleteRecord(request); err != nil {
-						return err
-					}
-				}
-			}
+		if !planutil.IsOwner(planRecord, session.UserID) {
+			return redirectToPlan(c, joinCode)
+		}
 
-			paymentsCollection, err := txDao.FindCollectionByNameOrId("payments")
-			if err != nil {
-				return err
-			}
+		err = audit.TrackWithDao(app.Dao(), planEvent(planRecord, session.UserID, audit.ActionPlanRestored), func(txDao *daos.Dao) error {
+			return billing.RestorePlanWithDao(txDao, planRecord.Id, now)
+		})
+		if err != nil {
+			return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/%s?error=Failed+to+restore+plan", joinCode))
+		}
 
-			paymentsFilter, err := planutil.BuildEqualsFilter(
-				planutil.FilterTerm{Field: "plan_id", Value: planRecord.Id},
-			)
-			if err != nil {
-				return err
-			}
+		return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/%s?success=Plan+restored.", joinCode))
+	}
+}
 
-			payments, err := txDao.FindRecordsByFilter(
-				paymentsCollection.Id,
-				paymentsFilter.Expression,
-				"",
-				-1,
-				0,
-				paymentsFilter.Params,
-			)
-			if err == nil {
-				for _, payment := range payments {
-					if err := txDao.DeleteRecord(payment); err != nil {
-						return err
-					}
-				}
-			}
+// HandlePurgePlan permanently deletes an archived plan and everything recorded against it, once the
+// retention period has passed and the owner has typed the join code to confirm.
+func HandlePurgePlan(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		now := clock.Now()
+
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil || planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		if !planutil.IsOwner(planRecord, session.UserID) {
+			return redirectToPlan(c, joinCode)
+		}
+
+		if !planutil.IsArchived(planRecord) {
+			return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/%s?error=Archive+the+plan+before+purging+it.", joinCode))
+		}
+
+		if purgeableAt := purgeAvailableAt(planRecord); now.Before(purgeableAt) {
+			values := url.Values{}
+			values.Set("error", fmt.Sprintf("Archived plans can be purged from %s.", purgeableAt.Format("January 2, 2006")))
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode+"?"+values.Encode())
+		}
 
-			return txDao.DeleteRecord(planRecord)
+		if strings.TrimSpace(c.FormValue("confirm_join_code")) != joinCode {
+			return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/%s?error=Type+the+plan%%27s+join+code+to+confirm+the+purge.", joinCode))
+		}
+
+		err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+			return purgePlanWithDao(txDao, planRecord)
 		})
 		if err != nil {
-			return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/%s?error=Failed+to+delete+plan", joinCode))
+			return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/%s?error=Failed+to+purge+plan", joinCode))
 		}
 
 		return c.Redirect(http.StatusSeeOther, "/family-plans")
 	}
 }
 
+// purgeAvailableAt returns when an archived plan's retention period ends.
+func purgeAvailableAt(planRecord *pbmodels.Record) time.Time {
+	return planutil.ArchivedAt(planRecord).Add(purgeRetention)
+}
+
+// purgePlanWithDao deletes a plan and every record that belongs to it.
+func purgePlanWithDao(dao *daos.Dao, planRecord *pbmodels.Record) error {
+	filter, err := planutil.BuildEqualsFilter(
+		planutil.FilterTerm{Field: "plan_id", Value: planRecord.Id},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range planRecordCollections {
+		records, err := dao.FindRecordsByFilter(collection, filter.Expression, "", -1, 0, filter.Params)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			if err := dao.DeleteRecord(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	return dao.DeleteRecord(planRecord)
+}
+
 // HandleUpdatePlan updates editable plan fields.
-func HandleUpdatePlan(app *pocketbase.PocketBase) echo.HandlerFunc {
+func HandleUpdatePlan(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		session, err := sessionOrRedirect(c)
 		if err != nil {
 			return err
@@ -153,25 +206,109 @@ func HandleUpdatePlan(app *pocketbase.PocketBase) echo.HandlerFunc {
 			return redirectToPlan(c, joinCode)
 		}
 
-		cost, err := money.ParseAmount(costStr)
+		currency, err := money.NormalizeCurrency(c.FormValue("currency"))
+		if err != nil || c.FormValue("currency") == "" {
+			currency = planutil.Currency(planRecord)
+		}
+
+		// Stored amounts are minor units, so they only keep their meaning in a currency with the same exponent.
+		if money.Exponent(currency) != money.Exponent(planutil.Currency(planRecord)) {
+			values := url.Values{}
+			values.Set("error", fmt.Sprintf("%s uses a different number of decimal places than %s, so existing amounts cannot be kept.", currency, planutil.Currency(planRecord)))
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode+"?"+values.Encode())
+		}
+
+		cost, err := parseFormAmount(c, costStr, currency)
 		if err != nil {
-			cost = money.Normalize(planRecord.GetFloat("cost"))
+			cost = money.New(planutil.Cost(planRecord).Minor, currency)
+		}
+
+		individualCost, err := parseFormAmount(c, individualCostStr, currency)
+		if err != nil {
+			individualCost = money.New(planutil.IndividualCost(planRecord).Minor, currency)
+		}
+
+		trialMonths := billing.TrialMonths(planRecord)
+		if value := strings.TrimSpace(c.FormValue("trial_months")); value != "" {
+			trialMonths, err = strconv.Atoi(value)
+			if err != nil || trialMonths < 0 || trialMonths > billing.MaxFreeMonths {
+				values := url.Values{}
+				values.Set("error", fmt.Sprintf("Free trial must be between 0 and %d months.", billing.MaxFreeMonths))
+				return c.Redirect(http.StatusSeeOther, "/"+joinCode+"?"+values.Encode())
+			}
 		}
 
-		individualCost, err := money.ParseAmount(individualCostStr)
+		lateFeeRule, err := parseLateFeeRule(c, planRecord, currency)
 		if err != nil {
-			individualCost = money.Normalize(planRecord.GetFloat("individual_cost"))
+			values := url.Values{}
+			values.Set("error", fmt.Sprintf("Due day must be between 1 and %d, and late fees must be zero or more (at most 100%%).", billing.MaxDueDay))
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode+"?"+values.Encode())
 		}
 
 		planRecord.Set("name", name)
 		planRecord.Set("description", description)
-		planRecord.Set("cost", cost)
-		planRecord.Set("individual_cost", individualCost)
+		planRecord.Set("currency", currency)
+		planRecord.Set("cost", cost.Minor)
+		planRecord.Set("individual_cost", individualCost.Minor)
+		planRecord.Set("trial_months", trialMonths)
+		planRecord.Set("due_day", lateFeeRule.DueDay)
+		planRecord.Set("late_fee_days", lateFeeRule.DaysOverdue)
+		planRecord.Set("late_fee_amount", lateFeeRule.Fixed.Minor)
+		planRecord.Set("late_fee_percent", lateFeeRule.Percent)
+
+		err = audit.TrackWithDao(app.Dao(), planEvent(planRecord, session.UserID, audit.ActionPlanUpdated), func(txDao *daos.Dao) error {
+			if err := txDao.SaveRecord(planRecord); err != nil {
+				return err
+			}
 
-		if err := app.Dao().SaveRecord(planRecord); err != nil {
+			// Apply a changed late fee rule now rather than at the next scheduled sync.
+			return billing.SyncLateFeesWithDao(txDao, planRecord.Id, now)
+		})
+		if err != nil {
 			return err
 		}
 
 		return redirectToPlan(c, joinCode)
 	}
 }
+
+// planEvent describes a change the owner made to the plan record itself.
+func planEvent(planRecord *pbmodels.Record, actorID, action string) audit.Event {
+	return audit.Event{
+		PlanID:   planRecord.Id,
+		ActorID:  actorID,
+		Action:   action,
+		Target:   "family_plans",
+		TargetID: planRecord.Id,
+	}
+}
+
+// parseLateFeeRule reads the due day and late fee fields, keeping the plan's current value for any left blank.
+func parseLateFeeRule(c echo.Context, planRecord *pbmodels.Record, currency string) (billing.LateFeeRule, error) {
+	rule := billing.LateFeeRuleFor(planRecord)
+	rule.Fixed = money.New(rule.Fixed.Minor, currency)
+
+	var err error
+	if value := strings.TrimSpace(c.FormValue("due_day")); value != "" {
+		if rule.DueDay, err = strconv.Atoi(value); err != nil {
+			return rule, err
+		}
+	}
+	if value := strings.TrimSpace(c.FormValue("late_fee_days")); value != "" {
+		if rule.DaysOverdue, err = strconv.Atoi(value); err != nil {
+			return rule, err
+		}
+	}
+	if value := strings.TrimSpace(c.FormValue("late_fee_amount")); value != "" {
+		if rule.Fixed, err = parseFormAmount(c, value, currency); err != nil {
+			return rule, err
+		}
+	}
+	if value := strings.TrimSpace(c.FormValue("late_fee_percent")); value != "" {
+		if rule.Percent, err = strconv.ParseFloat(value, 64); err != nil {
+			return rule, err
+		}
+	}
+
+	return rule, rule.Validate()
+}
@@ -0,0 +1,129 @@
+package plans
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/domain"
+	"familyplan/src/internal/testapp"
+
+	"github.com/labstack/echo/v5"
+)
+
+func TestHandlePurgePlanRequiresArchiveRetentionAndConfirmation(t *testing.T) {
+	now := time.Date(2026, time.June, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		archivedAt time.Time
+		confirm    string
+		wantPurged bool
+	}{
+		{name: "plan in use", confirm: "ABC123"},
+		{name: "within retention", archivedAt: now.Add(-time.Hour), confirm: "ABC123"},
+		{name: "wrong confirmation", archivedAt: now.Add(-purgeRetention - time.Hour), confirm: "abc123"},
+		{name: "confirmed after retention", archivedAt: now.Add(-purgeRetention - time.Hour), confirm: "ABC123", wantPurged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := testapp.New(t)
+
+			owner := testapp.SaveUser(t, app, "owner")
+			member := testapp.SaveUser(t, app, "member")
+			plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 3000)
+			testapp.SaveRecord(t, app, "memberships", map[string]any{"plan_id": plan.Id, "user_id": member.Id})
+			testapp.SaveRecord(t, app, "payments", map[string]any{"plan_id": plan.Id, "user_id": member.Id, "amount": 500, "status": "approved", "date": now})
+			testapp.SaveRecord(t, app, billing.AdjustmentsCollection, map[string]any{"plan_id": plan.Id, "user_id": member.Id, "amount": -200, "for_month": now, "description": "Fee"})
+			testapp.SaveRecord(t, app, audit.CollectionName, map[string]any{"plan_id": plan.Id, "action": audit.ActionPlanUpdated})
+
+			if !tt.archivedAt.IsZero() {
+				if err := billing.ArchivePlanWithDao(app.Dao(), plan.Id, tt.archivedAt); err != nil {
+					t.Fatalf("ArchivePlanWithDao returned error: %v", err)
+				}
+			}
+
+			form := url.Values{"confirm_join_code": {tt.confirm}}
+			req := httptest.NewRequest(http.MethodPost, "/ABC123/purge", strings.NewReader(form.Encode()))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+			c.SetPathParams(echo.PathParams{{Name: "join_code", Value: "ABC123"}})
+			c.Set("session", domain.SessionData{IsAuthenticated: true, UserID: owner.Id})
+
+			if err := HandlePurgePlan(app, billing.FixedClock(now))(c); err != nil {
+				t.Fatalf("HandlePurgePlan returned error: %v", err)
+			}
+
+			_, err := app.Dao().FindRecordById("family_plans", plan.Id)
+			if purged := err != nil; purged != tt.wantPurged {
+				t.Fatalf("plan purged = %v, want %v (redirect %q)", purged, tt.wantPurged, rec.Header().Get("Location"))
+			}
+
+			for _, collection := range []string{"memberships", "payments", billing.AdjustmentsCollection, audit.CollectionName} {
+				records, err := app.Dao().FindRecordsByFilter(collection, "plan_id = {:plan}", "", -1, 0, map[string]any{"plan": plan.Id})
+				if err != nil {
+					t.Fatalf("failed to load %s: %v", collection, err)
+				}
+				if got := len(records) == 0; got != tt.wantPurged {
+					t.Fatalf("%s left = %d, want purged %v", collection, len(records), tt.wantPurged)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleUpdatePlanRecordsActivityForOwnersAndMembers(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 3000)
+	testapp.SaveRecord(t, app, "memberships", map[string]any{"plan_id": plan.Id, "user_id": member.Id})
+
+	form := url.Values{"name": {"Family Streaming"}, "cost": {"25.00"}, "individual_cost": {"15.00"}}
+	req := httptest.NewRequest(http.MethodPost, "/ABC123/update", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetPathParams(echo.PathParams{{Name: "join_code", Value: "ABC123"}})
+	c.Set("session", domain.SessionData{IsAuthenticated: true, UserID: owner.Id})
+
+	if err := HandleUpdatePlan(app, billing.SystemClock)(c); err != nil {
+		t.Fatalf("HandleUpdatePlan returned error: %v", err)
+	}
+
+	for _, viewer := range []struct {
+		userID  string
+		isOwner bool
+	}{{owner.Id, true}, {member.Id, false}} {
+		events, err := loadActivity(app, plan.Id, viewer.userID, viewer.isOwner, audit.Filter{})
+		if err != nil {
+			t.Fatalf("loadActivity returned error: %v", err)
+		}
+		if len(events) != 1 || events[0].Action != audit.ActionPlanUpdated || events[0].Actor != "owner" {
+			t.Fatalf("activity for %s = %+v, want the owner's plan update", viewer.userID, events)
+		}
+
+		changed := map[string]domain.AuditChange{}
+		for _, change := range events[0].Changes {
+			changed[change.Field] = change
+		}
+		if changed["name"].After != "Family Streaming" || changed["cost"].After != "2500" {
+			t.Fatalf("activity changes = %+v, want the new name and cost", events[0].Changes)
+		}
+	}
+
+	events, err := loadActivity(app, plan.Id, owner.Id, true, audit.Filter{Category: "payment"})
+	if err != nil {
+		t.Fatalf("loadActivity returned error: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("payment activity = %+v, want none", events)
+	}
+}
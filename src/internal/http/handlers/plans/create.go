@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 
+	"familyplan/src/internal/audit"
 	"familyplan/src/internal/money"
 	"familyplan/src/internal/support/random"
 
@@ -30,12 +31,17 @@ func HandleCreateFamilyPlan(app *pocketbase.PocketBase) echo.HandlerFunc {
 			return c.Redirect(http.StatusSeeOther, "/family-plans")
 		}
 
-		cost, err := money.ParseAmount(costStr)
+		currency, err := money.NormalizeCurrency(c.FormValue("currency"))
 		if err != nil {
 			return c.Redirect(http.StatusSeeOther, "/family-plans")
 		}
 
-		individualCost, err := money.ParseAmount(individualCostStr)
+		cost, err := parseFormAmount(c, costStr, currency)
+		if err != nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		individualCost, err := parseFormAmount(c, individualCostStr, currency)
 		if err != nil {
 			return c.Redirect(http.StatusSeeOther, "/family-plans")
 		}
@@ -54,8 +60,9 @@ func HandleCreateFamilyPlan(app *pocketbase.PocketBase) echo.HandlerFunc {
 			newPlan := pbmodels.NewRecord(plansCollection)
 			newPlan.Set("name", name)
 			newPlan.Set("description", description)
-			newPlan.Set("cost", cost)
-			newPlan.Set("individual_cost", individualCost)
+			newPlan.Set("currency", currency)
+			newPlan.Set("cost", cost.Minor)
+			newPlan.Set("individual_cost", individualCost.Minor)
 			newPlan.Set("owner", []string{session.UserID})
 			newPlan.Set("join_code", joinCode)
 
@@ -63,6 +70,18 @@ func HandleCreateFamilyPlan(app *pocketbase.PocketBase) echo.HandlerFunc {
 				return err
 			}
 
+			err = audit.RecordWithDao(txDao, audit.Event{
+				PlanID:   newPlan.Id,
+				ActorID:  session.UserID,
+				Action:   audit.ActionPlanCreated,
+				Target:   "family_plans",
+				TargetID: newPlan.Id,
+				After:    audit.Snapshot(newPlan),
+			})
+			if err != nil {
+				return err
+			}
+
 			membershipsCollection, err := txDao.FindCollectionByNameOrId("memberships")
 			if err != nil {
 				return err
@@ -1,8 +1,14 @@
 package plans
 
 import (
+	"net/http"
+	"net/url"
+
+	"familyplan/src/internal/audit"
 	"familyplan/src/internal/billing"
 	"familyplan/src/internal/domain"
+	"familyplan/src/internal/export"
+	"familyplan/src/internal/money"
 	"familyplan/src/internal/planutil"
 	"familyplan/src/internal/view"
 
@@ -10,9 +16,12 @@ import (
 	"github.com/pocketbase/pocketbase"
 )
 
-// HandlePlanDetails renders the plan detail page.
-func HandlePlanDetails(app *pocketbase.PocketBase) echo.HandlerFunc {
+// HandlePlanDetails renders the plan detail page. An as_of day shows the balances as they stood at
+// the end of that day.
+func HandlePlanDetails(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		session, err := sessionOrRedirect(c)
 		if err != nil {
 			return err
@@ -31,6 +40,13 @@ func HandlePlanDetails(app *pocketbase.PocketBase) echo.HandlerFunc {
 			})
 		}
 
+		balancesAsOf, err := export.ParseAsOf(c.QueryParam("as_of"), now)
+		if err != nil {
+			values := url.Values{}
+			values.Set("error", "Choose a balance date no later than today.")
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode+"?"+values.Encode())
+		}
+
 		isOwner := planutil.IsOwner(planRecord, session.UserID)
 		existingMembership, err := planutil.FindMembership(app, planRecord.Id, session.UserID)
 		if err != nil {
@@ -47,19 +63,47 @@ func HandlePlanDetails(app *pocketbase.PocketBase) echo.HandlerFunc {
 			pendingRequest = existingRequest != nil
 		}
 
-		familyPlan := buildFamilyPlan(planRecord, 0, 0)
+		familyPlan := buildFamilyPlan(planRecord, 0, money.Amount{})
 
 		members := []domain.Member{}
+		formerMembers := []domain.Member{}
 		totalMembers := 0
-		claimLinks := map[string]string{}
+		claimLinks := map[string]domain.ClaimLink{}
+		claimAttempts := []domain.ClaimAttempt{}
+		exchangeRates := []domain.ExchangeRate{}
+		activity := []domain.AuditEvent{}
+		activityFilter := audit.Filter{
+			Category: activityCategoryFilter(c.QueryParam(activityCategoryParam)),
+			UserID:   c.QueryParam(activityMemberParam),
+		}
 		if isMember {
-			members, totalMembers, err = loadMembers(app, familyPlan)
+			members, totalMembers, err = loadMembers(app, familyPlan, now, balancesAsOf)
+			if err != nil {
+				return err
+			}
+
+			exchangeRates, err = loadExchangeRates(app, planRecord.Id, familyPlan.Currency)
+			if err != nil {
+				return err
+			}
+
+			activity, err = loadActivity(app, planRecord.Id, session.UserID, isOwner, activityFilter)
 			if err != nil {
 				return err
 			}
 
 			if isOwner {
-				claimLinks, err = loadMemberClaimLinks(app, planRecord.Id, c.Scheme(), c.Request().Host)
+				claimLinks, err = loadMemberClaimLinks(app, planRecord.Id, c.Scheme(), c.Request().Host, now)
+				if err != nil {
+					return err
+				}
+
+				claimAttempts, err = loadMemberClaimAttempts(app, planRecord.Id)
+				if err != nil {
+					return err
+				}
+
+				formerMembers, err = loadFormerMembers(app, familyPlan, balancesAsOf)
 				if err != nil {
 					return err
 				}
@@ -77,37 +121,64 @@ func HandlePlanDetails(app *pocketbase.PocketBase) echo.HandlerFunc {
 		pendingPayments := []domain.Payment{}
 		userPayments := []domain.Payment{}
 		allPayments := []domain.Payment{}
+		adjustments := []domain.Adjustment{}
+		coverage := []domain.MemberCoverage{}
+		recurringClaims := []domain.RecurringClaim{}
 		memberPaymentsPagination := buildMemberPaymentsPagination(1, false)
+		methodFilter := paymentMethodFilter(c.QueryParam(paymentMethodParam))
 		if isMember {
 			if isOwner {
-				pendingPayments, err = loadPendingPayments(app, planRecord.Id)
+				pendingPayments, err = loadPendingPayments(app, familyPlan)
 				if err != nil {
 					return err
 				}
 
 				allPayments, memberPaymentsPagination, err = loadAllPaymentsPage(
 					app,
-					planRecord.Id,
+					familyPlan,
 					memberPaymentsPage(c.QueryParam(memberPaymentsPageParam)),
 					memberPaymentsPageSize,
+					methodFilter,
 				)
 				if err != nil {
 					return err
 				}
 			}
 
-			userPayments, err = loadUserPayments(app, planRecord.Id, session.UserID)
+			userPayments, err = loadUserPayments(app, familyPlan, session.UserID)
+			if err != nil {
+				return err
+			}
+
+			adjustments, err = loadAdjustments(app, familyPlan, session.UserID, isOwner)
+			if err != nil {
+				return err
+			}
+
+			coverage, err = loadCoverage(app, familyPlan, members, session.UserID, isOwner, now)
+			if err != nil {
+				return err
+			}
+
+			recurringClaims, err = loadRecurringClaims(app, familyPlan, session.UserID, isOwner)
 			if err != nil {
 				return err
 			}
 		}
 
-		totalSavings := calculateTotalSavings(app, planRecord)
-		planAgeDays := calculatePlanAgeDays(planRecord)
+		totalSavings := calculateTotalSavings(app, planRecord, now)
+		planAgeDays := calculatePlanAgeDays(planRecord, now)
 
-		userBalance := 0.0
+		userBalance := money.New(0, familyPlan.Currency)
 		if isMember && !isOwner {
-			userBalance, _ = billing.CalculateMemberBalance(app, planRecord.Id, session.UserID)
+			userBalance, _ = billing.CalculateMemberBalance(app, planRecord.Id, session.UserID, balancesAsOf)
+		}
+
+		purgeAvailable := ""
+		canPurge := false
+		if planutil.IsArchived(planRecord) {
+			purgeAvailable = purgeAvailableAt(planRecord).Format("January 2, 2006")
+			canPurge = !now.Before(purgeAvailableAt(planRecord))
 		}
 
 		return view.RenderPage(c, "plan_details.html", map[string]interface{}{
@@ -116,19 +187,37 @@ func HandlePlanDetails(app *pocketbase.PocketBase) echo.HandlerFunc {
 			"is_owner":                   isOwner,
 			"is_member":                  isMember,
 			"members":                    members,
+			"former_members":             formerMembers,
 			"claim_links":                claimLinks,
+			"claim_attempts":             claimAttempts,
+			"exchange_rates":             exchangeRates,
+			"activity":                   activity,
+			"activity_filter":            activityFilter,
+			"activity_categories":        activityCategories(),
 			"total_members":              totalMembers,
 			"join_requests":              joinRequests,
 			"pending_request":            pendingRequest,
 			"pending_payments":           pendingPayments,
 			"user_payments":              userPayments,
 			"user_balance":               userBalance,
+			"as_of":                      asOfDay(c, balancesAsOf),
 			"existingMembership":         existingMembership,
 			"all_payments":               allPayments,
+			"adjustments":                adjustments,
+			"coverage":                   coverage,
+			"recurring_claims":           recurringClaims,
+			"payment_methods":            loadPaymentMethods(planRecord),
+			"payment_method_filter":      methodFilter,
+			"journal_accounts":           export.DefaultJournalAccounts,
 			"member_payments_pagination": memberPaymentsPagination,
-			"total_payments":             calculateTotalPayments(app, planRecord.Id),
+			"total_payments":             calculateTotalPayments(app, planRecord),
 			"total_savings":              totalSavings,
 			"plan_age_days":              planAgeDays,
+			"purge_available_at":         purgeAvailable,
+			"can_purge":                  canPurge,
+			"error":                      c.QueryParam("error"),
+			"notice":                     c.QueryParam("notice"),
+			"success":                    c.QueryParam("success"),
 		})
 	}
 }
@@ -0,0 +1,80 @@
+package plans
+
+import (
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/domain"
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+const (
+	activityLimit         = 50
+	activityCategoryParam = "activity"
+	activityMemberParam   = "activity_member"
+)
+
+// loadActivity lists a plan's most recent audit events. Owners see every event and can filter them;
+// members see the events about them and the plan as a whole.
+func loadActivity(app *pocketbase.PocketBase, planID, userID string, isOwner bool, filter audit.Filter) ([]domain.AuditEvent, error) {
+	var records []*pbmodels.Record
+	var err error
+	if isOwner {
+		records, err = audit.FindWithDao(app.Dao(), planID, filter, activityLimit)
+	} else {
+		records, err = audit.FindForMemberWithDao(app.Dao(), planID, userID, activityLimit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]string, 0, len(records)*2)
+	for _, record := range records {
+		userIDs = append(userIDs, record.GetString("actor_id"), record.GetString("user_id"))
+	}
+
+	identities, err := planutil.FindIdentitiesWithDao(app.Dao(), planID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]domain.AuditEvent, 0, len(records))
+	for _, record := range records {
+		changes := []domain.AuditChange{}
+		for _, change := range audit.Changes(record) {
+			changes = append(changes, domain.AuditChange{Field: change.Field, Before: change.Before, After: change.After})
+		}
+
+		events = append(events, domain.AuditEvent{
+			Action:     record.GetString("action"),
+			Label:      audit.Label(record.GetString("action")),
+			Actor:      identities[record.GetString("actor_id")].DisplayName(),
+			Member:     identities[record.GetString("user_id")].DisplayName(),
+			OccurredAt: record.GetDateTime("created").String(),
+			Changes:    changes,
+		})
+	}
+
+	return events, nil
+}
+
+// activityCategoryFilter returns the category the owner's activity feed is filtered by, if it is a known one.
+func activityCategoryFilter(raw string) string {
+	for _, category := range audit.Categories {
+		if raw == category {
+			return raw
+		}
+	}
+
+	return ""
+}
+
+func activityCategories() []domain.AuditCategory {
+	categories := make([]domain.AuditCategory, 0, len(audit.Categories))
+	for _, category := range audit.Categories {
+		categories = append(categories, domain.AuditCategory{Value: category, Label: audit.CategoryLabel(category)})
+	}
+
+	return categories
+}
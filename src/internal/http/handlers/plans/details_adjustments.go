@@ -0,0 +1,55 @@
+package plans
+
+import (
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/domain"
+
+	"github.com/pocketbase/pocketbase"
+)
+
+// loadAdjustments returns every adjustment for the owner, and a member's own plus the shared ones otherwise.
+func loadAdjustments(app *pocketbase.PocketBase, plan domain.FamilyPlan, userID string, isOwner bool) ([]domain.Adjustment, error) {
+	records, err := billing.FindAdjustmentsWithDao(app.Dao(), plan.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]string, 0, len(records))
+	for _, record := range records {
+		userIDs = append(userIDs, record.GetString("user_id"))
+	}
+
+	identities, err := loadPaymentIdentities(app, plan.ID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	adjustments := make([]domain.Adjustment, 0, len(records))
+	for _, record := range records {
+		allMembers := billing.AppliesToAllMembers(record)
+		adjustmentUserID := record.GetString("user_id")
+		if !isOwner && !allMembers && adjustmentUserID != userID {
+			continue
+		}
+
+		identity := identities[adjustmentUserID]
+		name := identity.Name
+		if name == "" {
+			name = identity.Username
+		}
+
+		adjustments = append(adjustments, domain.Adjustment{
+			ID:         record.Id,
+			UserID:     adjustmentUserID,
+			Name:       name,
+			Amount:     billing.AdjustmentAmount(record, plan.Currency),
+			Reason:     record.GetString("reason"),
+			ForMonth:   record.GetDateTime("for_month").Time().Format("January 2006"),
+			AllMembers: allMembers,
+			LateFee:    billing.IsLateFee(record),
+			Waived:     billing.IsWaived(record),
+		})
+	}
+
+	return adjustments, nil
+}
@@ -2,32 +2,66 @@ package plans
 
 import (
 	"fmt"
+	"time"
 
+	"familyplan/src/internal/domain"
 	"familyplan/src/internal/memberclaim"
 
 	"github.com/pocketbase/pocketbase"
 )
 
-func loadMemberClaimLinks(app *pocketbase.PocketBase, planID, scheme, host string) (map[string]string, error) {
+const claimAttemptsLimit = 10
+
+func loadMemberClaimLinks(app *pocketbase.PocketBase, planID, scheme, host string, now time.Time) (map[string]domain.ClaimLink, error) {
 	records, err := memberclaim.FindAllForPlan(app, planID)
 	if err != nil {
 		return nil, err
 	}
 
-	links := make(map[string]string, len(records))
+	links := make(map[string]domain.ClaimLink, len(records))
 	for _, record := range records {
 		token := record.GetString("token")
 		artificialMemberID := record.GetString("artificial_member_id")
-		if token == "" || artificialMemberID == "" {
+		if token == "" || artificialMemberID == "" || !memberclaim.IsActive(record, now) {
 			continue
 		}
 
-		links[artificialMemberID] = absoluteClaimLink(scheme, host, token)
+		links[artificialMemberID] = domain.ClaimLink{
+			URL:       absoluteClaimLink(scheme, host, token),
+			ExpiresAt: record.GetDateTime("expires_at").String(),
+		}
 	}
 
 	return links, nil
 }
 
+func loadMemberClaimAttempts(app *pocketbase.PocketBase, planID string) ([]domain.ClaimAttempt, error) {
+	records, err := memberclaim.FindAttemptsForPlan(app, planID, claimAttemptsLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := make([]domain.ClaimAttempt, 0, len(records))
+	for _, record := range records {
+		username := ""
+		if userID := record.GetString("user_id"); userID != "" {
+			if userRecord, err := app.Dao().FindRecordById("users", userID); err == nil && userRecord != nil {
+				username = userRecord.GetString("username")
+			}
+		}
+
+		attempts = append(attempts, domain.ClaimAttempt{
+			ArtificialName: record.GetString("artificial_name"),
+			Username:       username,
+			Succeeded:      record.GetBool("succeeded"),
+			Reason:         record.GetString("reason"),
+			AttemptedAt:    record.GetDateTime("created").String(),
+		})
+	}
+
+	return attempts, nil
+}
+
 func absoluteClaimLink(scheme, host, token string) string {
 	if scheme == "" {
 		scheme = "http"
@@ -0,0 +1,85 @@
+package plans
+
+import (
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/domain"
+	"familyplan/src/internal/money"
+
+	"github.com/pocketbase/pocketbase"
+)
+
+const (
+	coverageMonthsBack  = 5
+	coverageMonthsAhead = 6
+)
+
+// Coverage grid cell states.
+const (
+	coveragePaid     = "paid"
+	coveragePartial  = "partial"
+	coverageUnpaid   = "unpaid"
+	coverageUpcoming = "upcoming"
+	coverageFree     = "free"
+)
+
+// loadCoverage builds the paid/unpaid grid around the month of now.
+// The owner sees every member; a member sees only their own row.
+func loadCoverage(app *pocketbase.PocketBase, plan domain.FamilyPlan, members []domain.Member, userID string, isOwner bool, now time.Time) ([]domain.MemberCoverage, error) {
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	first := thisMonth.AddDate(0, -coverageMonthsBack, 0)
+	last := thisMonth.AddDate(0, coverageMonthsAhead, 0)
+
+	rows := []domain.MemberCoverage{}
+	for _, member := range members {
+		if member.ID == plan.Owner || (!isOwner && member.ID != userID) {
+			continue
+		}
+
+		coverage, err := billing.CoverageWithDao(app.Dao(), plan.ID, member.ID, first, last, now)
+		if err != nil {
+			return nil, err
+		}
+
+		name := member.Name
+		if name == "" {
+			name = member.Username
+		}
+
+		row := domain.MemberCoverage{MemberID: member.ID, Name: name}
+		for _, month := range coverage {
+			monthTime, err := time.Parse("2006-01", month.Month)
+			if err != nil {
+				return nil, err
+			}
+
+			row.Months = append(row.Months, domain.CoverageMonth{
+				Month:   month.Month,
+				Label:   monthTime.Format("Jan 2006"),
+				Due:     money.New(month.DueCents, plan.Currency),
+				Covered: money.New(month.CoveredCents, plan.Currency),
+				Status:  coverageStatus(month.DueCents, month.CoveredCents, monthTime.After(thisMonth)),
+			})
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func coverageStatus(dueCents, coveredCents int64, future bool) string {
+	switch {
+	case dueCents <= 0:
+		return coverageFree
+	case coveredCents >= dueCents:
+		return coveragePaid
+	case coveredCents > 0:
+		return coveragePartial
+	case future:
+		return coverageUpcoming
+	default:
+		return coverageUnpaid
+	}
+}
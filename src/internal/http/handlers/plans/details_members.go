@@ -1,15 +1,20 @@
 package plans
 
 import (
+	"time"
+
 	"familyplan/src/internal/billing"
 	"familyplan/src/internal/domain"
+	"familyplan/src/internal/money"
 	"familyplan/src/internal/planutil"
 	"familyplan/src/internal/userprofile"
 
 	"github.com/pocketbase/pocketbase"
+	pbmodels "github.com/pocketbase/pocketbase/models"
 )
 
-func loadMembers(app *pocketbase.PocketBase, plan domain.FamilyPlan) ([]domain.Member, int, error) {
+// loadMembers returns the plan's current members with their balances as of balancesAsOf.
+func loadMembers(app *pocketbase.PocketBase, plan domain.FamilyPlan, now, balancesAsOf time.Time) ([]domain.Member, int, error) {
 	usersCollection, err := app.Dao().FindCollectionByNameOrId("users")
 	if err != nil {
 		return nil, 0, err
@@ -25,7 +30,7 @@ func loadMembers(app *pocketbase.PocketBase, plan domain.FamilyPlan) ([]domain.M
 			Username:  ownerRecord.GetString("username"),
 			Name:      ownerRecord.GetString("name"),
 			AvatarURL: userprofile.AvatarURL(ownerRecord),
-			Balance:   0,
+			Balance:   money.New(0, plan.Currency),
 		})
 		uniqueMembers[ownerRecord.Id] = true
 	}
@@ -65,7 +70,9 @@ func loadMembers(app *pocketbase.PocketBase, plan domain.FamilyPlan) ([]domain.M
 			continue
 		}
 
-		balance, _ := billing.CalculateMemberBalance(app, plan.ID, userID)
+		balance, _ := billing.CalculateMemberBalance(app, plan.ID, userID, balancesAsOf)
+		freeReason, freeUntil := currentFreePeriod(membership, now)
+		paused, pausedFrom, pausedUntil := currentPause(membership, now)
 
 		if membership.GetBool("is_artificial") {
 			members = append(members, domain.Member{
@@ -75,6 +82,11 @@ func loadMembers(app *pocketbase.PocketBase, plan domain.FamilyPlan) ([]domain.M
 				LeaveRequested: membership.GetBool("leave_requested"),
 				DateEnded:      membership.GetDateTime("date_ended").String(),
 				IsArtificial:   true,
+				FreeReason:     freeReason,
+				FreeUntil:      freeUntil,
+				Paused:         paused,
+				PausedFrom:     pausedFrom,
+				PausedUntil:    pausedUntil,
 			})
 			uniqueMembers[userID] = true
 			continue
@@ -94,6 +106,11 @@ func loadMembers(app *pocketbase.PocketBase, plan domain.FamilyPlan) ([]domain.M
 			LeaveRequested: membership.GetBool("leave_requested"),
 			DateEnded:      membership.GetDateTime("date_ended").String(),
 			IsArtificial:   false,
+			FreeReason:     freeReason,
+			FreeUntil:      freeUntil,
+			Paused:         paused,
+			PausedFrom:     pausedFrom,
+			PausedUntil:    pausedUntil,
 		})
 		uniqueMembers[userRecord.Id] = true
 	}
@@ -101,6 +118,109 @@ func loadMembers(app *pocketbase.PocketBase, plan domain.FamilyPlan) ([]domain.M
 	return members, len(members), nil
 }
 
+// currentFreePeriod returns the reason and last month of a trial or grace period covering the month of now.
+func currentFreePeriod(membership *pbmodels.Record, now time.Time) (string, string) {
+	period, ok := billing.FreePeriodForMonth(membership, now)
+	if !ok {
+		return "", ""
+	}
+
+	return period.Reason, monthLabel(period.End)
+}
+
+// currentPause reports whether a membership has a current or upcoming pause, with its first and last month.
+// The last month is empty for a pause that lasts until the member is resumed.
+func currentPause(membership *pbmodels.Record, now time.Time) (bool, string, string) {
+	period, ok := billing.CurrentPause(membership, now)
+	if !ok {
+		return false, "", ""
+	}
+
+	return true, monthLabel(period.Start), monthLabel(period.End)
+}
+
+func monthLabel(monthKey string) string {
+	month, err := time.Parse("2006-01", monthKey)
+	if err != nil {
+		return ""
+	}
+
+	return month.Format("January 2006")
+}
+
+// loadFormerMembers returns the plan's former members with their balances as of balancesAsOf.
+func loadFormerMembers(app *pocketbase.PocketBase, plan domain.FamilyPlan, balancesAsOf time.Time) ([]domain.Member, error) {
+	membershipsCollection, err := app.Dao().FindCollectionByNameOrId("memberships")
+	if err != nil {
+		return nil, err
+	}
+
+	membershipFilter, err := planutil.BuildEqualsFilter(
+		planutil.FilterTerm{Field: "plan_id", Value: plan.ID},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	membershipRecords, err := app.Dao().FindRecordsByFilter(
+		membershipsCollection.Id,
+		membershipFilter.Expression,
+		"-date_ended",
+		-1,
+		0,
+		membershipFilter.Params,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	usersCollection, err := app.Dao().FindCollectionByNameOrId("users")
+	if err != nil {
+		return nil, err
+	}
+
+	formerMembers := make([]domain.Member, 0)
+	for _, membership := range membershipRecords {
+		userID := membership.GetString("user_id")
+		dateEnded := membership.GetDateTime("date_ended")
+		if dateEnded.IsZero() || userID == plan.Owner {
+			continue
+		}
+
+		balance, err := billing.CalculateMemberBalance(app, plan.ID, userID, balancesAsOf)
+		if err != nil {
+			return nil, err
+		}
+
+		if membership.GetBool("is_artificial") {
+			formerMembers = append(formerMembers, domain.Member{
+				ID:           userID,
+				Name:         membership.GetString("name"),
+				Balance:      balance,
+				DateEnded:    dateEnded.String(),
+				IsArtificial: true,
+			})
+			continue
+		}
+
+		userRecord, err := app.Dao().FindRecordById(usersCollection.Id, userID)
+		if err != nil || userRecord == nil {
+			continue
+		}
+
+		formerMembers = append(formerMembers, domain.Member{
+			ID:        userRecord.Id,
+			Username:  userRecord.GetString("username"),
+			Name:      userRecord.GetString("name"),
+			AvatarURL: userprofile.AvatarURL(userRecord),
+			Balance:   balance,
+			DateEnded: dateEnded.String(),
+		})
+	}
+
+	return formerMembers, nil
+}
+
 func loadJoinRequests(app *pocketbase.PocketBase, planID string) ([]domain.JoinRequest, error) {
 	joinRequestsCollection, err := app.Dao().FindCollectionByNameOrId("join_requests")
 	if err != nil {
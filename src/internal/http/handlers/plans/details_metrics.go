@@ -11,18 +11,21 @@ import (
 	pbmodels "github.com/pocketbase/pocketbase/models"
 )
 
-func calculateTotalPayments(app *pocketbase.PocketBase, planID string) float64 {
+func calculateTotalPayments(app *pocketbase.PocketBase, plan *pbmodels.Record) money.Amount {
+	currency := planutil.Currency(plan)
+	total := money.New(0, currency)
+
 	paymentsCollection, err := app.Dao().FindCollectionByNameOrId("payments")
 	if err != nil {
-		return 0
+		return total
 	}
 
 	filter, err := planutil.BuildEqualsFilter(
-		planutil.FilterTerm{Field: "plan_id", Value: planID},
+		planutil.FilterTerm{Field: "plan_id", Value: plan.Id},
 		planutil.FilterTerm{Field: "status", Value: "approved"},
 	)
 	if err != nil {
-		return 0
+		return total
 	}
 
 	approvedPayments, err := app.Dao().FindRecordsByFilter(
@@ -34,24 +37,26 @@ func calculateTotalPayments(app *pocketbase.PocketBase, planID string) float64 {
 		filter.Params,
 	)
 	if err != nil {
-		return 0
+		return total
 	}
 
-	totalPaymentsCents := int64(0)
 	for _, payment := range approvedPayments {
-		totalPaymentsCents += money.ToCents(payment.GetFloat("amount"))
+		if billing.IsWriteOff(payment) {
+			continue
+		}
+		total = total.Add(billing.PaymentAmount(payment, currency))
 	}
 
-	return money.FromCents(totalPaymentsCents)
+	return total
 }
 
-func calculateTotalSavings(app *pocketbase.PocketBase, plan *pbmodels.Record) float64 {
+func calculateTotalSavings(app *pocketbase.PocketBase, plan *pbmodels.Record, now time.Time) money.Amount {
+	currency := planutil.Currency(plan)
 	totalSavingsCents := int64(0)
-	individualCostCents := money.ToCents(plan.GetFloat("individual_cost"))
-	familyPlanCostCents := money.ToCents(plan.GetFloat("cost"))
+	individualCostCents := planutil.IndividualCost(plan).Minor
+	familyPlanCostCents := planutil.Cost(plan).Minor
 
 	planCreationTime := plan.GetDateTime("created").Time()
-	currentTime := time.Now()
 	startDate := time.Date(
 		planCreationTime.Year(),
 		planCreationTime.Month(),
@@ -63,13 +68,14 @@ func calculateTotalSavings(app *pocketbase.PocketBase, plan *pbmodels.Record) fl
 		planCreationTime.Location(),
 	)
 
-	for currentDate := startDate; currentDate.Before(currentTime); currentDate = currentDate.AddDate(0, 1, 0) {
-		activeMemberships, err := billing.GetActiveMembershipsForMonth(app, plan.Id, currentDate)
+	for currentDate := startDate; currentDate.Before(now); currentDate = currentDate.AddDate(0, 1, 0) {
+		// Members on a free trial or grace period still count: they save the whole individual price.
+		servedMemberships, err := billing.GetServedMembershipsForMonth(app, plan.Id, currentDate)
 		if err != nil {
 			continue
 		}
 
-		memberCount := len(activeMemberships)
+		memberCount := len(servedMemberships)
 		if memberCount == 0 {
 			continue
 		}
@@ -80,9 +86,9 @@ func calculateTotalSavings(app *pocketbase.PocketBase, plan *pbmodels.Record) fl
 		}
 	}
 
-	return money.FromCents(totalSavingsCents)
+	return money.New(totalSavingsCents, currency)
 }
 
-func calculatePlanAgeDays(plan *pbmodels.Record) int {
-	return int(time.Since(plan.GetDateTime("created").Time()).Hours() / 24)
+func calculatePlanAgeDays(plan *pbmodels.Record, now time.Time) int {
+	return int(now.Sub(plan.GetDateTime("created").Time()).Hours() / 24)
 }
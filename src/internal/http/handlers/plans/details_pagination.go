@@ -6,6 +6,7 @@ import "strconv"
 const (
 	memberPaymentsPageParam = "member_payments_page"
 	memberPaymentsPageSize  = 10
+	paymentMethodParam      = "payment_method"
 )
 
 func memberPaymentsPage(raw string) int {
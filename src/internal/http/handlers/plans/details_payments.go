@@ -1,6 +1,8 @@
 package plans
 
 import (
+	"sort"
+
 	"familyplan/src/internal/domain"
 	"familyplan/src/internal/planutil"
 
@@ -8,17 +10,23 @@ import (
 	pbmodels "github.com/pocketbase/pocketbase/models"
 )
 
-func loadPendingPayments(app *pocketbase.PocketBase, planID string) ([]domain.Payment, error) {
-	return loadPaymentsByTerms(app, planID, -1, 0,
-		planutil.FilterTerm{Field: "plan_id", Value: planID},
+const userPaymentsLimit = 20
+
+func loadPendingPayments(app *pocketbase.PocketBase, plan domain.FamilyPlan) ([]domain.Payment, error) {
+	return loadPaymentsByTerms(app, plan, -1, 0,
+		planutil.FilterTerm{Field: "plan_id", Value: plan.ID},
 		planutil.FilterTerm{Field: "status", Value: "pending"},
 	)
 }
 
-func loadAllPaymentsPage(app *pocketbase.PocketBase, planID string, page, pageSize int) ([]domain.Payment, domain.MemberPaymentsPagination, error) {
-	payments, err := loadPaymentsByTerms(app, planID, pageSize+1, (page-1)*pageSize,
-		planutil.FilterTerm{Field: "plan_id", Value: planID},
-	)
+// loadAllPaymentsPage returns one page of the plan's payments, only those made by method when it is set.
+func loadAllPaymentsPage(app *pocketbase.PocketBase, plan domain.FamilyPlan, page, pageSize int, method string) ([]domain.Payment, domain.MemberPaymentsPagination, error) {
+	terms := []planutil.FilterTerm{{Field: "plan_id", Value: plan.ID}}
+	if method != "" {
+		terms = append(terms, planutil.FilterTerm{Field: "method", Value: method})
+	}
+
+	payments, err := loadPaymentsByTerms(app, plan, pageSize+1, (page-1)*pageSize, terms...)
 	if err != nil {
 		return nil, domain.MemberPaymentsPagination{}, err
 	}
@@ -31,41 +39,68 @@ func loadAllPaymentsPage(app *pocketbase.PocketBase, planID string, page, pageSi
 	return payments, buildMemberPaymentsPagination(page, hasNext), nil
 }
 
-func loadUserPayments(app *pocketbase.PocketBase, planID, userID string) ([]domain.Payment, error) {
+func loadUserPayments(app *pocketbase.PocketBase, plan domain.FamilyPlan, userID string) ([]domain.Payment, error) {
 	paymentsCollection, err := app.Dao().FindCollectionByNameOrId("payments")
 	if err != nil {
 		return nil, err
 	}
 
-	filter, err := planutil.BuildEqualsFilter(
-		planutil.FilterTerm{Field: "plan_id", Value: planID},
-		planutil.FilterTerm{Field: "user_id", Value: userID},
-	)
-	if err != nil {
-		return nil, err
+	// Members see payments credited to them as well as payments they made for someone else.
+	seen := map[string]struct{}{}
+	paymentRecords := []*pbmodels.Record{}
+	for _, field := range []string{"user_id", "payer_id"} {
+		filter, err := planutil.BuildEqualsFilter(
+			planutil.FilterTerm{Field: "plan_id", Value: plan.ID},
+			planutil.FilterTerm{Field: field, Value: userID},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		records, err := app.Dao().FindRecordsByFilter(
+			paymentsCollection.Id,
+			filter.Expression,
+			"-created",
+			userPaymentsLimit,
+			0,
+			filter.Params,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, record := range records {
+			if _, ok := seen[record.Id]; ok {
+				continue
+			}
+
+			seen[record.Id] = struct{}{}
+			paymentRecords = append(paymentRecords, record)
+		}
 	}
 
-	paymentRecords, err := app.Dao().FindRecordsByFilter(
-		paymentsCollection.Id,
-		filter.Expression,
-		"-created",
-		20,
-		0,
-		filter.Params,
-	)
+	sort.SliceStable(paymentRecords, func(i, j int) bool {
+		return paymentRecords[i].GetDateTime("created").Time().After(paymentRecords[j].GetDateTime("created").Time())
+	})
+	if len(paymentRecords) > userPaymentsLimit {
+		paymentRecords = paymentRecords[:userPaymentsLimit]
+	}
+
+	identities, err := loadPaymentIdentities(app, plan.ID, paymentUserIDs(paymentRecords))
 	if err != nil {
 		return nil, err
 	}
 
 	payments := make([]domain.Payment, 0, len(paymentRecords))
 	for _, paymentRecord := range paymentRecords {
-		payments = append(payments, buildPayment(paymentRecord, "", ""))
+		identity := identities[paymentRecord.GetString("user_id")]
+		payments = append(payments, withPayerName(buildPayment(paymentRecord, plan.Currency, identity.Username, identity.Name), identities))
 	}
 
 	return payments, nil
 }
 
-func loadPaymentsByTerms(app *pocketbase.PocketBase, planID string, limit, offset int, terms ...planutil.FilterTerm) ([]domain.Payment, error) {
+func loadPaymentsByTerms(app *pocketbase.PocketBase, plan domain.FamilyPlan, limit, offset int, terms ...planutil.FilterTerm) ([]domain.Payment, error) {
 	paymentsCollection, err := app.Dao().FindCollectionByNameOrId("payments")
 	if err != nil {
 		return nil, err
@@ -88,7 +123,7 @@ func loadPaymentsByTerms(app *pocketbase.PocketBase, planID string, limit, offse
 		return nil, err
 	}
 
-	identities, err := loadPaymentIdentities(app, planID, paymentUserIDs(paymentRecords))
+	identities, err := loadPaymentIdentities(app, plan.ID, paymentUserIDs(paymentRecords))
 	if err != nil {
 		return nil, err
 	}
@@ -100,122 +135,31 @@ func loadPaymentsByTerms(app *pocketbase.PocketBase, planID string, limit, offse
 			continue
 		}
 
-		payments = append(payments, buildPayment(paymentRecord, identity.Username, identity.Name))
+		payments = append(payments, withPayerName(buildPayment(paymentRecord, plan.Currency, identity.Username, identity.Name), identities))
 	}
 
 	return payments, nil
 }
 
-type paymentIdentity struct {
-	Username string
-	Name     string
-}
-
-func loadPaymentIdentities(app *pocketbase.PocketBase, planID string, userIDs []string) (map[string]paymentIdentity, error) {
-	identities := make(map[string]paymentIdentity, len(userIDs))
-	remainingUserIDs := uniqueNonEmptyStrings(userIDs)
-	if len(remainingUserIDs) == 0 {
-		return identities, nil
-	}
-
-	membershipsCollection, err := app.Dao().FindCollectionByNameOrId("memberships")
-	if err != nil {
-		return nil, err
-	}
-
-	filter, err := planutil.BuildEqualsFilter(
-		planutil.FilterTerm{Field: "plan_id", Value: planID},
-		planutil.FilterTerm{Field: "is_artificial", Value: true},
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	artificialMemberships, err := app.Dao().FindRecordsByFilter(
-		membershipsCollection.Id,
-		filter.Expression,
-		"",
-		-1,
-		0,
-		filter.Params,
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	unresolved := make(map[string]struct{}, len(remainingUserIDs))
-	for _, userID := range remainingUserIDs {
-		unresolved[userID] = struct{}{}
+func withPayerName(payment domain.Payment, identities map[string]planutil.Identity) domain.Payment {
+	payer, ok := identities[payment.PayerID]
+	if !ok {
+		return payment
 	}
 
-	for _, artificialMembership := range artificialMemberships {
-		userID := artificialMembership.GetString("user_id")
-		if _, ok := unresolved[userID]; !ok {
-			continue
-		}
-
-		identities[userID] = paymentIdentity{
-			Name: artificialMembership.GetString("name"),
-		}
-		delete(unresolved, userID)
-	}
-
-	if len(unresolved) == 0 {
-		return identities, nil
-	}
-
-	usersCollection, err := app.Dao().FindCollectionByNameOrId("users")
-	if err != nil {
-		return nil, err
-	}
-
-	userRecords, err := app.Dao().FindRecordsByIds(usersCollection.Id, mapKeys(unresolved))
-	if err != nil {
-		return nil, err
-	}
-
-	for _, userRecord := range userRecords {
-		identities[userRecord.Id] = paymentIdentity{
-			Username: userRecord.GetString("username"),
-			Name:     userRecord.GetString("name"),
-		}
-	}
+	payment.PayerName = payer.DisplayName()
+	return payment
+}
 
-	return identities, nil
+func loadPaymentIdentities(app *pocketbase.PocketBase, planID string, userIDs []string) (map[string]planutil.Identity, error) {
+	return planutil.FindIdentitiesWithDao(app.Dao(), planID, userIDs)
 }
 
 func paymentUserIDs(paymentRecords []*pbmodels.Record) []string {
-	userIDs := make([]string, 0, len(paymentRecords))
+	userIDs := make([]string, 0, len(paymentRecords)*2)
 	for _, paymentRecord := range paymentRecords {
-		userIDs = append(userIDs, paymentRecord.GetString("user_id"))
+		userIDs = append(userIDs, paymentRecord.GetString("user_id"), paymentRecord.GetString("payer_id"))
 	}
 
 	return userIDs
 }
-
-func mapKeys(values map[string]struct{}) []string {
-	keys := make([]string, 0, len(values))
-	for key := range values {
-		keys = append(keys, key)
-	}
-
-	return keys
-}
-
-func uniqueNonEmptyStrings(values []string) []string {
-	unique := make([]string, 0, len(values))
-	seen := make(map[string]struct{}, len(values))
-	for _, value := range values {
-		if value == "" {
-			continue
-		}
-		if _, ok := seen[value]; ok {
-			continue
-		}
-
-		seen[value] = struct{}{}
-		unique = append(unique, value)
-	}
-
-	return unique
-}
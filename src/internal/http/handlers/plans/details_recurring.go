@@ -0,0 +1,63 @@
+package plans
+
+import (
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/domain"
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase"
+)
+
+// loadRecurringClaims returns every active standing order for the owner, and the member's own otherwise.
+func loadRecurringClaims(app *pocketbase.PocketBase, plan domain.FamilyPlan, userID string, isOwner bool) ([]domain.RecurringClaim, error) {
+	records, err := billing.FindRecurringClaimsWithDao(app.Dao(), plan.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]string, 0, len(records))
+	for _, record := range records {
+		userIDs = append(userIDs, record.GetString("user_id"))
+	}
+
+	identities, err := loadPaymentIdentities(app, plan.ID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make([]domain.RecurringClaim, 0, len(records))
+	for _, record := range records {
+		claimUserID := record.GetString("user_id")
+		if !isOwner && claimUserID != userID {
+			continue
+		}
+
+		membership, err := planutil.FindMembership(app, plan.ID, claimUserID)
+		if err != nil {
+			return nil, err
+		}
+		if membership == nil {
+			continue
+		}
+
+		identity := identities[claimUserID]
+		name := identity.Name
+		if name == "" {
+			name = identity.Username
+		}
+
+		claims = append(claims, domain.RecurringClaim{
+			ID:          record.Id,
+			UserID:      claimUserID,
+			Name:        name,
+			Amount:      billing.PaymentAmount(record, plan.Currency),
+			Method:      billing.PaymentMethodLabel(record.GetString("method")),
+			DayOfMonth:  record.GetInt("day_of_month"),
+			StartMonth:  monthLabel(record.GetString("start_month")),
+			EndMonth:    monthLabel(record.GetString("end_month")),
+			AutoApprove: membership.GetBool("auto_approve_recurring"),
+		})
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,69 @@
+package plans
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/domain"
+	"familyplan/src/internal/testapp"
+
+	"github.com/labstack/echo/v5"
+)
+
+func TestHandlePlanDetailsShowsBalancesAsOf(t *testing.T) {
+	now := time.Date(2026, time.March, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		asOf         string
+		wantBalance  string
+		wantRedirect bool
+	}{
+		{name: "today", wantBalance: "Balance: -$30.00"},
+		{name: "mid february", asOf: "2026-02-15", wantBalance: "Balance: -$15.00"},
+		{name: "end of january", asOf: "2026-01-31", wantBalance: "Balance: $0.00"},
+		{name: "future", asOf: "2026-04-01", wantRedirect: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := testapp.New(t)
+
+			owner := testapp.SaveUser(t, app, "owner")
+			member := testapp.SaveUser(t, app, "member")
+			plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 3000)
+			testapp.SaveMembership(t, app, plan.Id, owner.Id, time.Date(2026, time.January, 10, 0, 0, 0, 0, time.UTC))
+			testapp.SaveMembership(t, app, plan.Id, member.Id, time.Date(2026, time.January, 10, 0, 0, 0, 0, time.UTC))
+			testapp.SavePayment(t, app, plan.Id, member.Id, 1500, time.Date(2026, time.January, 20, 0, 0, 0, 0, time.UTC), "approved")
+
+			req := httptest.NewRequest(http.MethodGet, "/ABC123?as_of="+tt.asOf, nil)
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+			c.SetPathParams(echo.PathParams{{Name: "join_code", Value: "ABC123"}})
+			c.Set("session", domain.SessionData{IsAuthenticated: true, UserID: member.Id})
+
+			if err := HandlePlanDetails(app, billing.FixedClock(now))(c); err != nil {
+				t.Fatalf("HandlePlanDetails returned error: %v", err)
+			}
+
+			if tt.wantRedirect {
+				if location := rec.Header().Get("Location"); rec.Code != http.StatusSeeOther || !strings.Contains(location, "error=") {
+					t.Fatalf("response = %d %q, want a redirect with an error", rec.Code, location)
+				}
+				return
+			}
+
+			body := rec.Body.String()
+			if !strings.Contains(body, tt.wantBalance) {
+				t.Fatalf("plan page does not show %q", tt.wantBalance)
+			}
+			if !strings.Contains(body, `value="`+tt.asOf+`"`) {
+				t.Fatalf("plan page does not keep the as_of day %q in its form", tt.asOf)
+			}
+		})
+	}
+}
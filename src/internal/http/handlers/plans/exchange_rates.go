@@ -0,0 +1,102 @@
+package plans
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/domain"
+	"familyplan/src/internal/fxrates"
+	"familyplan/src/internal/planutil"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+// HandleSaveExchangeRate stores the owner's rate for paying the plan in another currency.
+func HandleSaveExchangeRate(app *pocketbase.PocketBase) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil || planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		if !planutil.IsOwner(planRecord, session.UserID) {
+			return redirectToPlan(c, joinCode)
+		}
+
+		values := url.Values{}
+		value, err := strconv.ParseFloat(strings.TrimSpace(c.FormValue("rate")), 64)
+		if err != nil {
+			value = 0
+		}
+
+		rate, err := fxrates.NewRate(c.FormValue("base"), planutil.Currency(planRecord), value)
+		if err != nil {
+			if errors.Is(err, fxrates.ErrInvalidRate) {
+				values.Set("error", "Exchange rate must be a positive number for a currency other than the plan's.")
+			} else {
+				values.Set("error", err.Error())
+			}
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode+"?"+values.Encode())
+		}
+
+		err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+			if err := fxrates.SaveWithDao(txDao, planRecord.Id, rate, fxrates.SourceManual); err != nil {
+				return err
+			}
+
+			return audit.RecordWithDao(txDao, audit.Event{
+				PlanID:  planRecord.Id,
+				ActorID: session.UserID,
+				Action:  audit.ActionExchangeRateSaved,
+				Target:  fxrates.CollectionName,
+				After:   map[string]any{"base": rate.Base, "quote": rate.Quote, "rate": rate.Value},
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		values.Set("success", "Exchange rate saved.")
+		return c.Redirect(http.StatusSeeOther, "/"+joinCode+"?"+values.Encode())
+	}
+}
+
+func loadExchangeRates(app *pocketbase.PocketBase, planID, planCurrency string) ([]domain.ExchangeRate, error) {
+	records, err := fxrates.ListWithDao(app.Dao(), planID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Plan rates come first, so a shared rate for the same currency is hidden behind the owner's rate.
+	seen := map[string]bool{}
+	rates := make([]domain.ExchangeRate, 0, len(records))
+	for _, record := range records {
+		base := record.GetString("base")
+		if record.GetString("quote") != planCurrency || seen[base] {
+			continue
+		}
+		seen[base] = true
+
+		rates = append(rates, domain.ExchangeRate{
+			Base:   base,
+			Quote:  record.GetString("quote"),
+			Rate:   record.GetFloat("rate"),
+			Source: record.GetString("source"),
+			Shared: record.GetString("plan_id") == "",
+		})
+	}
+
+	return rates, nil
+}
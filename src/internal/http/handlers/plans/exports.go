@@ -0,0 +1,283 @@
+package plans
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"familyplan/src/internal/archive"
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/export"
+	"familyplan/src/internal/planutil"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// HandleExportPayments streams the plan's payments as CSV to the owner.
+func HandleExportPayments(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return handleCSVExport(app, clock, "payments", export.PaymentStatuses,
+		func(w io.Writer, dao *daos.Dao, plan *pbmodels.Record, filter export.Filter, now time.Time) error {
+			return export.WritePaymentsCSV(w, dao, plan, filter)
+		})
+}
+
+// HandleExportMembers streams the plan's members and their balances as CSV to the owner.
+func HandleExportMembers(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return handleCSVExport(app, clock, "members", export.MemberStatuses,
+		func(w io.Writer, dao *daos.Dao, plan *pbmodels.Record, filter export.Filter, now time.Time) error {
+			return export.WriteMembersCSV(w, dao, plan, filter, now)
+		})
+}
+
+// HandleExportCharges streams what each member was charged per month as CSV to the owner.
+func HandleExportCharges(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return handleCSVExport(app, clock, "charges", export.MemberStatuses,
+		func(w io.Writer, dao *daos.Dao, plan *pbmodels.Record, filter export.Filter, now time.Time) error {
+			return export.WriteChargesCSV(w, dao, plan, filter, now)
+		})
+}
+
+// handleCSVExport checks the owner and the from, to and status query parameters, then streams
+// the export as a CSV download, built as of the clock's time.
+func handleCSVExport(
+	app *pocketbase.PocketBase,
+	clock billing.Clock,
+	name string,
+	statuses []string,
+	write func(io.Writer, *daos.Dao, *pbmodels.Record, export.Filter, time.Time) error,
+) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		now := clock.Now()
+
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil || planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		if !planutil.IsOwner(planRecord, session.UserID) {
+			return redirectToPlan(c, joinCode)
+		}
+
+		filter, err := export.ParseFilter(c.QueryParam("from"), c.QueryParam("to"), c.QueryParam("status"), statuses)
+		if err != nil {
+			values := url.Values{}
+			values.Set("error", "Choose a valid date range and status to export.")
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode+"?"+values.Encode())
+		}
+
+		response := c.Response()
+		response.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+		response.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", joinCode+"-"+name+".csv"))
+		response.WriteHeader(http.StatusOK)
+
+		return write(response, app.Dao(), planRecord, filter, now)
+	}
+}
+
+// HandleExportJournal lets a member download their own history with the plan as a ledger or
+// beancount journal, booked against the accounts they name.
+func HandleExportJournal(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		now := clock.Now()
+
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil || planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		membership, err := planutil.FindMembership(app, planRecord.Id, session.UserID)
+		if err != nil {
+			return err
+		}
+		if membership == nil {
+			return redirectToPlan(c, joinCode)
+		}
+
+		format := c.QueryParam("format")
+		accounts := export.JournalAccounts{
+			Liability: c.QueryParam("liability_account"),
+			Expense:   c.QueryParam("expense_account"),
+			Funding:   c.QueryParam("funding_account"),
+		}
+		if _, err := accounts.WithDefaults().ForPlan(planRecord.GetString("name")); err != nil || !validJournalFormat(format) {
+			values := url.Values{}
+			values.Set("error", "Account names must start with Assets, Liabilities, Equity, Income or Expenses, with each part starting with a capital letter or digit.")
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode+"?"+values.Encode())
+		}
+
+		entries, err := billing.MemberStatementWithDao(app.Dao(), planRecord.Id, session.UserID, now)
+		if err != nil {
+			return err
+		}
+
+		response := c.Response()
+		response.Header().Set(echo.HeaderContentType, "text/plain; charset=utf-8")
+		response.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", joinCode+"."+format))
+		response.WriteHeader(http.StatusOK)
+
+		return export.WriteJournal(response, format, planRecord, entries, accounts)
+	}
+}
+
+func validJournalFormat(format string) bool {
+	return format == export.JournalFormatLedger || format == export.JournalFormatBeancount
+}
+
+// HandleExportStatement lets a member download their charges and approved payments for the plan
+// as an OFX or QIF file. An as_of day builds the statement as it stood at the end of that day, and
+// the owner can name any member to see their history.
+func HandleExportStatement(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		now := clock.Now()
+
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil || planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		userID := session.UserID
+		if memberID := c.QueryParam("member"); memberID != "" && memberID != session.UserID {
+			if !planutil.IsOwner(planRecord, session.UserID) {
+				return redirectToPlan(c, joinCode)
+			}
+			userID = memberID
+		}
+
+		membership, err := planutil.FindMembership(app, planRecord.Id, userID)
+		if err != nil {
+			return err
+		}
+		format := c.QueryParam("format")
+		if membership == nil || !export.ValidStatementFormat(format) {
+			return redirectToPlan(c, joinCode)
+		}
+
+		asOf, err := export.ParseAsOf(c.QueryParam("as_of"), now)
+		if err != nil {
+			values := url.Values{}
+			values.Set("error", "Choose a statement date no later than today.")
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode+"?"+values.Encode())
+		}
+
+		statement, err := export.FindPlanStatementWithDao(app.Dao(), planRecord, userID, asOf)
+		if err != nil {
+			return err
+		}
+
+		filename := joinCode
+		if day := asOfDay(c, asOf); day != "" {
+			filename += "-" + day
+		}
+
+		return writeStatementDownload(c, filename, format, []export.PlanStatement{statement}, asOf)
+	}
+}
+
+// HandleExportAllStatements lets a member download their statements for every plan they belong
+// to as one OFX or QIF file, as of the end of an as_of day when one is given.
+func HandleExportAllStatements(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		now := clock.Now()
+
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+
+		format := c.QueryParam("format")
+		if !export.ValidStatementFormat(format) {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		asOf, err := export.ParseAsOf(c.QueryParam("as_of"), now)
+		if err != nil {
+			values := url.Values{}
+			values.Set("error", "Choose a statement date no later than today.")
+			return c.Redirect(http.StatusSeeOther, "/family-plans?"+values.Encode())
+		}
+
+		statements, err := export.FindMemberStatementsWithDao(app.Dao(), session.UserID, asOf)
+		if err != nil {
+			return err
+		}
+
+		filename := "family-plans"
+		if day := asOfDay(c, asOf); day != "" {
+			filename += "-" + day
+		}
+
+		return writeStatementDownload(c, filename, format, statements, asOf)
+	}
+}
+
+// writeStatementDownload sends statements as an OFX or QIF download, stamped as of asOf.
+func writeStatementDownload(c echo.Context, filename, format string, statements []export.PlanStatement, asOf time.Time) error {
+	contentType := "application/x-ofx"
+	if format == export.StatementFormatQIF {
+		contentType = "application/qif"
+	}
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, contentType)
+	response.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename+"."+format))
+	response.WriteHeader(http.StatusOK)
+
+	return export.WriteStatements(response, format, statements, asOf)
+}
+
+// HandleExportArchive lets the owner download the whole plan as a JSON archive.
+func HandleExportArchive(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		now := clock.Now()
+
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil || planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		if !planutil.IsOwner(planRecord, session.UserID) {
+			return redirectToPlan(c, joinCode)
+		}
+
+		planArchive, err := archive.ExportPlanWithDao(app.Dao(), planRecord.Id, now)
+		if err != nil {
+			return err
+		}
+
+		response := c.Response()
+		response.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+		response.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", joinCode+"-archive.json"))
+		response.WriteHeader(http.StatusOK)
+
+		return archive.Write(response, planArchive)
+	}
+}
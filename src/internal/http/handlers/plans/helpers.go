@@ -3,29 +3,55 @@ package plans
 import (
 	"fmt"
 	"net/http"
+	"time"
 
+	"familyplan/src/internal/billing"
 	"familyplan/src/internal/domain"
 	"familyplan/src/internal/money"
+	"familyplan/src/internal/planutil"
+	"familyplan/src/internal/view"
 
 	"github.com/labstack/echo/v5"
 	pbmodels "github.com/pocketbase/pocketbase/models"
 )
 
-func buildFamilyPlan(record *pbmodels.Record, membersCount int, balance float64) domain.FamilyPlan {
+func buildFamilyPlan(record *pbmodels.Record, membersCount int, balance money.Amount) domain.FamilyPlan {
+	currency := planutil.Currency(record)
+	lateFeeRule := billing.LateFeeRuleFor(record)
+
+	archivedAt := ""
+	if planutil.IsArchived(record) {
+		archivedAt = planutil.ArchivedAt(record).Format("2006-01-02")
+	}
+
 	return domain.FamilyPlan{
 		ID:             record.Id,
 		Name:           record.GetString("name"),
 		Description:    record.GetString("description"),
-		Cost:           money.Normalize(record.GetFloat("cost")),
-		IndividualCost: money.Normalize(record.GetFloat("individual_cost")),
+		Currency:       currency,
+		Cost:           planutil.Cost(record),
+		IndividualCost: planutil.IndividualCost(record),
+		TrialMonths:    billing.TrialMonths(record),
+		DueDay:         lateFeeRule.DueDay,
+		LateFeeDays:    lateFeeRule.DaysOverdue,
+		LateFeeAmount:  lateFeeRule.Fixed,
+		LateFeePercent: lateFeeRule.Percent,
 		Owner:          ownerID(record),
 		JoinCode:       record.GetString("join_code"),
 		CreatedAt:      record.GetDateTime("created").String(),
 		MembersCount:   membersCount,
-		Balance:        money.Normalize(balance),
+		Balance:        balance,
+		IsArchived:     planutil.IsArchived(record),
+		ArchivedAt:     archivedAt,
 	}
 }
 
+// parseFormAmount reads an amount submitted from a number input.
+// Browsers submit number inputs with a "." decimal separator; anything else is read in the request's locale.
+func parseFormAmount(c echo.Context, value, currency string) (money.Amount, error) {
+	return money.ParseInput(value, currency, view.RequestLocale(c))
+}
+
 func ownerID(plan *pbmodels.Record) string {
 	ownerIDs := plan.GetStringSlice("owner")
 	if len(ownerIDs) == 0 {
@@ -47,25 +73,38 @@ func activeMembershipCount(memberships []*pbmodels.Record) int {
 	return count
 }
 
-func buildPayment(record *pbmodels.Record, username, name string) domain.Payment {
+func buildPayment(record *pbmodels.Record, currency, username, name string) domain.Payment {
 	paymentDate := record.GetDateTime("date")
 	dateValue := ""
 	if !paymentDate.IsZero() {
 		dateValue = paymentDate.Time().Format("2006-01-02")
 	}
 
-	return domain.Payment{
-		ID:       record.Id,
-		PlanID:   record.GetString("plan_id"),
-		UserID:   record.GetString("user_id"),
-		Amount:   money.Normalize(record.GetFloat("amount")),
-		Date:     dateValue,
-		Status:   record.GetString("status"),
-		Notes:    record.GetString("notes"),
-		ForMonth: formatForMonth(record),
-		Username: username,
-		Name:     name,
+	payment := domain.Payment{
+		ID:          record.Id,
+		PlanID:      record.GetString("plan_id"),
+		UserID:      record.GetString("user_id"),
+		Amount:      billing.PaymentAmount(record, currency),
+		Date:        dateValue,
+		Status:      record.GetString("status"),
+		Notes:       record.GetString("notes"),
+		ForMonth:    formatForMonth(record),
+		Kind:        record.GetString("kind"),
+		PayerID:     billing.PayerID(record),
+		Username:    username,
+		Name:        name,
+		Method:      record.GetString("method"),
+		MethodLabel: billing.PaymentMethodLabel(record.GetString("method")),
+		Reference:   record.GetString("reference"),
+	}
+
+	if originalCurrency := record.GetString("original_currency"); originalCurrency != "" && originalCurrency != currency {
+		payment.OriginalAmount = money.New(int64(record.GetInt("original_amount")), originalCurrency)
+		payment.ExchangeRate = record.GetFloat("exchange_rate")
+		payment.IsConverted = true
 	}
+
+	return payment
 }
 
 func formatForMonth(record *pbmodels.Record) string {
@@ -77,6 +116,15 @@ func formatForMonth(record *pbmodels.Record) string {
 	return forMonth.Time().Format("2006-01")
 }
 
+// asOfDay returns the day an as_of query asked for, or "" when the request is for now.
+func asOfDay(c echo.Context, asOf time.Time) string {
+	if c.QueryParam("as_of") == "" {
+		return ""
+	}
+
+	return asOf.Format("2006-01-02")
+}
+
 func redirectToPlan(c echo.Context, joinCode string) error {
 	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/%s", joinCode))
 }
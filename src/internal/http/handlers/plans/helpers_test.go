@@ -6,6 +6,8 @@ import (
 	"testing"
 	"time"
 
+	"familyplan/src/internal/money"
+
 	"github.com/labstack/echo/v5"
 	pbmodels "github.com/pocketbase/pocketbase/models"
 	"github.com/pocketbase/pocketbase/models/schema"
@@ -59,28 +61,30 @@ func TestBuildFamilyPlanMapsRecordFields(t *testing.T) {
 		&schema.SchemaField{Name: "cost", Type: schema.FieldTypeNumber},
 		&schema.SchemaField{Name: "individual_cost", Type: schema.FieldTypeNumber},
 		&schema.SchemaField{Name: "join_code", Type: schema.FieldTypeText},
+		&schema.SchemaField{Name: "currency", Type: schema.FieldTypeText},
 	)
 	record.Id = "plan_123"
 	record.Set("name", "Streaming Bundle")
 	record.Set("description", "Shared video plan")
-	record.Set("cost", 19.99)
-	record.Set("individual_cost", 8.25)
+	record.Set("cost", 1999)
+	record.Set("individual_cost", 825)
 	record.Set("owner", []string{"owner_1"})
 	record.Set("join_code", "JOIN42")
+	record.Set("currency", "EUR")
 	record.Set("created", mustDateTime(t, time.Date(2026, time.March, 15, 10, 30, 0, 0, time.UTC)))
 
-	got := buildFamilyPlan(record, 4, 12.34)
+	got := buildFamilyPlan(record, 4, money.New(1234, "EUR"))
 
 	if got.ID != "plan_123" || got.Name != "Streaming Bundle" || got.Description != "Shared video plan" {
 		t.Fatalf("buildFamilyPlan() returned unexpected basic fields: %+v", got)
 	}
-	if got.Cost != 19.99 || got.IndividualCost != 8.25 {
+	if got.Currency != "EUR" || got.Cost != money.New(1999, "EUR") || got.IndividualCost != money.New(825, "EUR") {
 		t.Fatalf("buildFamilyPlan() returned unexpected costs: %+v", got)
 	}
 	if got.Owner != "owner_1" || got.JoinCode != "JOIN42" {
 		t.Fatalf("buildFamilyPlan() returned unexpected owner/join code: %+v", got)
 	}
-	if got.MembersCount != 4 || got.Balance != 12.34 {
+	if got.MembersCount != 4 || got.Balance != money.New(1234, "EUR") {
 		t.Fatalf("buildFamilyPlan() returned unexpected counts/balance: %+v", got)
 	}
 	if got.CreatedAt == "" {
@@ -101,18 +105,18 @@ func TestBuildPaymentFormatsDateFields(t *testing.T) {
 	record.Id = "payment_123"
 	record.Set("plan_id", "plan_123")
 	record.Set("user_id", "user_456")
-	record.Set("amount", 15.5)
+	record.Set("amount", 1550)
 	record.Set("date", mustDateTime(t, time.Date(2026, time.April, 1, 12, 0, 0, 0, time.UTC)))
 	record.Set("status", "approved")
 	record.Set("notes", "paid")
 	record.Set("for_month", mustDateTime(t, time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)))
 
-	got := buildPayment(record, "marcus", "Marcus")
+	got := buildPayment(record, "USD", "marcus", "Marcus")
 
 	if got.ID != "payment_123" || got.PlanID != "plan_123" || got.UserID != "user_456" {
 		t.Fatalf("buildPayment() returned unexpected ids: %+v", got)
 	}
-	if got.Amount != 15.5 || got.Date != "2026-04-01" || got.ForMonth != "2026-03" {
+	if got.Amount != money.New(1550, "USD") || got.Date != "2026-04-01" || got.ForMonth != "2026-03" {
 		t.Fatalf("buildPayment() returned unexpected date data: %+v", got)
 	}
 	if got.Status != "approved" || got.Notes != "paid" || got.Username != "marcus" || got.Name != "Marcus" {
@@ -148,6 +152,48 @@ func TestRedirectToPlanUsesSeeOther(t *testing.T) {
 	}
 }
 
+func TestParseFormAmountReadsTheRequestLocale(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		value          string
+		want           int64
+		wantErr        bool
+	}{
+		{name: "number input", acceptLanguage: "de-DE", value: "12.50", want: 1250},
+		{name: "german decimal comma", acceptLanguage: "de-DE", value: "1,50", want: 150},
+		{name: "english grouping", acceptLanguage: "en-US", value: "1,500", want: 150000},
+		{name: "english decimal comma", acceptLanguage: "en-US", value: "1,50", wantErr: true},
+		{name: "no language", value: "1,50", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			got, err := parseFormAmount(c, tt.value, "EUR")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseFormAmount(%q) = %+v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFormAmount(%q) returned error: %v", tt.value, err)
+			}
+			if got.Minor != tt.want {
+				t.Fatalf("parseFormAmount(%q) = %d, want %d", tt.value, got.Minor, tt.want)
+			}
+		})
+	}
+}
+
 func newTestRecord(fields ...*schema.SchemaField) *pbmodels.Record {
 	collection := &pbmodels.Collection{
 		Name:   "test_collection",
@@ -168,3 +214,30 @@ func mustDateTime(t *testing.T, value time.Time) types.DateTime {
 
 	return dt
 }
+
+func TestCoverageStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		due     int64
+		covered int64
+		future  bool
+		want    string
+	}{
+		{name: "nothing due", due: 0, covered: 0, want: coverageFree},
+		{name: "fully covered", due: 1000, covered: 1000, want: coveragePaid},
+		{name: "prepaid future month", due: 1000, covered: 1000, future: true, want: coveragePaid},
+		{name: "partly covered", due: 1000, covered: 400, want: coveragePartial},
+		{name: "past month unpaid", due: 1000, covered: 0, want: coverageUnpaid},
+		{name: "future month not yet paid", due: 1000, covered: 0, future: true, want: coverageUpcoming},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := coverageStatus(tt.due, tt.covered, tt.future); got != tt.want {
+				t.Fatalf("coverageStatus(%d, %d, %v) = %q, want %q", tt.due, tt.covered, tt.future, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package plans
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"familyplan/src/internal/archive"
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// maxArchiveSize caps uploaded plan archives.
+const maxArchiveSize = 10 << 20
+
+// HandleImportPlan recreates a plan from an uploaded archive with the current user as its owner.
+// Other members come back as artificial members the owner can send claim links to.
+func HandleImportPlan(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		now := clock.Now()
+
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+
+		values := url.Values{}
+		fileHeader, err := c.FormFile("archive")
+		if err != nil || fileHeader.Size > maxArchiveSize {
+			values.Set("error", "Choose a plan archive of 10 MB or less to import.")
+			return c.Redirect(http.StatusSeeOther, "/family-plans?"+values.Encode())
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		planArchive, err := archive.Read(http.MaxBytesReader(c.Response(), file, maxArchiveSize))
+		if err != nil {
+			if errors.Is(err, archive.ErrUnsupportedVersion) {
+				values.Set("error", "This archive was made by a different version of FamilyPlan and can't be imported.")
+			} else {
+				values.Set("error", "That file is not a plan archive.")
+			}
+			return c.Redirect(http.StatusSeeOther, "/family-plans?"+values.Encode())
+		}
+
+		var planRecord *pbmodels.Record
+		err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+			planRecord, err = archive.ImportPlanWithDao(txDao, planArchive, session.UserID, nil, now)
+			if err != nil {
+				return err
+			}
+
+			return audit.RecordWithDao(txDao, audit.Event{
+				PlanID:   planRecord.Id,
+				ActorID:  session.UserID,
+				Action:   audit.ActionPlanImported,
+				Target:   "family_plans",
+				TargetID: planRecord.Id,
+				After:    audit.Snapshot(planRecord),
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		values.Set("success", "Plan imported. Send claim links to the members so they can take over their seats.")
+		return c.Redirect(http.StatusSeeOther, "/"+planRecord.GetString("join_code")+"?"+values.Encode())
+	}
+}
@@ -1,8 +1,13 @@
 package plans
 
 import (
+	"net/http"
+	"net/url"
+
 	"familyplan/src/internal/billing"
 	"familyplan/src/internal/domain"
+	"familyplan/src/internal/export"
+	"familyplan/src/internal/money"
 	"familyplan/src/internal/planutil"
 	"familyplan/src/internal/view"
 
@@ -11,13 +16,25 @@ import (
 	pbmodels "github.com/pocketbase/pocketbase/models"
 )
 
-// HandleFamilyPlansList renders the current user's plans.
-func HandleFamilyPlansList(app *pocketbase.PocketBase) echo.HandlerFunc {
+// HandleFamilyPlansList renders the current user's plans. Archived plans are only listed
+// when the archived query parameter asks for them, and an as_of day shows the balances as they
+// stood at the end of that day.
+func HandleFamilyPlansList(app *pocketbase.PocketBase, clock billing.Clock) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		now := clock.Now()
+
 		session, err := sessionOrRedirect(c)
 		if err != nil {
 			return err
 		}
+		showArchived := c.QueryParam("archived") == "1"
+
+		balancesAsOf, err := export.ParseAsOf(c.QueryParam("as_of"), now)
+		if err != nil {
+			values := url.Values{}
+			values.Set("error", "Choose a balance date no later than today.")
+			return c.Redirect(http.StatusSeeOther, "/family-plans?"+values.Encode())
+		}
 
 		plansCollection, err := app.Dao().FindCollectionByNameOrId("family_plans")
 		if err != nil {
@@ -87,7 +104,15 @@ func HandleFamilyPlansList(app *pocketbase.PocketBase) echo.HandlerFunc {
 		}
 
 		plansList := make([]domain.FamilyPlan, 0, len(planMap))
+		archivedCount := 0
 		for _, planRecord := range planMap {
+			if planutil.IsArchived(planRecord) {
+				archivedCount++
+				if !showArchived {
+					continue
+				}
+			}
+
 			planMembershipFilter, err := planutil.BuildEqualsFilter(
 				planutil.FilterTerm{Field: "plan_id", Value: planRecord.Id},
 			)
@@ -109,10 +134,10 @@ func HandleFamilyPlansList(app *pocketbase.PocketBase) echo.HandlerFunc {
 				membersCount = activeMembershipCount(membershipRecords)
 			}
 
-			balance := 0.0
+			balance := money.New(0, planutil.Currency(planRecord))
 			isOwner := ownerID(planRecord) == session.UserID
 			if !isOwner && membershipMap[planRecord.Id] != nil {
-				balanceAmount, err := billing.CalculateMemberBalance(app, planRecord.Id, session.UserID)
+				balanceAmount, err := billing.CalculateMemberBalance(app, planRecord.Id, session.UserID, balancesAsOf)
 				if err == nil {
 					balance = balanceAmount
 				}
@@ -122,8 +147,12 @@ func HandleFamilyPlansList(app *pocketbase.PocketBase) echo.HandlerFunc {
 		}
 
 		return view.RenderPage(c, "family_plans.html", map[string]interface{}{
-			"title": "My Family Plans",
-			"plans": plansList,
+			"title":          "My Family Plans",
+			"plans":          plansList,
+			"show_archived":  showArchived,
+			"archived_count": archivedCount,
+			"as_of":          asOfDay(c, balancesAsOf),
+			"error":          c.QueryParam("error"),
 		})
 	}
 }
@@ -0,0 +1,42 @@
+package plans
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/domain"
+	"familyplan/src/internal/testapp"
+
+	"github.com/labstack/echo/v5"
+)
+
+func TestHandleFamilyPlansListShowsBalancesAsOf(t *testing.T) {
+	now := time.Date(2026, time.March, 15, 12, 0, 0, 0, time.UTC)
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 3000)
+	testapp.SaveMembership(t, app, plan.Id, member.Id, time.Date(2026, time.January, 10, 0, 0, 0, 0, time.UTC))
+
+	req := httptest.NewRequest(http.MethodGet, "/family-plans?as_of=2026-01-31", nil)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.Set("session", domain.SessionData{IsAuthenticated: true, UserID: member.Id})
+
+	if err := HandleFamilyPlansList(app, billing.FixedClock(now))(c); err != nil {
+		t.Fatalf("HandleFamilyPlansList returned error: %v", err)
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "Balance: -$15.00") {
+		t.Fatal("plans list does not show the balance at the end of January")
+	}
+	if !strings.Contains(body, "/family-plans/export?format=ofx&as_of=2026-01-31") {
+		t.Fatal("plans list statement links do not carry the as_of day")
+	}
+}
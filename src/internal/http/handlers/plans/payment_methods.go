@@ -0,0 +1,103 @@
+package plans
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/domain"
+	"familyplan/src/internal/planutil"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// HandleSavePaymentMethods stores which payment methods the owner accepts and how to pay by each.
+// Every method is submitted as accept_<method> with its instructions as instructions_<method>.
+func HandleSavePaymentMethods(app *pocketbase.PocketBase) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		session, err := sessionOrRedirect(c)
+		if err != nil {
+			return err
+		}
+		joinCode := c.PathParam("join_code")
+
+		planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+		if err != nil || planRecord == nil {
+			return c.Redirect(http.StatusSeeOther, "/family-plans")
+		}
+
+		if !planutil.IsOwner(planRecord, session.UserID) {
+			return redirectToPlan(c, joinCode)
+		}
+
+		accepted := []billing.AcceptedPaymentMethod{}
+		for _, method := range billing.PaymentMethods {
+			if c.FormValue("accept_"+method) == "" {
+				continue
+			}
+
+			accepted = append(accepted, billing.AcceptedPaymentMethod{
+				Method:       method,
+				Instructions: c.FormValue("instructions_" + method),
+			})
+		}
+
+		values := url.Values{}
+		if err := billing.SetAcceptedPaymentMethods(planRecord, accepted); err != nil {
+			if errors.Is(err, billing.ErrPaymentInstructionsTooLong) {
+				values.Set("error", fmt.Sprintf("Payment instructions must be %d characters or fewer.", billing.MaxPaymentInstructionsLength))
+			} else {
+				values.Set("error", err.Error())
+			}
+			return c.Redirect(http.StatusSeeOther, "/"+joinCode+"?"+values.Encode())
+		}
+
+		err = audit.TrackWithDao(app.Dao(), planEvent(planRecord, session.UserID, audit.ActionPaymentMethodsSaved), func(txDao *daos.Dao) error {
+			return txDao.SaveRecord(planRecord)
+		})
+		if err != nil {
+			return err
+		}
+
+		values.Set("success", "Payment methods saved.")
+		return c.Redirect(http.StatusSeeOther, "/"+joinCode+"?"+values.Encode())
+	}
+}
+
+// loadPaymentMethods lists every payment method with whether the plan accepts it.
+// When the owner has not chosen any, all of them are offered.
+func loadPaymentMethods(plan *pbmodels.Record) []domain.PaymentMethodOption {
+	accepted := map[string]billing.AcceptedPaymentMethod{}
+	for _, method := range billing.AcceptedPaymentMethods(plan) {
+		accepted[method.Method] = method
+	}
+
+	options := make([]domain.PaymentMethodOption, 0, len(billing.PaymentMethods))
+	for _, method := range billing.PaymentMethods {
+		acceptedMethod, ok := accepted[method]
+		options = append(options, domain.PaymentMethodOption{
+			Method:       method,
+			Label:        billing.PaymentMethodLabel(method),
+			Accepted:     ok,
+			Offered:      ok || len(accepted) == 0,
+			Instructions: acceptedMethod.Instructions,
+		})
+	}
+
+	return options
+}
+
+// paymentMethodFilter returns the method the owner's payments table is filtered by, if it is a known one.
+func paymentMethodFilter(raw string) string {
+	if billing.PaymentMethodLabel(raw) == "" {
+		return ""
+	}
+
+	return raw
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"familyplan/src/internal/planutil"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+)
+
+// RejectArchivedPlans keeps archived plans read-only by sending changes back to the plan page.
+func RejectArchivedPlans(app *pocketbase.PocketBase) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			joinCode := c.PathParam("join_code")
+			planRecord, err := planutil.FindPlanByJoinCode(app, joinCode)
+			if err != nil {
+				return err
+			}
+
+			if planRecord != nil && planutil.IsArchived(planRecord) {
+				return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/%s?error=This+plan+is+archived.+Restore+it+to+make+changes.", joinCode))
+			}
+
+			return next(c)
+		}
+	}
+}
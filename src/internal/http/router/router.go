@@ -4,7 +4,9 @@ import (
 	"net/http"
 	"time"
 
+	"familyplan/src/internal/billing"
 	authhandlers "familyplan/src/internal/http/handlers/auth"
+	"familyplan/src/internal/http/handlers/households"
 	"familyplan/src/internal/http/handlers/memberships"
 	"familyplan/src/internal/http/handlers/payments"
 	"familyplan/src/internal/http/handlers/plans"
@@ -16,8 +18,8 @@ import (
 	"github.com/pocketbase/pocketbase"
 )
 
-// Setup configures the application routes.
-func Setup(app *pocketbase.PocketBase, e *echo.Echo) {
+// Setup configures the application routes. Handlers read the time from clock.
+func Setup(app *pocketbase.PocketBase, e *echo.Echo, clock billing.Clock) {
 	e.Use(authmw.SetupAuth(app))
 
 	authLimiter := echomw.RateLimiterWithConfig(echomw.RateLimiterConfig{
@@ -38,37 +40,71 @@ func Setup(app *pocketbase.PocketBase, e *echo.Echo) {
 
 	e.GET("/", authhandlers.HandleHome())
 	e.GET("/login", authhandlers.HandleLoginPage())
-	e.POST("/login", authhandlers.HandleLoginSubmit(app), authLimiter)
+	e.POST("/login", authhandlers.HandleLoginSubmit(app, clock), authLimiter)
 	e.GET("/register", authhandlers.HandleRegisterPage())
-	e.POST("/register", authhandlers.HandleRegisterSubmit(app), authLimiter)
+	e.POST("/register", authhandlers.HandleRegisterSubmit(app, clock), authLimiter)
 	e.GET("/logout", authhandlers.HandleLogout())
-	e.GET("/claim-member/:token", memberships.HandleClaimMemberPage(app))
-	e.POST("/claim-member/:token", memberships.HandleClaimMember(app))
+	e.GET("/claim-member/:token", memberships.HandleClaimMemberPage(app, clock))
+	e.POST("/claim-member/:token", memberships.HandleClaimMember(app, clock))
 
 	authenticated := e.Group("", authmw.RequireAuth)
+	// Archived plans are read-only, so every change to a plan is registered on this group.
+	planChanges := authenticated.Group("", authmw.RejectArchivedPlans(app))
 
 	authenticated.GET("/profile", profilehandlers.HandleProfilePage(app))
 	authenticated.POST("/profile", profilehandlers.HandleProfileUpdate(app))
 
-	authenticated.GET("/family-plans", plans.HandleFamilyPlansList(app))
+	authenticated.GET("/family-plans", plans.HandleFamilyPlansList(app, clock))
 	authenticated.POST("/family-plans/create", plans.HandleCreateFamilyPlan(app))
 	authenticated.POST("/family-plans/join", plans.HandleJoinPlan(app))
-	authenticated.GET("/:join_code", plans.HandlePlanDetails(app))
-	authenticated.POST("/:join_code/delete", plans.HandleDeletePlan(app))
-	authenticated.POST("/:join_code/update", plans.HandleUpdatePlan(app))
+	authenticated.GET("/family-plans/export", plans.HandleExportAllStatements(app, clock))
+	authenticated.POST("/family-plans/import", plans.HandleImportPlan(app, clock))
+	authenticated.GET("/households", households.HandleHouseholdsList(app))
+	authenticated.POST("/households/create", households.HandleCreateHousehold(app))
+	authenticated.GET("/households/:household_id", households.HandleHouseholdDetails(app, clock))
+	authenticated.POST("/households/:household_id/add-plan", households.HandleAddHouseholdPlan(app))
+	authenticated.POST("/households/:household_id/remove-plan", households.HandleRemoveHouseholdPlan(app))
+	authenticated.POST("/households/:household_id/delete", households.HandleDeleteHousehold(app))
+	authenticated.GET("/:join_code", plans.HandlePlanDetails(app, clock))
+	authenticated.POST("/:join_code/restore", plans.HandleRestorePlan(app, clock))
+	authenticated.POST("/:join_code/purge", plans.HandlePurgePlan(app, clock))
+	planChanges.POST("/:join_code/archive", plans.HandleArchivePlan(app, clock))
+	planChanges.POST("/:join_code/update", plans.HandleUpdatePlan(app, clock))
+	planChanges.POST("/:join_code/exchange-rate", plans.HandleSaveExchangeRate(app))
+	planChanges.POST("/:join_code/payment-methods", plans.HandleSavePaymentMethods(app))
+	authenticated.GET("/:join_code/export/payments.csv", plans.HandleExportPayments(app, clock))
+	authenticated.GET("/:join_code/export/members.csv", plans.HandleExportMembers(app, clock))
+	authenticated.GET("/:join_code/export/charges.csv", plans.HandleExportCharges(app, clock))
+	authenticated.GET("/:join_code/export/journal", plans.HandleExportJournal(app, clock))
+	authenticated.GET("/:join_code/export/statement", plans.HandleExportStatement(app, clock))
+	authenticated.GET("/:join_code/export/archive.json", plans.HandleExportArchive(app, clock))
 
-	authenticated.GET("/:join_code/request-join", memberships.HandleRequestJoin(app))
-	authenticated.POST("/:join_code/request-join", memberships.HandleRequestJoin(app))
-	authenticated.POST("/:join_code/approve-request", memberships.HandleApproveRequest(app))
-	authenticated.POST("/:join_code/deny-request", memberships.HandleDenyRequest(app))
-	authenticated.POST("/:join_code/remove-member", memberships.HandleRemoveMember(app))
-	authenticated.POST("/:join_code/leave", memberships.HandleLeavePlan(app))
-	authenticated.POST("/:join_code/add-artificial-member", memberships.HandleAddArtificialMember(app))
-	authenticated.POST("/:join_code/create-member-claim-link", memberships.HandleCreateMemberClaimLink(app))
-	authenticated.POST("/:join_code/transfer-membership", memberships.HandleTransferMembership(app))
+	planChanges.GET("/:join_code/request-join", memberships.HandleRequestJoin(app))
+	planChanges.POST("/:join_code/request-join", memberships.HandleRequestJoin(app))
+	planChanges.POST("/:join_code/approve-request", memberships.HandleApproveRequest(app, clock))
+	planChanges.POST("/:join_code/deny-request", memberships.HandleDenyRequest(app))
+	planChanges.POST("/:join_code/remove-member", memberships.HandleRemoveMember(app, clock))
+	planChanges.POST("/:join_code/reinstate-member", memberships.HandleReinstateMember(app, clock))
+	planChanges.POST("/:join_code/write-off-member", memberships.HandleWriteOffMember(app, clock))
+	planChanges.POST("/:join_code/grant-grace", memberships.HandleGrantGracePeriod(app))
+	planChanges.POST("/:join_code/pause-member", memberships.HandlePauseMember(app, clock))
+	planChanges.POST("/:join_code/resume-member", memberships.HandleResumeMember(app, clock))
+	planChanges.POST("/:join_code/leave", memberships.HandleLeavePlan(app, clock))
+	planChanges.POST("/:join_code/add-artificial-member", memberships.HandleAddArtificialMember(app, clock))
+	planChanges.POST("/:join_code/create-member-claim-link", memberships.HandleCreateMemberClaimLink(app, clock))
+	planChanges.POST("/:join_code/regenerate-member-claim-link", memberships.HandleRegenerateMemberClaimLink(app, clock))
+	planChanges.POST("/:join_code/revoke-member-claim-link", memberships.HandleRevokeMemberClaimLink(app, clock))
+	planChanges.POST("/:join_code/transfer-membership", memberships.HandleTransferMembership(app, clock))
+	planChanges.POST("/:join_code/merge-member", memberships.HandleMergeArtificialMember(app, clock))
 
-	authenticated.POST("/:join_code/claim-payment", payments.HandleClaimPayment(app))
-	authenticated.POST("/:join_code/approve-payment", payments.HandleApprovePayment(app))
-	authenticated.POST("/:join_code/reject-payment", payments.HandleRejectPayment(app))
-	authenticated.POST("/:join_code/add-payment", payments.HandleAddManualPayment(app))
+	planChanges.POST("/:join_code/claim-payment", payments.HandleClaimPayment(app, clock))
+	planChanges.POST("/:join_code/approve-payment", payments.HandleApprovePayment(app, clock))
+	planChanges.POST("/:join_code/reject-payment", payments.HandleRejectPayment(app))
+	planChanges.POST("/:join_code/add-payment", payments.HandleAddManualPayment(app, clock))
+	planChanges.POST("/:join_code/add-adjustment", payments.HandleAddAdjustment(app))
+	planChanges.POST("/:join_code/delete-adjustment", payments.HandleDeleteAdjustment(app))
+	planChanges.POST("/:join_code/waive-adjustment", payments.HandleWaiveAdjustment(app))
+	planChanges.POST("/:join_code/add-standing-order", payments.HandleAddRecurringClaim(app))
+	planChanges.POST("/:join_code/cancel-standing-order", payments.HandleCancelRecurringClaim(app, clock))
+	planChanges.POST("/:join_code/trust-standing-orders", payments.HandleTrustRecurringClaims(app))
 }
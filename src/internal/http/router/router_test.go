@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"testing"
 
+	"familyplan/src/internal/billing"
+
 	"github.com/labstack/echo/v5"
 	"github.com/pocketbase/pocketbase"
 )
@@ -12,34 +14,66 @@ func TestSetupRegistersExpectedRoutes(t *testing.T) {
 	t.Parallel()
 
 	e := echo.New()
-	Setup(&pocketbase.PocketBase{}, e)
+	Setup(&pocketbase.PocketBase{}, e, billing.SystemClock)
 
 	expected := map[string]string{
-		http.MethodGet + " /":                                     "/",
-		http.MethodGet + " /login":                                "/login",
-		http.MethodPost + " /login":                               "/login",
-		http.MethodGet + " /register":                             "/register",
-		http.MethodPost + " /register":                            "/register",
-		http.MethodGet + " /logout":                               "/logout",
-		http.MethodGet + " /claim-member/:token":                  "/claim-member/:token",
-		http.MethodPost + " /claim-member/:token":                 "/claim-member/:token",
-		http.MethodGet + " /profile":                              "/profile",
-		http.MethodPost + " /profile":                             "/profile",
-		http.MethodGet + " /family-plans":                         "/family-plans",
-		http.MethodPost + " /family-plans/create":                 "/family-plans/create",
-		http.MethodPost + " /family-plans/join":                   "/family-plans/join",
-		http.MethodGet + " /:join_code":                           "/:join_code",
-		http.MethodPost + " /:join_code/delete":                   "/:join_code/delete",
-		http.MethodPost + " /:join_code/update":                   "/:join_code/update",
-		http.MethodPost + " /:join_code/approve-request":          "/:join_code/approve-request",
-		http.MethodPost + " /:join_code/deny-request":             "/:join_code/deny-request",
-		http.MethodPost + " /:join_code/remove-member":            "/:join_code/remove-member",
-		http.MethodPost + " /:join_code/leave":                    "/:join_code/leave",
-		http.MethodPost + " /:join_code/add-artificial-member":    "/:join_code/add-artificial-member",
-		http.MethodPost + " /:join_code/create-member-claim-link": "/:join_code/create-member-claim-link",
-		http.MethodPost + " /:join_code/transfer-membership":      "/:join_code/transfer-membership",
-		http.MethodPost + " /:join_code/claim-payment":            "/:join_code/claim-payment",
-		http.MethodPost + " /:join_code/add-payment":              "/:join_code/add-payment",
+		http.MethodGet + " /":                                         "/",
+		http.MethodGet + " /login":                                    "/login",
+		http.MethodPost + " /login":                                   "/login",
+		http.MethodGet + " /register":                                 "/register",
+		http.MethodPost + " /register":                                "/register",
+		http.MethodGet + " /logout":                                   "/logout",
+		http.MethodGet + " /claim-member/:token":                      "/claim-member/:token",
+		http.MethodPost + " /claim-member/:token":                     "/claim-member/:token",
+		http.MethodGet + " /profile":                                  "/profile",
+		http.MethodPost + " /profile":                                 "/profile",
+		http.MethodGet + " /family-plans":                             "/family-plans",
+		http.MethodPost + " /family-plans/create":                     "/family-plans/create",
+		http.MethodPost + " /family-plans/join":                       "/family-plans/join",
+		http.MethodGet + " /family-plans/export":                      "/family-plans/export",
+		http.MethodPost + " /family-plans/import":                     "/family-plans/import",
+		http.MethodGet + " /households":                               "/households",
+		http.MethodPost + " /households/create":                       "/households/create",
+		http.MethodGet + " /households/:household_id":                 "/households/:household_id",
+		http.MethodPost + " /households/:household_id/add-plan":       "/households/:household_id/add-plan",
+		http.MethodPost + " /households/:household_id/remove-plan":    "/households/:household_id/remove-plan",
+		http.MethodPost + " /households/:household_id/delete":         "/households/:household_id/delete",
+		http.MethodGet + " /:join_code":                               "/:join_code",
+		http.MethodPost + " /:join_code/archive":                      "/:join_code/archive",
+		http.MethodPost + " /:join_code/restore":                      "/:join_code/restore",
+		http.MethodPost + " /:join_code/purge":                        "/:join_code/purge",
+		http.MethodPost + " /:join_code/update":                       "/:join_code/update",
+		http.MethodPost + " /:join_code/payment-methods":              "/:join_code/payment-methods",
+		http.MethodGet + " /:join_code/export/payments.csv":           "/:join_code/export/payments.csv",
+		http.MethodGet + " /:join_code/export/members.csv":            "/:join_code/export/members.csv",
+		http.MethodGet + " /:join_code/export/charges.csv":            "/:join_code/export/charges.csv",
+		http.MethodGet + " /:join_code/export/journal":                "/:join_code/export/journal",
+		http.MethodGet + " /:join_code/export/statement":              "/:join_code/export/statement",
+		http.MethodGet + " /:join_code/export/archive.json":           "/:join_code/export/archive.json",
+		http.MethodPost + " /:join_code/exchange-rate":                "/:join_code/exchange-rate",
+		http.MethodPost + " /:join_code/approve-request":              "/:join_code/approve-request",
+		http.MethodPost + " /:join_code/deny-request":                 "/:join_code/deny-request",
+		http.MethodPost + " /:join_code/remove-member":                "/:join_code/remove-member",
+		http.MethodPost + " /:join_code/reinstate-member":             "/:join_code/reinstate-member",
+		http.MethodPost + " /:join_code/write-off-member":             "/:join_code/write-off-member",
+		http.MethodPost + " /:join_code/grant-grace":                  "/:join_code/grant-grace",
+		http.MethodPost + " /:join_code/pause-member":                 "/:join_code/pause-member",
+		http.MethodPost + " /:join_code/resume-member":                "/:join_code/resume-member",
+		http.MethodPost + " /:join_code/leave":                        "/:join_code/leave",
+		http.MethodPost + " /:join_code/add-artificial-member":        "/:join_code/add-artificial-member",
+		http.MethodPost + " /:join_code/create-member-claim-link":     "/:join_code/create-member-claim-link",
+		http.MethodPost + " /:join_code/regenerate-member-claim-link": "/:join_code/regenerate-member-claim-link",
+		http.MethodPost + " /:join_code/revoke-member-claim-link":     "/:join_code/revoke-member-claim-link",
+		http.MethodPost + " /:join_code/transfer-membership":          "/:join_code/transfer-membership",
+		http.MethodPost + " /:join_code/merge-member":                 "/:join_code/merge-member",
+		http.MethodPost + " /:join_code/claim-payment":                "/:join_code/claim-payment",
+		http.MethodPost + " /:join_code/add-payment":                  "/:join_code/add-payment",
+		http.MethodPost + " /:join_code/add-adjustment":               "/:join_code/add-adjustment",
+		http.MethodPost + " /:join_code/delete-adjustment":            "/:join_code/delete-adjustment",
+		http.MethodPost + " /:join_code/waive-adjustment":             "/:join_code/waive-adjustment",
+		http.MethodPost + " /:join_code/add-standing-order":           "/:join_code/add-standing-order",
+		http.MethodPost + " /:join_code/cancel-standing-order":        "/:join_code/cancel-standing-order",
+		http.MethodPost + " /:join_code/trust-standing-orders":        "/:join_code/trust-standing-orders",
 	}
 
 	registered := map[string]string{}
@@ -0,0 +1,493 @@
+// Package integrity looks for records that the app would misread or silently skip, such as plan
+// data left behind by a deleted plan, and repairs the ones it knows how to.
+package integrity
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/memberclaim"
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// Check names identify the kind of problem found.
+const (
+	CheckMissingOwner                    = "missing_owner"
+	CheckMissingPlan                     = "missing_plan"
+	CheckMissingUser                     = "membership_missing_user"
+	CheckPaymentWithoutMembership        = "payment_without_membership"
+	CheckPayerWithoutMembership          = "payer_without_membership"
+	CheckAdjustmentWithoutMembership     = "adjustment_without_membership"
+	CheckRecurringClaimWithoutMembership = "recurring_claim_without_membership"
+	CheckDuplicateMembership             = "duplicate_membership"
+	CheckStaleClaimLink                  = "claim_link_ended_member"
+)
+
+// deletedUserName names the artificial member a deleted user's membership becomes.
+const deletedUserName = "Deleted user"
+
+// Problem is one record that fails a check, with the fix that would be applied to it.
+type Problem struct {
+	Check      string
+	Collection string
+	RecordID   string
+	PlanID     string
+	Message    string
+	// Fix describes the repair, or what to do by hand when there is none.
+	Fix    string
+	repair func(dao *daos.Dao, now time.Time) error
+}
+
+// Fixable reports whether the problem can be repaired automatically.
+func (p Problem) Fixable() bool {
+	return p.repair != nil
+}
+
+// planCollections hold records that belong to a plan through their plan_id.
+var planCollections = []string{
+	"memberships",
+	"payments",
+	billing.AdjustmentsCollection,
+	billing.RecurringClaimsCollection,
+	"join_requests",
+	memberclaim.CollectionName,
+}
+
+// check finds one kind of problem.
+type check func(data *snapshot, now time.Time) []Problem
+
+var checks = []check{
+	checkMissingOwners,
+	checkMissingPlans,
+	checkMissingUsers,
+	checkDuplicateMemberships,
+	checkRecordsWithoutMembership,
+	checkStaleClaimLinks,
+}
+
+// CheckWithDao runs every check and returns the problems found, grouped by check.
+func CheckWithDao(dao *daos.Dao, now time.Time) ([]Problem, error) {
+	data, err := loadSnapshotWithDao(dao)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := []Problem{}
+	for _, run := range checks {
+		problems = append(problems, run(data, now)...)
+	}
+
+	return problems, nil
+}
+
+// FixWithDao repairs every fixable problem in one transaction and rebuilds the allocations derived
+// from the repaired records. It returns how many problems were repaired; if any repair fails,
+// none are kept.
+func FixWithDao(dao *daos.Dao, problems []Problem, now time.Time) (int, error) {
+	fixed := 0
+	err := dao.RunInTransaction(func(txDao *daos.Dao) error {
+		for _, problem := range problems {
+			if !problem.Fixable() {
+				continue
+			}
+
+			if err := problem.repair(txDao, now); err != nil {
+				return fmt.Errorf("%s %s: %w", problem.Check, problem.RecordID, err)
+			}
+			fixed++
+		}
+
+		if fixed == 0 {
+			return nil
+		}
+
+		return billing.ReallocateAllWithDao(txDao, now)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return fixed, nil
+}
+
+// snapshot holds the records the checks read, loaded once.
+type snapshot struct {
+	planList []*pbmodels.Record
+	plans    map[string]*pbmodels.Record
+	users    map[string]bool
+	records  map[string][]*pbmodels.Record
+	// memberships groups memberships by plan and user id.
+	memberships map[membershipKey][]*pbmodels.Record
+}
+
+type membershipKey struct {
+	planID string
+	userID string
+}
+
+func loadSnapshotWithDao(dao *daos.Dao) (*snapshot, error) {
+	data := &snapshot{
+		plans:       map[string]*pbmodels.Record{},
+		users:       map[string]bool{},
+		records:     map[string][]*pbmodels.Record{},
+		memberships: map[membershipKey][]*pbmodels.Record{},
+	}
+
+	plans, err := dao.FindRecordsByFilter("family_plans", "id != ''", "created", -1, 0)
+	if err != nil {
+		return nil, err
+	}
+	data.planList = plans
+	for _, plan := range plans {
+		data.plans[plan.Id] = plan
+	}
+
+	users, err := dao.FindRecordsByFilter("users", "id != ''", "", -1, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		data.users[user.Id] = true
+	}
+
+	for _, collection := range planCollections {
+		records, err := dao.FindRecordsByFilter(collection, "id != ''", "created", -1, 0)
+		if err != nil {
+			return nil, err
+		}
+		data.records[collection] = records
+	}
+
+	for _, membership := range data.records["memberships"] {
+		key := membershipKey{planID: membership.GetString("plan_id"), userID: membership.GetString("user_id")}
+		data.memberships[key] = append(data.memberships[key], membership)
+	}
+
+	return data, nil
+}
+
+// hasPlan reports whether a record's plan exists. Records of missing plans are reported once, by
+// checkMissingPlans, and skipped by the other checks.
+func (data *snapshot) hasPlan(record *pbmodels.Record) bool {
+	_, ok := data.plans[record.GetString("plan_id")]
+	return ok
+}
+
+func checkMissingOwners(data *snapshot, now time.Time) []Problem {
+	problems := []Problem{}
+	for _, plan := range data.planList {
+		if ownerID := planutil.OwnerID(plan); ownerID == "" || !data.users[ownerID] {
+			problems = append(problems, Problem{
+				Check:      CheckMissingOwner,
+				Collection: "family_plans",
+				RecordID:   plan.Id,
+				PlanID:     plan.Id,
+				Message:    "plan " + plan.GetString("join_code") + " has no owner account",
+				Fix:        "none; export the plan and import it under a new owner",
+			})
+		}
+	}
+
+	return problems
+}
+
+func checkMissingPlans(data *snapshot, now time.Time) []Problem {
+	problems := []Problem{}
+	for _, collection := range planCollections {
+		for _, record := range data.records[collection] {
+			if data.hasPlan(record) {
+				continue
+			}
+
+			problems = append(problems, Problem{
+				Check:      CheckMissingPlan,
+				Collection: collection,
+				RecordID:   record.Id,
+				PlanID:     record.GetString("plan_id"),
+				Message:    "belongs to plan " + record.GetString("plan_id") + ", which does not exist",
+				Fix:        "delete the record",
+				repair:     deleteRepair(collection, record.Id),
+			})
+		}
+	}
+
+	return problems
+}
+
+// checkMissingUsers finds real memberships whose user account was deleted. The plan owner's own
+// membership is left to checkMissingOwners.
+func checkMissingUsers(data *snapshot, now time.Time) []Problem {
+	problems := []Problem{}
+	for _, membership := range data.records["memberships"] {
+		userID := membership.GetString("user_id")
+		if !data.hasPlan(membership) || membership.GetBool("is_artificial") || data.users[userID] {
+			continue
+		}
+		if planutil.OwnerID(data.plans[membership.GetString("plan_id")]) == userID {
+			continue
+		}
+
+		problems = append(problems, Problem{
+			Check:      CheckMissingUser,
+			Collection: "memberships",
+			RecordID:   membership.Id,
+			PlanID:     membership.GetString("plan_id"),
+			Message:    "user " + userID + " no longer exists",
+			Fix:        fmt.Sprintf("make it an artificial member named %q, keeping its payments and balance", deletedUserName),
+			repair: func(dao *daos.Dao, now time.Time) error {
+				record, err := findRecordWithDao(dao, "memberships", membership.Id)
+				if err != nil || record == nil {
+					return err
+				}
+
+				record.Set("is_artificial", true)
+				record.Set("name", deletedUserName)
+				return dao.SaveRecord(record)
+			},
+		})
+	}
+
+	return problems
+}
+
+// checkDuplicateMemberships finds plans with more than one membership for the same user. The
+// earliest is kept, so billing still starts when the member first joined, and it stays open if
+// any of the duplicates was.
+func checkDuplicateMemberships(data *snapshot, now time.Time) []Problem {
+	keys := make([]membershipKey, 0, len(data.memberships))
+	for key, memberships := range data.memberships {
+		if len(memberships) > 1 && data.hasPlan(memberships[0]) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].planID != keys[j].planID {
+			return keys[i].planID < keys[j].planID
+		}
+		return keys[i].userID < keys[j].userID
+	})
+
+	problems := []Problem{}
+	for _, key := range keys {
+		memberships := data.memberships[key]
+		kept := memberships[0]
+		duplicateIDs := make([]string, 0, len(memberships)-1)
+		for _, membership := range memberships[1:] {
+			duplicateIDs = append(duplicateIDs, membership.Id)
+		}
+
+		problems = append(problems, Problem{
+			Check:      CheckDuplicateMembership,
+			Collection: "memberships",
+			RecordID:   kept.Id,
+			PlanID:     key.planID,
+			Message:    fmt.Sprintf("user %s has %d memberships in the plan", key.userID, len(memberships)),
+			Fix:        fmt.Sprintf("keep the earliest membership and delete %d duplicates", len(duplicateIDs)),
+			repair: func(dao *daos.Dao, now time.Time) error {
+				record, err := findRecordWithDao(dao, "memberships", kept.Id)
+				if err != nil || record == nil {
+					return err
+				}
+
+				latestEnd := record.GetDateTime("date_ended")
+				open := latestEnd.IsZero()
+				for _, id := range duplicateIDs {
+					duplicate, err := findRecordWithDao(dao, "memberships", id)
+					if err != nil {
+						return err
+					}
+					if duplicate == nil {
+						continue
+					}
+
+					ended := duplicate.GetDateTime("date_ended")
+					if ended.IsZero() {
+						open = true
+					} else if ended.Time().After(latestEnd.Time()) {
+						latestEnd = ended
+					}
+
+					if err := dao.DeleteRecord(duplicate); err != nil {
+						return err
+					}
+				}
+
+				if open {
+					record.Set("date_ended", "")
+				} else {
+					record.Set("date_ended", latestEnd)
+				}
+				return dao.SaveRecord(record)
+			},
+		})
+	}
+
+	return problems
+}
+
+// memberRecords are the records tied to a member of their plan, each reported under its own check
+// when that member has no membership in the plan. Rejected payments, waived adjustments and cancelled
+// standing orders count toward nothing and are skipped.
+var memberRecords = []struct {
+	check      string
+	collection string
+	field      string
+	// describe phrases the missing member for the problem's message.
+	describe string
+	skip     func(record *pbmodels.Record) bool
+}{
+	{
+		check:      CheckPaymentWithoutMembership,
+		collection: "payments",
+		field:      "user_id",
+		describe:   "paid for %s",
+		skip:       isRejected,
+	},
+	{
+		check:      CheckPayerWithoutMembership,
+		collection: "payments",
+		field:      "payer_id",
+		describe:   "paid by %s",
+		skip:       isRejected,
+	},
+	{
+		check:      CheckAdjustmentWithoutMembership,
+		collection: billing.AdjustmentsCollection,
+		field:      "user_id",
+		describe:   "adjusts the balance of %s",
+		skip:       billing.IsWaived,
+	},
+	{
+		check:      CheckRecurringClaimWithoutMembership,
+		collection: billing.RecurringClaimsCollection,
+		field:      "user_id",
+		describe:   "files payments for %s",
+		skip:       isCancelled,
+	},
+}
+
+// checkRecordsWithoutMembership finds payments, adjustments and standing orders tied to someone
+// who has no membership in the plan. Balances and the plan page skip them, but plan totals would
+// still count them. Which member they belong to can't be told from the record, so they are left to
+// be reassigned or rejected by hand, except standing orders, which are cancelled so they stop filing.
+func checkRecordsWithoutMembership(data *snapshot, now time.Time) []Problem {
+	problems := []Problem{}
+	for _, memberRecord := range memberRecords {
+		for _, record := range data.records[memberRecord.collection] {
+			userID := record.GetString(memberRecord.field)
+			if userID == "" || !data.hasPlan(record) || memberRecord.skip(record) {
+				continue
+			}
+
+			key := membershipKey{planID: record.GetString("plan_id"), userID: userID}
+			if len(data.memberships[key]) > 0 {
+				continue
+			}
+			if memberRecord.field == "payer_id" && planutil.OwnerID(data.plans[key.planID]) == userID {
+				// The owner can pay on a member's behalf without a membership of their own.
+				continue
+			}
+
+			problem := Problem{
+				Check:      memberRecord.check,
+				Collection: memberRecord.collection,
+				RecordID:   record.Id,
+				PlanID:     key.planID,
+				Message:    fmt.Sprintf(memberRecord.describe, userID) + ", who has no membership in the plan",
+				Fix:        "none; move it to the member it belongs to, or reject it",
+			}
+			if memberRecord.collection == billing.RecurringClaimsCollection {
+				problem.Fix = "cancel the standing order"
+				problem.repair = cancelRecurringClaimRepair(record.Id)
+			}
+			problems = append(problems, problem)
+		}
+	}
+
+	return problems
+}
+
+func isRejected(payment *pbmodels.Record) bool {
+	return payment.GetString("status") == "rejected"
+}
+
+func isCancelled(claim *pbmodels.Record) bool {
+	return !claim.GetDateTime("cancelled_at").IsZero()
+}
+
+func cancelRecurringClaimRepair(id string) func(dao *daos.Dao, now time.Time) error {
+	return func(dao *daos.Dao, now time.Time) error {
+		record, err := findRecordWithDao(dao, billing.RecurringClaimsCollection, id)
+		if err != nil || record == nil {
+			return err
+		}
+
+		record.Set("cancelled_at", now)
+		return dao.SaveRecord(record)
+	}
+}
+
+// checkStaleClaimLinks finds claim links that still work for an artificial member who has left the
+// plan or no longer exists.
+func checkStaleClaimLinks(data *snapshot, now time.Time) []Problem {
+	problems := []Problem{}
+	for _, link := range data.records[memberclaim.CollectionName] {
+		if !data.hasPlan(link) || !memberclaim.IsActive(link, now) {
+			continue
+		}
+
+		key := membershipKey{planID: link.GetString("plan_id"), userID: link.GetString("artificial_member_id")}
+		memberships := data.memberships[key]
+		if len(memberships) > 0 && memberships[0].GetDateTime("date_ended").IsZero() {
+			continue
+		}
+
+		problems = append(problems, Problem{
+			Check:      CheckStaleClaimLink,
+			Collection: memberclaim.CollectionName,
+			RecordID:   link.Id,
+			PlanID:     key.planID,
+			Message:    "still claims artificial member " + key.userID + ", who has left the plan",
+			Fix:        "revoke the link",
+			repair: func(dao *daos.Dao, now time.Time) error {
+				record, err := findRecordWithDao(dao, memberclaim.CollectionName, link.Id)
+				if err != nil || record == nil {
+					return err
+				}
+
+				record.Set("revoked_at", now)
+				return dao.SaveRecord(record)
+			},
+		})
+	}
+
+	return problems
+}
+
+func deleteRepair(collection, id string) func(dao *daos.Dao, now time.Time) error {
+	return func(dao *daos.Dao, now time.Time) error {
+		record, err := findRecordWithDao(dao, collection, id)
+		if err != nil || record == nil {
+			return err
+		}
+
+		return dao.DeleteRecord(record)
+	}
+}
+
+// findRecordWithDao loads a record, returning nil when an earlier repair already removed it.
+func findRecordWithDao(dao *daos.Dao, collection, id string) (*pbmodels.Record, error) {
+	record, err := dao.FindRecordById(collection, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return record, err
+}
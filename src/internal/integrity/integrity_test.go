@@ -0,0 +1,210 @@
+package integrity
+
+import (
+	"testing"
+	"time"
+
+	"familyplan/src/internal/testapp"
+
+	"github.com/pocketbase/pocketbase"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+func TestCheckFindsMissingOwnersAndPlans(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 3000)
+	ownerless := testapp.SavePlan(t, app, "deleted-user", "XYZ789", 3000)
+	testapp.SaveRecord(t, app, "memberships", map[string]any{"plan_id": plan.Id, "user_id": owner.Id})
+	stray := testapp.SaveRecord(t, app, "payments", map[string]any{"plan_id": "deleted-plan", "user_id": owner.Id, "amount": 500, "status": "approved"})
+
+	problems, err := CheckWithDao(app.Dao(), time.Now())
+	if err != nil {
+		t.Fatalf("CheckWithDao returned error: %v", err)
+	}
+
+	want := map[string]string{
+		CheckMissingOwner: ownerless.Id,
+		CheckMissingPlan:  stray.Id,
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("problems = %+v, want %d", problems, len(want))
+	}
+	for _, problem := range problems {
+		if want[problem.Check] != problem.RecordID {
+			t.Fatalf("problem %+v, want record %q for %s", problem, want[problem.Check], problem.Check)
+		}
+	}
+}
+
+func TestCheckFindsAndFixesProblems(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		check string
+		// setup saves the broken records and returns the one the problem is reported on.
+		setup func(t *testing.T, app *pocketbase.PocketBase, planID string) *pbmodels.Record
+		// verify checks the records after the fix.
+		verify func(t *testing.T, app *pocketbase.PocketBase, record *pbmodels.Record)
+	}{
+		{
+			name:  "membership of a deleted user becomes artificial",
+			check: CheckMissingUser,
+			setup: func(t *testing.T, app *pocketbase.PocketBase, planID string) *pbmodels.Record {
+				return testapp.SaveRecord(t, app, "memberships", map[string]any{"plan_id": planID, "user_id": "deleted-user"})
+			},
+			verify: func(t *testing.T, app *pocketbase.PocketBase, record *pbmodels.Record) {
+				membership := testapp.FindRecord(t, app, "memberships", record.Id)
+				if !membership.GetBool("is_artificial") || membership.GetString("name") != deletedUserName {
+					t.Fatalf("membership = %v, want an artificial %q", membership.SchemaData(), deletedUserName)
+				}
+			},
+		},
+		{
+			name:  "standing order without a membership is cancelled",
+			check: CheckRecurringClaimWithoutMembership,
+			setup: func(t *testing.T, app *pocketbase.PocketBase, planID string) *pbmodels.Record {
+				return testapp.SaveRecord(t, app, "recurring_claims", map[string]any{"plan_id": planID, "user_id": "stranger", "amount": 500, "day_of_month": 1, "start_month": now.Format("2006-01")})
+			},
+			verify: func(t *testing.T, app *pocketbase.PocketBase, record *pbmodels.Record) {
+				if testapp.FindRecord(t, app, "recurring_claims", record.Id).GetDateTime("cancelled_at").IsZero() {
+					t.Fatal("standing order was not cancelled")
+				}
+			},
+		},
+		{
+			name:  "duplicate memberships keep the earliest, still open",
+			check: CheckDuplicateMembership,
+			setup: func(t *testing.T, app *pocketbase.PocketBase, planID string) *pbmodels.Record {
+				member := testapp.SaveUser(t, app, "member")
+				kept := testapp.SaveRecord(t, app, "memberships", map[string]any{"plan_id": planID, "user_id": member.Id, "date_ended": now.AddDate(0, -2, 0)})
+				testapp.SaveRecord(t, app, "memberships", map[string]any{"plan_id": planID, "user_id": member.Id})
+				return kept
+			},
+			verify: func(t *testing.T, app *pocketbase.PocketBase, record *pbmodels.Record) {
+				memberships, err := app.Dao().FindRecordsByFilter("memberships", "user_id = {:user}", "", -1, 0, map[string]any{"user": record.GetString("user_id")})
+				if err != nil {
+					t.Fatalf("failed to load memberships: %v", err)
+				}
+				if len(memberships) != 1 || memberships[0].Id != record.Id || !memberships[0].GetDateTime("date_ended").IsZero() {
+					t.Fatalf("memberships = %d, want only %s left open", len(memberships), record.Id)
+				}
+			},
+		},
+		{
+			name:  "claim link of an ended artificial member is revoked",
+			check: CheckStaleClaimLink,
+			setup: func(t *testing.T, app *pocketbase.PocketBase, planID string) *pbmodels.Record {
+				testapp.SaveRecord(t, app, "memberships", map[string]any{"plan_id": planID, "user_id": "artificial-1", "is_artificial": true, "name": "Gran", "date_ended": now.AddDate(0, -1, 0)})
+				return testapp.SaveRecord(t, app, "member_claim_links", map[string]any{"plan_id": planID, "artificial_member_id": "artificial-1", "token": "claim-token", "expires_at": now.AddDate(0, 0, 7)})
+			},
+			verify: func(t *testing.T, app *pocketbase.PocketBase, record *pbmodels.Record) {
+				if testapp.FindRecord(t, app, "member_claim_links", record.Id).GetDateTime("revoked_at").IsZero() {
+					t.Fatal("claim link was not revoked")
+				}
+			},
+		},
+		{
+			name:  "records of a deleted plan are removed",
+			check: CheckMissingPlan,
+			setup: func(t *testing.T, app *pocketbase.PocketBase, planID string) *pbmodels.Record {
+				return testapp.SaveRecord(t, app, "join_requests", map[string]any{"plan_id": "deleted-plan", "user_id": "someone"})
+			},
+			verify: func(t *testing.T, app *pocketbase.PocketBase, record *pbmodels.Record) {
+				if _, err := app.Dao().FindRecordById("join_requests", record.Id); err == nil {
+					t.Fatal("join request of a deleted plan was not removed")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := testapp.New(t)
+
+			owner := testapp.SaveUser(t, app, "owner")
+			plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 3000)
+			testapp.SaveRecord(t, app, "memberships", map[string]any{"plan_id": plan.Id, "user_id": owner.Id})
+			record := tt.setup(t, app, plan.Id)
+
+			problems, err := CheckWithDao(app.Dao(), now)
+			if err != nil {
+				t.Fatalf("CheckWithDao returned error: %v", err)
+			}
+			if len(problems) != 1 || problems[0].Check != tt.check || problems[0].RecordID != record.Id || !problems[0].Fixable() {
+				t.Fatalf("problems = %+v, want a fixable %s on %s", problems, tt.check, record.Id)
+			}
+
+			fixed, err := FixWithDao(app.Dao(), problems, now)
+			if err != nil {
+				t.Fatalf("FixWithDao returned error: %v", err)
+			}
+			if fixed != 1 {
+				t.Fatalf("fixed = %d, want 1", fixed)
+			}
+			tt.verify(t, app, record)
+
+			problems, err = CheckWithDao(app.Dao(), now)
+			if err != nil {
+				t.Fatalf("CheckWithDao returned error: %v", err)
+			}
+			if len(problems) != 0 {
+				t.Fatalf("problems after fix = %+v, want none", problems)
+			}
+		})
+	}
+}
+
+func TestCheckReportsMemberRecordsWithoutMembershipWithoutFixingThem(t *testing.T) {
+	app := testapp.New(t)
+	now := time.Now()
+
+	owner := testapp.SaveUser(t, app, "owner")
+	member := testapp.SaveUser(t, app, "member")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 3000)
+	testapp.SaveRecord(t, app, "memberships", map[string]any{"plan_id": plan.Id, "user_id": owner.Id})
+	testapp.SaveRecord(t, app, "memberships", map[string]any{"plan_id": plan.Id, "user_id": member.Id})
+
+	payment := testapp.SaveRecord(t, app, "payments", map[string]any{"plan_id": plan.Id, "user_id": "stranger", "amount": 500, "status": "approved", "date": now})
+	payer := testapp.SaveRecord(t, app, "payments", map[string]any{"plan_id": plan.Id, "user_id": member.Id, "payer_id": "stranger", "amount": 500, "status": "approved", "date": now})
+	adjustment := testapp.SaveRecord(t, app, "adjustments", map[string]any{"plan_id": plan.Id, "user_id": "stranger", "amount": -500, "reason": "Late fee", "for_month": now, "kind": "late_fee"})
+
+	// None of these count toward anything, so they are not problems.
+	testapp.SaveRecord(t, app, "payments", map[string]any{"plan_id": plan.Id, "user_id": "stranger", "amount": 500, "status": "rejected", "date": now})
+	testapp.SaveRecord(t, app, "payments", map[string]any{"plan_id": plan.Id, "user_id": member.Id, "payer_id": owner.Id, "amount": 500, "status": "approved", "date": now})
+	testapp.SaveRecord(t, app, "adjustments", map[string]any{"plan_id": plan.Id, "amount": -300, "reason": "Shared discount", "for_month": now})
+
+	problems, err := CheckWithDao(app.Dao(), now)
+	if err != nil {
+		t.Fatalf("CheckWithDao returned error: %v", err)
+	}
+
+	want := map[string]string{
+		CheckPaymentWithoutMembership:    payment.Id,
+		CheckPayerWithoutMembership:      payer.Id,
+		CheckAdjustmentWithoutMembership: adjustment.Id,
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("problems = %+v, want %d", problems, len(want))
+	}
+	for _, problem := range problems {
+		if want[problem.Check] != problem.RecordID || problem.Fixable() {
+			t.Fatalf("problem %+v, want an unfixable report on %q for %s", problem, want[problem.Check], problem.Check)
+		}
+	}
+
+	fixed, err := FixWithDao(app.Dao(), problems, now)
+	if err != nil {
+		t.Fatalf("FixWithDao returned error: %v", err)
+	}
+	if fixed != 0 {
+		t.Fatalf("fixed = %d, want 0", fixed)
+	}
+	for _, id := range []string{payment.Id, payer.Id} {
+		if status := testapp.FindRecord(t, app, "payments", id).GetString("status"); status != "approved" {
+			t.Fatalf("payment %s status = %q, want it left approved", id, status)
+		}
+	}
+}
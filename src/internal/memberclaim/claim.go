@@ -4,7 +4,10 @@ import (
 	"database/sql"
 	"errors"
 	"strings"
+	"time"
 
+	"familyplan/src/internal/audit"
+	"familyplan/src/internal/billing"
 	"familyplan/src/internal/planutil"
 	"familyplan/src/internal/support/random"
 
@@ -16,6 +19,10 @@ import (
 const (
 	// CollectionName is the PocketBase collection that stores public claim links.
 	CollectionName = "member_claim_links"
+	// AttemptsCollectionName is the PocketBase collection that records claim attempts.
+	AttemptsCollectionName = "member_claim_attempts"
+	// DefaultTTL is how long a newly issued claim link stays valid.
+	DefaultTTL = 7 * 24 * time.Hour
 )
 
 var (
@@ -25,6 +32,14 @@ var (
 	ErrArtificialMemberUnavailable = errors.New("artificial member is unavailable")
 	// ErrAlreadyMember indicates that the claiming user already belongs to the plan.
 	ErrAlreadyMember = errors.New("user already belongs to the plan")
+	// ErrClaimLinkExpired indicates that the claim link is past its expiry.
+	ErrClaimLinkExpired = errors.New("member claim link expired")
+	// ErrClaimLinkRevoked indicates that the owner revoked the claim link.
+	ErrClaimLinkRevoked = errors.New("member claim link revoked")
+	// ErrPlanArchived indicates that the link belongs to a plan its owner has archived.
+	ErrPlanArchived = errors.New("plan is archived")
+	// ErrMergeTargetUnavailable indicates that the membership to merge into is missing or ended.
+	ErrMergeTargetUnavailable = errors.New("merge target membership is unavailable")
 )
 
 // Info describes an active artificial-member claim link.
@@ -55,8 +70,12 @@ func ErrorMessage(err error) string {
 	switch {
 	case errors.Is(err, ErrAlreadyMember):
 		return "You're already a member of this plan."
-	case errors.Is(err, ErrClaimLinkNotFound), errors.Is(err, ErrArtificialMemberUnavailable):
+	case errors.Is(err, ErrClaimLinkExpired):
+		return "This claim link has expired. Ask the plan owner for a new one."
+	case errors.Is(err, ErrClaimLinkNotFound), errors.Is(err, ErrClaimLinkRevoked), errors.Is(err, ErrArtificialMemberUnavailable):
 		return "This claim link is no longer available."
+	case errors.Is(err, ErrPlanArchived):
+		return "This plan has been archived and can no longer be joined."
 	default:
 		return ""
 	}
@@ -82,13 +101,52 @@ func FindByTokenWithDao(dao *daos.Dao, token string) (*pbmodels.Record, error) {
 	return record, err
 }
 
-// FindForArtificialMember loads the active claim link for an artificial member.
-func FindForArtificialMember(app *pocketbase.PocketBase, planID, artificialMemberID string) (*pbmodels.Record, error) {
-	return FindForArtificialMemberWithDao(app.Dao(), planID, artificialMemberID)
+// FindForArtificialMember loads the claim link for an artificial member that is active at now.
+func FindForArtificialMember(app *pocketbase.PocketBase, planID, artificialMemberID string, now time.Time) (*pbmodels.Record, error) {
+	return FindForArtificialMemberWithDao(app.Dao(), planID, artificialMemberID, now)
 }
 
-// FindForArtificialMemberWithDao loads the active claim link for an artificial member using the provided dao.
-func FindForArtificialMemberWithDao(dao *daos.Dao, planID, artificialMemberID string) (*pbmodels.Record, error) {
+// FindForArtificialMemberWithDao loads the claim link for an artificial member that is active at now using the
+// provided dao. Revoked and expired links are ignored.
+func FindForArtificialMemberWithDao(dao *daos.Dao, planID, artificialMemberID string, now time.Time) (*pbmodels.Record, error) {
+	records, err := findAllForArtificialMemberWithDao(dao, planID, artificialMemberID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if IsActive(record, now) {
+			return record, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// IsActive reports whether a claim link is neither revoked nor expired at the given time.
+func IsActive(record *pbmodels.Record, now time.Time) bool {
+	return linkStatusError(record, now) == nil
+}
+
+// ExpiresAt returns the link expiry time.
+func ExpiresAt(record *pbmodels.Record) time.Time {
+	return record.GetDateTime("expires_at").Time()
+}
+
+func linkStatusError(record *pbmodels.Record, now time.Time) error {
+	if !record.GetDateTime("revoked_at").IsZero() {
+		return ErrClaimLinkRevoked
+	}
+
+	expiresAt := record.GetDateTime("expires_at")
+	if expiresAt.IsZero() || !now.Before(expiresAt.Time()) {
+		return ErrClaimLinkExpired
+	}
+
+	return nil
+}
+
+func findAllForArtificialMemberWithDao(dao *daos.Dao, planID, artificialMemberID string) ([]*pbmodels.Record, error) {
 	collection, err := dao.FindCollectionByNameOrId(CollectionName)
 	if err != nil {
 		return nil, err
@@ -102,16 +160,14 @@ func FindForArtificialMemberWithDao(dao *daos.Dao, planID, artificialMemberID st
 		return nil, err
 	}
 
-	record, err := dao.FindFirstRecordByFilter(
+	return dao.FindRecordsByFilter(
 		collection.Id,
 		filter.Expression,
+		"-created",
+		-1,
+		0,
 		filter.Params,
 	)
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
-	}
-
-	return record, err
 }
 
 // FindAllForPlan loads all stored claim links for a plan.
@@ -139,17 +195,56 @@ func FindAllForPlan(app *pocketbase.PocketBase, planID string) ([]*pbmodels.Reco
 }
 
 // Ensure makes sure an artificial member has a reusable public claim link.
-func Ensure(app *pocketbase.PocketBase, planID, artificialMemberID string) (*pbmodels.Record, error) {
-	return EnsureWithDao(app.Dao(), planID, artificialMemberID)
+func Ensure(app *pocketbase.PocketBase, planID, artificialMemberID string, now time.Time) (*pbmodels.Record, error) {
+	return EnsureWithDao(app.Dao(), planID, artificialMemberID, now)
 }
 
 // EnsureWithDao makes sure an artificial member has a reusable public claim link using the provided dao.
-func EnsureWithDao(dao *daos.Dao, planID, artificialMemberID string) (*pbmodels.Record, error) {
-	existing, err := FindForArtificialMemberWithDao(dao, planID, artificialMemberID)
+// A new link is issued when the previous one was revoked or has expired by now.
+func EnsureWithDao(dao *daos.Dao, planID, artificialMemberID string, now time.Time) (*pbmodels.Record, error) {
+	existing, err := FindForArtificialMemberWithDao(dao, planID, artificialMemberID, now)
 	if err != nil || existing != nil {
 		return existing, err
 	}
 
+	return issueWithDao(dao, planID, artificialMemberID, now)
+}
+
+// RegenerateWithDao revokes any active claim link for the artificial member and issues a new one.
+func RegenerateWithDao(dao *daos.Dao, planID, artificialMemberID string, now time.Time) (*pbmodels.Record, error) {
+	if err := revokeWithDao(dao, planID, artificialMemberID, now); err != nil {
+		return nil, err
+	}
+
+	return issueWithDao(dao, planID, artificialMemberID, now)
+}
+
+// RevokeWithDao revokes, as of now, every active claim link for the artificial member.
+func RevokeWithDao(dao *daos.Dao, planID, artificialMemberID string, now time.Time) error {
+	return revokeWithDao(dao, planID, artificialMemberID, now)
+}
+
+func revokeWithDao(dao *daos.Dao, planID, artificialMemberID string, now time.Time) error {
+	records, err := findAllForArtificialMemberWithDao(dao, planID, artificialMemberID)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if !record.GetDateTime("revoked_at").IsZero() {
+			continue
+		}
+
+		record.Set("revoked_at", now)
+		if err := dao.SaveRecord(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func issueWithDao(dao *daos.Dao, planID, artificialMemberID string, now time.Time) (*pbmodels.Record, error) {
 	collection, err := dao.FindCollectionByNameOrId(CollectionName)
 	if err != nil {
 		return nil, err
@@ -164,6 +259,7 @@ func EnsureWithDao(dao *daos.Dao, planID, artificialMemberID string) (*pbmodels.
 	record.Set("plan_id", planID)
 	record.Set("artificial_member_id", artificialMemberID)
 	record.Set("token", token)
+	record.Set("expires_at", now.Add(DefaultTTL))
 
 	if err := dao.SaveRecord(record); err != nil {
 		return nil, err
@@ -173,12 +269,13 @@ func EnsureWithDao(dao *daos.Dao, planID, artificialMemberID string) (*pbmodels.
 }
 
 // Lookup resolves a public claim token into the related plan and artificial member metadata.
-func Lookup(app *pocketbase.PocketBase, token string) (*Info, error) {
-	return LookupWithDao(app.Dao(), token)
+func Lookup(app *pocketbase.PocketBase, token string, now time.Time) (*Info, error) {
+	return LookupWithDao(app.Dao(), token, now)
 }
 
 // LookupWithDao resolves a public claim token into the related plan and artificial member metadata using the provided dao.
-func LookupWithDao(dao *daos.Dao, token string) (*Info, error) {
+// Links that are revoked or expired at now are rejected.
+func LookupWithDao(dao *daos.Dao, token string, now time.Time) (*Info, error) {
 	record, err := FindByTokenWithDao(dao, token)
 	if err != nil {
 		return nil, err
@@ -186,6 +283,9 @@ func LookupWithDao(dao *daos.Dao, token string) (*Info, error) {
 	if record == nil {
 		return nil, ErrClaimLinkNotFound
 	}
+	if err := linkStatusError(record, now); err != nil {
+		return nil, err
+	}
 
 	plansCollection, err := dao.FindCollectionByNameOrId("family_plans")
 	if err != nil {
@@ -199,6 +299,9 @@ func LookupWithDao(dao *daos.Dao, token string) (*Info, error) {
 	if err != nil {
 		return nil, err
 	}
+	if planutil.IsArchived(planRecord) {
+		return nil, ErrPlanArchived
+	}
 
 	artificialMemberID := record.GetString("artificial_member_id")
 	artificialMembership, err := findArtificialMembershipWithDao(dao, planRecord.Id, artificialMemberID)
@@ -257,16 +360,46 @@ func DeleteForArtificialMemberWithDao(dao *daos.Dao, planID, artificialMemberID
 }
 
 // Claim converts an artificial member claim link into a real membership for the provided user.
-func Claim(app *pocketbase.PocketBase, token, realUserID string) (Result, error) {
+// Every attempt is recorded, whether or not it succeeds.
+func Claim(app *pocketbase.PocketBase, token, realUserID string, now time.Time) (Result, error) {
 	result := Result{}
 
-	err := app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
-		info, err := LookupWithDao(txDao, token)
+	attempt, err := newAttemptWithDao(app.Dao(), token, realUserID)
+	if err != nil {
+		return result, err
+	}
+
+	err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		info, err := LookupWithDao(txDao, token, now)
+		if err != nil {
+			return err
+		}
+
+		artificialMembership, err := findArtificialMembershipWithDao(txDao, info.PlanID, info.ArtificialMemberID)
+		if err != nil {
+			return err
+		}
+
+		if err := TransferArtificialMembership(txDao, info.PlanRecord, info.ArtificialMemberID, realUserID, now); err != nil {
+			return err
+		}
+
+		membership, err := planutil.FindMembershipWithDao(txDao, info.PlanID, realUserID)
 		if err != nil {
 			return err
 		}
 
-		if err := TransferArtificialMembership(txDao, info.PlanRecord, info.ArtificialMemberID, realUserID); err != nil {
+		err = audit.RecordWithDao(txDao, audit.Event{
+			PlanID:   info.PlanID,
+			ActorID:  realUserID,
+			Action:   audit.ActionMemberClaimed,
+			Target:   "memberships",
+			TargetID: membership.Id,
+			UserID:   realUserID,
+			Before:   audit.Snapshot(artificialMembership),
+			After:    audit.Snapshot(membership),
+		})
+		if err != nil {
 			return err
 		}
 
@@ -279,11 +412,80 @@ func Claim(app *pocketbase.PocketBase, token, realUserID string) (Result, error)
 		return nil
 	})
 
+	if attempt != nil {
+		attempt.Set("succeeded", err == nil)
+		if err != nil {
+			attempt.Set("reason", err.Error())
+		}
+		if saveErr := app.Dao().SaveRecord(attempt); saveErr != nil && err == nil {
+			return result, saveErr
+		}
+	}
+
 	return result, err
 }
 
-// TransferArtificialMembership replaces a placeholder membership with a real account.
-func TransferArtificialMembership(txDao *daos.Dao, planRecord *pbmodels.Record, artificialMemberID, realUserID string) error {
+// newAttemptWithDao prepares an attempt record for the link behind the token.
+// Unknown tokens are not recorded because they cannot be tied to a plan.
+func newAttemptWithDao(dao *daos.Dao, token, userID string) (*pbmodels.Record, error) {
+	link, err := FindByTokenWithDao(dao, token)
+	if err != nil || link == nil {
+		return nil, err
+	}
+
+	collection, err := dao.FindCollectionByNameOrId(AttemptsCollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	planID := link.GetString("plan_id")
+	artificialMemberID := link.GetString("artificial_member_id")
+
+	attempt := pbmodels.NewRecord(collection)
+	attempt.Set("plan_id", planID)
+	attempt.Set("link_id", link.Id)
+	attempt.Set("artificial_member_id", artificialMemberID)
+	attempt.Set("user_id", userID)
+
+	membership, err := findArtificialMembershipWithDao(dao, planID, artificialMemberID)
+	if err != nil {
+		return nil, err
+	}
+	if membership != nil {
+		attempt.Set("artificial_name", membership.GetString("name"))
+	}
+
+	return attempt, nil
+}
+
+// FindAttemptsForPlan loads the most recent claim attempts for a plan.
+func FindAttemptsForPlan(app *pocketbase.PocketBase, planID string, limit int) ([]*pbmodels.Record, error) {
+	collection, err := app.Dao().FindCollectionByNameOrId(AttemptsCollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := planutil.BuildEqualsFilter(
+		planutil.FilterTerm{Field: "plan_id", Value: planID},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.Dao().FindRecordsByFilter(
+		collection.Id,
+		filter.Expression,
+		"-created",
+		limit,
+		0,
+		filter.Params,
+	)
+}
+
+// TransferArtificialMembership replaces a placeholder membership with a real account. The placeholder's
+// payments, adjustments, standing orders and inactive months pass to the account, and the plan's
+// allocations are rebuilt as of now.
+func TransferArtificialMembership(txDao *daos.Dao, planRecord *pbmodels.Record, artificialMemberID, realUserID string, now time.Time) error {
 	if planRecord == nil {
 		return ErrArtificialMemberUnavailable
 	}
@@ -320,38 +522,10 @@ func TransferArtificialMembership(txDao *daos.Dao, planRecord *pbmodels.Record,
 		return ErrArtificialMemberUnavailable
 	}
 
-	paymentsCollection, err := txDao.FindCollectionByNameOrId("payments")
-	if err != nil {
+	if err := reassignMemberRecordsWithDao(txDao, planRecord.Id, artificialMemberID, realUserID); err != nil {
 		return err
 	}
 
-	artificialPaymentsFilter, err := planutil.BuildEqualsFilter(
-		planutil.FilterTerm{Field: "plan_id", Value: planRecord.Id},
-		planutil.FilterTerm{Field: "user_id", Value: artificialMemberID},
-	)
-	if err != nil {
-		return err
-	}
-
-	artificialPayments, err := txDao.FindRecordsByFilter(
-		paymentsCollection.Id,
-		artificialPaymentsFilter.Expression,
-		"",
-		-1,
-		0,
-		artificialPaymentsFilter.Params,
-	)
-	if err != nil {
-		return err
-	}
-
-	for _, payment := range artificialPayments {
-		payment.Set("user_id", realUserID)
-		if err := txDao.SaveRecord(payment); err != nil {
-			return err
-		}
-	}
-
 	if strings.TrimSpace(realUserRecord.GetString("name")) == "" {
 		if artificialName := strings.TrimSpace(artificialMembership.GetString("name")); artificialName != "" {
 			realUserRecord.Set("name", artificialName)
@@ -375,6 +549,8 @@ func TransferArtificialMembership(txDao *daos.Dao, planRecord *pbmodels.Record,
 	newMembership.Set("user_id", realUserID)
 	newMembership.Set("is_artificial", false)
 	newMembership.Set("created", artificialMembership.GetDateTime("created"))
+	newMembership.Set("inactive_periods", artificialMembership.Get("inactive_periods"))
+	newMembership.Set("auto_approve_recurring", artificialMembership.GetBool("auto_approve_recurring"))
 	if err := txDao.SaveRecord(newMembership); err != nil {
 		return err
 	}
@@ -389,7 +565,11 @@ func TransferArtificialMembership(txDao *daos.Dao, planRecord *pbmodels.Record,
 		}
 	}
 
-	return DeleteForArtificialMemberWithDao(txDao, planRecord.Id, artificialMemberID)
+	if err := DeleteForArtificialMemberWithDao(txDao, planRecord.Id, artificialMemberID); err != nil {
+		return err
+	}
+
+	return billing.ReallocatePlanWithDao(txDao, planRecord.Id, now)
 }
 
 func findArtificialMembershipWithDao(dao *daos.Dao, planID, artificialMemberID string) (*pbmodels.Record, error) {
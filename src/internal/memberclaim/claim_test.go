@@ -1,10 +1,14 @@
 package memberclaim
 
 import (
+	"errors"
 	"testing"
 	"time"
 
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/money"
 	"familyplan/src/internal/planutil"
+	"familyplan/src/internal/testapp"
 
 	_ "familyplan/migrations"
 
@@ -26,7 +30,7 @@ func TestTransferArtificialMembershipPreservesCreated(t *testing.T) {
 	artificialCreated := mustDateTime(t, time.Date(2026, time.January, 15, 10, 30, 0, 0, time.UTC))
 	saveTestMembership(t, app, plan.Id, artificialMemberID, true, artificialCreated)
 
-	if err := TransferArtificialMembership(app.Dao(), plan, artificialMemberID, realUser.Id); err != nil {
+	if err := TransferArtificialMembership(app.Dao(), plan, artificialMemberID, realUser.Id, time.Now()); err != nil {
 		t.Fatalf("TransferArtificialMembership returned error: %v", err)
 	}
 
@@ -43,6 +47,287 @@ func TestTransferArtificialMembershipPreservesCreated(t *testing.T) {
 	}
 }
 
+func TestTransferArtificialMembershipKeepsThePlaceholdersRecords(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	kid := testapp.SaveUser(t, app, "kid")
+	realUser := testapp.SaveUser(t, app, "real")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 3000)
+	asOf := time.Date(2026, time.March, 20, 0, 0, 0, 0, time.UTC)
+
+	artificialMemberID := "placeholder-member"
+	placeholder := testapp.SaveArtificialMembership(t, app, plan.Id, artificialMemberID, "Parent", time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC))
+	placeholder.Set("inactive_periods", []billing.InactivePeriod{{Start: "2026-02", End: "2026-02", Reason: billing.InactiveReasonGrace}})
+	placeholder.Set("auto_approve_recurring", true)
+	if err := app.Dao().SaveRecord(placeholder); err != nil {
+		t.Fatalf("failed to update placeholder: %v", err)
+	}
+	testapp.SaveMembership(t, app, plan.Id, kid.Id, time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC))
+
+	testapp.SavePayment(t, app, plan.Id, artificialMemberID, 1000, time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC), "approved")
+	forKid := testapp.SavePayment(t, app, plan.Id, kid.Id, 400, time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC), "approved")
+	forKid.Set("payer_id", artificialMemberID)
+	if err := app.Dao().SaveRecord(forKid); err != nil {
+		t.Fatalf("failed to save on-behalf payment: %v", err)
+	}
+
+	lateFee, err := billing.CreateAdjustmentWithDao(app.Dao(), billing.Adjustment{
+		PlanID:   plan.Id,
+		UserID:   artificialMemberID,
+		Amount:   money.New(-500, "USD"),
+		Reason:   "Late fee",
+		ForMonth: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Kind:     billing.AdjustmentKindLateFee,
+	})
+	if err != nil {
+		t.Fatalf("CreateAdjustmentWithDao returned error: %v", err)
+	}
+
+	standingOrder, err := billing.CreateRecurringClaimWithDao(app.Dao(), billing.RecurringClaim{
+		PlanID:     plan.Id,
+		UserID:     artificialMemberID,
+		Amount:     money.New(1000, "USD"),
+		DayOfMonth: 1,
+		StartMonth: time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("CreateRecurringClaimWithDao returned error: %v", err)
+	}
+
+	before, err := billing.CalculateMemberBalanceWithDao(app.Dao(), plan.Id, artificialMemberID, asOf)
+	if err != nil {
+		t.Fatalf("CalculateMemberBalanceWithDao(placeholder) returned error: %v", err)
+	}
+
+	if err := TransferArtificialMembership(app.Dao(), plan, artificialMemberID, realUser.Id, asOf); err != nil {
+		t.Fatalf("TransferArtificialMembership returned error: %v", err)
+	}
+
+	after, err := billing.CalculateMemberBalanceWithDao(app.Dao(), plan.Id, realUser.Id, asOf)
+	if err != nil {
+		t.Fatalf("CalculateMemberBalanceWithDao(real) returned error: %v", err)
+	}
+	if after.Minor != before.Minor {
+		t.Fatalf("transferred balance = %d, want the placeholder's %d", after.Minor, before.Minor)
+	}
+
+	membership, err := planutil.FindMembershipWithDao(app.Dao(), plan.Id, realUser.Id)
+	if err != nil {
+		t.Fatalf("FindMembershipWithDao returned error: %v", err)
+	}
+	if !membership.GetBool("auto_approve_recurring") {
+		t.Fatal("expected the transferred membership to keep auto-approving standing orders")
+	}
+
+	if got := testapp.FindRecord(t, app, "payments", forKid.Id).GetString("payer_id"); got != realUser.Id {
+		t.Fatalf("on-behalf payment payer_id = %q, want %q", got, realUser.Id)
+	}
+	if got := testapp.FindRecord(t, app, billing.AdjustmentsCollection, lateFee.Id).GetString("user_id"); got != realUser.Id {
+		t.Fatalf("late fee user_id = %q, want %q", got, realUser.Id)
+	}
+	if got := testapp.FindRecord(t, app, billing.RecurringClaimsCollection, standingOrder.Id).GetString("user_id"); got != realUser.Id {
+		t.Fatalf("standing order user_id = %q, want %q", got, realUser.Id)
+	}
+
+	allocations, err := app.Dao().FindRecordsByFilter(billing.AllocationsCollection, "user_id = {:user}", "", -1, 0, map[string]any{"user": realUser.Id})
+	if err != nil {
+		t.Fatalf("failed to load allocations: %v", err)
+	}
+	if len(allocations) == 0 {
+		t.Fatal("expected allocations to be rebuilt for the transferred membership")
+	}
+}
+
+func TestLookupRejectsExpiredAndRevokedLinks(t *testing.T) {
+	app := newMigratedTestApp(t)
+
+	owner := saveTestUser(t, app, "owner")
+	plan := saveTestPlan(t, app, owner.Id)
+	artificialMemberID := "placeholder-member"
+	now := time.Now()
+	saveTestMembership(t, app, plan.Id, artificialMemberID, true, mustDateTime(t, now.AddDate(0, -1, 0)))
+
+	link, err := Ensure(app, plan.Id, artificialMemberID, now)
+	if err != nil {
+		t.Fatalf("Ensure returned error: %v", err)
+	}
+	if ExpiresAt(link).IsZero() {
+		t.Fatal("expected new claim link to have an expiry")
+	}
+	if _, err := Lookup(app, link.GetString("token"), now); err != nil {
+		t.Fatalf("Lookup of active link returned error: %v", err)
+	}
+
+	link.Set("expires_at", now.Add(-time.Minute))
+	if err := app.Dao().SaveRecord(link); err != nil {
+		t.Fatalf("failed to expire claim link: %v", err)
+	}
+	if _, err := Lookup(app, link.GetString("token"), now); !errors.Is(err, ErrClaimLinkExpired) {
+		t.Fatalf("Lookup of expired link error = %v, want %v", err, ErrClaimLinkExpired)
+	}
+
+	fresh, err := Ensure(app, plan.Id, artificialMemberID, now)
+	if err != nil {
+		t.Fatalf("Ensure after expiry returned error: %v", err)
+	}
+	if fresh.Id == link.Id {
+		t.Fatal("expected Ensure to issue a new link after expiry")
+	}
+
+	if err := RevokeWithDao(app.Dao(), plan.Id, artificialMemberID, now); err != nil {
+		t.Fatalf("RevokeWithDao returned error: %v", err)
+	}
+	if _, err := Lookup(app, fresh.GetString("token"), now); !errors.Is(err, ErrClaimLinkRevoked) {
+		t.Fatalf("Lookup of revoked link error = %v, want %v", err, ErrClaimLinkRevoked)
+	}
+}
+
+func TestRegenerateWithDaoReplacesActiveLink(t *testing.T) {
+	app := newMigratedTestApp(t)
+
+	owner := saveTestUser(t, app, "owner")
+	plan := saveTestPlan(t, app, owner.Id)
+	artificialMemberID := "placeholder-member"
+	now := time.Now()
+	saveTestMembership(t, app, plan.Id, artificialMemberID, true, mustDateTime(t, now.AddDate(0, -1, 0)))
+
+	original, err := Ensure(app, plan.Id, artificialMemberID, now)
+	if err != nil {
+		t.Fatalf("Ensure returned error: %v", err)
+	}
+
+	regenerated, err := RegenerateWithDao(app.Dao(), plan.Id, artificialMemberID, now)
+	if err != nil {
+		t.Fatalf("RegenerateWithDao returned error: %v", err)
+	}
+	if regenerated.GetString("token") == original.GetString("token") {
+		t.Fatal("expected regenerated link to use a new token")
+	}
+
+	if _, err := Lookup(app, original.GetString("token"), now); !errors.Is(err, ErrClaimLinkRevoked) {
+		t.Fatalf("Lookup of replaced link error = %v, want %v", err, ErrClaimLinkRevoked)
+	}
+
+	current, err := FindForArtificialMember(app, plan.Id, artificialMemberID, now)
+	if err != nil {
+		t.Fatalf("FindForArtificialMember returned error: %v", err)
+	}
+	if current == nil || current.Id != regenerated.Id {
+		t.Fatal("expected regenerated link to be the active link")
+	}
+}
+
+func TestClaimRecordsAttempts(t *testing.T) {
+	app := newMigratedTestApp(t)
+
+	owner := saveTestUser(t, app, "owner")
+	realUser := saveTestUser(t, app, "real")
+	plan := saveTestPlan(t, app, owner.Id)
+	artificialMemberID := "placeholder-member"
+	now := time.Now()
+	saveTestMembership(t, app, plan.Id, artificialMemberID, true, mustDateTime(t, now.AddDate(0, -1, 0)))
+
+	link, err := Ensure(app, plan.Id, artificialMemberID, now)
+	if err != nil {
+		t.Fatalf("Ensure returned error: %v", err)
+	}
+
+	if _, err := Claim(app, link.GetString("token"), owner.Id, now); !errors.Is(err, ErrAlreadyMember) {
+		t.Fatalf("Claim by owner error = %v, want %v", err, ErrAlreadyMember)
+	}
+	if _, err := Claim(app, link.GetString("token"), realUser.Id, now); err != nil {
+		t.Fatalf("Claim returned error: %v", err)
+	}
+
+	attempts, err := FindAttemptsForPlan(app, plan.Id, -1)
+	if err != nil {
+		t.Fatalf("FindAttemptsForPlan returned error: %v", err)
+	}
+	if len(attempts) != 2 {
+		t.Fatalf("recorded %d attempts, want 2", len(attempts))
+	}
+
+	succeeded := 0
+	for _, attempt := range attempts {
+		if attempt.GetString("artificial_name") != "Placeholder" {
+			t.Fatalf("attempt artificial_name = %q, want %q", attempt.GetString("artificial_name"), "Placeholder")
+		}
+		if attempt.GetBool("succeeded") {
+			succeeded++
+			if attempt.GetString("user_id") != realUser.Id {
+				t.Fatalf("successful attempt user_id = %q, want %q", attempt.GetString("user_id"), realUser.Id)
+			}
+		} else if attempt.GetString("reason") != ErrAlreadyMember.Error() {
+			t.Fatalf("failed attempt reason = %q, want %q", attempt.GetString("reason"), ErrAlreadyMember.Error())
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("recorded %d successful attempts, want 1", succeeded)
+	}
+}
+
+func TestMergeArtificialMembershipIntoExistingMember(t *testing.T) {
+	app := newMigratedTestApp(t)
+
+	owner := saveTestUser(t, app, "owner")
+	realUser := saveTestUser(t, app, "grandma")
+	plan := saveTestPlan(t, app, owner.Id)
+
+	artificialMemberID := "placeholder-member"
+	artificialCreated := mustDateTime(t, time.Date(2026, time.January, 15, 10, 30, 0, 0, time.UTC))
+	saveTestMembership(t, app, plan.Id, artificialMemberID, true, artificialCreated)
+	saveTestMembership(t, app, plan.Id, realUser.Id, false, mustDateTime(t, time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC)))
+	payment := saveTestPayment(t, app, plan.Id, artificialMemberID, 1250)
+
+	if _, err := Ensure(app, plan.Id, artificialMemberID, time.Now()); err != nil {
+		t.Fatalf("Ensure returned error: %v", err)
+	}
+
+	if err := MergeArtificialMembership(app.Dao(), plan, artificialMemberID, artificialMemberID, time.Now()); !errors.Is(err, ErrMergeTargetUnavailable) {
+		t.Fatalf("self merge error = %v, want %v", err, ErrMergeTargetUnavailable)
+	}
+
+	if err := MergeArtificialMembership(app.Dao(), plan, artificialMemberID, realUser.Id, time.Now()); err != nil {
+		t.Fatalf("MergeArtificialMembership returned error: %v", err)
+	}
+
+	membership, err := planutil.FindMembershipWithDao(app.Dao(), plan.Id, realUser.Id)
+	if err != nil {
+		t.Fatalf("FindMembershipWithDao returned error: %v", err)
+	}
+	if membership == nil {
+		t.Fatal("expected merged membership to exist")
+	}
+	if got := membership.GetDateTime("created").String(); got != artificialCreated.String() {
+		t.Fatalf("merged membership created = %q, want %q", got, artificialCreated.String())
+	}
+
+	placeholder, err := planutil.FindMembershipWithDao(app.Dao(), plan.Id, artificialMemberID)
+	if err != nil {
+		t.Fatalf("FindMembershipWithDao returned error: %v", err)
+	}
+	if placeholder != nil {
+		t.Fatal("expected artificial membership to be deleted")
+	}
+
+	movedPayment, err := app.Dao().FindRecordById("payments", payment.Id)
+	if err != nil {
+		t.Fatalf("failed to reload payment: %v", err)
+	}
+	if got := movedPayment.GetString("user_id"); got != realUser.Id {
+		t.Fatalf("payment user_id = %q, want %q", got, realUser.Id)
+	}
+
+	links, err := FindAllForPlan(app, plan.Id)
+	if err != nil {
+		t.Fatalf("FindAllForPlan returned error: %v", err)
+	}
+	if len(links) != 0 {
+		t.Fatalf("expected claim links to be deleted, found %d", len(links))
+	}
+}
+
 func newMigratedTestApp(t *testing.T) *pocketbase.PocketBase {
 	t.Helper()
 
@@ -109,7 +394,7 @@ func saveTestPlan(t *testing.T, app *pocketbase.PocketBase, ownerID string) *pbm
 	record := pbmodels.NewRecord(collection)
 	record.Set("name", "Test Family")
 	record.Set("description", "")
-	record.Set("cost", 1000)
+	record.Set("cost", 100000)
 	record.Set("individual_cost", 0)
 	record.Set("owner", ownerID)
 	record.Set("join_code", "ABC123")
@@ -141,6 +426,27 @@ func saveTestMembership(t *testing.T, app *pocketbase.PocketBase, planID, userID
 	return record
 }
 
+func saveTestPayment(t *testing.T, app *pocketbase.PocketBase, planID, userID string, amountCents int64) *pbmodels.Record {
+	t.Helper()
+
+	collection, err := app.Dao().FindCollectionByNameOrId("payments")
+	if err != nil {
+		t.Fatalf("failed to find payments collection: %v", err)
+	}
+
+	record := pbmodels.NewRecord(collection)
+	record.Set("plan_id", planID)
+	record.Set("user_id", userID)
+	record.Set("amount", amountCents)
+	record.Set("date", time.Now())
+	record.Set("status", "approved")
+	if err := app.Dao().SaveRecord(record); err != nil {
+		t.Fatalf("failed to save payment: %v", err)
+	}
+
+	return record
+}
+
 func mustDateTime(t *testing.T, value time.Time) types.DateTime {
 	t.Helper()
 
@@ -0,0 +1,170 @@
+package memberclaim
+
+import (
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/planutil"
+
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// memberFields are the fields, by collection, that tie a plan's records to a member. They follow the
+// member whenever a placeholder membership is handed over to another one.
+var memberFields = []struct {
+	collection string
+	field      string
+}{
+	{collection: "payments", field: "user_id"},
+	{collection: "payments", field: "payer_id"},
+	{collection: billing.AdjustmentsCollection, field: "user_id"},
+	{collection: billing.RecurringClaimsCollection, field: "user_id"},
+}
+
+// MergeArtificialMembership folds a placeholder membership into another active membership of the same plan.
+// The placeholder's payments, adjustments and standing orders move to the target, which keeps the earlier
+// start date along with the placeholder's inactive months from before it joined. The placeholder and its
+// claim links are then removed and the plan's allocations rebuilt as of now.
+func MergeArtificialMembership(txDao *daos.Dao, planRecord *pbmodels.Record, artificialMemberID, targetMemberID string, now time.Time) error {
+	if planRecord == nil {
+		return ErrArtificialMemberUnavailable
+	}
+	if targetMemberID == "" || targetMemberID == artificialMemberID {
+		return ErrMergeTargetUnavailable
+	}
+
+	artificialMembership, err := findArtificialMembershipWithDao(txDao, planRecord.Id, artificialMemberID)
+	if err != nil {
+		return err
+	}
+	if artificialMembership == nil || !artificialMembership.GetDateTime("date_ended").IsZero() {
+		return ErrArtificialMemberUnavailable
+	}
+
+	targetMembership, err := planutil.FindMembershipWithDao(txDao, planRecord.Id, targetMemberID)
+	if err != nil {
+		return err
+	}
+	if targetMembership == nil || !targetMembership.GetDateTime("date_ended").IsZero() {
+		return ErrMergeTargetUnavailable
+	}
+
+	if err := reassignMemberRecordsWithDao(txDao, planRecord.Id, artificialMemberID, targetMemberID); err != nil {
+		return err
+	}
+
+	artificialCreated := artificialMembership.GetDateTime("created")
+	targetCreated := targetMembership.GetDateTime("created").Time()
+	if artificialCreated.Time().Before(targetCreated) {
+		// Until the target joined, the placeholder was the only record of this member, so its
+		// inactive months from then on are the member's.
+		periods := billing.InactivePeriods(targetMembership)
+		periods = append(periods, inactivePeriodsBefore(artificialMembership, targetCreated)...)
+
+		targetMembership.Set("created", artificialCreated)
+		targetMembership.Set("inactive_periods", periods)
+		if err := txDao.SaveRecord(targetMembership); err != nil {
+			return err
+		}
+	}
+
+	if err := txDao.DeleteRecord(artificialMembership); err != nil {
+		return err
+	}
+
+	if err := DeleteForArtificialMemberWithDao(txDao, planRecord.Id, artificialMemberID); err != nil {
+		return err
+	}
+
+	return billing.ReallocatePlanWithDao(txDao, planRecord.Id, now)
+}
+
+// inactivePeriodsBefore returns a membership's inactive periods cut off before the month of joined.
+func inactivePeriodsBefore(membership *pbmodels.Record, joined time.Time) []billing.InactivePeriod {
+	joinedMonth := joined.Format("2006-01")
+	lastMonth := time.Date(joined.Year(), joined.Month()-1, 1, 0, 0, 0, 0, joined.Location()).Format("2006-01")
+
+	periods := []billing.InactivePeriod{}
+	for _, period := range billing.InactivePeriods(membership) {
+		if period.Start >= joinedMonth {
+			continue
+		}
+		if period.End == "" || period.End >= joinedMonth {
+			period.End = lastMonth
+		}
+		periods = append(periods, period)
+	}
+
+	return periods
+}
+
+// reassignMemberRecordsWithDao moves every record of a plan keyed to one member over to another.
+func reassignMemberRecordsWithDao(dao *daos.Dao, planID, fromUserID, toUserID string) error {
+	for _, memberField := range memberFields {
+		filter, err := planutil.BuildEqualsFilter(
+			planutil.FilterTerm{Field: "plan_id", Value: planID},
+			planutil.FilterTerm{Field: memberField.field, Value: fromUserID},
+		)
+		if err != nil {
+			return err
+		}
+
+		records, err := dao.FindRecordsByFilter(
+			memberField.collection,
+			filter.Expression,
+			"",
+			-1,
+			0,
+			filter.Params,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			if memberField.collection == billing.AdjustmentsCollection {
+				if err := releaseGeneratedKindWithDao(dao, record, toUserID); err != nil {
+					return err
+				}
+			}
+
+			record.Set(memberField.field, toUserID)
+			if err := dao.SaveRecord(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// releaseGeneratedKindWithDao turns a generated adjustment into a plain one when the member it moves
+// to already has one of the same kind for that month. Only one generated adjustment of a kind is
+// kept per member and month, and keeping the amount keeps the merged balance whole.
+func releaseGeneratedKindWithDao(dao *daos.Dao, adjustment *pbmodels.Record, toUserID string) error {
+	kind := adjustment.GetString("kind")
+	if kind == "" {
+		return nil
+	}
+
+	filter, err := planutil.BuildEqualsFilter(
+		planutil.FilterTerm{Field: "plan_id", Value: adjustment.GetString("plan_id")},
+		planutil.FilterTerm{Field: "user_id", Value: toUserID},
+		planutil.FilterTerm{Field: "for_month", Value: adjustment.GetString("for_month")},
+		planutil.FilterTerm{Field: "kind", Value: kind},
+	)
+	if err != nil {
+		return err
+	}
+
+	existing, err := dao.FindRecordsByFilter(billing.AdjustmentsCollection, filter.Expression, "", 1, 0, filter.Params)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		adjustment.Set("kind", "")
+	}
+
+	return nil
+}
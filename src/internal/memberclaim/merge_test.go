@@ -0,0 +1,117 @@
+package memberclaim
+
+import (
+	"testing"
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/money"
+	"familyplan/src/internal/testapp"
+)
+
+func TestMergeArtificialMembershipKeepsTheCombinedBalance(t *testing.T) {
+	app := testapp.New(t)
+
+	owner := testapp.SaveUser(t, app, "owner")
+	grandma := testapp.SaveUser(t, app, "grandma")
+	plan := testapp.SavePlan(t, app, owner.Id, "ABC123", 3000)
+	asOf := time.Date(2026, time.March, 20, 0, 0, 0, 0, time.UTC)
+
+	// The owner paused the placeholder when grandma joined with her own account in March.
+	artificialMemberID := "placeholder-member"
+	placeholder := testapp.SaveArtificialMembership(t, app, plan.Id, artificialMemberID, "Grandma", time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC))
+	placeholder.Set("inactive_periods", []billing.InactivePeriod{{Start: "2026-03", Reason: billing.InactiveReasonPaused}})
+	if err := app.Dao().SaveRecord(placeholder); err != nil {
+		t.Fatalf("failed to pause placeholder: %v", err)
+	}
+	testapp.SaveMembership(t, app, plan.Id, grandma.Id, time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC))
+
+	testapp.SavePayment(t, app, plan.Id, artificialMemberID, 1000, time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC), "approved")
+	onBehalf := testapp.SavePayment(t, app, plan.Id, owner.Id, 200, time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC), "approved")
+	onBehalf.Set("payer_id", artificialMemberID)
+	if err := app.Dao().SaveRecord(onBehalf); err != nil {
+		t.Fatalf("failed to save on-behalf payment: %v", err)
+	}
+
+	lateFee, err := billing.CreateAdjustmentWithDao(app.Dao(), billing.Adjustment{
+		PlanID:   plan.Id,
+		UserID:   artificialMemberID,
+		Amount:   money.New(-500, "USD"),
+		Reason:   "Late fee",
+		ForMonth: time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC),
+		Kind:     billing.AdjustmentKindLateFee,
+	})
+	if err != nil {
+		t.Fatalf("CreateAdjustmentWithDao returned error: %v", err)
+	}
+
+	// Grandma's own account was charged a late fee for the same month, which only one generated fee may hold.
+	if _, err := billing.CreateAdjustmentWithDao(app.Dao(), billing.Adjustment{
+		PlanID:   plan.Id,
+		UserID:   grandma.Id,
+		Amount:   money.New(-300, "USD"),
+		Reason:   "Late fee",
+		ForMonth: time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC),
+		Kind:     billing.AdjustmentKindLateFee,
+	}); err != nil {
+		t.Fatalf("CreateAdjustmentWithDao(grandma) returned error: %v", err)
+	}
+
+	standingOrder, err := billing.CreateRecurringClaimWithDao(app.Dao(), billing.RecurringClaim{
+		PlanID:     plan.Id,
+		UserID:     artificialMemberID,
+		Amount:     money.New(1500, "USD"),
+		DayOfMonth: 1,
+		StartMonth: time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("CreateRecurringClaimWithDao returned error: %v", err)
+	}
+
+	if err := billing.ReallocatePlanWithDao(app.Dao(), plan.Id, asOf); err != nil {
+		t.Fatalf("ReallocatePlanWithDao returned error: %v", err)
+	}
+
+	placeholderBefore, err := billing.CalculateMemberBalanceWithDao(app.Dao(), plan.Id, artificialMemberID, asOf)
+	if err != nil {
+		t.Fatalf("CalculateMemberBalanceWithDao(placeholder) returned error: %v", err)
+	}
+	grandmaBefore, err := billing.CalculateMemberBalanceWithDao(app.Dao(), plan.Id, grandma.Id, asOf)
+	if err != nil {
+		t.Fatalf("CalculateMemberBalanceWithDao(grandma) returned error: %v", err)
+	}
+
+	if err := MergeArtificialMembership(app.Dao(), plan, artificialMemberID, grandma.Id, asOf); err != nil {
+		t.Fatalf("MergeArtificialMembership returned error: %v", err)
+	}
+
+	merged, err := billing.CalculateMemberBalanceWithDao(app.Dao(), plan.Id, grandma.Id, asOf)
+	if err != nil {
+		t.Fatalf("CalculateMemberBalanceWithDao(merged) returned error: %v", err)
+	}
+	if want := placeholderBefore.Minor + grandmaBefore.Minor; merged.Minor != want {
+		t.Fatalf("merged balance = %d, want %d + %d = %d", merged.Minor, placeholderBefore.Minor, grandmaBefore.Minor, want)
+	}
+
+	if got := testapp.FindRecord(t, app, "payments", onBehalf.Id).GetString("payer_id"); got != grandma.Id {
+		t.Fatalf("on-behalf payment payer_id = %q, want %q", got, grandma.Id)
+	}
+	movedFee := testapp.FindRecord(t, app, billing.AdjustmentsCollection, lateFee.Id)
+	if got := movedFee.GetString("user_id"); got != grandma.Id {
+		t.Fatalf("late fee user_id = %q, want %q", got, grandma.Id)
+	}
+	if got := movedFee.GetString("kind"); got != "" {
+		t.Fatalf("moved late fee kind = %q, want a plain adjustment beside grandma's own late fee", got)
+	}
+	if got := testapp.FindRecord(t, app, billing.RecurringClaimsCollection, standingOrder.Id).GetString("user_id"); got != grandma.Id {
+		t.Fatalf("standing order user_id = %q, want %q", got, grandma.Id)
+	}
+
+	stale, err := app.Dao().FindRecordsByFilter(billing.AllocationsCollection, "user_id = {:user}", "", -1, 0, map[string]any{"user": artificialMemberID})
+	if err != nil {
+		t.Fatalf("failed to load allocations: %v", err)
+	}
+	if len(stale) != 0 {
+		t.Fatalf("found %d allocations for the removed placeholder, want 0", len(stale))
+	}
+}
@@ -0,0 +1,301 @@
+package money
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// DefaultCurrency is used for plans created before currencies were tracked.
+const DefaultCurrency = "USD"
+
+// symbolAfterLanguages lists languages that conventionally write the currency symbol after the number.
+var symbolAfterLanguages = map[string]bool{
+	"cs": true,
+	"da": true,
+	"de": true,
+	"es": true,
+	"fi": true,
+	"fr": true,
+	"it": true,
+	"nb": true,
+	"pl": true,
+	"pt": true,
+	"ru": true,
+	"sv": true,
+}
+
+// Amount is a quantity of money held in the minor units of its currency.
+// The zero value is zero in the default currency.
+type Amount struct {
+	Minor    int64
+	Currency string
+}
+
+// NormalizeCurrency validates an ISO 4217 code and returns it in canonical form.
+// An empty code resolves to the default currency.
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return DefaultCurrency, nil
+	}
+
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a supported currency", code)
+	}
+
+	return unit.String(), nil
+}
+
+// Exponent returns the number of minor-unit digits for a currency, such as 2 for USD and 0 for JPY.
+func Exponent(code string) int {
+	unit, err := currency.ParseISO(currencyOrDefault(code))
+	if err != nil {
+		return 2
+	}
+
+	scale, _ := currency.Standard.Rounding(unit)
+	return scale
+}
+
+// New builds an amount from minor units.
+func New(minor int64, code string) Amount {
+	return Amount{Minor: minor, Currency: currencyOrDefault(code)}
+}
+
+// CurrencyCode returns the amount's currency, falling back to the default currency.
+func (a Amount) CurrencyCode() string {
+	return currencyOrDefault(a.Currency)
+}
+
+// IsZero reports whether the amount is exactly zero.
+func (a Amount) IsZero() bool {
+	return a.Minor == 0
+}
+
+// IsNegative reports whether the amount is below zero.
+func (a Amount) IsNegative() bool {
+	return a.Minor < 0
+}
+
+// IsPositive reports whether the amount is above zero.
+func (a Amount) IsPositive() bool {
+	return a.Minor > 0
+}
+
+// Neg returns the amount with its sign flipped.
+func (a Amount) Neg() Amount {
+	return Amount{Minor: -a.Minor, Currency: a.Currency}
+}
+
+// Add returns the sum of two amounts in the same currency.
+func (a Amount) Add(b Amount) Amount {
+	return Amount{Minor: a.Minor + b.Minor, Currency: a.Currency}
+}
+
+// Sub returns the difference of two amounts in the same currency.
+func (a Amount) Sub(b Amount) Amount {
+	return Amount{Minor: a.Minor - b.Minor, Currency: a.Currency}
+}
+
+// DivideBy splits the amount into equal parts, rounding to the nearest minor unit.
+func (a Amount) DivideBy(parts int) Amount {
+	return Amount{Minor: SplitEvenly(a.Minor, parts), Currency: a.Currency}
+}
+
+// Convert multiplies the amount by an exchange rate and rounds it into the target currency.
+// The rate is how many units of the target currency one unit of the amount's currency buys.
+func (a Amount) Convert(rate float64, code string) Amount {
+	code = currencyOrDefault(code)
+	scale := math.Pow10(Exponent(code) - Exponent(a.Currency))
+
+	return Amount{Minor: int64(math.Round(float64(a.Minor) * rate * scale)), Currency: code}
+}
+
+// Decimal renders the amount as a plain decimal string, suitable for form inputs.
+func (a Amount) Decimal() string {
+	exponent := Exponent(a.Currency)
+	if exponent == 0 {
+		return strconv.FormatInt(a.Minor, 10)
+	}
+
+	sign := ""
+	minor := a.Minor
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+
+	digits := fmt.Sprintf("%0*d", exponent+1, minor)
+	split := len(digits) - exponent
+	return sign + digits[:split] + "." + digits[split:]
+}
+
+// Format renders the amount with its currency symbol using the locale's number conventions.
+func (a Amount) Format(tag language.Tag) string {
+	code := a.CurrencyCode()
+	printer := message.NewPrinter(tag)
+
+	symbol := code
+	if unit, err := currency.ParseISO(code); err == nil {
+		symbol = printer.Sprint(currency.NarrowSymbol(unit))
+	}
+
+	minor := a.Minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+
+	exponent := Exponent(code)
+	digits := printer.Sprint(number.Decimal(float64(minor)/math.Pow10(exponent), number.Scale(exponent)))
+
+	base, _ := tag.Base()
+	if symbolAfterLanguages[base.String()] {
+		return sign + digits + "\u00a0" + symbol
+	}
+
+	return sign + symbol + digits
+}
+
+// String renders the amount using English number conventions.
+func (a Amount) String() string {
+	return a.Format(language.English)
+}
+
+// Parse reads a decimal string written with the locale's separators into an amount.
+// Grouping separators must split the whole part into groups of three digits, so "1,50" is
+// rejected rather than read as 150, and more fraction digits than the currency allows are rejected.
+func Parse(value, code string, tag language.Tag) (Amount, error) {
+	code, err := NormalizeCurrency(code)
+	if err != nil {
+		return Amount{}, err
+	}
+
+	decimalSeparator, groupSeparator := separators(tag)
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Amount{}, fmt.Errorf("amount is empty")
+	}
+
+	sign := int64(1)
+	if strings.HasPrefix(value, "-") {
+		sign = -1
+		value = strings.TrimPrefix(value, "-")
+	}
+
+	if groupSeparator == " " || groupSeparator == " " {
+		value = strings.ReplaceAll(value, " ", groupSeparator)
+	}
+
+	parts := strings.Split(value, decimalSeparator)
+	if len(parts) > 2 {
+		return Amount{}, fmt.Errorf("amount has too many decimal separators")
+	}
+
+	wholePart, err := ungroup(parts[0], groupSeparator)
+	if err != nil {
+		return Amount{}, err
+	}
+	if wholePart == "" {
+		wholePart = "0"
+	}
+
+	whole, err := strconv.ParseInt(wholePart, 10, 64)
+	if err != nil || whole < 0 {
+		return Amount{}, fmt.Errorf("amount is invalid")
+	}
+
+	exponent := Exponent(code)
+	fraction := ""
+	if len(parts) == 2 {
+		fraction = parts[1]
+	}
+	if len(fraction) > exponent {
+		return Amount{}, fmt.Errorf("amount has more than %d decimal places", exponent)
+	}
+
+	fraction += strings.Repeat("0", exponent-len(fraction))
+	minorPart := int64(0)
+	if fraction != "" {
+		minorPart, err = strconv.ParseInt(fraction, 10, 64)
+		if err != nil || minorPart < 0 {
+			return Amount{}, fmt.Errorf("amount is invalid")
+		}
+	}
+
+	scale := int64(math.Pow10(exponent))
+	return Amount{Minor: sign * (whole*scale + minorPart), Currency: code}, nil
+}
+
+// ParseInput reads an amount submitted from a form.
+// Number inputs always submit a "." decimal separator, so that is tried before the locale's own separators.
+func ParseInput(value, code string, tag language.Tag) (Amount, error) {
+	amount, err := Parse(value, code, language.Und)
+	if err == nil || tag == language.Und {
+		return amount, err
+	}
+
+	return Parse(value, code, tag)
+}
+
+// ungroup removes grouping separators from the whole part of an amount,
+// rejecting separators that are not followed by exactly three digits.
+func ungroup(whole, groupSeparator string) (string, error) {
+	if groupSeparator == "" || !strings.Contains(whole, groupSeparator) {
+		return whole, nil
+	}
+
+	groups := strings.Split(whole, groupSeparator)
+	if groups[0] == "" || len(groups[0]) > 3 {
+		return "", fmt.Errorf("amount has a misplaced grouping separator")
+	}
+	for _, group := range groups[1:] {
+		if len(group) != 3 {
+			return "", fmt.Errorf("amount has a misplaced grouping separator")
+		}
+	}
+
+	return strings.Join(groups, ""), nil
+}
+
+// separators discovers the locale's decimal and grouping separators by formatting a sample number.
+func separators(tag language.Tag) (string, string) {
+	if tag == language.Und {
+		return ".", ","
+	}
+
+	nonDigits := []rune{}
+	for _, r := range message.NewPrinter(tag).Sprint(number.Decimal(1234567.5, number.Scale(1))) {
+		if r < '0' || r > '9' {
+			nonDigits = append(nonDigits, r)
+		}
+	}
+	if len(nonDigits) == 0 {
+		return ".", ","
+	}
+
+	decimalSeparator := string(nonDigits[len(nonDigits)-1])
+	if len(nonDigits) == 1 {
+		return decimalSeparator, ""
+	}
+
+	return decimalSeparator, string(nonDigits[0])
+}
+
+func currencyOrDefault(code string) string {
+	if code == "" {
+		return DefaultCurrency
+	}
+
+	return code
+}
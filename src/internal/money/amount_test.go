@@ -0,0 +1,154 @@
+package money
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestParseUsesLocaleSeparatorsAndCurrencyExponent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		currency string
+		tag      language.Tag
+		want     int64
+		wantErr  bool
+	}{
+		{name: "canonical dollars", input: "12.34", currency: "USD", tag: language.Und, want: 1234},
+		{name: "english grouping", input: "1,234.5", currency: "USD", tag: language.English, want: 123450},
+		{name: "german euros", input: "1.234,56", currency: "EUR", tag: language.German, want: 123456},
+		{name: "french spacing", input: "1 234,5", currency: "EUR", tag: language.French, want: 123450},
+		{name: "yen has no minor units", input: "1500", currency: "JPY", tag: language.Japanese, want: 1500},
+		{name: "yen rejects decimals", input: "15.5", currency: "JPY", tag: language.Und, wantErr: true},
+		{name: "too many decimals", input: "1.999", currency: "USD", tag: language.Und, wantErr: true},
+		{name: "canonical grouping", input: "1,500", currency: "USD", tag: language.Und, want: 150000},
+		{name: "comma is not a canonical decimal", input: "1,50", currency: "USD", tag: language.Und, wantErr: true},
+		{name: "german grouping needs three digits", input: "1.50", currency: "EUR", tag: language.German, wantErr: true},
+		{name: "unknown currency", input: "1", currency: "XYZ1", tag: language.Und, wantErr: true},
+		{name: "empty", input: " ", currency: "USD", tag: language.Und, wantErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Parse(test.input, test.currency, test.tag)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Minor != test.want || got.Currency != test.currency {
+				t.Fatalf("Parse(%q) = %+v, want %d %s", test.input, got, test.want, test.currency)
+			}
+		})
+	}
+}
+
+func TestParseInputFallsBackToTheLocale(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		tag     language.Tag
+		want    int64
+		wantErr bool
+	}{
+		{name: "number input", input: "12.50", tag: language.German, want: 1250},
+		{name: "german decimal comma", input: "1,50", tag: language.German, want: 150},
+		{name: "english decimal comma", input: "1,50", tag: language.English, wantErr: true},
+		{name: "unknown locale", input: "1,50", tag: language.Und, wantErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseInput(test.input, "EUR", test.tag)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Minor != test.want {
+				t.Fatalf("ParseInput(%q) = %+v, want %d", test.input, got, test.want)
+			}
+		})
+	}
+}
+
+func TestAmountFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		amount Amount
+		tag    language.Tag
+		want   string
+	}{
+		{name: "dollars", amount: New(123450, "USD"), tag: language.English, want: "$1,234.50"},
+		{name: "negative dollars", amount: New(-550, "USD"), tag: language.English, want: "-$5.50"},
+		{name: "euros in german", amount: New(123450, "EUR"), tag: language.German, want: "1.234,50\u00a0€"},
+		{name: "yen", amount: New(1500, "JPY"), tag: language.English, want: "¥1,500"},
+		{name: "zero value uses default currency", amount: Amount{}, tag: language.English, want: "$0.00"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := test.amount.Format(test.tag); got != test.want {
+				t.Fatalf("Format() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecimalRespectsExponent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		amount Amount
+		want   string
+	}{
+		{amount: New(1999, "EUR"), want: "19.99"},
+		{amount: New(5, "USD"), want: "0.05"},
+		{amount: New(-250, "USD"), want: "-2.50"},
+		{amount: New(1500, "JPY"), want: "1500"},
+		{amount: New(12345, "KWD"), want: "12.345"},
+	}
+
+	for _, test := range tests {
+		if got := test.amount.Decimal(); got != test.want {
+			t.Fatalf("Decimal(%+v) = %q, want %q", test.amount, got, test.want)
+		}
+	}
+}
+
+func TestConvertRoundsIntoTargetCurrency(t *testing.T) {
+	t.Parallel()
+
+	if got := New(2000, "GBP").Convert(1.17, "EUR"); got != New(2340, "EUR") {
+		t.Fatalf("Convert(GBP->EUR) = %+v, want 23.40 EUR", got)
+	}
+	if got := New(1000, "USD").Convert(151.237, "JPY"); got != New(1512, "JPY") {
+		t.Fatalf("Convert(USD->JPY) = %+v, want 1512 JPY", got)
+	}
+}
@@ -16,6 +16,8 @@ func TestParseCents(t *testing.T) {
 		{name: "two decimals", input: "12.34", want: 1234},
 		{name: "trim spaces", input: " 9.99 ", want: 999},
 		{name: "too many decimals", input: "1.999", wantErr: true},
+		{name: "thousands", input: "1,234.5", want: 123450},
+		{name: "decimal comma", input: "1,50", wantErr: true},
 		{name: "empty", input: "", wantErr: true},
 	}
 
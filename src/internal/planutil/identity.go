@@ -0,0 +1,112 @@
+package planutil
+
+import (
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+// Identity is how a plan member is shown: artificial members only have a name.
+type Identity struct {
+	Username string
+	Name     string
+}
+
+// DisplayName returns the member's name, falling back to their username.
+func (i Identity) DisplayName() string {
+	if i.Name != "" {
+		return i.Name
+	}
+
+	return i.Username
+}
+
+// FindIdentitiesWithDao resolves user ids to identities, preferring the plan's artificial members
+// and falling back to user accounts. Ids that match neither are left out of the map.
+func FindIdentitiesWithDao(dao *daos.Dao, planID string, userIDs []string) (map[string]Identity, error) {
+	identities := make(map[string]Identity, len(userIDs))
+	remainingUserIDs := uniqueNonEmptyStrings(userIDs)
+	if len(remainingUserIDs) == 0 {
+		return identities, nil
+	}
+
+	filter, err := BuildEqualsFilter(
+		FilterTerm{Field: "plan_id", Value: planID},
+		FilterTerm{Field: "is_artificial", Value: true},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	artificialMemberships, err := dao.FindRecordsByFilter(
+		collectionMemberships,
+		filter.Expression,
+		"",
+		-1,
+		0,
+		filter.Params,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	unresolved := make(map[string]struct{}, len(remainingUserIDs))
+	for _, userID := range remainingUserIDs {
+		unresolved[userID] = struct{}{}
+	}
+
+	for _, artificialMembership := range artificialMemberships {
+		userID := artificialMembership.GetString("user_id")
+		if _, ok := unresolved[userID]; !ok {
+			continue
+		}
+
+		identities[userID] = Identity{
+			Name: artificialMembership.GetString("name"),
+		}
+		delete(unresolved, userID)
+	}
+
+	if len(unresolved) == 0 {
+		return identities, nil
+	}
+
+	userRecords, err := dao.FindRecordsByIds("users", mapKeys(unresolved))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, userRecord := range userRecords {
+		identities[userRecord.Id] = Identity{
+			Username: userRecord.GetString("username"),
+			Name:     userRecord.GetString("name"),
+		}
+	}
+
+	return identities, nil
+}
+
+func mapKeys(values map[string]struct{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func uniqueNonEmptyStrings(values []string) []string {
+	unique := make([]string, 0, len(values))
+	seen := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		if _, ok := seen[value]; ok {
+			continue
+		}
+
+		seen[value] = struct{}{}
+		unique = append(unique, value)
+	}
+
+	return unique
+}
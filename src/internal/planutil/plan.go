@@ -3,6 +3,9 @@ package planutil
 import (
 	"database/sql"
 	"errors"
+	"time"
+
+	"familyplan/src/internal/money"
 
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/daos"
@@ -45,11 +48,45 @@ func OwnerID(plan *pbmodels.Record) string {
 	return ownerIDs[0]
 }
 
+// Currency returns the plan's ISO 4217 currency code.
+func Currency(plan *pbmodels.Record) string {
+	if code := plan.GetString("currency"); code != "" {
+		return code
+	}
+
+	return money.DefaultCurrency
+}
+
+// Cost returns the plan's monthly cost, stored in minor units of the plan currency.
+func Cost(plan *pbmodels.Record) money.Amount {
+	return money.New(int64(plan.GetInt("cost")), Currency(plan))
+}
+
+// IndividualCost returns what one person would pay on their own, in minor units of the plan currency.
+func IndividualCost(plan *pbmodels.Record) money.Amount {
+	return money.New(int64(plan.GetInt("individual_cost")), Currency(plan))
+}
+
 // IsOwner reports whether the user owns the plan.
 func IsOwner(plan *pbmodels.Record, userID string) bool {
 	return OwnerID(plan) == userID
 }
 
+// ArchivedAt returns when the plan was archived, or the zero time for a plan in use.
+func ArchivedAt(plan *pbmodels.Record) time.Time {
+	archivedAt := plan.GetDateTime("archived_at")
+	if archivedAt.IsZero() {
+		return time.Time{}
+	}
+
+	return archivedAt.Time()
+}
+
+// IsArchived reports whether the owner has archived the plan. Archived plans are read-only.
+func IsArchived(plan *pbmodels.Record) bool {
+	return !ArchivedAt(plan).IsZero()
+}
+
 // FindMembership returns the membership record for a plan/user pair.
 func FindMembership(app *pocketbase.PocketBase, planID, userID string) (*pbmodels.Record, error) {
 	return FindMembershipWithDao(app.Dao(), planID, userID)
@@ -0,0 +1,424 @@
+// Package seed fills an empty database with demo users, plans, members and payments. The same seed
+// and clock always produce the same records, so the balances they add up to can be asserted.
+package seed
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/memberclaim"
+
+	"github.com/google/uuid"
+	"github.com/pocketbase/pocketbase/daos"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+)
+
+// DefaultSeed is the seed the seed command uses unless told otherwise.
+const DefaultSeed int64 = 1
+
+// Password is the password of every demo user.
+const Password = "password123"
+
+// ErrNotEmpty indicates a database that already has plans, which seeding would mix demo data into.
+var ErrNotEmpty = errors.New("the database already has plans")
+
+// Options controls the generated dataset.
+type Options struct {
+	Seed int64
+	// Now is the clock the dataset is built around: joins, leaves and payments fall in the months
+	// before it, and claim links expire after it.
+	Now time.Time
+}
+
+// Summary describes the records created: the demo usernames and plan join codes, and how many of
+// everything else.
+type Summary struct {
+	Users        []string
+	Plans        []string
+	Memberships  int
+	Payments     int
+	ClaimLinks   int
+	JoinRequests int
+}
+
+// Usernames lists the demo users, who all log in with Password.
+var Usernames = []string{"alice", "bob", "carol", "dave", "erin", "frank"}
+
+type planSpec struct {
+	name        string
+	description string
+	currency    string
+	cost        int64
+	owner       string
+	members     []string
+	artificial  []string
+	// requests are users waiting for the owner to approve them.
+	requests []string
+}
+
+var planSpecs = []planSpec{
+	{
+		name:        "Streaming",
+		description: "The family video streaming plan",
+		currency:    "USD",
+		cost:        2299,
+		owner:       "alice",
+		members:     []string{"bob", "carol", "dave"},
+		artificial:  []string{"Grandma"},
+		requests:    []string{"frank"},
+	},
+	{
+		name:        "Music",
+		description: "Shared music subscription",
+		currency:    "EUR",
+		cost:        1699,
+		owner:       "bob",
+		members:     []string{"alice", "erin"},
+		artificial:  []string{"Kids tablet"},
+	},
+	{
+		name:        "Cloud Storage",
+		description: "2 TB of photo and file storage",
+		currency:    "GBP",
+		cost:        799,
+		owner:       "carol",
+		members:     []string{"dave", "frank", "erin"},
+		artificial:  []string{"Uncle Joe"},
+	},
+}
+
+// planAge is how many months before the clock's month every plan starts.
+const planAge = 12
+
+var paymentMethods = []string{"bank_transfer", "paypal", "cash", "card"}
+
+// RunWithDao creates the demo dataset in one transaction and rebuilds the allocations and late
+// fees derived from it.
+func RunWithDao(dao *daos.Dao, options Options) (Summary, error) {
+	existing, err := dao.FindRecordsByFilter("family_plans", "id != ''", "", 1, 0)
+	if err != nil {
+		return Summary{}, err
+	}
+	if len(existing) > 0 {
+		return Summary{}, ErrNotEmpty
+	}
+
+	now := options.Now.UTC()
+
+	summary := Summary{}
+	err = dao.RunInTransaction(func(txDao *daos.Dao) error {
+		g := &generator{
+			dao:     txDao,
+			rng:     rand.New(rand.NewSource(options.Seed)),
+			now:     now,
+			month:   time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+			summary: &summary,
+			users:   map[string]string{},
+		}
+
+		if err := g.run(); err != nil {
+			return err
+		}
+
+		if err := billing.ReallocateAllWithDao(txDao, now); err != nil {
+			return err
+		}
+
+		return billing.SyncAllLateFeesWithDao(txDao, now)
+	})
+	if err != nil {
+		return Summary{}, err
+	}
+
+	return summary, nil
+}
+
+// generator creates the records. Every random choice, including record ids, comes from rng, so the
+// dataset depends only on the seed and the clock.
+type generator struct {
+	dao     *daos.Dao
+	rng     *rand.Rand
+	now     time.Time
+	month   time.Time
+	summary *Summary
+	// users maps usernames to user ids.
+	users map[string]string
+}
+
+// member is one membership the generator bills payments against.
+type member struct {
+	userID     string
+	artificial bool
+	joined     time.Time
+	ended      time.Time
+}
+
+func (g *generator) run() error {
+	for _, username := range Usernames {
+		if err := g.saveUser(username); err != nil {
+			return err
+		}
+	}
+
+	for _, spec := range planSpecs {
+		if err := g.savePlan(spec); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *generator) saveUser(username string) error {
+	record, err := g.newRecord("users")
+	if err != nil {
+		return err
+	}
+
+	record.Set("username", username)
+	record.Set("created", g.month.AddDate(0, -planAge, 0))
+	if err := record.SetPassword(Password); err != nil {
+		return err
+	}
+	if err := g.dao.SaveRecord(record); err != nil {
+		return err
+	}
+
+	g.users[username] = record.Id
+	g.summary.Users = append(g.summary.Users, username)
+	return nil
+}
+
+func (g *generator) savePlan(spec planSpec) error {
+	started := g.month.AddDate(0, -planAge, 0)
+	ownerID := g.users[spec.owner]
+
+	plan, err := g.newRecord("family_plans")
+	if err != nil {
+		return err
+	}
+
+	joinCode := g.code(6, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+	plan.Set("name", spec.name)
+	plan.Set("description", spec.description)
+	plan.Set("currency", spec.currency)
+	plan.Set("cost", spec.cost)
+	plan.Set("individual_cost", 0)
+	plan.Set("owner", []string{ownerID})
+	plan.Set("join_code", joinCode)
+	plan.Set("created", started)
+	if err := g.dao.SaveRecord(plan); err != nil {
+		return err
+	}
+	g.summary.Plans = append(g.summary.Plans, joinCode)
+
+	if err := g.saveMembership(plan.Id, member{userID: ownerID, joined: started}, ""); err != nil {
+		return err
+	}
+
+	members := make([]member, 0, len(spec.members)+len(spec.artificial))
+	for _, username := range spec.members {
+		members = append(members, g.newMember(g.users[username], false))
+	}
+	for range spec.artificial {
+		// Artificial members get a random UUID in place of a user id, as the app gives them.
+		artificialID, err := uuid.NewRandomFromReader(g.rng)
+		if err != nil {
+			return err
+		}
+		members = append(members, g.newMember(artificialID.String(), true))
+	}
+
+	// One real member of each plan has since left it.
+	leaver := g.rng.Intn(len(spec.members))
+	members[leaver].ended = g.leaveDate(members[leaver].joined)
+
+	for i := range members {
+		name := ""
+		if members[i].artificial {
+			name = spec.artificial[i-len(spec.members)]
+		}
+
+		if err := g.saveMembership(plan.Id, members[i], name); err != nil {
+			return err
+		}
+
+		if members[i].artificial {
+			if err := g.saveClaimLink(plan.Id, members[i].userID); err != nil {
+				return err
+			}
+		}
+
+		if err := g.savePayments(plan, members[i], len(spec.members)+len(spec.artificial)+1); err != nil {
+			return err
+		}
+	}
+
+	for _, username := range spec.requests {
+		request, err := g.newRecord("join_requests")
+		if err != nil {
+			return err
+		}
+
+		request.Set("plan_id", plan.Id)
+		request.Set("user_id", g.users[username])
+		request.Set("created", g.now.AddDate(0, 0, -2))
+		if err := g.dao.SaveRecord(request); err != nil {
+			return err
+		}
+		g.summary.JoinRequests++
+	}
+
+	return nil
+}
+
+// newMember picks when a member joined: between one and ten months before the clock's month.
+func (g *generator) newMember(userID string, artificial bool) member {
+	monthsAgo := 1 + g.rng.Intn(10)
+	return member{
+		userID:     userID,
+		artificial: artificial,
+		joined:     g.month.AddDate(0, -monthsAgo, g.rng.Intn(28)),
+	}
+}
+
+// leaveDate picks a day in a month after joined, up to the clock.
+func (g *generator) leaveDate(joined time.Time) time.Time {
+	joinedMonth := time.Date(joined.Year(), joined.Month(), 1, 0, 0, 0, 0, time.UTC)
+	left := joinedMonth.AddDate(0, 1+g.rng.Intn(monthsBetween(joinedMonth, g.month)), g.rng.Intn(28))
+	if left.After(g.now) {
+		return g.now
+	}
+
+	return left
+}
+
+func (g *generator) saveMembership(planID string, m member, name string) error {
+	record, err := g.newRecord("memberships")
+	if err != nil {
+		return err
+	}
+
+	record.Set("plan_id", planID)
+	record.Set("user_id", m.userID)
+	record.Set("is_artificial", m.artificial)
+	record.Set("name", name)
+	record.Set("created", m.joined)
+	if !m.ended.IsZero() {
+		record.Set("date_ended", m.ended)
+	}
+	if err := g.dao.SaveRecord(record); err != nil {
+		return err
+	}
+
+	g.summary.Memberships++
+	return nil
+}
+
+func (g *generator) saveClaimLink(planID, artificialMemberID string) error {
+	record, err := g.newRecord(memberclaim.CollectionName)
+	if err != nil {
+		return err
+	}
+
+	record.Set("plan_id", planID)
+	record.Set("artificial_member_id", artificialMemberID)
+	record.Set("token", g.code(32, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"))
+	record.Set("expires_at", g.now.Add(memberclaim.DefaultTTL))
+	if err := g.dao.SaveRecord(record); err != nil {
+		return err
+	}
+
+	g.summary.ClaimLinks++
+	return nil
+}
+
+// savePayments pays for most of a member's months, rounding an even share of the cost up to a whole
+// unit. Some months go unpaid, and some payments are still pending or were rejected.
+func (g *generator) savePayments(plan *pbmodels.Record, m member, payers int) error {
+	share := plan.GetInt("cost") / payers
+	amount := (share + 99) / 100 * 100
+
+	lastMonth := g.month
+	if !m.ended.IsZero() {
+		lastMonth = m.ended
+	}
+
+	joinedMonth := time.Date(m.joined.Year(), m.joined.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for month := joinedMonth; !month.After(lastMonth); month = month.AddDate(0, 1, 0) {
+		status := "approved"
+		switch roll := g.rng.Intn(10); {
+		case roll == 0:
+			continue
+		case roll == 1:
+			status = "rejected"
+		case roll == 2 || month.Equal(g.month):
+			status = "pending"
+		}
+
+		paid := month.AddDate(0, 0, g.rng.Intn(28))
+		if paid.After(g.now) {
+			paid = g.now
+		}
+
+		method := paymentMethods[g.rng.Intn(len(paymentMethods))]
+		if m.artificial {
+			// The owner records what artificial members hand over.
+			method = "cash"
+			if status == "pending" {
+				status = "approved"
+			}
+		}
+
+		record, err := g.newRecord("payments")
+		if err != nil {
+			return err
+		}
+
+		record.Set("plan_id", plan.Id)
+		record.Set("user_id", m.userID)
+		record.Set("amount", amount)
+		record.Set("status", status)
+		record.Set("date", paid)
+		record.Set("for_month", month)
+		record.Set("method", method)
+		if method == "bank_transfer" {
+			record.Set("reference", "FP-"+g.code(6, "0123456789"))
+		}
+		record.Set("created", paid)
+		if err := g.dao.SaveRecord(record); err != nil {
+			return err
+		}
+
+		g.summary.Payments++
+	}
+
+	return nil
+}
+
+// newRecord starts a record with an id drawn from rng, since member shares are split by sorted id.
+func (g *generator) newRecord(collectionName string) (*pbmodels.Record, error) {
+	collection, err := g.dao.FindCollectionByNameOrId(collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	record := pbmodels.NewRecord(collection)
+	record.SetId(g.code(15, "abcdefghijklmnopqrstuvwxyz0123456789"))
+	return record, nil
+}
+
+func (g *generator) code(length int, charset string) string {
+	value := make([]byte, length)
+	for i := range value {
+		value[i] = charset[g.rng.Intn(len(charset))]
+	}
+
+	return string(value)
+}
+
+func monthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
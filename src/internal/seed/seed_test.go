@@ -0,0 +1,134 @@
+package seed
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"familyplan/src/internal/billing"
+	"familyplan/src/internal/integrity"
+	"familyplan/src/internal/planutil"
+	"familyplan/src/internal/testapp"
+
+	"github.com/pocketbase/pocketbase"
+)
+
+func TestRunWithDaoIsDeterministic(t *testing.T) {
+	now := time.Date(2026, time.March, 15, 12, 0, 0, 0, time.UTC)
+
+	first := testapp.New(t)
+	summary, err := RunWithDao(first.Dao(), Options{Seed: DefaultSeed, Now: now})
+	if err != nil {
+		t.Fatalf("RunWithDao returned error: %v", err)
+	}
+	if len(summary.Users) != len(Usernames) || len(summary.Plans) != len(planSpecs) || summary.ClaimLinks != 3 || summary.JoinRequests != 1 {
+		t.Fatalf("summary = %+v, want every user and plan, a claim link per artificial member and one join request", summary)
+	}
+
+	second := testapp.New(t)
+	if _, err := RunWithDao(second.Dao(), Options{Seed: DefaultSeed, Now: now}); err != nil {
+		t.Fatalf("RunWithDao returned error: %v", err)
+	}
+
+	// Everything is placed relative to the clock's month, so the balances would be the same at any
+	// other time too.
+	want := map[string]int64{
+		"Streaming/bob":           -1145,
+		"Streaming/carol":         -4236,
+		"Streaming/dave":          80,
+		"Streaming/Grandma":       -2247,
+		"Music/alice":             -1112,
+		"Music/erin":              -350,
+		"Music/Kids tablet":       -1463,
+		"Cloud Storage/dave":      -440,
+		"Cloud Storage/frank":     164,
+		"Cloud Storage/erin":      -706,
+		"Cloud Storage/Uncle Joe": -707,
+	}
+	for _, app := range []*pocketbase.PocketBase{first, second} {
+		balances := seededBalances(t, app, now)
+		if len(balances) != len(want) {
+			t.Fatalf("balances = %v, want %v", balances, want)
+		}
+		for key, balance := range want {
+			if balances[key] != balance {
+				t.Fatalf("balance %s = %d, want %d", key, balances[key], balance)
+			}
+		}
+	}
+}
+
+func TestRunWithDaoCoversEveryPaymentStatusWithoutIntegrityProblems(t *testing.T) {
+	app := testapp.New(t)
+	now := time.Date(2026, time.January, 2, 9, 0, 0, 0, time.UTC)
+
+	if _, err := RunWithDao(app.Dao(), Options{Seed: DefaultSeed, Now: now}); err != nil {
+		t.Fatalf("RunWithDao returned error: %v", err)
+	}
+
+	for _, status := range []string{"pending", "approved", "rejected"} {
+		payments, err := app.Dao().FindRecordsByFilter("payments", "status = {:status}", "", 1, 0, map[string]any{"status": status})
+		if err != nil {
+			t.Fatalf("failed to load payments: %v", err)
+		}
+		if len(payments) == 0 {
+			t.Fatalf("no %s payments seeded", status)
+		}
+	}
+
+	ended, err := app.Dao().FindRecordsByFilter("memberships", "date_ended != ''", "", -1, 0)
+	if err != nil {
+		t.Fatalf("failed to load memberships: %v", err)
+	}
+	if len(ended) != len(planSpecs) {
+		t.Fatalf("ended memberships = %d, want one per plan", len(ended))
+	}
+
+	problems, err := integrity.CheckWithDao(app.Dao(), now)
+	if err != nil {
+		t.Fatalf("CheckWithDao returned error: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Fatalf("problems = %+v, want none", problems)
+	}
+
+	if _, err := RunWithDao(app.Dao(), Options{Seed: DefaultSeed, Now: now}); !errors.Is(err, ErrNotEmpty) {
+		t.Fatalf("second RunWithDao error = %v, want %v", err, ErrNotEmpty)
+	}
+}
+
+// seededBalances returns every member's balance as of now in minor units, keyed by plan name and member. Owners
+// are left out, since nobody bills them.
+func seededBalances(t *testing.T, app *pocketbase.PocketBase, now time.Time) map[string]int64 {
+	t.Helper()
+
+	memberships, err := app.Dao().FindRecordsByFilter("memberships", "id != ''", "", -1, 0)
+	if err != nil {
+		t.Fatalf("failed to load memberships: %v", err)
+	}
+
+	balances := map[string]int64{}
+	for _, membership := range memberships {
+		plan, err := app.Dao().FindRecordById("family_plans", membership.GetString("plan_id"))
+		if err != nil {
+			t.Fatalf("failed to load plan: %v", err)
+		}
+
+		if planutil.OwnerID(plan) == membership.GetString("user_id") {
+			continue
+		}
+
+		name := membership.GetString("name")
+		if user, err := app.Dao().FindRecordById("users", membership.GetString("user_id")); err == nil {
+			name = user.Username()
+		}
+
+		balance, err := billing.CalculateMemberBalanceWithDao(app.Dao(), plan.Id, membership.GetString("user_id"), now)
+		if err != nil {
+			t.Fatalf("CalculateMemberBalanceWithDao returned error: %v", err)
+		}
+		balances[plan.GetString("name")+"/"+name] = balance.Minor
+	}
+
+	return balances
+}
@@ -0,0 +1,175 @@
+// Package testapp builds migrated PocketBase apps for tests and saves the records they start from.
+package testapp
+
+import (
+	"testing"
+	"time"
+
+	_ "familyplan/migrations"
+
+	"github.com/pocketbase/pocketbase"
+	pbmigrations "github.com/pocketbase/pocketbase/migrations"
+	pbmodels "github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/migrate"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Password is the password of every user SaveUser creates.
+const Password = "password123"
+
+// New returns an app with its own data directory and every migration applied.
+func New(t testing.TB) *pocketbase.PocketBase {
+	t.Helper()
+
+	app := pocketbase.NewWithConfig(pocketbase.Config{
+		DefaultDataDir: t.TempDir(),
+	})
+
+	if err := app.Bootstrap(); err != nil {
+		t.Fatalf("failed to bootstrap app: %v", err)
+	}
+
+	runner, err := migrate.NewRunner(app.DB(), pbmigrations.AppMigrations)
+	if err != nil {
+		t.Fatalf("failed to create migrations runner: %v", err)
+	}
+	if _, err := runner.Up(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	if err := app.Bootstrap(); err != nil {
+		t.Fatalf("failed to refresh app after migrations: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := app.ResetBootstrapState(); err != nil {
+			t.Fatalf("failed to reset app bootstrap state: %v", err)
+		}
+	})
+
+	return app
+}
+
+// SaveRecord saves a record with the given fields.
+func SaveRecord(t testing.TB, app *pocketbase.PocketBase, collectionName string, fields map[string]any) *pbmodels.Record {
+	t.Helper()
+
+	collection, err := app.Dao().FindCollectionByNameOrId(collectionName)
+	if err != nil {
+		t.Fatalf("failed to find %s collection: %v", collectionName, err)
+	}
+
+	record := pbmodels.NewRecord(collection)
+	for field, value := range fields {
+		record.Set(field, value)
+	}
+	if err := app.Dao().SaveRecord(record); err != nil {
+		t.Fatalf("failed to save %s record: %v", collectionName, err)
+	}
+
+	return record
+}
+
+// SaveUser saves a user who logs in with Password.
+func SaveUser(t testing.TB, app *pocketbase.PocketBase, username string) *pbmodels.Record {
+	t.Helper()
+
+	return SaveNamedUser(t, app, username, "")
+}
+
+// SaveNamedUser saves a user with a display name, who logs in with Password.
+func SaveNamedUser(t testing.TB, app *pocketbase.PocketBase, username, name string) *pbmodels.Record {
+	t.Helper()
+
+	collection, err := app.Dao().FindCollectionByNameOrId("users")
+	if err != nil {
+		t.Fatalf("failed to find users collection: %v", err)
+	}
+
+	record := pbmodels.NewRecord(collection)
+	record.Set("username", username)
+	record.Set("name", name)
+	if err := record.SetPassword(Password); err != nil {
+		t.Fatalf("failed to set password for user %q: %v", username, err)
+	}
+	if err := app.Dao().SaveRecord(record); err != nil {
+		t.Fatalf("failed to save user %q: %v", username, err)
+	}
+
+	return record
+}
+
+// SavePlan saves a plan called Streaming with no individual cost to compare against.
+func SavePlan(t testing.TB, app *pocketbase.PocketBase, ownerID, joinCode string, costCents int64) *pbmodels.Record {
+	t.Helper()
+
+	return SaveRecord(t, app, "family_plans", map[string]any{
+		"name":            "Streaming",
+		"cost":            costCents,
+		"individual_cost": 0,
+		"owner":           ownerID,
+		"join_code":       joinCode,
+	})
+}
+
+// SaveMembership saves a real user's membership that started at created.
+func SaveMembership(t testing.TB, app *pocketbase.PocketBase, planID, userID string, created time.Time) *pbmodels.Record {
+	t.Helper()
+
+	return SaveRecord(t, app, "memberships", map[string]any{
+		"plan_id":       planID,
+		"user_id":       userID,
+		"is_artificial": false,
+		"created":       DateTime(t, created),
+	})
+}
+
+// SaveArtificialMembership saves a named member without an account that started at created.
+func SaveArtificialMembership(t testing.TB, app *pocketbase.PocketBase, planID, memberID, name string, created time.Time) *pbmodels.Record {
+	t.Helper()
+
+	return SaveRecord(t, app, "memberships", map[string]any{
+		"plan_id":       planID,
+		"user_id":       memberID,
+		"is_artificial": true,
+		"name":          name,
+		"created":       DateTime(t, created),
+	})
+}
+
+// SavePayment saves a payment made on date for the member userID.
+func SavePayment(t testing.TB, app *pocketbase.PocketBase, planID, userID string, amountCents int64, date time.Time, status string) *pbmodels.Record {
+	t.Helper()
+
+	return SaveRecord(t, app, "payments", map[string]any{
+		"plan_id": planID,
+		"user_id": userID,
+		"amount":  amountCents,
+		"date":    date,
+		"status":  status,
+	})
+}
+
+// FindRecord loads a record that the test expects to exist.
+func FindRecord(t testing.TB, app *pocketbase.PocketBase, collectionName, id string) *pbmodels.Record {
+	t.Helper()
+
+	record, err := app.Dao().FindRecordById(collectionName, id)
+	if err != nil {
+		t.Fatalf("failed to find %s record %s: %v", collectionName, id, err)
+	}
+
+	return record
+}
+
+// DateTime converts a time for a record's date field.
+func DateTime(t testing.TB, value time.Time) types.DateTime {
+	t.Helper()
+
+	result, err := types.ParseDateTime(value)
+	if err != nil {
+		t.Fatalf("failed to parse datetime: %v", err)
+	}
+
+	return result
+}
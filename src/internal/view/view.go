@@ -1,7 +1,6 @@
 package view
 
 import (
-	"fmt"
 	"html/template"
 	"strings"
 	"sync"
@@ -17,12 +16,10 @@ import (
 
 // Funcs holds shared template helpers.
 var Funcs = template.FuncMap{
-	"upper": strings.ToUpper,
-	"lower": strings.ToLower,
-	"title": cases.Title(language.English).String,
-	"formatMoney": func(amount float64) string {
-		return fmt.Sprintf("$%.2f", money.Normalize(amount))
-	},
+	"upper":       strings.ToUpper,
+	"lower":       strings.ToLower,
+	"title":       cases.Title(language.English).String,
+	"formatMoney": formatMoney(language.English),
 	"slice": func(s string, i, j int) string {
 		if i < 0 {
 			i = 0
@@ -59,6 +56,21 @@ var Funcs = template.FuncMap{
 	},
 }
 
+// supportedLocales are the locales money is formatted for; the first entry is the fallback.
+var supportedLocales = []language.Tag{
+	language.English,
+	language.BritishEnglish,
+	language.German,
+	language.French,
+	language.Spanish,
+	language.Italian,
+	language.Dutch,
+	language.Portuguese,
+	language.Japanese,
+}
+
+var localeMatcher = language.NewMatcher(supportedLocales)
+
 var (
 	templateCacheMu sync.RWMutex
 	templateCache   = map[string]*template.Template{}
@@ -78,7 +90,7 @@ func RenderPage(c echo.Context, page string, data map[string]interface{}) error
 		setDefault(data, "userId", session.UserID)
 	}
 
-	tmpl, err := loadTemplate(page)
+	tmpl, err := loadLocalizedTemplate(page, RequestLocale(c))
 	if err != nil {
 		return err
 	}
@@ -86,6 +98,12 @@ func RenderPage(c echo.Context, page string, data map[string]interface{}) error
 	return tmpl.ExecuteTemplate(c.Response().Writer, "layout", data)
 }
 
+func formatMoney(locale language.Tag) func(money.Amount) string {
+	return func(amount money.Amount) string {
+		return amount.Format(locale)
+	}
+}
+
 func setDefault(data map[string]interface{}, key string, value interface{}) {
 	if _, exists := data[key]; exists {
 		return
@@ -111,15 +129,35 @@ func toFloat(value interface{}) (float64, bool) {
 	}
 }
 
+// RequestLocale picks the supported locale that best matches the request's Accept-Language header.
+// Pages format money in it and forms read amounts typed in it.
+func RequestLocale(c echo.Context) language.Tag {
+	tags, _, err := language.ParseAcceptLanguage(c.Request().Header.Get("Accept-Language"))
+	if err != nil || len(tags) == 0 {
+		return supportedLocales[0]
+	}
+
+	_, index, _ := localeMatcher.Match(tags...)
+	return supportedLocales[index]
+}
+
 func loadTemplate(page string) (*template.Template, error) {
+	return loadLocalizedTemplate(page, supportedLocales[0])
+}
+
+func loadLocalizedTemplate(page string, locale language.Tag) (*template.Template, error) {
+	cacheKey := page + "|" + locale.String()
+
 	templateCacheMu.RLock()
-	cached := templateCache[page]
+	cached := templateCache[cacheKey]
 	templateCacheMu.RUnlock()
 	if cached != nil {
 		return cached, nil
 	}
 
-	tmpl, err := template.New("layout").Funcs(Funcs).ParseFS(
+	tmpl, err := template.New("layout").Funcs(Funcs).Funcs(template.FuncMap{
+		"formatMoney": formatMoney(locale),
+	}).ParseFS(
 		assets.TemplatesFS,
 		"templates/layout.html",
 		"templates/"+page,
@@ -129,8 +167,8 @@ func loadTemplate(page string) (*template.Template, error) {
 	}
 
 	templateCacheMu.Lock()
-	if cached = templateCache[page]; cached == nil {
-		templateCache[page] = tmpl
+	if cached = templateCache[cacheKey]; cached == nil {
+		templateCache[cacheKey] = tmpl
 		cached = tmpl
 	}
 	templateCacheMu.Unlock()
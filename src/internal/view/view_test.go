@@ -8,9 +8,12 @@ import (
 	"strings"
 	"testing"
 
+	"familyplan/src/internal/audit"
 	"familyplan/src/internal/domain"
+	"familyplan/src/internal/money"
 
 	"github.com/labstack/echo/v5"
+	"golang.org/x/text/language"
 )
 
 func TestRenderPageUsesSessionDefaults(t *testing.T) {
@@ -109,17 +112,41 @@ func TestLoadTemplateCachesTemplates(t *testing.T) {
 	}
 }
 
+func TestRequestLocaleMatchesAcceptLanguage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		header string
+		want   language.Tag
+	}{
+		{header: "", want: language.English},
+		{header: "de-DE,de;q=0.9,en;q=0.8", want: language.German},
+		{header: "fr-CA", want: language.French},
+		{header: "zz", want: language.English},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", test.header)
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+
+		if got := RequestLocale(c); got != test.want {
+			t.Fatalf("RequestLocale(%q) = %v, want %v", test.header, got, test.want)
+		}
+	}
+}
+
 func TestTemplateFuncsHandleFormattingAndMath(t *testing.T) {
 	t.Parallel()
 
-	formatMoney := Funcs["formatMoney"].(func(float64) string)
+	formatMoney := Funcs["formatMoney"].(func(money.Amount) string)
 	slice := Funcs["slice"].(func(string, int, int) string)
 	div := Funcs["div"].(func(interface{}, interface{}) float64)
 	mul := Funcs["mul"].(func(float64, float64) float64)
 	sub := Funcs["sub"].(func(float64, float64) float64)
 	toFloat64 := Funcs["float64"].(func(int) float64)
 
-	if got := formatMoney(12.345); got != "$12.35" {
+	if got := formatMoney(money.New(1235, "USD")); got != "$12.35" {
 		t.Fatalf("formatMoney() = %q, want %q", got, "$12.35")
 	}
 	if got := slice("abcdef", 1, 4); got != "bcd" {
@@ -162,31 +189,79 @@ func TestLoadTemplatePlanDetails(t *testing.T) {
 		"userId":       "owner-1",
 		"is_owner":     true,
 		"is_member":    true,
-		"user_balance": 0.0,
+		"user_balance": money.New(0, "USD"),
 		"plan": domain.FamilyPlan{
 			ID:             "plan-1",
 			Name:           "Test Plan",
 			Description:    "Plan description",
-			Cost:           12,
-			IndividualCost: 20,
+			Currency:       "USD",
+			Cost:           money.New(1200, "USD"),
+			IndividualCost: money.New(2000, "USD"),
+			DueDay:         5,
+			LateFeeDays:    3,
+			LateFeeAmount:  money.New(200, "USD"),
+			LateFeePercent: 10,
 			Owner:          "owner-1",
 			JoinCode:       "ABC123",
 		},
 		"members": []domain.Member{
 			{ID: "owner-1", Username: "owner", Name: "Owner"},
-			{ID: "member-1", Username: "member", Name: "Member", Balance: -4.5},
-			{ID: "artificial-1", Name: "Offline Person", IsArtificial: true},
+			{ID: "member-1", Username: "member", Name: "Member", Balance: money.New(-450, "USD")},
+			{ID: "member-2", Username: "newcomer", Name: "Newcomer", Balance: money.New(0, "USD"), FreeReason: "trial", FreeUntil: "May 2026"},
+			{ID: "artificial-1", Name: "Offline Person", IsArtificial: true, Paused: true, PausedFrom: "June 2026"},
+		},
+		"former_members": []domain.Member{
+			{ID: "former-1", Username: "former", Name: "Former", Balance: money.New(-750, "USD"), DateEnded: "2026-03-10 00:00:00.000Z"},
+		},
+		"claim_links": map[string]domain.ClaimLink{
+			"artificial-1": {URL: "http://example.com/claim-member/token", ExpiresAt: "2026-04-09 00:00:00.000Z"},
+		},
+		"claim_attempts": []domain.ClaimAttempt{
+			{ArtificialName: "Offline Person", Username: "stranger", Reason: "member claim link expired", AttemptedAt: "2026-04-08 00:00:00.000Z"},
 		},
 		"total_members":   3,
 		"join_requests":   []domain.JoinRequest{{UserID: "request-1", Username: "joiner", Name: "Joiner", RequestedAt: "2026-04-02 00:00:00Z"}},
 		"pending_request": false,
 		"pending_payments": []domain.Payment{
-			{ID: "payment-1", UserID: "member-1", Amount: 4.5, Date: "2026-04-02", Status: "pending", Name: "Member"},
+			{ID: "payment-1", UserID: "member-1", Amount: money.New(450, "USD"), Date: "2026-04-02", Status: "pending", Name: "Member"},
 		},
 		"user_payments":      []domain.Payment{},
 		"existingMembership": nil,
 		"all_payments": []domain.Payment{
-			{ID: "payment-2", UserID: "member-1", Amount: 4.5, Date: "2026-04-02", Status: "approved", Name: "Member"},
+			{ID: "payment-2", UserID: "member-1", Amount: money.New(450, "USD"), Date: "2026-04-02", Status: "approved", Name: "Member", PayerID: "owner-1", PayerName: "Owner"},
+			{ID: "payment-4", UserID: "member-1", Amount: money.New(600, "USD"), Date: "2026-04-05", Status: "approved", Name: "Member", Method: "paypal", MethodLabel: "PayPal", Reference: "PP-7781"},
+			{ID: "payment-3", UserID: "member-1", Amount: money.New(2340, "USD"), OriginalAmount: money.New(2000, "GBP"), ExchangeRate: 1.17, IsConverted: true, Date: "2026-04-03", Status: "approved", Name: "Member"},
+		},
+		"exchange_rates": []domain.ExchangeRate{
+			{Base: "GBP", Quote: "USD", Rate: 1.17, Source: "manual"},
+		},
+		"activity": []domain.AuditEvent{
+			{Action: "plan.updated", Label: "Plan settings changed", Actor: "Owner", OccurredAt: "2026-04-06 09:30:00.000Z", Changes: []domain.AuditChange{
+				{Field: "cost", Before: "1000", After: "1200"},
+			}},
+			{Action: "payment.approved", Label: "Payment approved", Actor: "Owner", Member: "Member", OccurredAt: "2026-04-02 10:00:00.000Z"},
+		},
+		"activity_filter":     audit.Filter{Category: "payment"},
+		"activity_categories": []domain.AuditCategory{{Value: "plan", Label: "Plan settings"}, {Value: "payment", Label: "Payments"}},
+		"coverage": []domain.MemberCoverage{
+			{MemberID: "member-1", Name: "Member", Months: []domain.CoverageMonth{
+				{Month: "2026-03", Label: "Mar 2026", Due: money.New(600, "USD"), Covered: money.New(600, "USD"), Status: "paid"},
+				{Month: "2026-04", Label: "Apr 2026", Due: money.New(600, "USD"), Covered: money.New(200, "USD"), Status: "partial"},
+			}},
+		},
+		"adjustments": []domain.Adjustment{
+			{ID: "adjustment-1", Amount: money.New(500, "USD"), Reason: "Promo month", ForMonth: "April 2026", AllMembers: true},
+			{ID: "adjustment-2", UserID: "member-1", Name: "Member", Amount: money.New(-300, "USD"), Reason: "Extra screen", ForMonth: "April 2026"},
+			{ID: "adjustment-3", UserID: "member-1", Name: "Member", Amount: money.New(-250, "USD"), Reason: "Late fee: March 2026 unpaid 3 days after its due date", ForMonth: "March 2026", LateFee: true},
+		},
+		"payment_methods": []domain.PaymentMethodOption{
+			{Method: "bank_transfer", Label: "Bank transfer", Accepted: true, Offered: true, Instructions: "IBAN DE00 1234"},
+			{Method: "paypal", Label: "PayPal", Accepted: true, Offered: true},
+			{Method: "cash", Label: "Cash"},
+		},
+		"payment_method_filter": "paypal",
+		"recurring_claims": []domain.RecurringClaim{
+			{ID: "recurring-1", UserID: "member-1", Name: "Member", Amount: money.New(600, "USD"), DayOfMonth: 10, StartMonth: "January 2026", AutoApprove: true},
 		},
 		"member_payments_pagination": domain.MemberPaymentsPagination{
 			CurrentPage: 1,
@@ -195,8 +270,8 @@ func TestLoadTemplatePlanDetails(t *testing.T) {
 			HasNext:     true,
 			NextPage:    2,
 		},
-		"total_payments":  4.5,
-		"total_savings":   24.0,
+		"total_payments":  money.New(450, "USD"),
+		"total_savings":   money.New(2400, "USD"),
 		"plan_age_days":   7,
 		"isAuthenticated": true,
 		"username":        "owner",
@@ -213,9 +288,49 @@ func TestLoadTemplatePlanDetails(t *testing.T) {
 		"Test Plan",
 		"Members",
 		"Pending Payment Claims",
+		"Former Members",
+		"write-off-member",
+		"revoke-member-claim-link",
+		"Claim Link Activity",
 		"Join Requests",
 		"Transfer Membership",
-		"member_payments_page=2#member-payments",
+		"merge-member",
+		"Paid by Owner",
+		"beneficiary_id",
+		"member_payments_page=2&payment_method=paypal#member-payments",
+		"1 GBP = 1.17 USD",
+		"paid £20.00 at 1.17",
+		"exchange-rate",
+		"add-adjustment",
+		"delete-adjustment",
+		"Extra screen",
+		"Free trial through May 2026",
+		"grant-grace",
+		"Paused from June 2026",
+		"resume-member",
+		"pause-member",
+		"Payment Coverage",
+		"Apr 2026: $2.00 of $6.00",
+		"trial_months",
+		"due_day",
+		"late_fee_percent",
+		"waive-adjustment",
+		"Standing Orders",
+		"Day 10 from January 2026",
+		"Require approval",
+		"PP-7781",
+		"Save Payment Methods",
+		"IBAN DE00 1234",
+		"/ABC123/export/payments.csv",
+		"/ABC123/export/charges.csv",
+		"/ABC123/export/archive.json",
+		"Member Statement",
+		`name="as_of"`,
+		"/ABC123/archive",
+		"Plan settings changed",
+		"Payment approved <span class=\"text-gray-500 font-normal\">for Member</span>",
+		`<option value="payment" selected>`,
+		"activity_member",
 	} {
 		if !strings.Contains(rendered, expected) {
 			t.Fatalf("rendered template missing %q", expected)
@@ -240,7 +355,7 @@ func TestLoadTemplateFamilyPlans(t *testing.T) {
 				ID:           "plan-1",
 				Name:         "Test Plan",
 				Description:  "Plan description",
-				Cost:         12,
+				Cost:         money.New(1200, "USD"),
 				Owner:        "owner-1",
 				JoinCode:     "ABC123",
 				MembersCount: 2,
@@ -262,6 +377,60 @@ func TestLoadTemplateFamilyPlans(t *testing.T) {
 		"Create New Family Plan",
 		"Join Existing Plan",
 		"go to url '/family-plans'",
+		"/households",
+		"/family-plans/export?format=ofx",
+		"/family-plans/import",
+	} {
+		if !strings.Contains(rendered, expected) {
+			t.Fatalf("rendered template missing %q", expected)
+		}
+	}
+}
+
+func TestLoadTemplateHouseholdDetails(t *testing.T) {
+	resetTemplateCache()
+	t.Cleanup(resetTemplateCache)
+
+	tmpl, err := loadTemplate("household_details.html")
+	if err != nil {
+		t.Fatalf("loadTemplate(household_details.html) error = %v", err)
+	}
+
+	data := map[string]interface{}{
+		"title": "Smith Household",
+		"household": domain.Household{
+			ID:        "household-1",
+			Name:      "Smith Household",
+			Owner:     "owner-1",
+			Plans:     []domain.FamilyPlan{{ID: "plan-1", Name: "Streaming", JoinCode: "ABC123"}},
+			PlanCount: 1,
+		},
+		"is_owner": true,
+		"positions": []domain.HouseholdPosition{
+			{PersonID: "owner-1", Name: "Owner", Net: money.New(1250, "USD")},
+			{PersonID: "member-1", Name: "Member", Net: money.New(-1250, "USD")},
+		},
+		"transfers": []domain.SettleUpTransfer{
+			{FromName: "Member", ToName: "Owner", Amount: money.New(1250, "USD")},
+		},
+		"available_plans": []domain.FamilyPlan{{ID: "plan-2", Name: "Music"}},
+		"isAuthenticated": true,
+		"username":        "owner",
+		"name":            "Owner",
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&out, "layout", data); err != nil {
+		t.Fatalf("ExecuteTemplate(layout) error = %v", err)
+	}
+
+	rendered := out.String()
+	for _, expected := range []string{
+		"Smith Household",
+		"Net Positions",
+		"Owes $12.50",
+		"Settle Up",
+		"/households/household-1/add-plan",
 	} {
 		if !strings.Contains(rendered, expected) {
 			t.Fatalf("rendered template missing %q", expected)